@@ -3,7 +3,11 @@ package main
 import (
 	"TugasAkhir/config"
 	"TugasAkhir/routes"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+	"TugasAkhir/utils/document"
 	"TugasAkhir/utils/fcm"
+	"TugasAkhir/utils/search"
 	"TugasAkhir/utils/storage"
 	"context"
 	"errors"
@@ -33,7 +37,10 @@ func main() {
 
 	config.ConnectDB()
 	storage.InitS3Client()
+	utils.InitJWTKeys()
+	document.InitSigner()
 	fcm.InitializeFCM() // [FIX] Init FCM after env loaded
+	search.InitIndex()
 	app := fiber.New()
 
 	app.Use(requestid.New())
@@ -49,6 +56,7 @@ func main() {
 	go func() {
 		log.Println("🚀 API running on :8080")
 		go fcm.StartNotifierConsumer(ctx)
+		go services.NewSearchService(config.DB, search.DefaultIndex()).StartIndexer(ctx)
 		if err := app.Listen(":8080"); err != nil && !errors.Is(err, net.ErrClosed) {
 			log.Fatalf("fiber server error: %v", err)
 		}
@@ -65,5 +73,10 @@ func main() {
 	if err := app.ShutdownWithContext(shutdownCtx); err != nil {
 		log.Printf("error during server shutdown: %v", err)
 	}
+	if idx := search.DefaultIndex(); idx != nil {
+		if err := idx.Close(); err != nil {
+			log.Printf("error closing search index: %v", err)
+		}
+	}
 	log.Println("✅ server gracefully stopped")
 }
@@ -3,18 +3,83 @@ package main
 import (
 	"TugasAkhir/config"
 	"TugasAkhir/models"
+	"TugasAkhir/services"
 	"log"
+
+	"gorm.io/gorm"
 )
 
 func main() {
 	db := config.ConnectDB()
+	if err := hashLegacyRefreshTokens(db); err != nil {
+		log.Fatalf("Migrating refresh tokens failed: %v", err)
+	}
 	if err := db.AutoMigrate(
+		&models.Unit{},
 		&models.User{},
 		&models.Letter{},
 		&models.PasswordResetToken{},
+		&models.UserSession{},
 		&models.RefreshToken{},
+		&models.LetterVerification{},
+		&models.LetterTemplate{},
+		&models.LetterTembusan{},
+		&models.LetterFileAccess{},
+		&models.Notification{},
+		&models.IdempotencyKey{},
+		&models.Permission{},
+		&models.RoleDefinition{},
+		&models.UserRoleAssignment{},
+		&models.LetterHistory{},
+		&models.UserInvitation{},
+		&models.UserTwoFactor{},
+		&models.UserRecoveryCode{},
+		&models.TwoFactorEnrolmentToken{},
+		&models.LoginThrottle{},
+		&models.AuthEvent{},
+		&models.UserIdentity{},
+		&models.SSOLoginState{},
+		&models.APIKey{},
+		&models.PasswordHistory{},
+		&models.SyncLetterDelivery{},
 	); err != nil {
 		log.Fatalf("AutoMigrate failed: %v", err)
 	}
+	if err := restoreDeletedUsers(db); err != nil {
+		log.Fatalf("Restoring deleted users failed: %v", err)
+	}
+	// Role bawaan & katalog permission (idempoten)
+	if err := services.SeedRBAC(db); err != nil {
+		log.Fatalf("Seeding roles failed: %v", err)
+	}
 	log.Println("✅ Migration completed")
 }
+
+// hashLegacyRefreshTokens - refresh_tokens versi lama menyimpan token mentah di
+// kolom token. Token tersebut di-hash ke token_hash (tetap bisa dipakai) lalu
+// kolom lamanya dihapus sebelum AutoMigrate membuat index baru.
+func hashLegacyRefreshTokens(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&models.RefreshToken{}) || !m.HasColumn(&models.RefreshToken{}, "token") {
+		return nil
+	}
+	if !m.HasColumn(&models.RefreshToken{}, "token_hash") {
+		if err := m.AddColumn(&models.RefreshToken{}, "TokenHash"); err != nil {
+			return err
+		}
+	}
+	if err := db.Exec("UPDATE refresh_tokens SET token_hash = SHA2(token, 256)").Error; err != nil {
+		return err
+	}
+	return m.DropColumn(&models.RefreshToken{}, "token")
+}
+
+// restoreDeletedUsers - versi lama menghapus (soft delete) user sehingga nama
+// pembuat/verifikator hilang dari surat. User tersebut dikembalikan sebagai
+// user nonaktif; mereka tetap tidak bisa login.
+func restoreDeletedUsers(db *gorm.DB) error {
+	return db.Exec(
+		"UPDATE users SET status = ?, deactivated_at = deleted_at, deleted_at = NULL WHERE deleted_at IS NOT NULL",
+		models.UserStatusInactive,
+	).Error
+}
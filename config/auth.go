@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuthConfig mengatur pendaftaran akun dan login. Akun baru dibuat admin lewat
+// undangan; pendaftaran mandiri (POST /api/auth/register) mati kecuali diaktifkan.
+type AuthConfig struct {
+	AllowSelfRegistration bool
+	SelfRegistrationRoles []string // Role yang boleh dipilih saat daftar mandiri
+	InvitationTTL         time.Duration
+	InvitationURL         string
+	TwoFactorIssuer       string // Nama akun yang tampil di aplikasi authenticator
+
+	// Throttling login. Setelah LoginMaxAttempts kegagalan beruntun (per akun)
+	// atau LoginMaxAttemptsPerIP (per IP) dalam LoginAttemptWindow, login dikunci
+	// LoginLockout; setiap penguncian berikutnya dua kali lebih lama sampai
+	// LoginMaxLockout.
+	LoginMaxAttempts      int
+	LoginMaxAttemptsPerIP int
+	LoginAttemptWindow    time.Duration
+	LoginLockout          time.Duration
+	LoginMaxLockout       time.Duration
+}
+
+func LoadAuthConfig() AuthConfig {
+	allow, _ := strconv.ParseBool(os.Getenv("ALLOW_SELF_REGISTRATION"))
+
+	roles := []string{"staf_program", "staf_lembaga"}
+	if raw := strings.TrimSpace(os.Getenv("SELF_REGISTRATION_ROLES")); raw != "" {
+		roles = nil
+		for _, role := range strings.Split(raw, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	ttl := 72 * time.Hour
+	if raw := strings.TrimSpace(os.Getenv("INVITATION_TTL")); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			ttl = parsed
+		}
+	}
+
+	invitationURL := strings.TrimSpace(os.Getenv("INVITATION_URL"))
+	if invitationURL == "" {
+		invitationURL = "/api/auth/accept-invitation"
+	}
+
+	issuer := strings.TrimSpace(os.Getenv("TWO_FACTOR_ISSUER"))
+	if issuer == "" {
+		issuer = "Digital Mail"
+	}
+
+	return AuthConfig{
+		AllowSelfRegistration: allow,
+		SelfRegistrationRoles: roles,
+		InvitationTTL:         ttl,
+		InvitationURL:         invitationURL,
+		TwoFactorIssuer:       issuer,
+
+		LoginMaxAttempts:      envPositiveInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginMaxAttemptsPerIP: envPositiveInt("LOGIN_MAX_ATTEMPTS_PER_IP", 20),
+		LoginAttemptWindow:    envPositiveDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		LoginLockout:          envPositiveDuration("LOGIN_LOCKOUT", time.Minute),
+		LoginMaxLockout:       envPositiveDuration("LOGIN_MAX_LOCKOUT", time.Hour),
+	}
+}
+
+func envPositiveInt(key string, fallback int) int {
+	if raw := strings.TrimSpace(os.Getenv(key)); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func envPositiveDuration(key string, fallback time.Duration) time.Duration {
+	if raw := strings.TrimSpace(os.Getenv(key)); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// CanSelfRegisterAs - role boleh dipilih saat pendaftaran mandiri
+func (c AuthConfig) CanSelfRegisterAs(role string) bool {
+	if !c.AllowSelfRegistration {
+		return false
+	}
+	for _, allowed := range c.SelfRegistrationRoles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
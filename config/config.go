@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -24,6 +25,30 @@ func Validate() error {
 		return fmt.Errorf("email configuration: %w", err)
 	}
 
+	if err := ValidateSigningConfig(); err != nil {
+		return fmt.Errorf("signing configuration: %w", err)
+	}
+
+	if err := ValidateVerificationConfig(); err != nil {
+		return fmt.Errorf("verification configuration: %w", err)
+	}
+
+	if err := ValidateSearchConfig(); err != nil {
+		return fmt.Errorf("search configuration: %w", err)
+	}
+
+	if err := ValidateAuthConfig(); err != nil {
+		return fmt.Errorf("auth configuration: %w", err)
+	}
+
+	if err := ValidateSSOConfig(); err != nil {
+		return fmt.Errorf("sso configuration: %w", err)
+	}
+
+	if err := ValidatePasswordConfig(); err != nil {
+		return fmt.Errorf("password configuration: %w", err)
+	}
+
 	return nil
 }
 
@@ -47,9 +72,26 @@ func ValidateDatabaseConfig() error {
 }
 
 // ValidateJWTConfig ensures JWT environment variables are set and valid.
+// Either an HS256 secret or a private key file must be configured; key files
+// must be readable.
 func ValidateJWTConfig() error {
-	if strings.TrimSpace(os.Getenv("JWT_SECRET")) == "" {
-		return fmt.Errorf("JWT_SECRET environment variable is not set")
+	privateKeyFile := strings.TrimSpace(os.Getenv("JWT_PRIVATE_KEY_FILE"))
+	if strings.TrimSpace(os.Getenv("JWT_SECRET")) == "" && privateKeyFile == "" {
+		return fmt.Errorf("JWT_SECRET or JWT_PRIVATE_KEY_FILE environment variable must be set")
+	}
+
+	publicKeyFiles := splitList(os.Getenv("JWT_PUBLIC_KEY_FILES"), ",")
+	if privateKeyFile == "" && len(publicKeyFiles) > 0 {
+		return fmt.Errorf("JWT_PUBLIC_KEY_FILES requires JWT_PRIVATE_KEY_FILE")
+	}
+	files := publicKeyFiles
+	if privateKeyFile != "" {
+		files = append([]string{privateKeyFile}, files...)
+	}
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("cannot read %s: %w", file, err)
+		}
 	}
 
 	if ttl := strings.TrimSpace(os.Getenv("JWT_ACCESS_TTL")); ttl != "" {
@@ -89,3 +131,210 @@ func ValidateEmailConfig() error {
 
 	return nil
 }
+
+// ValidateSigningConfig ensures the optional document signing certificate and
+// key are configured together and point to readable files.
+func ValidateSigningConfig() error {
+	certFile := strings.TrimSpace(os.Getenv("SIGNING_CERT_FILE"))
+	keyFile := strings.TrimSpace(os.Getenv("SIGNING_KEY_FILE"))
+
+	if certFile == "" && keyFile == "" {
+		return nil
+	}
+	if certFile == "" || keyFile == "" {
+		return fmt.Errorf("SIGNING_CERT_FILE and SIGNING_KEY_FILE must be set together")
+	}
+
+	for _, file := range []string{certFile, keyFile} {
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("cannot read %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateVerificationConfig ensures the public verification URL encoded in
+// the QR code is absolute and the expose flag is a valid boolean.
+func ValidateVerificationConfig() error {
+	if raw := strings.TrimSpace(os.Getenv("VERIFICATION_BASE_URL")); raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("VERIFICATION_BASE_URL must be an absolute http(s) URL")
+		}
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("VERIFICATION_EXPOSE_BODY")); raw != "" {
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return fmt.Errorf("invalid VERIFICATION_EXPOSE_BODY: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateSearchConfig ensures the optional search indexer interval is a
+// positive duration.
+func ValidateSearchConfig() error {
+	if raw := strings.TrimSpace(os.Getenv("SEARCH_INDEX_INTERVAL")); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid SEARCH_INDEX_INTERVAL: %w", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("SEARCH_INDEX_INTERVAL must be positive")
+		}
+	}
+	return nil
+}
+
+// ValidateAuthConfig ensures the self-registration switch is a valid boolean,
+// the invitation lifetime is a positive duration and the login throttling
+// limits are positive.
+func ValidateAuthConfig() error {
+	if raw := strings.TrimSpace(os.Getenv("ALLOW_SELF_REGISTRATION")); raw != "" {
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return fmt.Errorf("invalid ALLOW_SELF_REGISTRATION: %w", err)
+		}
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("INVITATION_TTL")); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid INVITATION_TTL: %w", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("INVITATION_TTL must be positive")
+		}
+	}
+
+	for _, key := range []string{"LOGIN_MAX_ATTEMPTS", "LOGIN_MAX_ATTEMPTS_PER_IP"} {
+		if raw := strings.TrimSpace(os.Getenv(key)); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			if n <= 0 {
+				return fmt.Errorf("%s must be positive", key)
+			}
+		}
+	}
+	for _, key := range []string{"LOGIN_ATTEMPT_WINDOW", "LOGIN_LOCKOUT", "LOGIN_MAX_LOCKOUT"} {
+		if raw := strings.TrimSpace(os.Getenv(key)); raw != "" {
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			if d <= 0 {
+				return fmt.Errorf("%s must be positive", key)
+			}
+		}
+	}
+
+	cfg := LoadAuthConfig()
+	if cfg.LoginMaxLockout < cfg.LoginLockout {
+		return fmt.Errorf("LOGIN_MAX_LOCKOUT must not be shorter than LOGIN_LOCKOUT")
+	}
+	return nil
+}
+
+// ValidateSSOConfig ensures the optional LDAP and OIDC providers have every
+// required value once enabled, and that the group-to-role mapping is
+// well-formed.
+func ValidateSSOConfig() error {
+	if raw := strings.TrimSpace(os.Getenv("LDAP_URL")); raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+			return fmt.Errorf("LDAP_URL must be an ldap:// or ldaps:// URL")
+		}
+		if strings.TrimSpace(os.Getenv("LDAP_BASE_DN")) == "" {
+			return fmt.Errorf("LDAP_BASE_DN is required when LDAP_URL is set")
+		}
+		if strings.TrimSpace(os.Getenv("LDAP_BIND_DN")) != "" && os.Getenv("LDAP_BIND_PASSWORD") == "" {
+			return fmt.Errorf("LDAP_BIND_PASSWORD is required when LDAP_BIND_DN is set")
+		}
+		if caFile := strings.TrimSpace(os.Getenv("LDAP_CA_FILE")); caFile != "" {
+			if _, err := os.Stat(caFile); err != nil {
+				return fmt.Errorf("cannot read %s: %w", caFile, err)
+			}
+		}
+		if raw := strings.TrimSpace(os.Getenv("LDAP_TIMEOUT")); raw != "" {
+			if d, err := time.ParseDuration(raw); err != nil || d <= 0 {
+				return fmt.Errorf("LDAP_TIMEOUT must be a positive duration")
+			}
+		}
+	}
+
+	if strings.TrimSpace(os.Getenv("OIDC_ISSUER_URL")) != "" {
+		if strings.TrimSpace(os.Getenv("OIDC_CLIENT_ID")) == "" {
+			return fmt.Errorf("OIDC_CLIENT_ID is required when OIDC_ISSUER_URL is set")
+		}
+		for _, key := range []string{"OIDC_ISSUER_URL", "OIDC_ADMIN_REDIRECT_URL"} {
+			u, err := url.Parse(strings.TrimSpace(os.Getenv(key)))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%s must be an absolute http(s) URL", key)
+			}
+		}
+		// Callback aplikasi mobile boleh berupa custom scheme (RFC 8252)
+		if raw := strings.TrimSpace(os.Getenv("OIDC_REDIRECT_URL")); raw != "" {
+			if u, err := url.Parse(raw); err != nil || u.Scheme == "" {
+				return fmt.Errorf("OIDC_REDIRECT_URL must be an absolute URI")
+			}
+		}
+	}
+
+	if _, err := ParseGroupRoles(os.Getenv("SSO_GROUP_ROLES")); err != nil {
+		return fmt.Errorf("invalid SSO_GROUP_ROLES: %w", err)
+	}
+
+	return nil
+}
+
+// ValidatePasswordConfig ensures the password policy values are well-formed
+// and that the optional blocklist file is readable.
+func ValidatePasswordConfig() error {
+	if raw := strings.TrimSpace(os.Getenv("PASSWORD_MIN_LENGTH")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid PASSWORD_MIN_LENGTH: %w", err)
+		}
+		if n < 8 || n > 72 {
+			return fmt.Errorf("PASSWORD_MIN_LENGTH must be between 8 and 72")
+		}
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("PASSWORD_HISTORY")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid PASSWORD_HISTORY: %w", err)
+		}
+		if n < 0 || n > 24 {
+			return fmt.Errorf("PASSWORD_HISTORY must be between 0 and 24")
+		}
+	}
+
+	for _, key := range []string{"PASSWORD_REQUIRE_UPPER", "PASSWORD_REQUIRE_LOWER", "PASSWORD_REQUIRE_DIGIT", "PASSWORD_REQUIRE_SYMBOL"} {
+		if raw := strings.TrimSpace(os.Getenv(key)); raw != "" {
+			if _, err := strconv.ParseBool(raw); err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+		}
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("PASSWORD_MAX_AGE")); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid PASSWORD_MAX_AGE: %w", err)
+		}
+		if d < 0 {
+			return fmt.Errorf("PASSWORD_MAX_AGE must not be negative")
+		}
+	}
+
+	if path := strings.TrimSpace(os.Getenv("PASSWORD_BLOCKLIST_FILE")); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("PASSWORD_BLOCKLIST_FILE: %w", err)
+		}
+	}
+	return nil
+}
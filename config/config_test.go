@@ -1,6 +1,11 @@
 package config
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
 
 func TestValidateDatabaseConfigMissing(t *testing.T) {
 	t.Setenv("DB_HOST", "")
@@ -84,3 +89,239 @@ func TestValidateAggregatesSections(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestValidateSigningConfigOptional(t *testing.T) {
+	t.Setenv("SIGNING_CERT_FILE", "")
+	t.Setenv("SIGNING_KEY_FILE", "")
+
+	if err := ValidateSigningConfig(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateSigningConfigRequiresBothFiles(t *testing.T) {
+	t.Setenv("SIGNING_CERT_FILE", "/etc/digital-mail/org.crt")
+	t.Setenv("SIGNING_KEY_FILE", "")
+
+	if err := ValidateSigningConfig(); err == nil {
+		t.Fatal("expected validation error when only the certificate is configured")
+	}
+}
+
+func TestValidateSigningConfigMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "org.crt")
+	if err := os.WriteFile(certFile, []byte("cert"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SIGNING_CERT_FILE", certFile)
+	t.Setenv("SIGNING_KEY_FILE", filepath.Join(dir, "missing.key"))
+
+	if err := ValidateSigningConfig(); err == nil {
+		t.Fatal("expected validation error for missing key file")
+	}
+}
+
+func TestValidateVerificationConfigRejectsRelativeURL(t *testing.T) {
+	t.Setenv("VERIFICATION_BASE_URL", "/verify")
+	t.Setenv("VERIFICATION_EXPOSE_BODY", "")
+
+	if err := ValidateVerificationConfig(); err == nil {
+		t.Fatal("expected validation error for relative verification URL")
+	}
+}
+
+func TestValidateVerificationConfigSuccess(t *testing.T) {
+	t.Setenv("VERIFICATION_BASE_URL", "https://surat.example.org/verify")
+	t.Setenv("VERIFICATION_EXPOSE_BODY", "true")
+
+	if err := ValidateVerificationConfig(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateSearchConfigInvalidInterval(t *testing.T) {
+	t.Setenv("SEARCH_INDEX_INTERVAL", "-5s")
+
+	if err := ValidateSearchConfig(); err == nil {
+		t.Fatal("expected validation error for negative search index interval")
+	}
+}
+
+func TestValidateAuthConfigInvalidSwitch(t *testing.T) {
+	t.Setenv("ALLOW_SELF_REGISTRATION", "maybe")
+	t.Setenv("INVITATION_TTL", "")
+
+	if err := ValidateAuthConfig(); err == nil {
+		t.Fatal("expected validation error for invalid ALLOW_SELF_REGISTRATION")
+	}
+}
+
+func TestValidateAuthConfigInvalidInvitationTTL(t *testing.T) {
+	t.Setenv("ALLOW_SELF_REGISTRATION", "")
+	t.Setenv("INVITATION_TTL", "0s")
+
+	if err := ValidateAuthConfig(); err == nil {
+		t.Fatal("expected validation error for non-positive INVITATION_TTL")
+	}
+}
+
+func TestLoadAuthConfigSelfRegistrationDisabledByDefault(t *testing.T) {
+	t.Setenv("ALLOW_SELF_REGISTRATION", "")
+	t.Setenv("SELF_REGISTRATION_ROLES", "")
+
+	cfg := LoadAuthConfig()
+	if cfg.AllowSelfRegistration || cfg.CanSelfRegisterAs("staf_program") {
+		t.Fatal("expected self-registration to be disabled by default")
+	}
+}
+
+func TestLoadAuthConfigSelfRegistrationRoles(t *testing.T) {
+	t.Setenv("ALLOW_SELF_REGISTRATION", "true")
+	t.Setenv("SELF_REGISTRATION_ROLES", "staf_program, pengurus")
+
+	cfg := LoadAuthConfig()
+	if !cfg.CanSelfRegisterAs("pengurus") {
+		t.Fatal("expected pengurus to be allowed")
+	}
+	if cfg.CanSelfRegisterAs("direktur") {
+		t.Fatal("expected direktur to be rejected")
+	}
+}
+
+func TestValidateAuthConfigInvalidLoginThrottle(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "0")
+
+	if err := ValidateAuthConfig(); err == nil {
+		t.Fatal("expected validation error for non-positive LOGIN_MAX_ATTEMPTS")
+	}
+}
+
+func TestValidateAuthConfigLockoutOrder(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "")
+	t.Setenv("LOGIN_LOCKOUT", "10m")
+	t.Setenv("LOGIN_MAX_LOCKOUT", "5m")
+
+	if err := ValidateAuthConfig(); err == nil {
+		t.Fatal("expected validation error when LOGIN_MAX_LOCKOUT is shorter than LOGIN_LOCKOUT")
+	}
+}
+
+func TestValidateJWTConfigPrivateKeyWithoutSecret(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "jwt.pem")
+	if err := os.WriteFile(keyFile, []byte("key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_PRIVATE_KEY_FILE", keyFile)
+	t.Setenv("JWT_PUBLIC_KEY_FILES", "")
+
+	if err := ValidateJWTConfig(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateJWTConfigMissingPublicKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "jwt.pem")
+	if err := os.WriteFile(keyFile, []byte("key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_PRIVATE_KEY_FILE", keyFile)
+	t.Setenv("JWT_PUBLIC_KEY_FILES", keyFile+", "+filepath.Join(dir, "old.pem"))
+
+	if err := ValidateJWTConfig(); err == nil {
+		t.Fatal("expected validation error for missing public key file")
+	}
+}
+
+func TestValidateJWTConfigPublicKeysRequirePrivateKey(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("JWT_PRIVATE_KEY_FILE", "")
+	t.Setenv("JWT_PUBLIC_KEY_FILES", "/etc/digital-mail/jwt-old.pem")
+
+	if err := ValidateJWTConfig(); err == nil {
+		t.Fatal("expected validation error for public keys without a private key")
+	}
+}
+
+func TestValidateSSOConfigLDAPRequiresBaseDN(t *testing.T) {
+	t.Setenv("LDAP_URL", "ldaps://ldap.yayasan.org")
+	t.Setenv("LDAP_BASE_DN", "")
+
+	if err := ValidateSSOConfig(); err == nil {
+		t.Fatal("expected validation error for LDAP without base DN")
+	}
+}
+
+func TestValidateSSOConfigOIDCRequiresAdminRedirect(t *testing.T) {
+	t.Setenv("OIDC_ISSUER_URL", "https://sso.yayasan.org/realms/staf")
+	t.Setenv("OIDC_CLIENT_ID", "digital-mail")
+	t.Setenv("OIDC_ADMIN_REDIRECT_URL", "/admin/login/sso/callback")
+
+	if err := ValidateSSOConfig(); err == nil {
+		t.Fatal("expected validation error for relative admin redirect URL")
+	}
+
+	t.Setenv("OIDC_ADMIN_REDIRECT_URL", "https://mail.yayasan.org/admin/login/sso/callback")
+	t.Setenv("OIDC_REDIRECT_URL", "id.yayasan.digitalmail:/oauth")
+	if err := ValidateSSOConfig(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseGroupRolesKeepsDNGroups(t *testing.T) {
+	mappings, err := ParseGroupRoles("cn=direksi,ou=groups,dc=yayasan,dc=org=direktur; staf = staf_program")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mappings) != 2 ||
+		mappings[0] != (GroupRole{Group: "cn=direksi,ou=groups,dc=yayasan,dc=org", Role: "direktur"}) ||
+		mappings[1] != (GroupRole{Group: "staf", Role: "staf_program"}) {
+		t.Fatalf("unexpected mappings: %+v", mappings)
+	}
+
+	if _, err := ParseGroupRoles("direksi"); err == nil {
+		t.Fatal("expected error for mapping without role")
+	}
+}
+
+func TestValidatePasswordConfigMinLengthRange(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_LENGTH", "6")
+
+	if err := ValidatePasswordConfig(); err == nil {
+		t.Fatal("expected validation error for minimum length below 8")
+	}
+}
+
+func TestValidatePasswordConfigMissingBlocklist(t *testing.T) {
+	t.Setenv("PASSWORD_BLOCKLIST_FILE", filepath.Join(t.TempDir(), "missing.txt"))
+
+	if err := ValidatePasswordConfig(); err == nil {
+		t.Fatal("expected validation error for missing blocklist file")
+	}
+}
+
+func TestPasswordConfigExpired(t *testing.T) {
+	t.Setenv("PASSWORD_MAX_AGE", "2160h")
+	cfg := LoadPasswordConfig()
+
+	now := time.Now()
+	recent := now.Add(-24 * time.Hour)
+	if cfg.Expired(&recent, now, now) {
+		t.Fatal("password changed yesterday should not be expired")
+	}
+	if !cfg.Expired(nil, now.Add(-100*24*time.Hour), now) {
+		t.Fatal("password without change date should expire from the fallback time")
+	}
+
+	t.Setenv("PASSWORD_MAX_AGE", "")
+	if LoadPasswordConfig().Expired(nil, time.Time{}, now) {
+		t.Fatal("passwords must not expire when PASSWORD_MAX_AGE is unset")
+	}
+}
@@ -3,12 +3,19 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 )
 
 type JWTConfig struct {
-	SecretKey       []byte
+	// SecretKey - secret HS256 lama. Jika PrivateKeyFile diisi, secret hanya
+	// dipakai untuk memverifikasi token HS256 yang terbit sebelum migrasi.
+	SecretKey []byte
+	// PrivateKeyFile - private key PEM (RSA atau Ed25519) untuk menandatangani token
+	PrivateKeyFile string
+	// PublicKeyFiles - public key PEM lama yang masih diterima saat rotasi key
+	PublicKeyFiles  []string
 	Issuer          string
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
@@ -23,8 +30,9 @@ func LoadJWTConfig() JWTConfig {
 	jwtOnce.Do(func() {
 
 		secret := os.Getenv("JWT_SECRET")
-		if secret == "" {
-			log.Fatal("JWT_SECRET environment variable is not set")
+		privateKeyFile := strings.TrimSpace(os.Getenv("JWT_PRIVATE_KEY_FILE"))
+		if secret == "" && privateKeyFile == "" {
+			log.Fatal("JWT_SECRET or JWT_PRIVATE_KEY_FILE environment variable must be set")
 		}
 
 		issuer := os.Getenv("JWT_ISSUER")
@@ -50,8 +58,15 @@ func LoadJWTConfig() JWTConfig {
 			}
 		}
 
+		var secretKey []byte
+		if secret != "" {
+			secretKey = []byte(secret)
+		}
+
 		jwtConfig = JWTConfig{
-			SecretKey:       []byte(secret),
+			SecretKey:       secretKey,
+			PrivateKeyFile:  privateKeyFile,
+			PublicKeyFiles:  splitList(os.Getenv("JWT_PUBLIC_KEY_FILES"), ","),
 			Issuer:          issuer,
 			AccessTokenTTL:  ttl,
 			RefreshTokenTTL: refreshTTL,
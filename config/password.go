@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"TugasAkhir/utils/password"
+)
+
+// PasswordConfig - kebijakan password lokal. Tidak berlaku untuk akun yang
+// login lewat LDAP / OIDC karena password-nya dikelola direktori.
+type PasswordConfig struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	History       int           // 0 = password lama boleh dipakai ulang
+	MaxAge        time.Duration // 0 = password tidak kedaluwarsa
+	BlocklistFile string        // Daftar password bocor tambahan (opsional)
+}
+
+func LoadPasswordConfig() PasswordConfig {
+	cfg := PasswordConfig{
+		MinLength:     envPositiveInt("PASSWORD_MIN_LENGTH", 8),
+		RequireUpper:  envBool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:  envBool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:  envBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol: envBool("PASSWORD_REQUIRE_SYMBOL", false),
+		History:       5,
+		BlocklistFile: strings.TrimSpace(os.Getenv("PASSWORD_BLOCKLIST_FILE")),
+	}
+	if raw := strings.TrimSpace(os.Getenv("PASSWORD_HISTORY")); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed >= 0 {
+			cfg.History = parsed
+		}
+	}
+	if raw := strings.TrimSpace(os.Getenv("PASSWORD_MAX_AGE")); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			cfg.MaxAge = parsed
+		}
+	}
+	return cfg
+}
+
+// Policy - aturan komposisi untuk pemeriksaan password baru
+func (c PasswordConfig) Policy() password.Policy {
+	return password.Policy{
+		MinLength:     c.MinLength,
+		RequireUpper:  c.RequireUpper,
+		RequireLower:  c.RequireLower,
+		RequireDigit:  c.RequireDigit,
+		RequireSymbol: c.RequireSymbol,
+		History:       c.History,
+	}
+}
+
+// Expired - password yang diganti pada changedAt sudah melewati MaxAge.
+// Password tanpa tanggal ganti (akun lama) dihitung dari fallback.
+func (c PasswordConfig) Expired(changedAt *time.Time, fallback, now time.Time) bool {
+	if c.MaxAge <= 0 {
+		return false
+	}
+	since := fallback
+	if changedAt != nil {
+		since = *changedAt
+	}
+	return now.Sub(since) >= c.MaxAge
+}
+
+func envBool(key string, fallback bool) bool {
+	if raw := strings.TrimSpace(os.Getenv(key)); raw != "" {
+		if parsed, err := strconv.ParseBool(raw); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
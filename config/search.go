@@ -0,0 +1,31 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// SearchConfig mengatur index full-text (bleve) yang disimpan di disk server
+type SearchConfig struct {
+	IndexPath     string
+	IndexInterval time.Duration // Jeda polling indexer untuk surat yang berubah
+}
+
+func LoadSearchConfig() SearchConfig {
+	path := os.Getenv("SEARCH_INDEX_PATH")
+	if path == "" {
+		path = "data/search.bleve"
+	}
+
+	interval := 30 * time.Second
+	if raw := os.Getenv("SEARCH_INDEX_INTERVAL"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			interval = d
+		}
+	}
+
+	return SearchConfig{
+		IndexPath:     path,
+		IndexInterval: interval,
+	}
+}
@@ -0,0 +1,31 @@
+package config
+
+import "os"
+
+// SigningConfig berisi lokasi sertifikat & private key organisasi yang dipakai
+// untuk tanda tangan digital (PAdES) surat keluar yang disetujui Direktur.
+type SigningConfig struct {
+	CertFile string
+	KeyFile  string
+	Reason   string
+	Location string
+}
+
+func LoadSigningConfig() SigningConfig {
+	reason := os.Getenv("SIGNING_REASON")
+	if reason == "" {
+		reason = "Disetujui oleh Direktur"
+	}
+
+	return SigningConfig{
+		CertFile: os.Getenv("SIGNING_CERT_FILE"),
+		KeyFile:  os.Getenv("SIGNING_KEY_FILE"),
+		Reason:   reason,
+		Location: os.Getenv("SIGNING_LOCATION"),
+	}
+}
+
+// Enabled bernilai true jika sertifikat dan key sudah dikonfigurasi.
+func (c SigningConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// SSOConfig mengatur login lewat direktori yayasan. LDAP dipakai langsung oleh
+// form login (email + password direktori); OIDC lewat redirect ke identity
+// provider. Keduanya opsional.
+type SSOConfig struct {
+	LDAP LDAPConfig
+	OIDC OIDCConfig
+
+	// GroupRoles memetakan grup direktori ke role, urutan = prioritas (grup
+	// pertama yang cocok menentukan role). User tanpa grup yang cocok mendapat
+	// DefaultRole; jika kosong, akun baru tidak dibuat.
+	GroupRoles  []GroupRole
+	DefaultRole string
+}
+
+// GroupRole - satu entri SSO_GROUP_ROLES
+type GroupRole struct {
+	Group string // Nama grup OIDC atau DN grup LDAP (tidak case-sensitive)
+	Role  string
+}
+
+type LDAPConfig struct {
+	URL          string // ldap://host:389 atau ldaps://host:636
+	BindDN       string // Akun layanan untuk mencari user; kosong = pencarian anonim
+	BindPassword string
+	BaseDN       string
+	CAFile       string // CA tambahan untuk ldaps://
+
+	LoginAttribute    string // Atribut yang dicocokkan dengan email di form login
+	UsernameAttribute string
+	GroupAttribute    string
+	Timeout           time.Duration
+}
+
+func (c LDAPConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL - callback aplikasi mobile (deep link / halaman yang meneruskan
+	// code & state ke POST /api/auth/oidc/callback). Kosong = OIDC hanya untuk
+	// panel admin.
+	RedirectURL string
+	// AdminRedirectURL - callback panel admin, mengarah ke /admin/login/sso/callback
+	AdminRedirectURL string
+	Scopes           []string
+	GroupsClaim      string
+}
+
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != ""
+}
+
+func LoadSSOConfig() SSOConfig {
+	scopes := splitList(os.Getenv("OIDC_SCOPES"), " ")
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	groupRoles, _ := ParseGroupRoles(os.Getenv("SSO_GROUP_ROLES"))
+
+	return SSOConfig{
+		LDAP: LDAPConfig{
+			URL:               strings.TrimSpace(os.Getenv("LDAP_URL")),
+			BindDN:            strings.TrimSpace(os.Getenv("LDAP_BIND_DN")),
+			BindPassword:      os.Getenv("LDAP_BIND_PASSWORD"),
+			BaseDN:            strings.TrimSpace(os.Getenv("LDAP_BASE_DN")),
+			CAFile:            strings.TrimSpace(os.Getenv("LDAP_CA_FILE")),
+			LoginAttribute:    envDefault("LDAP_LOGIN_ATTRIBUTE", "mail"),
+			UsernameAttribute: envDefault("LDAP_USERNAME_ATTRIBUTE", "uid"),
+			GroupAttribute:    envDefault("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+			Timeout:           envPositiveDuration("LDAP_TIMEOUT", 5*time.Second),
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:        strings.TrimSpace(os.Getenv("OIDC_ISSUER_URL")),
+			ClientID:         strings.TrimSpace(os.Getenv("OIDC_CLIENT_ID")),
+			ClientSecret:     os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:      strings.TrimSpace(os.Getenv("OIDC_REDIRECT_URL")),
+			AdminRedirectURL: strings.TrimSpace(os.Getenv("OIDC_ADMIN_REDIRECT_URL")),
+			Scopes:           scopes,
+			GroupsClaim:      envDefault("OIDC_GROUPS_CLAIM", "groups"),
+		},
+		GroupRoles:  groupRoles,
+		DefaultRole: strings.TrimSpace(os.Getenv("SSO_DEFAULT_ROLE")),
+	}
+}
+
+// ParseGroupRoles membaca "grup=role;grup=role". Role diambil dari '=' terakhir
+// karena DN grup LDAP sendiri mengandung '=' (cn=direksi,ou=groups,...).
+func ParseGroupRoles(raw string) ([]GroupRole, error) {
+	var mappings []GroupRole
+	for _, entry := range splitList(raw, ";") {
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 || i == len(entry)-1 {
+			return nil, fmt.Errorf("invalid group mapping %q (expected group=role)", entry)
+		}
+		mappings = append(mappings, GroupRole{
+			Group: strings.TrimSpace(entry[:i]),
+			Role:  strings.TrimSpace(entry[i+1:]),
+		})
+	}
+	return mappings, nil
+}
+
+// splitList memecah daftar berpemisah dan membuang entri kosong
+func splitList(raw, sep string) []string {
+	var values []string
+	for _, v := range strings.Split(raw, sep) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func envDefault(key, fallback string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return fallback
+}
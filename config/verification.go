@@ -0,0 +1,33 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// VerificationConfig mengatur halaman verifikasi publik surat keluar
+// (URL yang di-encode ke QR code dan apakah isi surat boleh ditampilkan).
+type VerificationConfig struct {
+	BaseURL    string
+	ExposeBody bool
+}
+
+func LoadVerificationConfig() VerificationConfig {
+	baseURL := strings.TrimRight(strings.TrimSpace(os.Getenv("VERIFICATION_BASE_URL")), "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080/verify"
+	}
+
+	exposeBody, _ := strconv.ParseBool(os.Getenv("VERIFICATION_EXPOSE_BODY"))
+
+	return VerificationConfig{
+		BaseURL:    baseURL,
+		ExposeBody: exposeBody,
+	}
+}
+
+// URLFor menghasilkan link verifikasi untuk token tertentu
+func (c VerificationConfig) URLFor(token string) string {
+	return c.BaseURL + "/" + token
+}
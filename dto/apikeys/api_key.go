@@ -0,0 +1,71 @@
+package apikeys
+
+import (
+	"strings"
+	"time"
+
+	"TugasAkhir/models"
+)
+
+type APIKeyCreateRequest struct {
+	Name      string     `json:"name"`
+	OwnerID   uint       `json:"owner_id"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"` // Kosong = tidak kedaluwarsa
+}
+
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	OwnerID    uint       `json:"owner_id"`
+	OwnerEmail string     `json:"owner_email,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyCreatedResponse - Key hanya dikembalikan sekali, saat dibuat
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+func (r *APIKeyCreateRequest) Validate(now time.Time) map[string]string {
+	errors := make(map[string]string)
+
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 100 {
+		errors["name"] = "name is required (max 100 characters)"
+	}
+	if r.OwnerID == 0 {
+		errors["owner_id"] = "owner_id is required"
+	}
+	if len(r.Scopes) == 0 {
+		errors["scopes"] = "at least one scope is required"
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(now) {
+		errors["expires_at"] = "expires_at must be in the future"
+	}
+
+	return errors
+}
+
+func NewAPIKeyResponse(key models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		OwnerID:    key.OwnerID,
+		OwnerEmail: key.Owner.Email,
+		Scopes:     key.ScopeList(),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
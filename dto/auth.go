@@ -9,6 +9,20 @@ import (
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
+	DeviceInfo
+}
+
+// OIDCAuthorizeResponse - URL halaman login identity provider untuk dibuka aplikasi
+type OIDCAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// OIDCCallbackRequest - code & state yang diterima aplikasi dari redirect
+// identity provider
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+	DeviceInfo
 }
 
 type LoginResponse struct {
@@ -17,6 +31,15 @@ type LoginResponse struct {
 	TokenType    string      `json:"token_type,omitempty"`
 	ExpiresAt    time.Time   `json:"expires_at"`
 	User         UserSummary `json:"user"`
+
+	// Role yang dibawa token & semua role yang bisa dipilih lewat /auth/switch-role
+	ActiveRole models.ActingRole   `json:"active_role"`
+	Roles      []models.ActingRole `json:"roles"`
+
+	// Diisi sekali saat 2FA baru diaktifkan lewat enrolment wajib di login
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+
+	SessionID uint `json:"session_id,omitempty"`
 }
 
 type UserSummary struct {
@@ -28,6 +51,9 @@ type UserSummary struct {
 	Role      models.Role `json:"role"`
 	Jabatan   string      `json:"jabatan,omitempty"`
 	Atribut   string      `json:"atribut,omitempty"`
+	UnitID    *uint       `json:"unit_id"`
+
+	HasSignature bool `json:"has_signature"`
 }
 
 type RegisterRequest struct {
@@ -48,6 +74,7 @@ type RegisterResponse struct {
 
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
+	DeviceInfo
 }
 
 type RefreshTokenResponse struct {
@@ -55,6 +82,21 @@ type RefreshTokenResponse struct {
 	RefreshToken string    `json:"refresh_token,omitempty"`
 	TokenType    string    `json:"token_type,omitempty"`
 	ExpiresAt    time.Time `json:"expires_at"`
+
+	// Kembali ke role utama jika penugasan role sebelumnya sudah berakhir
+	ActiveRole models.ActingRole `json:"active_role"`
+}
+
+// SwitchRoleRequest - ganti role aktif. Refresh token lama ikut dirotasi agar
+// sesi berikutnya memakai role yang baru.
+type SwitchRoleRequest struct {
+	Role         models.Role `json:"role"`
+	RefreshToken string      `json:"refresh_token"`
+}
+
+type RolesResponse struct {
+	ActiveRole models.Role         `json:"active_role"`
+	Roles      []models.ActingRole `json:"roles"`
 }
 
 type PasswordResetRequest struct {
@@ -66,3 +108,10 @@ type PasswordResetSubmission struct {
 	Password        string `json:"password" form:"password" binding:"required,min=8"`
 	ConfirmPassword string `json:"confirm_password" form:"confirm_password" binding:"required,eqfield=Password"`
 }
+
+// AcceptInvitationRequest - user undangan mengatur password pertamanya
+type AcceptInvitationRequest struct {
+	Token           string `json:"token" form:"token" binding:"required"`
+	Password        string `json:"password" form:"password" binding:"required,min=8"`
+	ConfirmPassword string `json:"confirm_password" form:"confirm_password" binding:"required,eqfield=Password"`
+}
@@ -0,0 +1,56 @@
+package letters
+
+import (
+	"TugasAkhir/models"
+	"time"
+)
+
+// LetterHistoryResponse - satu aksi workflow surat. actor_role adalah role
+// yang dipakai saat aksi dilakukan, bisa berbeda dari role utama actor.
+// api_key diisi jika aksi dilakukan integrasi atas nama actor.
+type LetterHistoryResponse struct {
+	ID         uint                `json:"id"`
+	Action     string              `json:"action"`
+	FromStatus models.LetterStatus `json:"from_status"`
+	ToStatus   models.LetterStatus `json:"to_status"`
+	ActorID    uint                `json:"actor_id"`
+	Actor      *LetterUserResponse `json:"actor"`
+	ActorRole  models.Role         `json:"actor_role"`
+	ActorLabel string              `json:"actor_label,omitempty"`
+	Catatan    string              `json:"catatan,omitempty"`
+	APIKey     *LetterAPIKeyRef    `json:"api_key,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+type LetterAPIKeyRef struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+}
+
+func toLetterAPIKeyRef(key *models.APIKey) *LetterAPIKeyRef {
+	if key == nil {
+		return nil
+	}
+	return &LetterAPIKeyRef{ID: key.ID, Name: key.Name, Prefix: key.Prefix}
+}
+
+func NewLetterHistoryResponses(history []models.LetterHistory) []LetterHistoryResponse {
+	responses := make([]LetterHistoryResponse, 0, len(history))
+	for _, h := range history {
+		responses = append(responses, LetterHistoryResponse{
+			ID:         h.ID,
+			Action:     h.Action,
+			FromStatus: h.FromStatus,
+			ToStatus:   h.ToStatus,
+			ActorID:    h.ActorID,
+			Actor:      toLetterUserResponse(h.Actor),
+			ActorRole:  h.ActorRole,
+			ActorLabel: h.ActorLabel,
+			Catatan:    h.Catatan,
+			APIKey:     toLetterAPIKeyRef(h.APIKey),
+			CreatedAt:  h.CreatedAt,
+		})
+	}
+	return responses
+}
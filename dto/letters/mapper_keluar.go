@@ -40,6 +40,7 @@ func (r *CreateLetterKeluarRequest) ToModel() models.Letter {
 	}
 	letter.TanggalDisposisi = r.TanggalDisposisi
 	letter.InReplyToID = r.InReplyToID // Reply linking
+	letter.Penerima = strings.TrimSpace(r.Penerima)
 
 	return letter
 }
@@ -104,4 +105,7 @@ func ApplyUpdate(letter *models.Letter, req *UpdateLetterKeluarRequest) {
 	if req.DisposedByID != nil {
 		letter.DisposedByID = req.DisposedByID
 	}
+	if req.Penerima != nil {
+		letter.Penerima = strings.TrimSpace(*req.Penerima)
+	}
 }
@@ -43,6 +43,18 @@ type CreateLetterKeluarRequest struct {
 
 	// Reply Linking: ID surat masuk yang dibalas (opsional)
 	InReplyToID *uint `json:"in_reply_to_id" form:"in_reply_to_id"`
+
+	// Tujuan surat (Kepada Yth.), dipakai saat surat dibuat dari template
+	Penerima string `json:"penerima" form:"penerima"`
+
+	// Tembusan (CC): array JSON, atau string JSON untuk multipart
+	Tembusan TembusanList `json:"tembusan" form:"tembusan"`
+}
+
+// CreateLetterFromTemplateRequest - Req surat keluar yang PDF-nya dibuat dari template
+type CreateLetterFromTemplateRequest struct {
+	CreateLetterKeluarRequest
+	TemplateID uint `json:"template_id" form:"template_id"`
 }
 
 type UpdateLetterKeluarRequest struct {
@@ -62,10 +74,14 @@ type UpdateLetterKeluarRequest struct {
 	FilePath         *string              `json:"file_path"` // Diisi manual handler
 	Status           *models.LetterStatus `json:"status" form:"status"`
 
-	CreatedByID        *uint `json:"created_by_id" form:"created_by_id"`
-	VerifiedByID       *uint `json:"verified_by_id" form:"verified_by_id"`
-	DisposedByID       *uint `json:"disposed_by_id" form:"disposed_by_id"`
-	AssignedVerifierID *uint `json:"assigned_verifier_id" form:"assigned_verifier_id"`
+	CreatedByID        *uint   `json:"created_by_id" form:"created_by_id"`
+	VerifiedByID       *uint   `json:"verified_by_id" form:"verified_by_id"`
+	DisposedByID       *uint   `json:"disposed_by_id" form:"disposed_by_id"`
+	AssignedVerifierID *uint   `json:"assigned_verifier_id" form:"assigned_verifier_id"`
+	Penerima           *string `json:"penerima" form:"penerima"`
+
+	// Jika dikirim, menggantikan seluruh daftar tembusan
+	Tembusan *TembusanList `json:"tembusan" form:"tembusan"`
 }
 
 func (r *CreateLetterKeluarRequest) Validate() map[string]string {
@@ -92,6 +108,26 @@ func (r *CreateLetterKeluarRequest) Validate() map[string]string {
 		errors["status"] = "status invalid"
 	}
 
+	for k, v := range r.Tembusan.Validate() {
+		errors[k] = v
+	}
+
+	return errors
+}
+
+func (r *CreateLetterFromTemplateRequest) Validate() map[string]string {
+	errors := r.CreateLetterKeluarRequest.Validate()
+
+	if r.TemplateID == 0 {
+		errors["template_id"] = "template_id is required"
+	}
+	if strings.TrimSpace(r.IsiSurat) == "" {
+		errors["isi_surat"] = "isi_surat is required"
+	}
+	if strings.TrimSpace(r.Penerima) == "" {
+		errors["penerima"] = "penerima is required"
+	}
+
 	return errors
 }
 
@@ -108,6 +144,12 @@ func (r *UpdateLetterKeluarRequest) Validate() map[string]string {
 		errors["status"] = "status invalid"
 	}
 
+	if r.Tembusan != nil {
+		for k, v := range r.Tembusan.Validate() {
+			errors[k] = v
+		}
+	}
+
 	return errors
 }
 
@@ -21,6 +21,9 @@ type CreateLetterMasukRequest struct {
 	// Status: kosong/"draft" = simpan sebagai draft, "belum_disposisi" = kirim ke Direktur
 	Status models.LetterStatus `json:"status" form:"status"`
 
+	// Tembusan (CC): string JSON array, lihat TembusanList
+	Tembusan TembusanList `json:"tembusan" form:"tembusan"`
+
 	// Note: FilePath di-handle handler
 }
 
@@ -39,6 +42,9 @@ type UpdateLetterMasukRequest struct {
 
 	// Status: "belum_disposisi" = submit draft ke Direktur
 	Status *models.LetterStatus `json:"status" form:"status"`
+
+	// Jika dikirim, menggantikan seluruh daftar tembusan
+	Tembusan *TembusanList `json:"tembusan" form:"tembusan"`
 }
 
 func (r *CreateLetterMasukRequest) Validate() map[string]string {
@@ -60,6 +66,10 @@ func (r *CreateLetterMasukRequest) Validate() map[string]string {
 		// Validasi scope jika perlu
 	}
 
+	for k, v := range r.Tembusan.Validate() {
+		errors[k] = v
+	}
+
 	return errors
 }
 
@@ -68,6 +78,11 @@ func (r *UpdateLetterMasukRequest) Validate() map[string]string {
 	if r.Prioritas != nil && !isValidPriorityString(*r.Prioritas) {
 		errors["prioritas"] = "prioritas must be biasa, segera, or penting"
 	}
+	if r.Tembusan != nil {
+		for k, v := range r.Tembusan.Validate() {
+			errors[k] = v
+		}
+	}
 	return errors
 }
 
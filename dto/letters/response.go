@@ -2,26 +2,31 @@ package letters
 
 import (
 	"TugasAkhir/models"
+	"fmt"
 	"time"
 )
 
 type LetterResponse struct {
-	IDSurat          uint                `json:"id_surat"`
-	Pengirim         string              `json:"pengirim"`
-	NomorSurat       string              `json:"nomor_surat"`
-	NomorAgenda      string              `json:"nomor_agenda"`
-	Disposisi        string              `json:"disposisi"`
-	TanggalDisposisi *time.Time          `json:"tanggal_disposisi"`
-	BidangTujuan     string              `json:"bidang_tujuan"`
-	JenisSurat       models.LetterType   `json:"jenis_surat"`
-	Prioritas        models.Priority     `json:"prioritas"`
-	IsiSurat         string              `json:"isi_surat"`
-	TanggalSurat     *time.Time          `json:"tanggal_surat"`
-	TanggalMasuk     *time.Time          `json:"tanggal_masuk"`
-	JudulSurat       string              `json:"judul_surat"`
-	Kesimpulan       string              `json:"kesimpulan"`
-	FilePath         string              `json:"file_path"`
-	Status           models.LetterStatus `json:"status"`
+	IDSurat          uint                 `json:"id_surat"`
+	Pengirim         string               `json:"pengirim"`
+	NomorSurat       string               `json:"nomor_surat"`
+	NomorAgenda      string               `json:"nomor_agenda"`
+	Disposisi        string               `json:"disposisi"`
+	TanggalDisposisi *time.Time           `json:"tanggal_disposisi"`
+	BidangTujuan     string               `json:"bidang_tujuan"`
+	TujuanUnitID     *uint                `json:"tujuan_unit_id"`
+	TujuanUnit       *LetterUnitResponse  `json:"tujuan_unit"`
+	JenisSurat       models.LetterType    `json:"jenis_surat"`
+	Prioritas        models.Priority      `json:"prioritas"`
+	IsiSurat         string               `json:"isi_surat"`
+	TanggalSurat     *time.Time           `json:"tanggal_surat"`
+	TanggalMasuk     *time.Time           `json:"tanggal_masuk"`
+	JudulSurat       string               `json:"judul_surat"`
+	Kesimpulan       string               `json:"kesimpulan"`
+	HasFile          bool                 `json:"has_file"`
+	Files            []LetterFileResponse `json:"files"`
+	Status           models.LetterStatus  `json:"status"`
+	Version          uint                 `json:"version"`
 
 	// UPDATE: Ubah jadi uint (bukan pointer) sesuai Models
 	CreatedByID uint                `json:"created_by_id"`
@@ -33,6 +38,84 @@ type LetterResponse struct {
 	DisposedBy   *LetterUserResponse `json:"disposed_by"`
 	CreatedAt    time.Time           `json:"created_at"`
 	UpdatedAt    time.Time           `json:"updated_at"`
+
+	Scope              string              `json:"scope"`
+	UnitID             *uint               `json:"unit_id"`
+	Unit               *LetterUnitResponse `json:"unit"`
+	AssignedVerifierID *uint               `json:"assigned_verifier_id"`
+	AssignedVerifier   *LetterUserResponse `json:"assigned_verifier"`
+	Penerima           string              `json:"penerima"`
+	TemplateID         *uint               `json:"template_id"`
+	NeedsReply         bool                `json:"needs_reply"`
+	InReplyToID        *uint               `json:"in_reply_to_id"`
+
+	// Tanda tangan & verifikasi (surat keluar yang sudah disetujui)
+	SignedAt             *time.Time                  `json:"signed_at"`
+	SignedBy             *LetterUserResponse         `json:"signed_by"`
+	SignatureCertSubject string                      `json:"signature_cert_subject,omitempty"`
+	SignatureFingerprint string                      `json:"signature_fingerprint,omitempty"`
+	Verification         *LetterVerificationResponse `json:"verification,omitempty"`
+
+	Tembusan []TembusanResponse `json:"tembusan"`
+}
+
+// LetterSummaryResponse adalah bentuk ringkas surat untuk list: tanpa isi
+// surat, relasi lengkap maupun URL file
+type LetterSummaryResponse struct {
+	IDSurat      uint                `json:"id_surat"`
+	NomorSurat   string              `json:"nomor_surat"`
+	NomorAgenda  string              `json:"nomor_agenda"`
+	JudulSurat   string              `json:"judul_surat"`
+	Pengirim     string              `json:"pengirim"`
+	JenisSurat   models.LetterType   `json:"jenis_surat"`
+	Prioritas    models.Priority     `json:"prioritas"`
+	Scope        string              `json:"scope"`
+	UnitID       *uint               `json:"unit_id"`
+	TujuanUnitID *uint               `json:"tujuan_unit_id"`
+	Status       models.LetterStatus `json:"status"`
+	Version      uint                `json:"version"`
+	TanggalSurat *time.Time          `json:"tanggal_surat"`
+	TanggalMasuk *time.Time          `json:"tanggal_masuk"`
+	NeedsReply   bool                `json:"needs_reply"`
+	HasFile      bool                `json:"has_file"`
+	CreatedBy    *LetterUserResponse `json:"created_by"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// LetterFileResponse menunjuk ke endpoint proxy file, bukan ke S3 langsung
+type LetterFileResponse struct {
+	Variant string `json:"variant"`
+	URL     string `json:"url"`
+}
+
+type LetterVerificationResponse struct {
+	Status           string     `json:"status"`
+	FileHash         string     `json:"file_hash"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+}
+
+type TembusanResponse struct {
+	UserID        *uint               `json:"user_id,omitempty"`
+	User          *LetterUserResponse `json:"user,omitempty"`
+	Role          *models.Role        `json:"role,omitempty"`
+	NamaEksternal string              `json:"nama_eksternal,omitempty"`
+	Urutan        int                 `json:"urutan"`
+}
+
+// LetterUnitResponse adalah ringkasan unit (bidang) di data surat
+type LetterUnitResponse struct {
+	ID   uint   `json:"id"`
+	Kode string `json:"kode"`
+	Nama string `json:"nama"`
+}
+
+func toLetterUnitResponse(unit *models.Unit) *LetterUnitResponse {
+	if unit == nil {
+		return nil
+	}
+	return &LetterUnitResponse{ID: unit.ID, Kode: unit.Kode, Nama: unit.Nama}
 }
 
 type LetterUserResponse struct {
@@ -67,6 +150,8 @@ func NewLetterResponse(letter *models.Letter) LetterResponse {
 		Disposisi:        letter.Disposisi,
 		TanggalDisposisi: letter.TanggalDisposisi,
 		BidangTujuan:     letter.BidangTujuan,
+		TujuanUnitID:     letter.TujuanUnitID,
+		TujuanUnit:       toLetterUnitResponse(letter.TujuanUnit),
 		JenisSurat:       letter.JenisSurat,
 		Prioritas:        letter.Prioritas,
 		IsiSurat:         letter.IsiSurat,
@@ -74,8 +159,10 @@ func NewLetterResponse(letter *models.Letter) LetterResponse {
 		TanggalMasuk:     letter.TanggalMasuk,
 		JudulSurat:       letter.JudulSurat,
 		Kesimpulan:       letter.Kesimpulan,
-		FilePath:         letter.FilePath,
+		HasFile:          letter.FilePath != "",
+		Files:            toLetterFileResponses(letter),
 		Status:           letter.Status,
+		Version:          letter.Version,
 
 		// Assignment langsung (uint ke uint)
 		CreatedByID: letter.CreatedByID,
@@ -87,5 +174,106 @@ func NewLetterResponse(letter *models.Letter) LetterResponse {
 		DisposedBy:   toLetterUserResponse(letter.DisposedBy),
 		CreatedAt:    letter.CreatedAt,
 		UpdatedAt:    letter.UpdatedAt,
+
+		Scope:              letter.Scope,
+		UnitID:             letter.UnitID,
+		Unit:               toLetterUnitResponse(letter.Unit),
+		AssignedVerifierID: letter.AssignedVerifierID,
+		AssignedVerifier:   toLetterUserResponse(letter.AssignedVerifier),
+		Penerima:           letter.Penerima,
+		TemplateID:         letter.TemplateID,
+		NeedsReply:         letter.NeedsReply,
+		InReplyToID:        letter.InReplyToID,
+
+		SignedAt:             letter.SignedAt,
+		SignedBy:             toLetterUserResponse(letter.SignedBy),
+		SignatureCertSubject: letter.SignatureCertSubject,
+		SignatureFingerprint: letter.SignatureFingerprint,
+		Verification:         toLetterVerificationResponse(letter.Verification),
+
+		Tembusan: toTembusanResponses(letter.Tembusan),
+	}
+}
+
+func NewLetterSummaryResponse(letter *models.Letter) LetterSummaryResponse {
+	if letter == nil {
+		return LetterSummaryResponse{}
+	}
+
+	return LetterSummaryResponse{
+		IDSurat:      letter.ID,
+		NomorSurat:   letter.NomorSurat,
+		NomorAgenda:  letter.NomorAgenda,
+		JudulSurat:   letter.JudulSurat,
+		Pengirim:     letter.Pengirim,
+		JenisSurat:   letter.JenisSurat,
+		Prioritas:    letter.Prioritas,
+		Scope:        letter.Scope,
+		UnitID:       letter.UnitID,
+		TujuanUnitID: letter.TujuanUnitID,
+		Status:       letter.Status,
+		Version:      letter.Version,
+		TanggalSurat: letter.TanggalSurat,
+		TanggalMasuk: letter.TanggalMasuk,
+		NeedsReply:   letter.NeedsReply,
+		HasFile:      letter.FilePath != "",
+		CreatedBy:    toLetterUserResponse(letter.CreatedBy),
+		CreatedAt:    letter.CreatedAt,
+		UpdatedAt:    letter.UpdatedAt,
+	}
+}
+
+func NewLetterSummaryResponses(letters []models.Letter) []LetterSummaryResponse {
+	responses := make([]LetterSummaryResponse, 0, len(letters))
+	for i := range letters {
+		responses = append(responses, NewLetterSummaryResponse(&letters[i]))
+	}
+	return responses
+}
+
+func toLetterFileResponses(letter *models.Letter) []LetterFileResponse {
+	files := []LetterFileResponse{}
+	if letter.FilePath != "" {
+		files = append(files, LetterFileResponse{
+			Variant: models.FileVariantCurrent,
+			URL:     fmt.Sprintf("/api/letters/%d/file", letter.ID),
+		})
+	}
+	if letter.OriginalFilePath != "" {
+		files = append(files, LetterFileResponse{
+			Variant: models.FileVariantOriginal,
+			URL:     fmt.Sprintf("/api/letters/%d/file/%s", letter.ID, models.FileVariantOriginal),
+		})
+	}
+	return files
+}
+
+func toLetterVerificationResponse(v *models.LetterVerification) *LetterVerificationResponse {
+	if v == nil {
+		return nil
+	}
+	resp := &LetterVerificationResponse{
+		Status:           VerificationValid,
+		FileHash:         v.FileHash,
+		RevokedAt:        v.RevokedAt,
+		RevocationReason: v.RevocationReason,
+	}
+	if v.IsRevoked() {
+		resp.Status = VerificationRevoked
+	}
+	return resp
+}
+
+func toTembusanResponses(tembusan []models.LetterTembusan) []TembusanResponse {
+	responses := make([]TembusanResponse, 0, len(tembusan))
+	for _, t := range tembusan {
+		responses = append(responses, TembusanResponse{
+			UserID:        t.UserID,
+			User:          toLetterUserResponse(t.User),
+			Role:          t.Role,
+			NamaEksternal: t.NamaEksternal,
+			Urutan:        t.Urutan,
+		})
 	}
+	return responses
 }
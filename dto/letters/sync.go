@@ -0,0 +1,104 @@
+package letters
+
+import (
+	"TugasAkhir/models"
+	"time"
+)
+
+const (
+	TombstoneLetter       = "letter"
+	TombstoneNotification = "notification"
+)
+
+// SyncResponse adalah hasil GET /sync. Klien menyimpan cursor dan mengirimnya
+// lagi di ?since= untuk mengambil perubahan berikutnya.
+type SyncResponse struct {
+	Cursor        string                 `json:"cursor"`
+	HasMore       bool                   `json:"has_more"`
+	Letters       []LetterResponse       `json:"letters"`
+	Dispositions  []DispositionResponse  `json:"dispositions"`
+	Notifications []NotificationResponse `json:"notifications"`
+	Deleted       []TombstoneResponse    `json:"deleted"`
+}
+
+// DispositionResponse adalah data disposisi Direktur atas surat masuk.
+// Disposisi disimpan di surat, jadi LetterID sekaligus menjadi ID-nya.
+type DispositionResponse struct {
+	LetterID         uint                `json:"letter_id"`
+	Disposisi        string              `json:"disposisi"`
+	BidangTujuan     string              `json:"bidang_tujuan"`
+	TujuanUnitID     *uint               `json:"tujuan_unit_id"`
+	TanggalDisposisi *time.Time          `json:"tanggal_disposisi"`
+	NeedsReply       bool                `json:"needs_reply"`
+	DisposedByID     *uint               `json:"disposed_by_id"`
+	DisposedBy       *LetterUserResponse `json:"disposed_by"`
+}
+
+type NotificationResponse struct {
+	ID        uint      `json:"id"`
+	LetterID  *uint     `json:"letter_id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TombstoneResponse menandai data yang sudah dihapus (atau surat yang tidak
+// lagi boleh dilihat user) dan harus dibuang dari cache klien. Tombstone surat
+// juga berlaku untuk disposisinya.
+type TombstoneResponse struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+func NewSyncResponse(cursor string, hasMore bool, letters, deletedLetters []models.Letter, revokedLetters []models.SyncLetterDelivery, notifications, deletedNotifications []models.Notification) SyncResponse {
+	resp := SyncResponse{
+		Cursor:        cursor,
+		HasMore:       hasMore,
+		Letters:       make([]LetterResponse, 0, len(letters)),
+		Dispositions:  []DispositionResponse{},
+		Notifications: make([]NotificationResponse, 0, len(notifications)),
+		Deleted:       make([]TombstoneResponse, 0, len(deletedLetters)+len(revokedLetters)+len(deletedNotifications)),
+	}
+
+	for i := range letters {
+		l := &letters[i]
+		resp.Letters = append(resp.Letters, NewLetterResponse(l))
+		if l.TanggalDisposisi != nil {
+			resp.Dispositions = append(resp.Dispositions, DispositionResponse{
+				LetterID:         l.ID,
+				Disposisi:        l.Disposisi,
+				BidangTujuan:     l.BidangTujuan,
+				TujuanUnitID:     l.TujuanUnitID,
+				TanggalDisposisi: l.TanggalDisposisi,
+				NeedsReply:       l.NeedsReply,
+				DisposedByID:     l.DisposedByID,
+				DisposedBy:       toLetterUserResponse(l.DisposedBy),
+			})
+		}
+	}
+	for _, l := range deletedLetters {
+		resp.Deleted = append(resp.Deleted, TombstoneResponse{Type: TombstoneLetter, ID: l.ID, DeletedAt: l.DeletedAt.Time})
+	}
+	// Surat yang tidak lagi boleh dilihat dibuang dari cache seperti surat yang dihapus
+	for _, d := range revokedLetters {
+		resp.Deleted = append(resp.Deleted, TombstoneResponse{Type: TombstoneLetter, ID: d.LetterID, DeletedAt: *d.RevokedAt})
+	}
+
+	for _, n := range notifications {
+		resp.Notifications = append(resp.Notifications, NotificationResponse{
+			ID:        n.ID,
+			LetterID:  n.LetterID,
+			Title:     n.Title,
+			Body:      n.Body,
+			Status:    n.Status,
+			CreatedAt: n.CreatedAt,
+		})
+	}
+	for _, n := range deletedNotifications {
+		resp.Deleted = append(resp.Deleted, TombstoneResponse{Type: TombstoneNotification, ID: n.ID, DeletedAt: n.DeletedAt.Time})
+	}
+
+	return resp
+}
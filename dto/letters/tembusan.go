@@ -0,0 +1,113 @@
+package letters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"TugasAkhir/models"
+)
+
+const maxTembusan = 50
+
+// TembusanItem - satu penerima tembusan, isi tepat satu field
+type TembusanItem struct {
+	UserID *uint        `json:"user_id,omitempty"`
+	Role   *models.Role `json:"role,omitempty"` // Unit internal
+	Nama   string       `json:"nama,omitempty"` // Pihak eksternal
+}
+
+// TembusanList menerima array JSON biasa, atau string berisi array JSON untuk
+// request multipart/form-data (field `tembusan`)
+type TembusanList []TembusanItem
+
+func (l *TembusanList) UnmarshalText(text []byte) error {
+	if strings.TrimSpace(string(text)) == "" {
+		*l = TembusanList{}
+		return nil
+	}
+	var items []TembusanItem
+	if err := json.Unmarshal(text, &items); err != nil {
+		return fmt.Errorf("tembusan must be a JSON array: %w", err)
+	}
+	*l = items
+	return nil
+}
+
+func (l *TembusanList) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		return l.UnmarshalText([]byte(raw))
+	}
+	var items []TembusanItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	*l = items
+	return nil
+}
+
+func (l TembusanList) Validate() map[string]string {
+	errors := make(map[string]string)
+	if len(l) > maxTembusan {
+		errors["tembusan"] = fmt.Sprintf("maximum %d tembusan", maxTembusan)
+		return errors
+	}
+
+	for i, item := range l {
+		key := fmt.Sprintf("tembusan[%d]", i)
+		filled := 0
+		if item.UserID != nil {
+			filled++
+		}
+		if item.Role != nil {
+			filled++
+			if !item.Role.IsValid() {
+				errors[key] = "role is invalid"
+				continue
+			}
+		}
+		if strings.TrimSpace(item.Nama) != "" {
+			filled++
+		}
+		if filled != 1 {
+			errors[key] = "exactly one of user_id, role or nama is required"
+		}
+	}
+	return errors
+}
+
+// UserIDs mengembalikan daftar user internal yang ditembuskan
+func (l TembusanList) UserIDs() []uint {
+	var ids []uint
+	for _, item := range l {
+		if item.UserID != nil {
+			ids = append(ids, *item.UserID)
+		}
+	}
+	return ids
+}
+
+// Roles mengembalikan daftar role yang ditembuskan
+func (l TembusanList) Roles() []models.Role {
+	var roles []models.Role
+	for _, item := range l {
+		if item.Role != nil {
+			roles = append(roles, *item.Role)
+		}
+	}
+	return roles
+}
+
+func (l TembusanList) ToModels() []models.LetterTembusan {
+	result := make([]models.LetterTembusan, 0, len(l))
+	for i, item := range l {
+		result = append(result, models.LetterTembusan{
+			UserID:        item.UserID,
+			Role:          item.Role,
+			NamaEksternal: strings.TrimSpace(item.Nama),
+			Urutan:        i + 1,
+		})
+	}
+	return result
+}
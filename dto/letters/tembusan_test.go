@@ -0,0 +1,97 @@
+package letters
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"TugasAkhir/models"
+)
+
+func TestTembusanListUnmarshalJSON(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		want    int
+		wantErr bool
+	}{
+		{"array", `{"tembusan": [{"user_id": 3}, {"role": "direktur"}, {"nama": "Dinas Sosial"}]}`, 3, false},
+		// multipart/form-data mengirim array sebagai string
+		{"string berisi array", `{"tembusan": "[{\"user_id\": 3}, {\"nama\": \"Dinas Sosial\"}]"}`, 2, false},
+		{"string kosong", `{"tembusan": "  "}`, 0, false},
+		{"array kosong", `{"tembusan": []}`, 0, false},
+		{"string bukan array", `{"tembusan": "Dinas Sosial"}`, 0, true},
+		{"objek", `{"tembusan": {"user_id": 3}}`, 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var req struct {
+				Tembusan TembusanList `json:"tembusan"`
+			}
+			err := json.Unmarshal([]byte(tc.body), &req)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", req.Tembusan)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if req.Tembusan == nil || len(req.Tembusan) != tc.want {
+				t.Fatalf("tembusan = %+v, want %d items", req.Tembusan, tc.want)
+			}
+		})
+	}
+
+	var req struct {
+		Tembusan TembusanList `json:"tembusan"`
+	}
+	if err := json.Unmarshal([]byte(`{"tembusan": "[{\"user_id\": 3}, {\"role\": \"direktur\"}]"}`), &req); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if ids := req.Tembusan.UserIDs(); len(ids) != 1 || ids[0] != 3 {
+		t.Errorf("UserIDs = %v", ids)
+	}
+	if roles := req.Tembusan.Roles(); len(roles) != 1 || roles[0] != models.RoleDirektur {
+		t.Errorf("Roles = %v", roles)
+	}
+}
+
+func TestTembusanListValidate(t *testing.T) {
+	userID := uint(3)
+	direktur, malformed := models.RoleDirektur, models.Role("Bendahara Umum")
+
+	cases := []struct {
+		name string
+		list TembusanList
+		want map[string]string
+	}{
+		{"kosong", nil, map[string]string{}},
+		{"valid", TembusanList{{UserID: &userID}, {Role: &direktur}, {Nama: "Dinas Sosial"}}, map[string]string{}},
+		{"item kosong", TembusanList{{UserID: &userID}, {Nama: "   "}}, map[string]string{"tembusan[1]": "exactly one of user_id, role or nama is required"}},
+		{"lebih dari satu field", TembusanList{{UserID: &userID, Nama: "Dinas Sosial"}}, map[string]string{"tembusan[0]": "exactly one of user_id, role or nama is required"}},
+		{"format role salah", TembusanList{{Role: &malformed}}, map[string]string{"tembusan[0]": "role is invalid"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.list.Validate()
+			if len(got) != len(tc.want) {
+				t.Fatalf("Validate = %v, want %v", got, tc.want)
+			}
+			for k, v := range tc.want {
+				if got[k] != v {
+					t.Fatalf("Validate = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+
+	tooMany := make(TembusanList, maxTembusan+1)
+	for i := range tooMany {
+		tooMany[i].Nama = strings.Repeat("x", i+1)
+	}
+	if got := tooMany.Validate(); len(got) != 1 || got["tembusan"] == "" {
+		t.Fatalf("Validate(%d items) = %v, want single tembusan error", len(tooMany), got)
+	}
+}
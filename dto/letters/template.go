@@ -0,0 +1,130 @@
+package letters
+
+import (
+	"strings"
+	"time"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils/document"
+)
+
+type LetterTemplateCreateRequest struct {
+	Nama           string `json:"nama"`
+	Deskripsi      string `json:"deskripsi"`
+	KopSurat       string `json:"kop_surat"`
+	Body           string `json:"body"`
+	SignatureBlock string `json:"signature_block"`
+	IsActive       *bool  `json:"is_active"`
+}
+
+type LetterTemplateUpdateRequest struct {
+	Nama           *string `json:"nama"`
+	Deskripsi      *string `json:"deskripsi"`
+	KopSurat       *string `json:"kop_surat"`
+	Body           *string `json:"body"`
+	SignatureBlock *string `json:"signature_block"`
+	IsActive       *bool   `json:"is_active"`
+}
+
+type LetterTemplateResponse struct {
+	ID             uint   `json:"id"`
+	Nama           string `json:"nama"`
+	Deskripsi      string `json:"deskripsi"`
+	KopSurat       string `json:"kop_surat"`
+	Body           string `json:"body"`
+	SignatureBlock string `json:"signature_block"`
+	IsActive       bool   `json:"is_active"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+}
+
+func (r *LetterTemplateCreateRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if strings.TrimSpace(r.Nama) == "" {
+		errors["nama"] = "nama is required"
+	}
+	if strings.TrimSpace(r.KopSurat) == "" {
+		errors["kop_surat"] = "kop_surat is required"
+	}
+	validatePlaceholders(errors, "body", r.Body)
+	validatePlaceholders(errors, "signature_block", r.SignatureBlock)
+
+	return errors
+}
+
+func (r *LetterTemplateUpdateRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if r.Nama != nil && strings.TrimSpace(*r.Nama) == "" {
+		errors["nama"] = "nama cannot be empty"
+	}
+	if r.KopSurat != nil && strings.TrimSpace(*r.KopSurat) == "" {
+		errors["kop_surat"] = "kop_surat cannot be empty"
+	}
+	if r.Body != nil {
+		validatePlaceholders(errors, "body", *r.Body)
+	}
+	if r.SignatureBlock != nil {
+		validatePlaceholders(errors, "signature_block", *r.SignatureBlock)
+	}
+
+	return errors
+}
+
+func validatePlaceholders(errors map[string]string, field, text string) {
+	if unknown := document.UnknownPlaceholders(text); len(unknown) > 0 {
+		errors[field] = "unknown placeholder: " + strings.Join(unknown, ", ")
+	}
+}
+
+func (r *LetterTemplateCreateRequest) ToModel(createdByID uint) models.LetterTemplate {
+	isActive := true
+	if r.IsActive != nil {
+		isActive = *r.IsActive
+	}
+	return models.LetterTemplate{
+		Nama:           strings.TrimSpace(r.Nama),
+		Deskripsi:      strings.TrimSpace(r.Deskripsi),
+		KopSurat:       strings.TrimSpace(r.KopSurat),
+		Body:           r.Body,
+		SignatureBlock: strings.TrimSpace(r.SignatureBlock),
+		IsActive:       isActive,
+		CreatedByID:    createdByID,
+	}
+}
+
+func ApplyTemplateUpdate(tmpl *models.LetterTemplate, req *LetterTemplateUpdateRequest) {
+	if req.Nama != nil {
+		tmpl.Nama = strings.TrimSpace(*req.Nama)
+	}
+	if req.Deskripsi != nil {
+		tmpl.Deskripsi = strings.TrimSpace(*req.Deskripsi)
+	}
+	if req.KopSurat != nil {
+		tmpl.KopSurat = strings.TrimSpace(*req.KopSurat)
+	}
+	if req.Body != nil {
+		tmpl.Body = *req.Body
+	}
+	if req.SignatureBlock != nil {
+		tmpl.SignatureBlock = strings.TrimSpace(*req.SignatureBlock)
+	}
+	if req.IsActive != nil {
+		tmpl.IsActive = *req.IsActive
+	}
+}
+
+func NewLetterTemplateResponse(tmpl models.LetterTemplate) LetterTemplateResponse {
+	return LetterTemplateResponse{
+		ID:             tmpl.ID,
+		Nama:           tmpl.Nama,
+		Deskripsi:      tmpl.Deskripsi,
+		KopSurat:       tmpl.KopSurat,
+		Body:           tmpl.Body,
+		SignatureBlock: tmpl.SignatureBlock,
+		IsActive:       tmpl.IsActive,
+		CreatedAt:      tmpl.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      tmpl.UpdatedAt.Format(time.RFC3339),
+	}
+}
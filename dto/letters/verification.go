@@ -0,0 +1,72 @@
+package letters
+
+import (
+	"TugasAkhir/models"
+	"strings"
+	"time"
+)
+
+const (
+	VerificationValid   = "valid"
+	VerificationRevoked = "dicabut"
+)
+
+// VerificationResponse adalah data yang ditampilkan di halaman verifikasi publik.
+// Isi surat hanya diisi jika VERIFICATION_EXPOSE_BODY diaktifkan.
+type VerificationResponse struct {
+	Status               string     `json:"status"`
+	NomorSurat           string     `json:"nomor_surat"`
+	TanggalSurat         *time.Time `json:"tanggal_surat"`
+	SignedAt             *time.Time `json:"signed_at"`
+	SignerName           string     `json:"signer_name"`
+	SignerJabatan        string     `json:"signer_jabatan"`
+	SignatureCertSubject string     `json:"signature_cert_subject,omitempty"`
+	FileHash             string     `json:"file_hash"`
+	RevokedAt            *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason     string     `json:"revocation_reason,omitempty"`
+
+	JudulSurat string `json:"judul_surat,omitempty"`
+	IsiSurat   string `json:"isi_surat,omitempty"`
+	FileURL    string `json:"file_url,omitempty"`
+}
+
+// NewVerificationResponse memetakan data verifikasi. Surat yang sudah dihapus
+// (Letter nil) dianggap dicabut.
+func NewVerificationResponse(v *models.LetterVerification, exposeBody bool) VerificationResponse {
+	resp := VerificationResponse{
+		Status:           VerificationValid,
+		FileHash:         v.FileHash,
+		RevokedAt:        v.RevokedAt,
+		RevocationReason: v.RevocationReason,
+	}
+	if v.IsRevoked() {
+		resp.Status = VerificationRevoked
+	}
+
+	letter := v.Letter
+	if letter == nil {
+		resp.Status = VerificationRevoked
+		if resp.RevocationReason == "" {
+			resp.RevocationReason = "Surat telah dibatalkan"
+		}
+		return resp
+	}
+
+	resp.NomorSurat = letter.NomorSurat
+	resp.TanggalSurat = letter.TanggalSurat
+	resp.SignedAt = letter.SignedAt
+	resp.SignatureCertSubject = letter.SignatureCertSubject
+	if letter.SignedBy != nil {
+		resp.SignerName = strings.TrimSpace(letter.SignedBy.FirstName + " " + letter.SignedBy.LastName)
+		if resp.SignerName == "" {
+			resp.SignerName = letter.SignedBy.Username
+		}
+		resp.SignerJabatan = letter.SignedBy.Jabatan
+	}
+
+	if exposeBody && resp.Status == VerificationValid {
+		resp.JudulSurat = letter.JudulSurat
+		resp.IsiSurat = letter.IsiSurat
+	}
+	return resp
+}
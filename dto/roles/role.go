@@ -0,0 +1,111 @@
+package roles
+
+import (
+	"strings"
+	"time"
+
+	"TugasAkhir/models"
+)
+
+type RoleCreateRequest struct {
+	Name        models.Role `json:"name"`
+	DisplayName string      `json:"display_name"`
+	Description string      `json:"description"`
+	Permissions []string    `json:"permissions"`
+
+	RequireTwoFactor bool `json:"require_two_factor"`
+}
+
+// RoleUpdateRequest - partial update. Nama role tidak bisa diubah karena
+// disimpan di users.role. permissions (jika dikirim) mengganti seluruh daftar.
+type RoleUpdateRequest struct {
+	DisplayName *string  `json:"display_name"`
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+
+	RequireTwoFactor *bool `json:"require_two_factor"`
+}
+
+type RoleResponse struct {
+	ID               uint        `json:"id"`
+	Name             models.Role `json:"name"`
+	DisplayName      string      `json:"display_name"`
+	Description      string      `json:"description"`
+	IsSystem         bool        `json:"is_system"`
+	Permissions      []string    `json:"permissions"`
+	RequireTwoFactor bool        `json:"require_two_factor"`
+	CreatedAt        string      `json:"created_at"`
+	UpdatedAt        string      `json:"updated_at"`
+}
+
+type PermissionResponse struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+func (r *RoleCreateRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if !r.Name.IsValid() {
+		errors["name"] = "name is required (lowercase letters, digits and '_', max 50 characters)"
+	}
+	if strings.TrimSpace(r.DisplayName) == "" {
+		errors["display_name"] = "display_name is required"
+	}
+
+	return errors
+}
+
+func (r *RoleUpdateRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if r.DisplayName != nil && strings.TrimSpace(*r.DisplayName) == "" {
+		errors["display_name"] = "display_name cannot be empty"
+	}
+
+	return errors
+}
+
+func (r *RoleCreateRequest) ToModel() models.RoleDefinition {
+	return models.RoleDefinition{
+		Name:        r.Name,
+		DisplayName: strings.TrimSpace(r.DisplayName),
+		Description: strings.TrimSpace(r.Description),
+
+		RequireTwoFactor: r.RequireTwoFactor,
+	}
+}
+
+func ApplyRoleUpdate(role *models.RoleDefinition, req *RoleUpdateRequest) {
+	if req.DisplayName != nil {
+		role.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Description != nil {
+		role.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.RequireTwoFactor != nil {
+		role.RequireTwoFactor = *req.RequireTwoFactor
+	}
+}
+
+func NewRoleResponse(role models.RoleDefinition) RoleResponse {
+	perms := make([]string, 0, len(role.Permissions))
+	for _, p := range role.Permissions {
+		perms = append(perms, p.Code)
+	}
+	return RoleResponse{
+		ID:               role.ID,
+		Name:             role.Name,
+		DisplayName:      role.DisplayName,
+		Description:      role.Description,
+		IsSystem:         role.IsSystem,
+		Permissions:      perms,
+		RequireTwoFactor: role.RequireTwoFactor,
+		CreatedAt:        role.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:        role.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func NewPermissionResponse(perm models.Permission) PermissionResponse {
+	return PermissionResponse{Code: perm.Code, Description: perm.Description}
+}
@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"time"
+
+	"TugasAkhir/models"
+)
+
+// DeviceInfo - nama & platform perangkat untuk daftar sesi. Opsional: jika
+// kosong diambil dari header X-Device-Name / X-Platform dan User-Agent.
+type DeviceInfo struct {
+	DeviceName string `json:"device_name,omitempty" form:"device_name"`
+	Platform   string `json:"platform,omitempty" form:"platform"`
+}
+
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	DeviceName string    `json:"device_name"`
+	Platform   string    `json:"platform"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // Sesi yang dipakai request ini
+}
+
+func NewSessionResponse(session models.UserSession, currentID uint) SessionResponse {
+	return SessionResponse{
+		ID:         session.ID,
+		DeviceName: session.DeviceName,
+		Platform:   session.Platform,
+		IP:         session.IP,
+		UserAgent:  session.UserAgent,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    session.ID == currentID,
+	}
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"time"
+
+	"TugasAkhir/models"
+)
+
+// LinkLDAPRequest - user yang sudah login membuktikan akun direktorinya
+type LinkLDAPRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// LinkOIDCRequest - code & state dari redirect identity provider setelah
+// /settings/sso/oidc/authorize
+type LinkOIDCRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// AdminLinkIdentityRequest - admin menautkan akun provider ke user. Subject
+// adalah DN (LDAP) atau klaim sub (OIDC).
+type AdminLinkIdentityRequest struct {
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+}
+
+type IdentityResponse struct {
+	ID          uint       `json:"id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	LinkedAt    time.Time  `json:"linked_at"`
+}
+
+func NewIdentityResponse(identity models.UserIdentity) IdentityResponse {
+	return IdentityResponse{
+		ID:          identity.ID,
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		LastLoginAt: identity.LastLoginAt,
+		LinkedAt:    identity.CreatedAt,
+	}
+}
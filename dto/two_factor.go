@@ -0,0 +1,59 @@
+package dto
+
+import "time"
+
+// TwoFactorChallengeResponse - respons login jika password benar tapi kode 2FA
+// masih dibutuhkan. SetupRequired berarti role user mewajibkan 2FA dan user
+// harus mendaftarkan authenticator lewat /auth/2fa/setup (dengan token
+// enrolment dari admin) terlebih dahulu.
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	SetupRequired     bool      `json:"setup_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	PasswordExpired   bool      `json:"password_expired,omitempty"` // Kode ditukar dengan reset_token, bukan sesi
+}
+
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" form:"challenge_token"`
+	EnrolmentToken string `json:"enrolment_token" form:"enrolment_token"` // Dari admin, untuk /auth/2fa/setup
+}
+
+// TwoFactorVerifyRequest - code berisi kode 6 digit dari authenticator atau
+// salah satu recovery code
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" form:"challenge_token"`
+	Code           string `json:"code" form:"code"`
+	DeviceInfo
+}
+
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	QRCode          string `json:"qr_code"` // data URI PNG dari provisioning_uri
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorEnrolmentTokenResponse - token mentah hanya ditampilkan sekali
+type TwoFactorEnrolmentTokenResponse struct {
+	EnrolmentToken string    `json:"enrolment_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
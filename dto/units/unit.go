@@ -0,0 +1,173 @@
+package units
+
+import (
+	"strings"
+	"time"
+
+	"TugasAkhir/models"
+)
+
+type UnitCreateRequest struct {
+	Kode       string `json:"kode" form:"kode"`
+	Nama       string `json:"nama" form:"nama"`
+	ParentID   *uint  `json:"parent_id" form:"parent_id"`
+	HeadUserID *uint  `json:"head_user_id" form:"head_user_id"`
+	IsActive   *bool  `json:"is_active" form:"is_active"`
+}
+
+// UnitUpdateRequest - partial update. parent_id / head_user_id bernilai 0
+// menghapus parent (unit jadi unit teratas) / kepala unit.
+type UnitUpdateRequest struct {
+	Kode       *string `json:"kode"`
+	Nama       *string `json:"nama"`
+	ParentID   *uint   `json:"parent_id"`
+	HeadUserID *uint   `json:"head_user_id"`
+	IsActive   *bool   `json:"is_active"`
+}
+
+type UnitMembersRequest struct {
+	UserIDs []uint `json:"user_ids"`
+}
+
+type UnitResponse struct {
+	ID         uint                 `json:"id"`
+	Kode       string               `json:"kode"`
+	Nama       string               `json:"nama"`
+	ParentID   *uint                `json:"parent_id"`
+	Parent     *UnitSummaryResponse `json:"parent,omitempty"`
+	HeadUserID *uint                `json:"head_user_id"`
+	HeadUser   *UnitMemberResponse  `json:"head_user,omitempty"`
+	IsActive   bool                 `json:"is_active"`
+	Members    []UnitMemberResponse `json:"members,omitempty"`
+	CreatedAt  string               `json:"created_at"`
+	UpdatedAt  string               `json:"updated_at"`
+}
+
+type UnitSummaryResponse struct {
+	ID   uint   `json:"id"`
+	Kode string `json:"kode"`
+	Nama string `json:"nama"`
+}
+
+type UnitMemberResponse struct {
+	ID       uint        `json:"id"`
+	Username string      `json:"username"`
+	Email    string      `json:"email"`
+	Role     models.Role `json:"role"`
+	Jabatan  string      `json:"jabatan"`
+}
+
+const maxKodeLength = 20
+
+func (r *UnitCreateRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	validateKode(errors, r.Kode)
+	if strings.TrimSpace(r.Nama) == "" {
+		errors["nama"] = "nama is required"
+	}
+
+	return errors
+}
+
+func (r *UnitUpdateRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if r.Kode != nil {
+		validateKode(errors, *r.Kode)
+	}
+	if r.Nama != nil && strings.TrimSpace(*r.Nama) == "" {
+		errors["nama"] = "nama cannot be empty"
+	}
+
+	return errors
+}
+
+// Kode dipakai di nomor agenda ("12/KPP"), jadi tidak boleh berisi '/' atau spasi
+func validateKode(errors map[string]string, kode string) {
+	kode = strings.TrimSpace(kode)
+	switch {
+	case kode == "":
+		errors["kode"] = "kode is required"
+	case len(kode) > maxKodeLength:
+		errors["kode"] = "kode must be at most 20 characters"
+	case strings.ContainsAny(kode, "/ "):
+		errors["kode"] = "kode cannot contain '/' or spaces"
+	}
+}
+
+func (r *UnitCreateRequest) ToModel() models.Unit {
+	isActive := true
+	if r.IsActive != nil {
+		isActive = *r.IsActive
+	}
+	return models.Unit{
+		Kode:       strings.ToUpper(strings.TrimSpace(r.Kode)),
+		Nama:       strings.TrimSpace(r.Nama),
+		ParentID:   zeroToNil(r.ParentID),
+		HeadUserID: zeroToNil(r.HeadUserID),
+		IsActive:   isActive,
+	}
+}
+
+func ApplyUnitUpdate(unit *models.Unit, req *UnitUpdateRequest) {
+	if req.Kode != nil {
+		unit.Kode = strings.ToUpper(strings.TrimSpace(*req.Kode))
+	}
+	if req.Nama != nil {
+		unit.Nama = strings.TrimSpace(*req.Nama)
+	}
+	if req.ParentID != nil {
+		unit.ParentID = zeroToNil(req.ParentID)
+		unit.Parent = nil
+	}
+	if req.HeadUserID != nil {
+		unit.HeadUserID = zeroToNil(req.HeadUserID)
+		unit.HeadUser = nil
+	}
+	if req.IsActive != nil {
+		unit.IsActive = *req.IsActive
+	}
+}
+
+func zeroToNil(id *uint) *uint {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	v := *id
+	return &v
+}
+
+func NewUnitResponse(unit models.Unit) UnitResponse {
+	resp := UnitResponse{
+		ID:         unit.ID,
+		Kode:       unit.Kode,
+		Nama:       unit.Nama,
+		ParentID:   unit.ParentID,
+		HeadUserID: unit.HeadUserID,
+		IsActive:   unit.IsActive,
+		CreatedAt:  unit.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:  unit.UpdatedAt.Format(time.RFC3339),
+	}
+	if unit.Parent != nil {
+		resp.Parent = &UnitSummaryResponse{ID: unit.Parent.ID, Kode: unit.Parent.Kode, Nama: unit.Parent.Nama}
+	}
+	if unit.HeadUser != nil {
+		head := newUnitMemberResponse(*unit.HeadUser)
+		resp.HeadUser = &head
+	}
+	for _, m := range unit.Members {
+		resp.Members = append(resp.Members, newUnitMemberResponse(m))
+	}
+	return resp
+}
+
+func newUnitMemberResponse(user models.User) UnitMemberResponse {
+	return UnitMemberResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Role:     user.Role,
+		Jabatan:  user.Jabatan,
+	}
+}
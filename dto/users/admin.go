@@ -7,17 +7,21 @@ import (
 	"TugasAkhir/models"
 )
 
+// AdminUserCreateRequest - user baru dibuat berstatus pending tanpa password;
+// password diatur sendiri oleh user lewat link undangan.
 type AdminUserCreateRequest struct {
 	Username  string      `json:"username"`
 	FirstName string      `json:"first_name"`
 	LastName  string      `json:"last_name"`
 	Email     string      `json:"email"`
-	Password  string      `json:"password"`
 	Role      models.Role `json:"role"`
 	Jabatan   string      `json:"jabatan"`
 	Atribut   string      `json:"atribut"`
+	UnitID    *uint       `json:"unit_id"`
 }
 
+// AdminUserUpdateRequest - partial update. unit_id bernilai 0 mengeluarkan
+// user dari unitnya.
 type AdminUserUpdateRequest struct {
 	Username  *string      `json:"username"`
 	FirstName *string      `json:"first_name"`
@@ -27,19 +31,32 @@ type AdminUserUpdateRequest struct {
 	Role      *models.Role `json:"role"`
 	Jabatan   *string      `json:"jabatan"`
 	Atribut   *string      `json:"atribut"`
+	UnitID    *uint        `json:"unit_id"`
+}
+
+// AdminUserDeactivateRequest - pengganti verifikator untuk surat yang masih
+// menunggu user. reassignments (ID surat → ID verifikator) mengalahkan
+// reassign_to.
+type AdminUserDeactivateRequest struct {
+	ReassignTo    uint          `json:"reassign_to"`
+	Reassignments map[uint]uint `json:"reassignments"`
 }
 
 type AdminUserResponse struct {
-	ID        uint        `json:"id"`
-	Username  string      `json:"username"`
-	FirstName string      `json:"first_name"`
-	LastName  string      `json:"last_name"`
-	Email     string      `json:"email"`
-	Role      models.Role `json:"role"`
-	Jabatan   string      `json:"jabatan"`
-	Atribut   string      `json:"atribut"`
-	CreatedAt string      `json:"created_at"`
-	UpdatedAt string      `json:"updated_at"`
+	ID        uint              `json:"id"`
+	Username  string            `json:"username"`
+	FirstName string            `json:"first_name"`
+	LastName  string            `json:"last_name"`
+	Email     string            `json:"email"`
+	Role      models.Role       `json:"role"`
+	Jabatan   string            `json:"jabatan"`
+	Atribut   string            `json:"atribut"`
+	UnitID    *uint             `json:"unit_id"`
+	Status    models.UserStatus `json:"status"`
+	// DeactivatedAt hanya terisi untuk user berstatus inactive
+	DeactivatedAt *string `json:"deactivated_at,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+	UpdatedAt     string  `json:"updated_at"`
 }
 
 func (r *AdminUserCreateRequest) Validate() map[string]string {
@@ -51,12 +68,6 @@ func (r *AdminUserCreateRequest) Validate() map[string]string {
 	if strings.TrimSpace(r.Email) == "" {
 		errors["email"] = "email is required"
 	}
-	if strings.TrimSpace(r.Password) == "" {
-		errors["password"] = "password is required"
-	} else if len(r.Password) < 8 {
-		errors["password"] = "password must be at least 8 characters"
-	}
-	
 	if !r.Role.IsValid() {
 		errors["role"] = "role is invalid"
 	}
@@ -67,13 +78,6 @@ func (r *AdminUserCreateRequest) Validate() map[string]string {
 func (r *AdminUserUpdateRequest) Validate() map[string]string {
 	errors := make(map[string]string)
 
-	if r.Password != nil {
-		pwd := strings.TrimSpace(*r.Password)
-		if pwd != "" && len(pwd) < 8 {
-			errors["password"] = "password must be at least 8 characters"
-		}
-	}
-
 	if r.Role != nil && !(*r.Role).IsValid() {
 		errors["role"] = "role is invalid"
 	}
@@ -82,16 +86,61 @@ func (r *AdminUserUpdateRequest) Validate() map[string]string {
 }
 
 func NewAdminUserResponse(user models.User) AdminUserResponse {
+	var deactivatedAt *string
+	if user.DeactivatedAt != nil {
+		formatted := user.DeactivatedAt.Format(time.RFC3339)
+		deactivatedAt = &formatted
+	}
 	return AdminUserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-		Role:      user.Role,
-		Jabatan:   user.Jabatan,
-		Atribut:   user.Atribut,
-		CreatedAt: user.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
+		ID:            user.ID,
+		Username:      user.Username,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Email:         user.Email,
+		Role:          user.Role,
+		Jabatan:       user.Jabatan,
+		Atribut:       user.Atribut,
+		UnitID:        user.UnitID,
+		Status:        user.Status,
+		DeactivatedAt: deactivatedAt,
+		CreatedAt:     user.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     user.UpdatedAt.Format(time.RFC3339),
 	}
 }
+
+// InvitationStatus - hasil pengiriman undangan. Link hanya dikembalikan jika
+// email gagal terkirim, agar admin bisa menyampaikannya secara manual.
+type InvitationStatus struct {
+	Sent      bool   `json:"sent"`
+	ExpiresAt string `json:"expires_at"`
+	Link      string `json:"link,omitempty"`
+}
+
+type AdminUserInvitationResponse struct {
+	AdminUserResponse
+	Invitation InvitationStatus `json:"invitation"`
+}
+
+// AdminPendingLetterResponse - surat yang menunggu user sebagai verifikator,
+// beserta kandidat pengganti sesuai scope surat
+type AdminPendingLetterResponse struct {
+	ID         uint                `json:"id"`
+	NomorSurat string              `json:"nomor_surat"`
+	JudulSurat string              `json:"judul_surat"`
+	Scope      string              `json:"scope"`
+	Status     models.LetterStatus `json:"status"`
+	CreatedBy  string              `json:"created_by"`
+	Candidates []VerifierCandidate `json:"candidates"`
+}
+
+type VerifierCandidate struct {
+	ID       uint        `json:"id"`
+	Username string      `json:"username"`
+	Role     models.Role `json:"role"`
+	Jabatan  string      `json:"jabatan"`
+}
+
+type AdminUserDeactivateResponse struct {
+	AdminUserResponse
+	ReassignedLetters []uint `json:"reassigned_letters"`
+}
@@ -0,0 +1,70 @@
+package users
+
+import (
+	"strings"
+	"time"
+
+	"TugasAkhir/models"
+)
+
+// RoleAssignmentRequest - penugasan role tambahan (Plt./Plh.). valid_from &
+// valid_until (RFC3339) opsional; kosong berarti tidak dibatasi waktu.
+type RoleAssignmentRequest struct {
+	Role       models.Role `json:"role"`
+	Label      string      `json:"label"`
+	ValidFrom  *time.Time  `json:"valid_from"`
+	ValidUntil *time.Time  `json:"valid_until"`
+}
+
+type RoleAssignmentResponse struct {
+	ID           uint        `json:"id"`
+	UserID       uint        `json:"user_id"`
+	Role         models.Role `json:"role"`
+	Label        string      `json:"label"`
+	ValidFrom    *time.Time  `json:"valid_from"`
+	ValidUntil   *time.Time  `json:"valid_until"`
+	Active       bool        `json:"active"`
+	AssignedByID *uint       `json:"assigned_by_id"`
+	CreatedAt    string      `json:"created_at"`
+}
+
+func (r *RoleAssignmentRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if !r.Role.IsValid() {
+		errors["role"] = "role is invalid"
+	}
+	if len(strings.TrimSpace(r.Label)) > 100 {
+		errors["label"] = "label must be at most 100 characters"
+	}
+	if r.ValidFrom != nil && r.ValidUntil != nil && !r.ValidUntil.After(*r.ValidFrom) {
+		errors["valid_until"] = "valid_until must be after valid_from"
+	}
+
+	return errors
+}
+
+func (r *RoleAssignmentRequest) ToModel(userID uint, assignedByID *uint) models.UserRoleAssignment {
+	return models.UserRoleAssignment{
+		UserID:       userID,
+		Role:         r.Role,
+		Label:        strings.TrimSpace(r.Label),
+		ValidFrom:    r.ValidFrom,
+		ValidUntil:   r.ValidUntil,
+		AssignedByID: assignedByID,
+	}
+}
+
+func NewRoleAssignmentResponse(a models.UserRoleAssignment) RoleAssignmentResponse {
+	return RoleAssignmentResponse{
+		ID:           a.ID,
+		UserID:       a.UserID,
+		Role:         a.Role,
+		Label:        a.Label,
+		ValidFrom:    a.ValidFrom,
+		ValidUntil:   a.ValidUntil,
+		Active:       a.ActiveAt(time.Now()),
+		AssignedByID: a.AssignedByID,
+		CreatedAt:    a.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -20,8 +20,9 @@ func (r *ChangePasswordRequest) Validate() map[string]string {
 	if strings.TrimSpace(r.OldPassword) == "" {
 		errors["old_password"] = "password lama harus diisi"
 	}
-	if len(r.NewPassword) < 8 {
-		errors["new_password"] = "password baru minimal 8 karakter"
+	// Aturan password baru diperiksa services.PasswordService
+	if r.NewPassword == "" {
+		errors["new_password"] = "password baru harus diisi"
 	}
 	if r.NewPassword != r.ConfirmPassword {
 		errors["confirm_password"] = "konfirmasi password tidak cocok"
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"TugasAkhir/config"
+	apikeydto "TugasAkhir/dto/apikeys"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminListAPIKeys - GET /api/admin/api-keys
+func AdminListAPIKeys(c *fiber.Ctx) error {
+	keys, err := services.NewAPIKeyService(config.DB).List()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve api keys", err.Error())
+	}
+
+	responses := make([]apikeydto.APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		responses = append(responses, apikeydto.NewAPIKeyResponse(keys[i]))
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "api keys retrieved successfully", responses)
+}
+
+// AdminCreateAPIKey - POST /api/admin/api-keys
+// Key mentah hanya ada di respons ini; yang disimpan hanya hash-nya
+func AdminCreateAPIKey(c *fiber.Ctx) error {
+	var req apikeydto.APIKeyCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+	if validationErrors := req.Validate(time.Now()); len(validationErrors) > 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "validation error", validationErrors)
+	}
+
+	var createdByID *uint
+	admin, err := middleware.GetUserFromContext(c)
+	if err == nil {
+		createdByID = &admin.ID
+	}
+
+	key, raw, err := services.NewAPIKeyService(config.DB).Create(services.APIKeyInput{
+		Name:      req.Name,
+		OwnerID:   req.OwnerID,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}, createdByID)
+	if err != nil {
+		return apiKeyError(c, err)
+	}
+	recordAPIKeyEvent(c, models.AuthEventAPIKeyCreated, key, admin)
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "api key created successfully", apikeydto.APIKeyCreatedResponse{
+		APIKeyResponse: apikeydto.NewAPIKeyResponse(*key),
+		Key:            raw,
+	})
+}
+
+// AdminRevokeAPIKey - DELETE /api/admin/api-keys/:id
+// Key dicabut, bukan dihapus, agar riwayat surat tetap bisa menunjuknya
+func AdminRevokeAPIKey(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "api key not found", nil)
+	}
+
+	key, err := services.NewAPIKeyService(config.DB).Revoke(uint(id))
+	if err != nil {
+		return apiKeyError(c, err)
+	}
+	admin, _ := middleware.GetUserFromContext(c)
+	recordAPIKeyEvent(c, models.AuthEventAPIKeyRevoked, key, admin)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "api key revoked successfully", apikeydto.NewAPIKeyResponse(*key))
+}
+
+// recordAPIKeyEvent mencatat pembuatan / pencabutan key di log audit owner
+func recordAPIKeyEvent(c *fiber.Ctx, event models.AuthEventType, key *models.APIKey, admin *models.User) {
+	detail := fmt.Sprintf("%s (%s)", key.Name, key.Prefix)
+	if admin != nil {
+		detail += " by " + admin.Email
+	}
+	recordAuthEvent(c, event, &key.Owner, "", detail)
+}
+
+func apiKeyError(c *fiber.Ctx, err error) error {
+	var scopeErr *services.APIKeyScopeError
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "api key not found", nil)
+	case errors.Is(err, services.ErrAPIKeyNoOwner):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "validation error", fiber.Map{"owner_id": "user not found"})
+	case errors.Is(err, services.ErrAPIKeyNoScopes):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "validation error", fiber.Map{"scopes": err.Error()})
+	case errors.As(err, &scopeErr):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "validation error", fiber.Map{"scopes": err.Error()})
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to process api key", err.Error())
+}
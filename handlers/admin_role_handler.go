@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+
+	"TugasAkhir/config"
+	roledto "TugasAkhir/dto/roles"
+	"TugasAkhir/middleware"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminListPermissions - GET /api/admin/permissions
+// Katalog permission yang bisa diberikan ke role
+func AdminListPermissions(c *fiber.Ctx) error {
+	perms, err := services.NewRBACService(config.DB).ListPermissions()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve permissions", err.Error())
+	}
+
+	responses := make([]roledto.PermissionResponse, 0, len(perms))
+	for i := range perms {
+		responses = append(responses, roledto.NewPermissionResponse(perms[i]))
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "permissions retrieved successfully", responses)
+}
+
+// Create API
+func AdminCreateRole(c *fiber.Ctx) error {
+	var req roledto.RoleCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "invalid request body", err.Error())
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", validationErrors)
+	}
+
+	role := req.ToModel()
+	perms := req.Permissions
+	if perms == nil {
+		perms = []string{}
+	}
+
+	rbac := services.NewRBACService(config.DB)
+	if err := rbac.SaveRole(&role, perms, ""); err != nil {
+		return roleError(c, err)
+	}
+	return respondRole(c, rbac, role.ID, fiber.StatusCreated, "role created successfully")
+}
+
+// LIST
+func AdminListRoles(c *fiber.Ctx) error {
+	roles, err := services.NewRBACService(config.DB).ListRoles()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve roles", err.Error())
+	}
+
+	responses := make([]roledto.RoleResponse, 0, len(roles))
+	for i := range roles {
+		responses = append(responses, roledto.NewRoleResponse(roles[i]))
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "roles retrieved successfully", responses)
+}
+
+// READ ONE
+func AdminGetRole(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "role not found", nil)
+	}
+	return respondRole(c, services.NewRBACService(config.DB), uint(id), fiber.StatusOK, "role retrieved successfully")
+}
+
+// Update API (partial)
+func AdminUpdateRole(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "role not found", nil)
+	}
+
+	rbac := services.NewRBACService(config.DB)
+	role, err := rbac.GetRole(uint(id))
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	var req roledto.RoleUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "invalid request body", err.Error())
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", validationErrors)
+	}
+
+	claims, ok := middleware.GetJWTClaims(c)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	roledto.ApplyRoleUpdate(role, &req)
+	if err := rbac.SaveRole(role, req.Permissions, claims.Role); err != nil {
+		return roleError(c, err)
+	}
+	return respondRole(c, rbac, role.ID, fiber.StatusOK, "role updated successfully")
+}
+
+// Delete API (role bawaan & role yang masih dipakai user ditolak)
+func AdminDeleteRole(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "role not found", nil)
+	}
+
+	if err := services.NewRBACService(config.DB).DeleteRole(uint(id)); err != nil {
+		return roleError(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "role deleted successfully", nil)
+}
+
+func respondRole(c *fiber.Ctx, rbac *services.RBACService, id uint, status int, message string) error {
+	role, err := rbac.GetRole(id)
+	if err != nil {
+		return roleError(c, err)
+	}
+	return utils.SuccessResponse(c, status, message, roledto.NewRoleResponse(*role))
+}
+
+func roleError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "role not found", nil)
+	case errors.Is(err, services.ErrPermissionNotFound):
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"permissions": "unknown permission code"})
+	case errors.Is(err, services.ErrRoleSystem):
+		return utils.ErrorResponse(c, fiber.StatusConflict, "system role cannot be deleted", nil)
+	case errors.Is(err, services.ErrRoleLockout):
+		return utils.ErrorResponse(c, fiber.StatusConflict, err.Error(), nil)
+	case errors.Is(err, services.ErrRoleInUse):
+		return utils.ErrorResponse(c, fiber.StatusConflict, "role is still assigned to users", nil)
+	case utils.IsDuplicateError(err):
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "role name already exists", nil)
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to process role", err.Error())
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"TugasAkhir/config"
+	unitdto "TugasAkhir/dto/units"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ListActiveUnits - GET /api/units
+// Daftar unit aktif untuk dropdown (tujuan disposisi, filter list surat)
+func ListActiveUnits(c *fiber.Ctx) error {
+	var units []models.Unit
+	if err := config.DB.Where("is_active = ?", true).Order("kode ASC").Find(&units).Error; err != nil {
+		return utils.InternalServerError(c, "Gagal mengambil data unit")
+	}
+
+	responses := make([]unitdto.UnitResponse, 0, len(units))
+	for i := range units {
+		responses = append(responses, unitdto.NewUnitResponse(units[i]))
+	}
+	return utils.OK(c, "List unit berhasil diambil", responses)
+}
+
+// Create API
+func AdminCreateUnit(c *fiber.Ctx) error {
+	var req unitdto.UnitCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "invalid request body", err.Error())
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", validationErrors)
+	}
+
+	unit := req.ToModel()
+	unitService := services.NewUnitService(config.DB)
+	if err := validateUnitRelations(unitService, &unit); err != nil {
+		return unitError(c, err)
+	}
+
+	if err := config.DB.Create(&unit).Error; err != nil {
+		if utils.IsDuplicateError(err) {
+			return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "unit code already exists", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to create unit", err.Error())
+	}
+	return respondUnit(c, unitService, unit.ID, fiber.StatusCreated, "unit created successfully")
+}
+
+// LIST (termasuk unit nonaktif)
+func AdminListUnits(c *fiber.Ctx) error {
+	var units []models.Unit
+	if err := config.DB.Preload("Parent").Preload("HeadUser").Order("kode ASC").Find(&units).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve units", err.Error())
+	}
+
+	responses := make([]unitdto.UnitResponse, 0, len(units))
+	for i := range units {
+		responses = append(responses, unitdto.NewUnitResponse(units[i]))
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "units retrieved successfully", responses)
+}
+
+// READ ONE (beserta anggota)
+func AdminGetUnit(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "unit not found", nil)
+	}
+	return respondUnit(c, services.NewUnitService(config.DB), uint(id), fiber.StatusOK, "unit retrieved successfully")
+}
+
+// Update API (partial)
+func AdminUpdateUnit(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "unit not found", nil)
+	}
+
+	unitService := services.NewUnitService(config.DB)
+	unit, err := unitService.Get(uint(id))
+	if err != nil {
+		return unitError(c, err)
+	}
+
+	var req unitdto.UnitUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "invalid request body", err.Error())
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", validationErrors)
+	}
+
+	unitdto.ApplyUnitUpdate(unit, &req)
+	if err := validateUnitRelations(unitService, unit); err != nil {
+		return unitError(c, err)
+	}
+
+	if err := config.DB.Omit("Parent", "HeadUser", "Members").Save(unit).Error; err != nil {
+		if utils.IsDuplicateError(err) {
+			return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "unit code already exists", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to update unit", err.Error())
+	}
+	return respondUnit(c, unitService, unit.ID, fiber.StatusOK, "unit updated successfully")
+}
+
+// Delete API (soft delete, anggota dilepas dari unit)
+func AdminDeleteUnit(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "unit not found", nil)
+	}
+
+	if err := services.NewUnitService(config.DB).Delete(uint(id)); err != nil {
+		return unitError(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "unit deleted successfully", nil)
+}
+
+// AdminAddUnitMembers - POST /api/admin/units/:id/members
+// Memindahkan user ke unit ini (user hanya bisa menjadi anggota satu unit)
+func AdminAddUnitMembers(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "unit not found", nil)
+	}
+
+	unitService := services.NewUnitService(config.DB)
+	unit, err := unitService.GetActive(uint(id))
+	if err != nil {
+		return unitError(c, err)
+	}
+
+	var req unitdto.UnitMembersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "invalid request body", err.Error())
+	}
+	if len(req.UserIDs) == 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"user_ids": "user_ids is required"})
+	}
+
+	var found []uint
+	if err := config.DB.Model(&models.User{}).Where("id IN ?", req.UserIDs).Pluck("id", &found).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to add unit members", err.Error())
+	}
+	if missing := missingIDs(req.UserIDs, found); len(missing) > 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"user_ids": fmt.Sprintf("users not found: %v", missing)})
+	}
+
+	res := config.DB.Model(&models.User{}).Where("id IN ?", req.UserIDs).Update("unit_id", unit.ID)
+	if res.Error != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to add unit members", res.Error.Error())
+	}
+	return respondUnit(c, unitService, unit.ID, fiber.StatusOK, "unit members updated successfully")
+}
+
+// AdminRemoveUnitMember - DELETE /api/admin/units/:id/members/:userId
+func AdminRemoveUnitMember(c *fiber.Ctx) error {
+	res := config.DB.Model(&models.User{}).
+		Where("id = ? AND unit_id = ?", c.Params("userId"), c.Params("id")).
+		Update("unit_id", nil)
+	if res.Error != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to remove unit member", res.Error.Error())
+	}
+	if res.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "unit member not found", nil)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "unit member removed successfully", nil)
+}
+
+// AdminUnitReport - GET /api/admin/units/report?date_from=&date_to=
+// Rekap surat keluar & disposisi per unit
+func AdminUnitReport(c *fiber.Ctx) error {
+	errs := map[string]string{}
+	from, to := parseDateRange(c, errs)
+	if len(errs) > 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", errs)
+	}
+
+	rows, err := services.NewUnitService(config.DB).Report(from, to)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to build unit report", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "unit report retrieved successfully", rows)
+}
+
+// validateUnitRelations mengecek parent (tanpa siklus) & kepala unit
+func validateUnitRelations(unitService *services.UnitService, unit *models.Unit) error {
+	if err := unitService.ValidateParent(unit.ID, unit.ParentID); err != nil {
+		return err
+	}
+	return unitService.ValidateHead(unit.HeadUserID)
+}
+
+func respondUnit(c *fiber.Ctx, unitService *services.UnitService, id uint, status int, message string) error {
+	unit, err := unitService.Get(id)
+	if err != nil {
+		return unitError(c, err)
+	}
+	if err := config.DB.Where("unit_id = ?", unit.ID).Order("username ASC").Find(&unit.Members).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve unit members", err.Error())
+	}
+	return utils.SuccessResponse(c, status, message, unitdto.NewUnitResponse(*unit))
+}
+
+func unitError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrUnitNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "unit not found", nil)
+	case errors.Is(err, services.ErrUnitInactive):
+		return utils.ErrorResponse(c, fiber.StatusUnprocessableEntity, "unit is inactive", nil)
+	case errors.Is(err, services.ErrUnitCycle):
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"parent_id": "parent would create a cycle"})
+	case errors.Is(err, services.ErrUnitParentNotFound):
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"parent_id": "parent unit not found"})
+	case errors.Is(err, services.ErrUnitHeadNotFound):
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"head_user_id": "user not found"})
+	case errors.Is(err, services.ErrUnitHasChildren):
+		return utils.ErrorResponse(c, fiber.StatusConflict, "unit still has sub-units", nil)
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to process unit", err.Error())
+}
+
+// missingIDs mengembalikan ID di requested yang tidak ada di found
+func missingIDs(requested, found []uint) []uint {
+	exists := make(map[uint]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	var missing []uint
+	for _, id := range requested {
+		if !exists[id] {
+			missing = append(missing, id)
+			exists[id] = true
+		}
+	}
+	return missing
+}
@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"TugasAkhir/config"
 	userdto "TugasAkhir/dto/users"
+	"TugasAkhir/middleware"
 	"TugasAkhir/models"
+	"TugasAkhir/services"
 	"TugasAkhir/utils"
+	"TugasAkhir/utils/events"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
@@ -23,7 +29,7 @@ func isDupErr(err error) bool {
 	return err != nil && strings.Contains(strings.ToLower(err.Error()), "duplicate entry")
 }
 
-// Create API
+// Create API - user dibuat berstatus pending dan menerima email undangan
 func AdminCreateUser(c *fiber.Ctx) error {
 	var req userdto.AdminUserCreateRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -33,30 +39,52 @@ func AdminCreateUser(c *fiber.Ctx) error {
 	if validationErrors := req.Validate(); len(validationErrors) > 0 {
 		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", validationErrors)
 	}
-
-	passwordHash, err := utils.HashPassword(strings.TrimSpace(req.Password))
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to hash password", nil)
+	if !services.NewRBACService(config.DB).RoleExists(req.Role) {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"role": "role not found"})
 	}
 
 	user := models.User{
-		Username:     strings.TrimSpace(req.Username),
-		FirstName:    strings.TrimSpace(req.FirstName),
-		LastName:     strings.TrimSpace(req.LastName),
-		Email:        strings.TrimSpace(req.Email),
-		PasswordHash: passwordHash,
-		Role:         req.Role,
-		Jabatan:      strings.TrimSpace(req.Jabatan),
-		Atribut:      req.Atribut,
+		Username:  strings.TrimSpace(req.Username),
+		FirstName: strings.TrimSpace(req.FirstName),
+		LastName:  strings.TrimSpace(req.LastName),
+		Email:     strings.TrimSpace(req.Email),
+		Role:      req.Role,
+		Jabatan:   strings.TrimSpace(req.Jabatan),
+		Atribut:   req.Atribut,
+		Status:    models.UserStatusPending,
+	}
+	if req.UnitID != nil && *req.UnitID != 0 {
+		if _, err := services.NewUnitService(config.DB).GetActive(*req.UnitID); err != nil {
+			return userUnitError(c, err)
+		}
+		user.UnitID = req.UnitID
 	}
 
-	if err := config.DB.Create(&user).Error; err != nil {
+	var invitedByID *uint
+	if admin, err := middleware.GetUserFromContext(c); err == nil {
+		invitedByID = &admin.ID
+	}
+
+	// User & undangan dibuat dalam satu transaksi; email dikirim setelah commit
+	// sehingga kegagalan SMTP tidak membatalkan pembuatan user
+	var invitation *services.Invitation
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		var err error
+		invitation, err = services.NewInvitationService(tx).Issue(&user, invitedByID)
+		return err
+	}); err != nil {
 		if utils.IsDuplicateError(err) {
 			return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "username or email already exists", nil)
 		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to create user", err.Error())
 	}
-	return utils.SuccessResponse(c, fiber.StatusCreated, "user created successfully", userdto.NewAdminUserResponse(user))
+	return utils.SuccessResponse(c, fiber.StatusCreated, "user invited successfully", userdto.AdminUserInvitationResponse{
+		AdminUserResponse: userdto.NewAdminUserResponse(user),
+		Invitation:        deliverInvitation(&user, invitation),
+	})
 }
 
 // READ ONE
@@ -91,6 +119,12 @@ func AdminListUsers(c *fiber.Ctx) error {
 	if role != "" {
 		tx = tx.Where("role = ?", role)
 	}
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if unitID := c.QueryInt("unit_id"); unitID > 0 {
+		tx = tx.Where("unit_id = ?", unitID)
+	}
 	if q != "" {
 		like := "%" + q + "%"
 		tx = tx.Where(
@@ -152,7 +186,11 @@ func AdminUpdateUser(c *fiber.Ctx) error {
 	if req.Email != nil {
 		user.Email = strings.TrimSpace(*req.Email)
 	}
+	previousRole := user.Role
 	if req.Role != nil {
+		if !services.NewRBACService(config.DB).RoleExists(*req.Role) {
+			return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"role": "role not found"})
+		}
 		user.Role = *req.Role
 	}
 	if req.Jabatan != nil {
@@ -161,14 +199,32 @@ func AdminUpdateUser(c *fiber.Ctx) error {
 	if req.Atribut != nil {
 		user.Atribut = *req.Atribut
 	}
+	if req.UnitID != nil {
+		if *req.UnitID == 0 {
+			user.UnitID = nil
+		} else if user.UnitID == nil || *user.UnitID != *req.UnitID {
+			if _, err := services.NewUnitService(config.DB).GetActive(*req.UnitID); err != nil {
+				return userUnitError(c, err)
+			}
+			user.UnitID = req.UnitID
+		}
+	}
+	passwords := services.NewPasswordService(config.DB)
+	passwordChanged := false
 	if req.Password != nil {
 		pwd := strings.TrimSpace(*req.Password)
 		if pwd != "" {
+			if err := passwords.Validate(&user, pwd); err != nil {
+				return passwordPolicyResponse(c, "password", err)
+			}
 			hash, err := bcryptHash(pwd)
 			if err != nil {
 				return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to hash password", nil)
 			}
+			now := time.Now()
 			user.PasswordHash = hash
+			user.PasswordChangedAt = &now
+			passwordChanged = true
 		}
 	}
 
@@ -178,18 +234,176 @@ func AdminUpdateUser(c *fiber.Ctx) error {
 		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to update user", err.Error())
 	}
+	if passwordChanged {
+		if err := passwords.Record(user.ID, user.PasswordHash); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to record password history", err.Error())
+		}
+		recordAuthEvent(c, models.AuthEventPasswordChanged, &user, "", "set by admin")
+	}
+	// Password atau role diganti admin: semua perangkat user harus login ulang
+	if passwordChanged || user.Role != previousRole {
+		revokeUserSessions(user.ID)
+	}
 	return utils.SuccessResponse(c, fiber.StatusOK, "user updated successfully", userdto.NewAdminUserResponse(user))
 }
 
-// Delete User API
-func AdminDeleteUser(c *fiber.Ctx) error {
-	id := c.Params("id")
-	result := config.DB.Delete(&models.User{}, "id = ?", id)
-	if result.Error != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to delete user", result.Error.Error())
+// Deactivate User API - user tidak dihapus karena surat & riwayat masih
+// menunjuknya. Surat yang menunggu user sebagai verifikator wajib dialihkan;
+// jika belum ada pengganti, respons 409 memuat daftar surat tersebut.
+func AdminDeactivateUser(c *fiber.Ctx) error {
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "invalid user id", nil)
+	}
+
+	var req userdto.AdminUserDeactivateRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "invalid request body", err.Error())
+		}
+	}
+
+	admin, _ := middleware.GetUserFromContext(c)
+	plan := services.ReassignPlan{Default: req.ReassignTo, Letters: req.Reassignments}
+	user, reassigned, err := services.NewUserDeactivationService(config.DB).Deactivate(uint(userID), plan, admin)
+	if err != nil {
+		var unassigned *services.UnassignedLettersError
+		var replacement *services.ReplacementError
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "user not found", nil)
+		case errors.Is(err, services.ErrUserAlreadyInactive):
+			return utils.ErrorResponse(c, fiber.StatusConflict, "user is already inactive", nil)
+		case errors.Is(err, services.ErrDeactivateSelf):
+			return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "cannot deactivate your own account", nil)
+		case errors.As(err, &unassigned):
+			return utils.ErrorResponse(c, fiber.StatusConflict, "pending letters need a replacement verifier", fiber.Map{"letter_ids": unassigned.LetterIDs})
+		case errors.As(err, &replacement):
+			return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{
+				"reassignments": replacement.Error(),
+			})
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to deactivate user", err.Error())
+	}
+
+	ids := publishVerifierReassigned(reassigned)
+	recordUserStatusEvent(c, models.AuthEventUserDeactivated, user, admin, len(ids))
+	return utils.SuccessResponse(c, fiber.StatusOK, "user deactivated successfully", userdto.AdminUserDeactivateResponse{
+		AdminUserResponse: userdto.NewAdminUserResponse(*user),
+		ReassignedLetters: ids,
+	})
+}
+
+// Pending letters API - langkah pertama wizard penonaktifan: surat yang perlu
+// dialihkan beserta kandidat verifikator pengganti
+func AdminUserPendingLetters(c *fiber.Ctx) error {
+	var user models.User
+	if err := config.DB.First(&user, "id = ?", c.Params("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "user not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve user", err.Error())
+	}
+
+	pending, err := pendingLetterResponses(services.NewUserDeactivationService(config.DB), user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve pending letters", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "pending letters retrieved successfully", pending)
+}
+
+// Activate User API
+func AdminActivateUser(c *fiber.Ctx) error {
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "invalid user id", nil)
+	}
+	user, err := services.NewUserDeactivationService(config.DB).Reactivate(uint(userID))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "user not found", nil)
+		case errors.Is(err, services.ErrUserNotInactive):
+			return utils.ErrorResponse(c, fiber.StatusConflict, "user is not inactive", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to activate user", err.Error())
+	}
+	admin, _ := middleware.GetUserFromContext(c)
+	recordUserStatusEvent(c, models.AuthEventUserActivated, user, admin, 0)
+	return utils.SuccessResponse(c, fiber.StatusOK, "user activated successfully", userdto.NewAdminUserResponse(*user))
+}
+
+func recordUserStatusEvent(c *fiber.Ctx, event models.AuthEventType, user, admin *models.User, reassigned int) {
+	var parts []string
+	if admin != nil {
+		parts = append(parts, "by "+admin.Email)
+	}
+	if reassigned > 0 {
+		parts = append(parts, fmt.Sprintf("%d letters reassigned", reassigned))
+	}
+	recordAuthEvent(c, event, user, "", strings.Join(parts, "; "))
+}
+
+// pendingLetterResponses - kandidat pengganti dihitung sekali per scope
+func pendingLetterResponses(svc *services.UserDeactivationService, userID uint) ([]userdto.AdminPendingLetterResponse, error) {
+	letters, err := svc.PendingLetters(userID)
+	if err != nil {
+		return nil, err
 	}
-	if result.RowsAffected == 0 {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "user not found", nil)
+
+	candidates := make(map[string][]userdto.VerifierCandidate)
+	responses := make([]userdto.AdminPendingLetterResponse, 0, len(letters))
+	for _, letter := range letters {
+		scopeCandidates, ok := candidates[letter.Scope]
+		if !ok {
+			verifiers, err := svc.ReplacementVerifiers(letter.Scope, userID)
+			if err != nil {
+				return nil, err
+			}
+			scopeCandidates = make([]userdto.VerifierCandidate, 0, len(verifiers))
+			for _, v := range verifiers {
+				scopeCandidates = append(scopeCandidates, userdto.VerifierCandidate{ID: v.ID, Username: v.Username, Role: v.Role, Jabatan: v.Jabatan})
+			}
+			candidates[letter.Scope] = scopeCandidates
+		}
+
+		createdBy := ""
+		if letter.CreatedBy != nil {
+			createdBy = letter.CreatedBy.Username
+		}
+		responses = append(responses, userdto.AdminPendingLetterResponse{
+			ID:         letter.ID,
+			NomorSurat: letter.NomorSurat,
+			JudulSurat: letter.JudulSurat,
+			Scope:      letter.Scope,
+			Status:     letter.Status,
+			CreatedBy:  createdBy,
+			Candidates: scopeCandidates,
+		})
+	}
+	return responses, nil
+}
+
+// publishVerifierReassigned memberi tahu verifikator pengganti untuk surat
+// yang sedang menunggu verifikasi, lalu mengembalikan ID surat yang dialihkan
+func publishVerifierReassigned(letters []models.Letter) []uint {
+	ids := make([]uint, 0, len(letters))
+	for _, letter := range letters {
+		ids = append(ids, letter.ID)
+		if letter.Status == models.StatusPerluVerifikasi {
+			events.LetterEventBus <- events.LetterEvent{
+				Type:      events.LetterStatusMoved,
+				Letter:    letter,
+				OldStatus: letter.Status,
+			}
+		}
+	}
+	return ids
+}
+
+func userUnitError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrUnitNotFound) || errors.Is(err, services.ErrUnitInactive) {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"unit_id": "unit not found or inactive"})
 	}
-	return utils.SuccessResponse(c, fiber.StatusOK, "user deleted successfully", nil)
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve unit", err.Error())
 }
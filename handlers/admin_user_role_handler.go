@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+
+	"TugasAkhir/config"
+	userdto "TugasAkhir/dto/users"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// AdminListUserRoles - GET /api/admin/users/:id/roles
+// Semua penugasan role tambahan user, termasuk yang sudah berakhir
+func AdminListUserRoles(c *fiber.Ctx) error {
+	user, err := findAdminUser(c)
+	if err != nil {
+		return userRoleError(c, err)
+	}
+
+	assignments, err := services.NewUserRoleService(config.DB).ListAssignments(user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve role assignments", err.Error())
+	}
+
+	responses := make([]userdto.RoleAssignmentResponse, 0, len(assignments))
+	for _, a := range assignments {
+		responses = append(responses, userdto.NewRoleAssignmentResponse(a))
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "role assignments retrieved successfully", responses)
+}
+
+// AdminAssignUserRole - POST /api/admin/users/:id/roles
+// Menambah role tambahan (misal Plt. Direktur), opsional dibatasi waktu
+func AdminAssignUserRole(c *fiber.Ctx) error {
+	user, err := findAdminUser(c)
+	if err != nil {
+		return userRoleError(c, err)
+	}
+
+	var req userdto.RoleAssignmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "invalid request body", err.Error())
+	}
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", validationErrors)
+	}
+
+	var assignedByID *uint
+	if admin, err := middleware.GetUserFromContext(c); err == nil {
+		assignedByID = &admin.ID
+	}
+
+	assignment := req.ToModel(user.ID, assignedByID)
+	if err := services.NewUserRoleService(config.DB).Assign(&assignment); err != nil {
+		return userRoleError(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusCreated, "role assigned successfully", userdto.NewRoleAssignmentResponse(assignment))
+}
+
+// AdminRevokeUserRole - DELETE /api/admin/users/:id/roles/:assignmentId
+// Semua sesi user dicabut sehingga user harus login ulang; access token yang
+// sudah terbit langsung ditolak.
+func AdminRevokeUserRole(c *fiber.Ctx) error {
+	user, err := findAdminUser(c)
+	if err != nil {
+		return userRoleError(c, err)
+	}
+
+	assignmentID, err := c.ParamsInt("assignmentId")
+	if err != nil || assignmentID < 1 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "role assignment not found", nil)
+	}
+
+	if err := services.NewUserRoleService(config.DB).DeleteAssignment(user.ID, uint(assignmentID)); err != nil {
+		return userRoleError(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "role assignment revoked successfully", nil)
+}
+
+func findAdminUser(c *fiber.Ctx) (*models.User, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var user models.User
+	if err := config.DB.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func userRoleError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "user not found", nil)
+	case errors.Is(err, services.ErrRoleAssignmentNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "role assignment not found", nil)
+	case errors.Is(err, services.ErrRoleNotFound):
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"role": "role not found"})
+	case errors.Is(err, services.ErrRoleAssignmentPrimary):
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"role": "role is already the user's primary role"})
+	case errors.Is(err, services.ErrRoleAssignmentInvalidSpan):
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", fiber.Map{"valid_until": "valid_until must be after valid_from"})
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to process role assignment", err.Error())
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"strconv"
+
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Detail auth event untuk login lewat panel admin (selain itu lewat API)
+const authEventWebDetail = "admin panel"
+
+// recordAuthEvent mencatat kejadian autentikasi beserta IP & user agent
+// request. user boleh nil (misal email yang dicoba tidak terdaftar).
+func recordAuthEvent(c *fiber.Ctx, event models.AuthEventType, user *models.User, email, detail string) {
+	record := models.AuthEvent{
+		Email:     email,
+		Event:     event,
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Detail:    detail,
+	}
+	if user != nil {
+		record.UserID = &user.ID
+		record.Email = user.Email
+	}
+	services.NewAuthEventService(config.DB).Record(record)
+}
+
+// loginLock memeriksa apakah akun / IP sedang dikunci sebelum kredensial
+// diperiksa
+func loginLock(c *fiber.Ctx, email string) (*services.LoginLockError, error) {
+	err := services.NewLoginThrottleService(config.DB).Check(email, c.IP())
+	var lockErr *services.LoginLockError
+	if errors.As(err, &lockErr) {
+		return lockErr, nil
+	}
+	return nil, err
+}
+
+// loginFailed mencatat percobaan login yang gagal ke throttle dan log audit.
+// Mengembalikan *LoginLockError jika kegagalan ini membuat akun / IP terkunci.
+func loginFailed(c *fiber.Ctx, email string, user *models.User, reason string) *services.LoginLockError {
+	recordAuthEvent(c, models.AuthEventLoginFailure, user, email, reason)
+
+	err := services.NewLoginThrottleService(config.DB).RecordFailure(email, c.IP())
+	var lockErr *services.LoginLockError
+	if errors.As(err, &lockErr) {
+		recordAuthEvent(c, models.AuthEventLoginLocked, user, email, "locked for "+lockErr.RetryAfter.String())
+		return lockErr
+	}
+	if err != nil {
+		log.Printf("[login-throttle] gagal mencatat kegagalan login %q: %v", email, err)
+	}
+	return nil
+}
+
+// loginSucceeded mereset counter kegagalan akun dan mencatat login berhasil
+func loginSucceeded(c *fiber.Ctx, user *models.User, detail string) {
+	resetLoginThrottle(user)
+	recordAuthEvent(c, models.AuthEventLoginSuccess, user, "", detail)
+}
+
+// resetLoginThrottle mereset counter kegagalan akun setelah kredensial user
+// terbukti benar
+func resetLoginThrottle(user *models.User) {
+	if err := services.NewLoginThrottleService(config.DB).RecordSuccess(user.Email); err != nil {
+		log.Printf("[login-throttle] gagal mereset counter user %d: %v", user.ID, err)
+	}
+}
+
+// retryAfterSeconds - dibulatkan ke atas agar klien tidak mencoba terlalu cepat
+func retryAfterSeconds(lockErr *services.LoginLockError) int {
+	return int(math.Ceil(lockErr.RetryAfter.Seconds()))
+}
+
+func tooManyLoginAttempts(c *fiber.Ctx, lockErr *services.LoginLockError) error {
+	seconds := retryAfterSeconds(lockErr)
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return utils.ErrorResponse(c, fiber.StatusTooManyRequests, "too many failed login attempts, try again later", fiber.Map{
+		"retry_after": seconds,
+	})
+}
@@ -15,12 +15,13 @@ import (
 	"time"
 
 	"TugasAkhir/config"
+	"TugasAkhir/middleware"
 	"TugasAkhir/models"
+	"TugasAkhir/services"
 	"TugasAkhir/utils/mailer"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 // Login - Tetap sama
@@ -36,36 +37,85 @@ func Login(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "email and password are required", nil)
 	}
 
-	var user models.User
-	if err := config.DB.Where("email = ?", email).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid email or password", nil)
-		}
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to fetch user", err.Error())
+	// Akun / IP yang terlalu sering gagal dikunci sementara
+	lockErr, err := loginLock(c, email)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to check login attempts", err.Error())
+	}
+	if lockErr != nil {
+		recordAuthEvent(c, models.AuthEventLoginFailure, nil, email, "locked")
+		return tooManyLoginAttempts(c, lockErr)
 	}
 
-	if !utils.CheckPassword(user.PasswordHash, password) {
+	// Password lokal atau direktori LDAP (lihat authenticatePassword)
+	user, reason, err := authenticatePassword(c, email, password)
+	if errors.Is(err, services.ErrPasswordExpired) {
+		return passwordExpiredLogin(c, user)
+	}
+	if err != nil {
+		if isSSOError(err) {
+			return ssoErrorResponse(c, err)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to verify credentials", err.Error())
+	}
+	if reason != "" {
+		if lockErr := loginFailed(c, email, user, reason); lockErr != nil {
+			return tooManyLoginAttempts(c, lockErr)
+		}
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid email or password", nil)
 	}
 
-	accessToken, _, err := utils.GenerateAccessToken(user)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to generate access token", err.Error())
+	return finishLogin(c, *user, req.DeviceInfo)
+}
+
+// finishLogin - langkah setelah kredensial (password, LDAP, atau OIDC) valid
+func finishLogin(c *fiber.Ctx, user models.User, device dto.DeviceInfo) error {
+	if !user.CanLogin() {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "account is not active", nil)
 	}
 
-	refreshToken, refreshClaims, err := utils.GenerateRefreshToken(user)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to generate refresh token", err.Error())
+	// Akun dengan 2FA (atau yang role-nya mewajibkan 2FA) lanjut ke langkah
+	// kedua; token baru diterbitkan setelah kode diverifikasi
+	if challenged, err := startTwoFactorChallenge(c, user); challenged || err != nil {
+		return err
 	}
 
-	tokenRecord := models.RefreshToken{
-		UserID:    user.ID,
-		Token:     refreshToken,
-		ExpiresAt: refreshClaims.ExpiresAt.Time,
+	return completeLogin(c, user, device, nil)
+}
+
+// completeLogin membuat sesi baru dan menerbitkan access & refresh token
+// setelah semua langkah login berhasil
+func completeLogin(c *fiber.Ctx, user models.User, device dto.DeviceInfo, recoveryCodes []string) error {
+	// Login selalu dimulai dengan role utama; role lain dipilih lewat /auth/switch-role
+	roles, err := services.NewUserRoleService(config.DB).AvailableRoles(&user)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to fetch user roles", err.Error())
 	}
-	if err := config.DB.Create(&tokenRecord).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to store refresh token", err.Error())
+	active := roles[0]
+
+	var (
+		session       *models.UserSession
+		accessToken   string
+		refreshToken  string
+		refreshClaims *utils.JWTClaims
+	)
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		sessions := services.NewSessionService(tx)
+		var err error
+		if session, err = sessions.Start(user.ID, sessionDevice(c, device)); err != nil {
+			return err
+		}
+		if accessToken, _, err = utils.GenerateAccessTokenAs(user, active, session.ID); err != nil {
+			return err
+		}
+		if refreshToken, refreshClaims, err = utils.GenerateRefreshTokenAs(user, active, session.ID); err != nil {
+			return err
+		}
+		return sessions.Attach(session, refreshToken, refreshClaims.ExpiresAt.Time)
+	}); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to start session", err.Error())
 	}
+	loginSucceeded(c, &user, "")
 
 	resp := dto.LoginResponse{
 		AccessToken:  accessToken,
@@ -73,13 +123,25 @@ func Login(c *fiber.Ctx) error {
 		TokenType:    "Bearer",
 		ExpiresAt:    refreshClaims.ExpiresAt.Time,
 		User:         toUserSummary(user),
+		ActiveRole:   active,
+		Roles:        roles,
+
+		RecoveryCodes: recoveryCodes,
+		SessionID:     session.ID,
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "login successful", resp)
 }
 
-// Register - Update Validasi Role
+// Register - pendaftaran mandiri, mati secara default (ALLOW_SELF_REGISTRATION).
+// Akun baru normalnya dibuat admin lewat undangan; jika diaktifkan, role yang
+// bisa dipilih dibatasi SELF_REGISTRATION_ROLES.
 func Register(c *fiber.Ctx) error {
+	authCfg := config.LoadAuthConfig()
+	if !authCfg.AllowSelfRegistration {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "self-registration is disabled", nil)
+	}
+
 	var req dto.RegisterRequest
 	if err := c.BodyParser(&req); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
@@ -102,29 +164,39 @@ func Register(c *fiber.Ctx) error {
 	if _, err := mail.ParseAddress(req.Email); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid email format", nil)
 	}
-	if req.Password == "" || len(req.Password) < 8 {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "password must be at least 8 characters", nil)
+	if req.Password == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "password is required", nil)
 	}
 
-	if !req.Role.IsValid() {
+	if !req.Role.IsValid() || !services.NewRBACService(config.DB).RoleExists(req.Role) {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid role provided", nil)
 	}
+	if !authCfg.CanSelfRegisterAs(string(req.Role)) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "role is not available for self-registration", nil)
+	}
+
+	now := time.Now()
+	user := models.User{
+		Username:          req.Username,
+		FirstName:         req.FirstName,
+		LastName:          req.LastName,
+		Email:             req.Email,
+		Role:              req.Role,
+		Jabatan:           req.Jabatan,
+		Atribut:           req.Atribut,
+		Status:            models.UserStatusActive,
+		PasswordChangedAt: &now,
+	}
 
+	passwords := services.NewPasswordService(config.DB)
+	if err := passwords.Validate(&user, req.Password); err != nil {
+		return passwordPolicyResponse(c, "password", err)
+	}
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to process password", err.Error())
 	}
-
-	user := models.User{
-		Username:     req.Username,
-		FirstName:    req.FirstName,
-		LastName:     req.LastName,
-		Email:        req.Email,
-		PasswordHash: hashedPassword,
-		Role:         req.Role,
-		Jabatan:      req.Jabatan,
-		Atribut:      req.Atribut,
-	}
+	user.PasswordHash = hashedPassword
 
 	if err := config.DB.Create(&user).Error; err != nil {
 		if isDuplicateEntryError(err) {
@@ -132,6 +204,9 @@ func Register(c *fiber.Ctx) error {
 		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to create user", err.Error())
 	}
+	if err := passwords.Record(user.ID, hashedPassword); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to record password history", err.Error())
+	}
 
 	resp := dto.RegisterResponse{
 		User:    toUserSummary(user),
@@ -163,8 +238,9 @@ func RefreshToken(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to start transaction", tx.Error.Error())
 	}
 
-	var stored models.RefreshToken
-	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token = ?", token).First(&stored).Error; err != nil {
+	sessions := services.NewSessionService(tx)
+	stored, err := sessions.LockRefreshToken(token)
+	if err != nil {
 		tx.Rollback()
 		if err == gorm.ErrRecordNotFound {
 			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid or expired refresh token", nil)
@@ -178,7 +254,7 @@ func RefreshToken(c *fiber.Ctx) error {
 	}
 
 	if time.Now().After(stored.ExpiresAt) {
-		if err := tx.Delete(&stored).Error; err != nil {
+		if err := tx.Delete(stored).Error; err != nil {
 			tx.Rollback()
 			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to invalidate refresh token", err.Error())
 		}
@@ -196,30 +272,162 @@ func RefreshToken(c *fiber.Ctx) error {
 		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to fetch user", err.Error())
 	}
+	if !user.CanLogin() {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "account is not active", nil)
+	}
 
-	accessToken, _, err := utils.GenerateAccessToken(user)
+	// Role aktif di refresh token diperiksa ulang: penugasan yang sudah berakhir
+	// atau dicabut kembali ke role utama
+	active, err := services.NewUserRoleService(tx).Resolve(&user, claims.Role)
+	if errors.Is(err, services.ErrRoleNotAssigned) {
+		active = models.ActingRole{Role: user.Role, Primary: true}
+	} else if err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to fetch user roles", err.Error())
+	}
+
+	session, err := sessions.Resume(stored, sessionDevice(c, req.DeviceInfo))
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid or expired refresh token", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to fetch session", err.Error())
+	}
+
+	accessToken, _, err := utils.GenerateAccessTokenAs(user, active, session.ID)
 	if err != nil {
 		tx.Rollback()
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to generate access token", err.Error())
 	}
 
-	refreshToken, refreshClaims, err := utils.GenerateRefreshToken(user)
+	refreshToken, refreshClaims, err := utils.GenerateRefreshTokenAs(user, active, session.ID)
 	if err != nil {
 		tx.Rollback()
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to generate refresh token", err.Error())
 	}
 
-	if err := tx.Delete(&stored).Error; err != nil {
+	if err := sessions.Rotate(stored, session, refreshToken, refreshClaims.ExpiresAt.Time, c.IP()); err != nil {
 		tx.Rollback()
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to invalidate refresh token", err.Error())
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to store refresh token", err.Error())
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to store refresh token", err.Error())
 	}
+	recordAuthEvent(c, models.AuthEventTokenRefresh, &user, "", "")
 
-	newRecord := models.RefreshToken{
-		UserID:    user.ID,
-		Token:     refreshToken,
-		ExpiresAt: refreshClaims.ExpiresAt.Time,
+	resp := dto.RefreshTokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresAt:    refreshClaims.ExpiresAt.Time,
+		ActiveRole:   active,
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "token refreshed successfully", resp)
+}
+
+// SwitchRole - POST /api/auth/switch-role (butuh access token)
+// Mengganti role aktif, misal Manajer PKL yang bertugas sebagai Plt. Direktur.
+// Token baru membawa role tersebut; refresh token lama dicabut.
+func SwitchRole(c *fiber.Ctx) error {
+	current, ok := middleware.GetJWTClaims(c)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
 	}
-	if err := tx.Create(&newRecord).Error; err != nil {
+
+	var req dto.SwitchRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+
+	token := strings.TrimSpace(req.RefreshToken)
+	if token == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "refresh token is required", nil)
+	}
+	if req.Role == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "role is required", nil)
+	}
+
+	claims, err := utils.VerifyRefreshToken(token)
+	if err != nil || claims.UserID != current.UserID {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid or expired refresh token", nil)
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to start transaction", tx.Error.Error())
+	}
+
+	sessions := services.NewSessionService(tx)
+	stored, err := sessions.LockRefreshToken(token)
+	if err == nil && stored.UserID != current.UserID {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid or expired refresh token", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to validate refresh token", err.Error())
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid or expired refresh token", nil)
+	}
+
+	var user models.User
+	if err := tx.First(&user, current.UserID).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "user no longer exists", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to fetch user", err.Error())
+	}
+	if !user.CanLogin() {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "account is not active", nil)
+	}
+
+	roleService := services.NewUserRoleService(tx)
+	active, err := roleService.Resolve(&user, req.Role)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, services.ErrRoleNotAssigned) {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "role is not assigned to this user", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to fetch user roles", err.Error())
+	}
+	roles, err := roleService.AvailableRoles(&user)
+	if err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to fetch user roles", err.Error())
+	}
+
+	session, err := sessions.Resume(stored, sessionDevice(c, dto.DeviceInfo{}))
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid or expired refresh token", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to fetch session", err.Error())
+	}
+
+	accessToken, _, err := utils.GenerateAccessTokenAs(user, active, session.ID)
+	if err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to generate access token", err.Error())
+	}
+
+	refreshToken, refreshClaims, err := utils.GenerateRefreshTokenAs(user, active, session.ID)
+	if err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to generate refresh token", err.Error())
+	}
+
+	if err := sessions.Rotate(stored, session, refreshToken, refreshClaims.ExpiresAt.Time, c.IP()); err != nil {
 		tx.Rollback()
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to store refresh token", err.Error())
 	}
@@ -228,14 +436,18 @@ func RefreshToken(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to store refresh token", err.Error())
 	}
 
-	resp := dto.RefreshTokenResponse{
+	resp := dto.LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		TokenType:    "Bearer",
 		ExpiresAt:    refreshClaims.ExpiresAt.Time,
+		User:         toUserSummary(user),
+		ActiveRole:   active,
+		Roles:        roles,
+		SessionID:    session.ID,
 	}
 
-	return utils.SuccessResponse(c, fiber.StatusOK, "token refreshed successfully", resp)
+	return utils.SuccessResponse(c, fiber.StatusOK, "role switched successfully", resp)
 }
 
 // RequestPasswordReset - Tetap sama
@@ -257,44 +469,36 @@ func RequestPasswordReset(c *fiber.Ctx) error {
 	var user models.User
 	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			utils.SuccessResponse(c, fiber.StatusOK, "if the email exists, a reset link has been sent", nil)
+			recordAuthEvent(c, models.AuthEventPasswordResetRequested, nil, req.Email, "unknown email")
+			return utils.SuccessResponse(c, fiber.StatusOK, "if the email exists, a reset link has been sent", nil)
 		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to fetch user", err.Error())
 	}
-
-	usedAt := time.Now()
-	if err := config.DB.Model(&models.PasswordResetToken{}).
-		Where("user_id = ? AND used = ?", user.ID, false).
-		Updates(map[string]any{
-			"used":    true,
-			"used_at": &usedAt,
-		}).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to invalidate previous reset tokens", err.Error())
-	}
-
-	rawToken, tokenHash, err := generateResetToken()
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to generate token", err.Error())
+	// User undangan belum punya password; aktivasi lewat link undangan
+	if user.Status == models.UserStatusPending {
+		return utils.SuccessResponse(c, fiber.StatusOK, "if the email exists, a reset link has been sent", nil)
 	}
 
-	resetToken := models.PasswordResetToken{
-		UserID:    user.ID,
-		TokenHash: tokenHash,
-		ExpiresAt: time.Now().Add(models.PasswordResetTokenTTL),
+	if err := sendPasswordResetLink(c, &user, ""); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to send reset email", err.Error())
 	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "if the email exists, a reset link has been sent", nil)
+}
 
-	if err := config.DB.Create(&resetToken).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to store reset token", err.Error())
+// sendPasswordResetLink menerbitkan token reset baru dan mengirim link-nya ke
+// email user (dipakai lupa password dan password kedaluwarsa)
+func sendPasswordResetLink(c *fiber.Ctx, user *models.User, detail string) error {
+	rawToken, err := issuePasswordResetToken(user.ID)
+	if err != nil {
+		return err
 	}
+	recordAuthEvent(c, models.AuthEventPasswordResetRequested, user, "", detail)
 
 	resetLink := buildResetLink(rawToken)
 	fmt.Printf("\n[DEBUG] PASSWORD RESET LINK: %s\n\n", resetLink)
 	emailCfg := config.LoadEmailConfig()
 	mailClient := mailer.NewClient(emailCfg)
-	if err := mailClient.SendPasswordResetEmail(user.Email, resetLink); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to send reset email", err.Error())
-	}
-	return utils.SuccessResponse(c, fiber.StatusOK, "if the email exists, a reset link has been sent", nil)
+	return mailClient.SendPasswordResetEmail(user.Email, resetLink)
 }
 
 // ResetPassword - Tetap sama
@@ -310,8 +514,8 @@ func ResetPassword(c *fiber.Ctx) error {
 	if req.Token == "" {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "token is required", nil)
 	}
-	if len(req.Password) < 8 {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "password must be at least 8 characters", nil)
+	if req.Password == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "password is required", nil)
 	}
 	if req.Password != req.ConfirmPassword {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "password confirmation does not match", nil)
@@ -332,6 +536,10 @@ func ResetPassword(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid or expired token", nil)
 	}
 
+	// Token belum dipakai jika password ditolak, jadi user bisa mencoba lagi
+	if err := services.NewPasswordService(config.DB).Validate(&reset.User, req.Password); err != nil {
+		return passwordPolicyResponse(c, "password", err)
+	}
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to process password", err.Error())
@@ -341,9 +549,7 @@ func ResetPassword(c *fiber.Ctx) error {
 			return err
 		}
 
-		if err := tx.Model(&models.User{}).
-			Where("id = ?", reset.UserID).
-			Update("password_hash", hashedPassword).Error; err != nil {
+		if err := services.NewPasswordService(tx).Apply(&reset.User, hashedPassword); err != nil {
 			return err
 		}
 
@@ -351,7 +557,9 @@ func ResetPassword(c *fiber.Ctx) error {
 			return res.Error
 		}
 
-		return nil
+		// Semua perangkat harus login ulang dengan password baru
+		_, err := services.NewSessionService(tx).RevokeAll(reset.UserID)
+		return err
 	}); err != nil {
 		switch {
 		case errors.Is(err, models.ErrPasswordResetTokenExpired), errors.Is(err, models.ErrPasswordResetTokenUsed):
@@ -360,6 +568,7 @@ func ResetPassword(c *fiber.Ctx) error {
 			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to reset password", err.Error())
 		}
 	}
+	recordAuthEvent(c, models.AuthEventPasswordResetCompleted, &reset.User, "", "")
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "password has been reset successfully", nil)
 }
@@ -376,9 +585,16 @@ func Logout(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "refresh token is required", nil)
 	}
 
-	result := config.DB.Where("token = ?", token).Delete(&models.RefreshToken{})
-	if result.Error != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to revoke refresh token", result.Error.Error())
+	// Logout mengakhiri seluruh sesi perangkat ini, bukan hanya token yang dikirim
+	stored, err := services.NewSessionService(config.DB).RevokeToken(token)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to revoke refresh token", err.Error())
+	}
+	if stored != nil {
+		var user models.User
+		if err := config.DB.First(&user, stored.UserID).Error; err == nil {
+			recordAuthEvent(c, models.AuthEventLogout, &user, "", "")
+		}
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "logout successful", nil)
@@ -394,22 +610,9 @@ func toUserSummary(user models.User) dto.UserSummary {
 		Role:      user.Role,
 		Jabatan:   user.Jabatan,
 		Atribut:   user.Atribut,
-	}
-}
+		UnitID:    user.UnitID,
 
-// FIX: Helper isValidRole untuk Role Baru
-func isValidRole(role models.Role) bool {
-	switch role {
-	case models.RoleAdmin,
-		models.RoleDirektur,
-		models.RoleStafProgram,
-		models.RoleStafLembaga,
-		models.RoleManajerKPP,
-		models.RoleManajerPemas,
-		models.RoleManajerPKL:
-		return true
-	default:
-		return false
+		HasSignature: user.SignatureImagePath != "",
 	}
 }
 
@@ -421,6 +624,34 @@ func isDuplicateEntryError(err error) bool {
 	return strings.Contains(msg, "duplicate entry") || strings.Contains(msg, "unique constraint")
 }
 
+// issuePasswordResetToken membatalkan token reset lama user lalu menerbitkan
+// token baru (dipakai lupa password dan password kedaluwarsa)
+func issuePasswordResetToken(userID uint) (string, error) {
+	usedAt := time.Now()
+	if err := config.DB.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used = ?", userID, false).
+		Updates(map[string]any{
+			"used":    true,
+			"used_at": &usedAt,
+		}).Error; err != nil {
+		return "", err
+	}
+
+	rawToken, tokenHash, err := generateResetToken()
+	if err != nil {
+		return "", err
+	}
+	resetToken := models.PasswordResetToken{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(models.PasswordResetTokenTTL),
+	}
+	if err := config.DB.Create(&resetToken).Error; err != nil {
+		return "", err
+	}
+	return rawToken, nil
+}
+
 func generateResetToken() (string, string, error) {
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
@@ -478,14 +709,14 @@ func ShowResetPasswordForm(c *fiber.Ctx) error {
         <h2>Reset Kata Sandi</h2>
         <form action="/api/auth/reset-password" method="POST">
             <input type="hidden" name="token" value="%s">
-            <input type="password" name="password" placeholder="Kata Sandi Baru" required minlength="8">
-            <input type="password" name="confirm_password" placeholder="Konfirmasi Kata Sandi" required minlength="8">
+            <input type="password" name="password" placeholder="Kata Sandi Baru" required minlength="%[2]d">
+            <input type="password" name="confirm_password" placeholder="Konfirmasi Kata Sandi" required minlength="%[2]d">
             <button type="submit">Reset Kata Sandi</button>
         </form>
     </div>
 </body>
 </html>
-`, token)
+`, token, config.LoadPasswordConfig().MinLength)
 
 	c.Set("Content-Type", "text/html")
 	return c.SendString(html)
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"TugasAkhir/config"
+	"TugasAkhir/dto"
+	userdto "TugasAkhir/dto/users"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+	"TugasAkhir/utils/mailer"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// sendInvitation membuat undangan baru dan mengirimkannya ke email user
+func sendInvitation(user *models.User, invitedByID *uint) (userdto.InvitationStatus, error) {
+	invitation, err := services.NewInvitationService(config.DB).Issue(user, invitedByID)
+	if err != nil {
+		return userdto.InvitationStatus{}, err
+	}
+	return deliverInvitation(user, invitation), nil
+}
+
+// deliverInvitation mengirim email undangan. Kegagalan email tidak dianggap
+// error: link dikembalikan di status agar admin bisa menyampaikannya sendiri
+// atau mengirim ulang.
+func deliverInvitation(user *models.User, invitation *services.Invitation) userdto.InvitationStatus {
+	status := userdto.InvitationStatus{
+		Sent:      true,
+		ExpiresAt: invitation.ExpiresAt.Format(time.RFC3339),
+	}
+	mailClient := mailer.NewClient(config.LoadEmailConfig())
+	if err := mailClient.SendInvitationEmail(user.Email, user.Username, invitation.Link); err != nil {
+		log.Printf("[invitation] gagal mengirim undangan ke user %d: %v", user.ID, err)
+		status.Sent = false
+		status.Link = invitation.Link
+	}
+	return status
+}
+
+// AdminResendInvitation - POST /api/admin/users/:id/invitation
+// Membuat link undangan baru (link lama tidak berlaku lagi)
+func AdminResendInvitation(c *fiber.Ctx) error {
+	user, err := findAdminUser(c)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "user not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve user", err.Error())
+	}
+
+	var invitedByID *uint
+	if admin, err := middleware.GetUserFromContext(c); err == nil {
+		invitedByID = &admin.ID
+	}
+
+	status, err := sendInvitation(user, invitedByID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvitationNotPending) {
+			return utils.ErrorResponse(c, fiber.StatusConflict, "user has already activated the account", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to create invitation", err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "invitation sent successfully", userdto.AdminUserInvitationResponse{
+		AdminUserResponse: userdto.NewAdminUserResponse(*user),
+		Invitation:        status,
+	})
+}
+
+// AcceptInvitation - POST /api/auth/accept-invitation
+// Menerima JSON atau form (dari ShowAcceptInvitationForm)
+func AcceptInvitation(c *fiber.Ctx) error {
+	var req dto.AcceptInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+
+	req.Token = strings.TrimSpace(req.Token)
+	req.Password = strings.TrimSpace(req.Password)
+
+	if req.Token == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "token is required", nil)
+	}
+	if req.Password == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "password is required", nil)
+	}
+	if req.Password != req.ConfirmPassword {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "password confirmation does not match", nil)
+	}
+
+	// Kebijakan password memakai data akun (username / email) dari undangan
+	invitations := services.NewInvitationService(config.DB)
+	invitation, err := invitations.Lookup(req.Token)
+	if err != nil {
+		return invitationError(c, err)
+	}
+	if err := services.NewPasswordService(config.DB).Validate(&invitation.User, req.Password); err != nil {
+		return passwordPolicyResponse(c, "password", err)
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to process password", err.Error())
+	}
+
+	user, err := invitations.Accept(req.Token, hashedPassword)
+	if err != nil {
+		return invitationError(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "account activated successfully", toUserSummary(*user))
+}
+
+func invitationError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvitationNotFound),
+		errors.Is(err, services.ErrInvitationNotPending),
+		errors.Is(err, models.ErrInvitationExpired),
+		errors.Is(err, models.ErrInvitationUsed):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid or expired invitation", nil)
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to accept invitation", err.Error())
+}
+
+// ShowAcceptInvitationForm - GET /api/auth/accept-invitation?token=...
+func ShowAcceptInvitationForm(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	invitation, err := services.NewInvitationService(config.DB).Lookup(token)
+	if err != nil {
+		c.Set("Content-Type", "text/html")
+		return c.Status(fiber.StatusBadRequest).SendString(invitationPage(`
+        <h2>Undangan Tidak Berlaku</h2>
+        <p class="message">Link undangan tidak valid, sudah dipakai, atau kedaluwarsa. Hubungi administrator untuk mengirim ulang undangan.</p>`))
+	}
+
+	minLength := config.LoadPasswordConfig().MinLength
+	form := fmt.Sprintf(`
+        <h2>Aktivasi Akun</h2>
+        <p>Halo <strong>%s</strong>, atur kata sandi untuk akun Anda.</p>
+        <form action="/api/auth/accept-invitation" method="POST">
+            <input type="hidden" name="token" value="%s">
+            <input type="password" name="password" placeholder="Kata Sandi" required minlength="%d">
+            <input type="password" name="confirm_password" placeholder="Konfirmasi Kata Sandi" required minlength="%d">
+            <button type="submit">Aktifkan Akun</button>
+        </form>`, html.EscapeString(invitation.User.Username), html.EscapeString(token), minLength, minLength)
+
+	c.Set("Content-Type", "text/html")
+	return c.SendString(invitationPage(form))
+}
+
+func invitationPage(content string) string {
+	return `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Aktivasi Akun</title>
+    <style>
+        body { font-family: sans-serif; display: flex; justify-content: center; align-items: center; height: 100vh; background-color: #f0f2f5; }
+        .container { background: white; padding: 2rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); width: 100%; max-width: 400px; }
+        h2 { text-align: center; color: #1a73e8; }
+        input { width: 100%; padding: 10px; margin: 10px 0; border: 1px solid #ddd; border-radius: 4px; box-sizing: border-box; }
+        button { width: 100%; padding: 10px; background-color: #1a73e8; color: white; border: none; border-radius: 4px; cursor: pointer; font-size: 16px; }
+        button:hover { background-color: #1557b0; }
+        .message { text-align: center; color: red; margin-bottom: 10px; }
+    </style>
+</head>
+<body>
+    <div class="container">` + content + `
+    </div>
+</body>
+</html>
+`
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// JWKS - GET /.well-known/jwks.json
+// Public key untuk memverifikasi access token dari layanan lain. Formatnya
+// mengikuti RFC 7517 (bukan envelope SuccessResponse) agar bisa dibaca library
+// JWT standar.
+func JWKS(c *fiber.Ctx) error {
+	set, err := utils.CurrentJWKS()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to load signing keys", err.Error())
+	}
+
+	// Key lama tetap tercantum selama rotasi, jadi cache singkat cukup aman
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(set)
+}
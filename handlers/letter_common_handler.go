@@ -1,37 +1,29 @@
 package handlers
 
 import (
+	letterdto "TugasAkhir/dto/letters"
 	"TugasAkhir/middleware"
 	"TugasAkhir/models"
 	"TugasAkhir/services"
+	"TugasAkhir/utils"
 	"TugasAkhir/utils/storage"
+	"fmt"
+	"log"
+	"path"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
+// Presigned URL hasil redirect hanya perlu hidup sampai klien mulai mengunduh
+const fileRedirectExpiry = time.Minute
+
 type LetterCommonHandler struct {
 	db          *gorm.DB
 	permService *services.PermissionService
 }
 
-// AddPresignedURLToLetter - Helper untuk menambahkan presigned URL ke satu surat
-func AddPresignedURLToLetter(letter *models.Letter) {
-	if letter.FilePath != "" {
-		url, err := storage.GetPresignedURL(letter.FilePath)
-		if err == nil {
-			letter.FilePath = url
-		}
-	}
-}
-
-// AddPresignedURLsToLetters - Helper untuk menambahkan presigned URL ke slice surat
-func AddPresignedURLsToLetters(letters []models.Letter) {
-	for i := range letters {
-		AddPresignedURLToLetter(&letters[i])
-	}
-}
-
 func NewLetterCommonHandler(db *gorm.DB) *LetterCommonHandler {
 	return &LetterCommonHandler{
 		db:          db,
@@ -50,7 +42,10 @@ func (h *LetterCommonHandler) GetLetterByID(c *fiber.Ctx) error {
 
 	// Preload relasi lengkap agar frontend senang
 	var letter models.Letter
-	if err := h.db.Preload("CreatedBy").Preload("AssignedVerifier").Preload("VerifiedBy").Preload("DisposedBy").First(&letter, letterID).Error; err != nil {
+	if err := h.db.Preload("CreatedBy").Preload("AssignedVerifier").Preload("VerifiedBy").Preload("DisposedBy").Preload("SignedBy").Preload("Verification").
+		Preload("Unit").Preload("TujuanUnit").
+		Preload("Tembusan", func(db *gorm.DB) *gorm.DB { return db.Order("urutan ASC") }).Preload("Tembusan.User").
+		First(&letter, letterID).Error; err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Letter not found"})
 	}
 
@@ -60,10 +55,96 @@ func (h *LetterCommonHandler) GetLetterByID(c *fiber.Ctx) error {
 		return c.Status(403).JSON(fiber.Map{"error": "Anda tidak memiliki akses melihat surat ini"})
 	}
 
-	// [FIX] Generate Presigned URL agar gambar bisa dibuka di frontend
-	AddPresignedURLToLetter(&letter)
+	setLetterETag(c, &letter)
+
+	// File diakses lewat GET /letters/:id/file, detail hanya berisi metadata
+	data, err := utils.SelectFields(letterdto.NewLetterResponse(&letter), utils.ParseFields(c))
+	if err != nil {
+		return fieldsError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": data})
+}
+
+// GetMyTembusan - List surat yang ditembuskan ke saya (langsung atau lewat unit/role)
+func (h *LetterCommonHandler) GetMyTembusan(c *fiber.Ctx) error {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "Unauthorized")
+	}
+
+	query := h.db.Scopes(services.ScopeTembusanRecipient(user))
+	return respondLetterList(c, query, "-updated_at", "List surat tembusan berhasil diambil")
+}
+
+// GetLetterFile - Akses file surat (varian: current / original) setelah cek
+// izin lihat. Default redirect ke presigned URL singkat, ?mode=stream untuk
+// mengalirkan file lewat server. Setiap akses dicatat.
+func (h *LetterCommonHandler) GetLetterFile(c *fiber.Ctx) error {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "Unauthorized")
+	}
+
+	letterID, _ := c.ParamsInt("id")
+	variant := c.Params("variant", models.FileVariantCurrent)
+	mode := c.Query("mode", models.FileAccessRedirect)
+	if mode != models.FileAccessRedirect && mode != models.FileAccessStream {
+		return utils.BadRequest(c, "Parameter mode tidak valid", fiber.Map{"mode": "harus redirect atau stream"})
+	}
+
+	var letter models.Letter
+	if err := h.db.First(&letter, letterID).Error; err != nil {
+		return utils.NotFound(c, "Surat tidak ditemukan")
+	}
+
+	canView, _ := h.permService.CanUserViewLetter(user, &letter)
+	if !canView {
+		return utils.Forbidden(c, "Anda tidak memiliki akses melihat surat ini")
+	}
+
+	key := letter.FileKey(variant)
+	if key == "" {
+		return utils.NotFound(c, "File tidak tersedia")
+	}
+
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	access := models.LetterFileAccess{
+		LetterID:  letter.ID,
+		UserID:    user.ID,
+		Variant:   variant,
+		Mode:      mode,
+		IPAddress: c.IP(),
+		UserAgent: userAgent,
+	}
+	if err := h.db.Create(&access).Error; err != nil {
+		log.Printf("⚠️ Gagal mencatat akses file surat %d: %v", letter.ID, err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	if mode == models.FileAccessStream {
+		obj, err := storage.OpenFile(c.UserContext(), key)
+		if err != nil {
+			log.Printf("⚠️ Gagal membuka file surat %d: %v", letter.ID, err)
+			return utils.InternalServerError(c, "Gagal mengambil file")
+		}
+		if obj.ContentType != "" {
+			c.Set(fiber.HeaderContentType, obj.ContentType)
+		}
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", path.Base(key)))
+		// Body ditutup oleh fasthttp setelah selesai dikirim
+		return c.SendStream(obj.Body, int(obj.ContentLength))
+	}
 
-	return c.JSON(fiber.Map{"success": true, "data": letter})
+	url, err := storage.GetPresignedURLWithExpiry(key, fileRedirectExpiry)
+	if err != nil {
+		log.Printf("⚠️ Gagal membuat presigned URL surat %d: %v", letter.ID, err)
+		return utils.InternalServerError(c, "Gagal mengambil file")
+	}
+	return c.Redirect(url, fiber.StatusFound)
 }
 
 // DeleteLetter - Soft Delete / Cancel (Hanya Admin atau Pembuat saat Draft)
@@ -79,20 +160,50 @@ func (h *LetterCommonHandler) DeleteLetter(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "Letter not found"})
 	}
 
-	// Logic: Hanya Admin, ATAU Pembuat surat jika status masih Draft
-	isOwnerDraft := letter.CreatedByID == user.ID && letter.Status == models.StatusDraft
-	if !user.IsAdmin() && !isOwnerDraft {
+	// Logic: Hanya role dengan izin hapus semua surat (bawaan: Admin), ATAU Pembuat surat jika status masih Draft
+	canDelete, err := h.permService.CanUserDeleteLetter(user, &letter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal memeriksa izin"})
+	}
+	if !canDelete {
 		return c.Status(403).JSON(fiber.Map{"error": "Dilarang menghapus surat ini"})
 	}
-
-	// [FIX] Update Status Log (Revert parent status if this was a reply)
-	if letter.InReplyToID != nil {
-		// Revert status surat induk menjadi 'sudah_disposisi' agar muncul kembali di list 'butuh balasan'
-		h.db.Model(&models.Letter{}).
-			Where("id = ?", *letter.InReplyToID).
-			Update("status", models.StatusSudahDisposisi)
+	if !ifMatchSatisfied(c, &letter) {
+		return preconditionFailed(c, &letter)
 	}
 
-	h.db.Delete(&letter)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Versi dicek saat menghapus: surat yang berubah sejak dimuat tidak ikut terhapus
+		if err := services.DeleteLetterIfUnchanged(tx, &letter); err != nil {
+			return err
+		}
+
+		// [FIX] Update Status Log (Revert parent status if this was a reply)
+		if letter.InReplyToID != nil {
+			// Revert status surat induk menjadi 'sudah_disposisi' agar muncul kembali di list 'butuh balasan'
+			if err := tx.Model(&models.Letter{}).
+				Where("id = ?", *letter.InReplyToID).
+				Updates(map[string]any{
+					"status":  models.StatusSudahDisposisi,
+					"version": gorm.Expr("version + 1"),
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		return services.RecordLetterAction(tx, &letter, letter.Status, letterAction(c, models.LetterActionDeleted, ""))
+	})
+	if err != nil {
+		return letterSaveError(c, err, "Gagal menghapus surat")
+	}
 	return c.JSON(fiber.Map{"success": true, "message": "Surat berhasil dihapus"})
 }
+
+// loadEventTembusan mengisi penerima tembusan sebelum event surat dikirim.
+// Surat sudah tersimpan, jadi kegagalan hanya dicatat dan event tetap dikirim
+// (tanpa notifikasi ke penerima tembusan).
+func loadEventTembusan(ts *services.TembusanService, letter *models.Letter) {
+	if err := ts.LoadInto(letter); err != nil {
+		log.Printf("load tembusan for event (letter %d): %v", letter.ID, err)
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// letterETag dibentuk dari ID & versi surat, berubah setiap kali surat diubah
+func letterETag(letter *models.Letter) string {
+	return fmt.Sprintf(`"%d-%d"`, letter.ID, letter.Version)
+}
+
+func setLetterETag(c *fiber.Ctx, letter *models.Letter) {
+	c.Set(fiber.HeaderETag, letterETag(letter))
+}
+
+// ifMatchSatisfied mengecek header If-Match. Tanpa header, request tetap
+// diproses (klien lama) dan hanya dilindungi conditional update.
+func ifMatchSatisfied(c *fiber.Ctx, letter *models.Letter) bool {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return true
+	}
+
+	current := letterETag(letter)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+func preconditionFailed(c *fiber.Ctx, letter *models.Letter) error {
+	setLetterETag(c, letter)
+	return utils.ErrorResponse(c, fiber.StatusPreconditionFailed, "Surat sudah diubah oleh pengguna lain, muat ulang data terbaru", fiber.Map{"version": letter.Version})
+}
+
+// letterSaveError memetakan error simpan surat. Konflik versi menjadi 412 jika
+// klien mengirim If-Match, selain itu 409.
+func letterSaveError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, services.ErrLetterVersionConflict) {
+		if c.Get(fiber.HeaderIfMatch) != "" {
+			return utils.ErrorResponse(c, fiber.StatusPreconditionFailed, "Surat sudah diubah oleh pengguna lain, muat ulang data terbaru", nil)
+		}
+		return utils.Conflict(c, "Surat sudah diubah oleh pengguna lain, muat ulang data terbaru")
+	}
+	return utils.InternalServerError(c, message)
+}
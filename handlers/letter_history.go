@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	letterdto "TugasAkhir/dto/letters"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// letterAction menyiapkan baris riwayat untuk aksi workflow. Pelaku diambil
+// dari token: user beserta role aktif & label penugasannya (Plt./Plh.), dan
+// API key yang dipakai jika aksi dilakukan oleh integrasi.
+func letterAction(c *fiber.Ctx, action, catatan string) models.LetterHistory {
+	entry := models.LetterHistory{Action: action, Catatan: catatan}
+	if claims, ok := middleware.GetJWTClaims(c); ok {
+		entry.ActorID = claims.UserID
+		entry.ActorRole = claims.Role
+		entry.ActorLabel = claims.RoleLabel
+		if claims.APIKeyID != 0 {
+			entry.APIKeyID = &claims.APIKeyID
+		}
+	}
+	return entry
+}
+
+// GetLetterHistory - GET /api/letters/:id/history
+// Riwayat aksi workflow surat beserta role yang dipakai pelakunya
+func (h *LetterCommonHandler) GetLetterHistory(c *fiber.Ctx) error {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "Unauthorized")
+	}
+
+	letterID, _ := c.ParamsInt("id")
+	letter, err := h.permService.GetLetterByID(uint(letterID))
+	if err != nil {
+		return utils.NotFound(c, "Surat tidak ditemukan")
+	}
+
+	canView, _ := h.permService.CanUserViewLetter(user, letter)
+	if !canView {
+		return utils.Forbidden(c, "Anda tidak memiliki akses melihat surat ini")
+	}
+
+	history, err := services.ListLetterHistory(h.db, letter.ID)
+	if err != nil {
+		return utils.InternalServerError(c, "Gagal mengambil riwayat surat")
+	}
+	return utils.OK(c, "Riwayat surat berhasil diambil", letterdto.NewLetterHistoryResponses(history))
+}
@@ -8,18 +8,24 @@ import (
 	"TugasAkhir/utils" // Imported for response helpers
 	"TugasAkhir/utils/events"
 	"TugasAkhir/utils/storage"
+	"errors"
 	"fmt"
+	"log"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type LetterKeluarHandler struct {
-	db          *gorm.DB
-	permService *services.PermissionService
+	db              *gorm.DB
+	permService     *services.PermissionService
+	docService      *services.DocumentService
+	tembusanService *services.TembusanService
+	unitService     *services.UnitService
 }
 type VerifierResponse struct {
 	ID       uint   `json:"id"`
@@ -31,8 +37,11 @@ type VerifierResponse struct {
 
 func NewLetterKeluarHandler(db *gorm.DB) *LetterKeluarHandler {
 	return &LetterKeluarHandler{
-		db:          db,
-		permService: services.NewPermissionService(db),
+		db:              db,
+		permService:     services.NewPermissionService(db),
+		docService:      services.NewDocumentService(db),
+		tembusanService: services.NewTembusanService(db),
+		unitService:     services.NewUnitService(db),
 	}
 }
 
@@ -102,6 +111,53 @@ func (h *LetterKeluarHandler) CreateSuratKeluar(c *fiber.Ctx) error {
 		}
 	}
 
+	return h.saveNewSuratKeluar(c, user, &req, uploadedPath, nil, isDraftMode)
+}
+
+// CreateSuratKeluarFromTemplate - Membuat surat keluar tanpa upload file,
+// PDF dibuat di server dari template yang dipilih
+func (h *LetterKeluarHandler) CreateSuratKeluarFromTemplate(c *fiber.Ctx) error {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "Unauthorized")
+	}
+
+	var req letters.CreateLetterFromTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Format data tidak valid", err.Error())
+	}
+	if req.JenisSurat == "" {
+		req.JenisSurat = models.LetterKeluar
+	}
+
+	if errMap := req.Validate(); len(errMap) > 0 {
+		return utils.BadRequest(c, "Validasi gagal", errMap)
+	}
+
+	canCreate, _ := h.permService.CanUserCreateLetter(user, req.Scope, models.LetterKeluar)
+	if !canCreate {
+		return utils.Forbidden(c, "Anda tidak memiliki izin membuat surat keluar dengan scope ini")
+	}
+
+	tmpl, err := h.docService.FindTemplate(req.TemplateID)
+	if err != nil || !tmpl.IsActive {
+		return utils.UnprocessableEntity(c, "Template surat tidak ditemukan atau tidak aktif", fiber.Map{"template_id": "invalid"})
+	}
+
+	isDraftMode := req.Status == "" || req.Status == models.StatusDraft
+
+	// PDF dirender dari data terstruktur di saveNewSuratKeluar setelah validasi
+	return h.saveNewSuratKeluar(c, user, &req.CreateLetterKeluarRequest, "", tmpl, isDraftMode)
+}
+
+// saveNewSuratKeluar - Bagian bersama CreateSuratKeluar & CreateSuratKeluarFromTemplate:
+// penentuan verifikator, reply linking, penyimpanan dan notifikasi. Jika tmpl
+// diisi, PDF dibuat dari template menggantikan uploadedPath.
+func (h *LetterKeluarHandler) saveNewSuratKeluar(c *fiber.Ctx, user *models.User, req *letters.CreateLetterKeluarRequest, uploadedPath string, tmpl *models.LetterTemplate, isDraftMode bool) error {
+	if err := h.tembusanService.Validate(req.Tembusan.UserIDs(), req.Tembusan.Roles()); err != nil {
+		return tembusanError(c, err)
+	}
+
 	// 6. Logic Penentuan Verifikator (Auto-Assign vs Manual) - HANYA jika bukan draft
 	var verifierID *uint
 
@@ -109,14 +165,14 @@ func (h *LetterKeluarHandler) CreateSuratKeluar(c *fiber.Ctx) error {
 		if strings.EqualFold(req.Scope, models.ScopeInternal) {
 			// === LOGIC BROADCAST (Internal) ===
 			// Untuk surat internal, TIDAK perlu assign ke manajer spesifik.
-			// Semua Manajer PKL akan bisa melihat dan memverifikasi surat ini.
-			// Cukup validasi bahwa ada minimal 1 Manajer PKL di sistem.
+			// Semua verifikator internal (bawaan: Manajer PKL) bisa melihat dan memverifikasi surat ini.
+			// Cukup validasi bahwa ada minimal 1 verifikator internal di sistem.
 			var count int64
-			h.db.Model(&models.User{}).Where("role = ?", models.RoleManajerPKL).Count(&count)
+			h.db.Model(&models.User{}).Scopes(services.ScopeActiveUsers, services.ScopeUsersWithRoles(h.permService.RolesWithPermission(models.PermLetterVerifyInternal))).Count(&count)
 			if count == 0 {
-				return utils.InternalServerError(c, "Sistem Gagal: Tidak ada Manajer PKL terdaftar di sistem")
+				return utils.InternalServerError(c, "Sistem Gagal: Tidak ada verifikator surat internal (Manajer PKL) terdaftar di sistem")
 			}
-			// verifierID tetap nil - semua Manajer PKL akan melihat surat ini
+			// verifierID tetap nil - semua verifikator internal akan melihat surat ini
 
 		} else {
 			// === LOGIC MANUAL (Eksternal) ===
@@ -124,16 +180,20 @@ func (h *LetterKeluarHandler) CreateSuratKeluar(c *fiber.Ctx) error {
 			if req.AssignedVerifierID == nil {
 				return utils.UnprocessableEntity(c, "Untuk surat Eksternal, Anda wajib memilih Verifikator (Manajer)", nil)
 			}
+			if !h.verifierAvailable(*req.AssignedVerifierID, req.Scope) {
+				return utils.UnprocessableEntity(c, "Verifikator tidak aktif atau tidak berwenang memverifikasi surat ini", fiber.Map{"assigned_verifier_id": "invalid"})
+			}
 			verifierID = req.AssignedVerifierID
 		}
 	}
 
 	// 6.5 Validasi Reply Linking (Opsional) + STATUS UPDATE
 	// Jika user menyertakan InReplyToID, validasi bahwa surat induk ada dan perlu balasan
+	var parentLetter *models.Letter
 	if req.InReplyToID != nil {
-		var parentLetter models.Letter
+		parentLetter = &models.Letter{}
 		// Load parent letter with transactional lock if possible, or just standard load
-		if err := h.db.First(&parentLetter, *req.InReplyToID).Error; err != nil {
+		if err := h.db.First(parentLetter, *req.InReplyToID).Error; err != nil {
 			return utils.BadRequest(c, "Surat yang akan dibalas tidak ditemukan", nil)
 		}
 		if !parentLetter.IsSuratMasuk() {
@@ -148,12 +208,10 @@ func (h *LetterKeluarHandler) CreateSuratKeluar(c *fiber.Ctx) error {
 			return utils.BadRequest(c, "Surat masuk ini tidak ditandai perlu balasan", nil)
 		}
 
-		// [FIX] Validasi Role vs Scope Surat Induk
-		if user.Role == models.RoleStafProgram && parentLetter.Scope != models.ScopeEksternal {
-			return utils.Forbidden(c, "Staf Program hanya boleh membalas surat eksternal")
-		}
-		if user.Role == models.RoleStafLembaga && parentLetter.Scope != models.ScopeInternal {
-			return utils.Forbidden(c, "Staf Lembaga hanya boleh membalas surat internal")
+		// [FIX] Validasi Permission vs Scope Surat Induk: hanya boleh membalas surat
+		// dengan scope yang boleh dibuat user (Staf Program → Eksternal, Staf Lembaga → Internal)
+		if !h.permService.HasPermission(user, models.KeluarCreatePermission(parentLetter.Scope)) {
+			return utils.Forbidden(c, "Anda tidak berhak membalas surat dengan scope "+parentLetter.Scope)
 		}
 
 		// [FIX] Update Status Surat Induk menjadi 'diarsipkan' agar tidak muncul lagi di list 'needs-reply'
@@ -175,17 +233,36 @@ func (h *LetterKeluarHandler) CreateSuratKeluar(c *fiber.Ctx) error {
 	letter.CreatedByID = user.ID
 	letter.AssignedVerifierID = verifierID
 	letter.FilePath = uploadedPath
+	letter.Tembusan = req.Tembusan.ToModels() // Ikut tersimpan saat Create
 
 	// Default Prioritas jika kosong
 	if letter.Prioritas == "" {
 		letter.Prioritas = models.PriorityBiasa
 	}
 
+	// Unit pembuat menentukan urutan nomor agenda & rekap per unit
+	creatorUnit, err := h.unitService.UserUnit(user.ID)
+	if err != nil {
+		return utils.InternalServerError(c, "Gagal mengambil unit pembuat surat")
+	}
+	if creatorUnit != nil {
+		letter.UnitID = &creatorUnit.ID
+	}
+
+	// Render PDF dari data terstruktur, hasilnya dipakai seperti file upload biasa.
+	// Dibuat setelah semua validasi agar request yang ditolak tidak meninggalkan file.
+	if tmpl != nil {
+		if err := h.docService.GenerateFromTemplate(c.Context(), &letter, tmpl); err != nil {
+			return utils.InternalServerError(c, "Gagal membuat dokumen dari template")
+		}
+		letter.TemplateID = &tmpl.ID
+	}
+
 	// 8. Simpan ke Database (Transactional with Auto-Increment Nomor Agenda)
 	err = h.db.Transaction(func(tx *gorm.DB) error {
 		// Only generate nomor agenda if NOT draft mode
 		if !isDraftMode {
-			nomorAgenda, err := utils.GenerateNomorAgenda(tx, models.LetterKeluar)
+			nomorAgenda, err := utils.GenerateNomorAgenda(tx, models.LetterKeluar, creatorUnit)
 			if err != nil {
 				return err
 			}
@@ -196,12 +273,24 @@ func (h *LetterKeluarHandler) CreateSuratKeluar(c *fiber.Ctx) error {
 		if err := tx.Create(&letter).Error; err != nil {
 			return err
 		}
+		if err := services.RecordLetterAction(tx, &letter, "", letterAction(c, models.LetterActionCreated, "")); err != nil {
+			return err
+		}
 
 		// [FIX] Jika ini adalah balasan (InReplyToID != nil), update status surat induk
-		if letter.InReplyToID != nil {
+		if parentLetter != nil {
 			if err := tx.Model(&models.Letter{}).
-				Where("id = ?", *letter.InReplyToID).
-				Update("status", models.StatusDiarsipkan).Error; err != nil {
+				Where("id = ?", parentLetter.ID).
+				Updates(map[string]any{
+					"status":  models.StatusDiarsipkan,
+					"version": gorm.Expr("version + 1"),
+				}).Error; err != nil {
+				return err
+			}
+			fromStatus := parentLetter.Status
+			parentLetter.Status = models.StatusDiarsipkan
+			catatan := fmt.Sprintf("Dibalas dengan surat keluar #%d", letter.ID)
+			if err := services.RecordLetterAction(tx, parentLetter, fromStatus, letterAction(c, models.LetterActionArchived, catatan)); err != nil {
 				return err
 			}
 		}
@@ -210,6 +299,7 @@ func (h *LetterKeluarHandler) CreateSuratKeluar(c *fiber.Ctx) error {
 	})
 
 	if err != nil {
+		h.docService.DiscardGeneratedFile(c.Context(), letter.FilePath)
 		return utils.InternalServerError(c, "Gagal menyimpan data surat: "+err.Error())
 	}
 
@@ -222,12 +312,10 @@ func (h *LetterKeluarHandler) CreateSuratKeluar(c *fiber.Ctx) error {
 			Type:   events.LetterCreated,
 			Letter: letter,
 		}
-		AddPresignedURLToLetter(&letter)
-		return utils.Created(c, "Surat keluar berhasil dibuat dan diteruskan ke Verifikator", letter)
+		return utils.Created(c, "Surat keluar berhasil dibuat dan diteruskan ke Verifikator", letters.NewLetterResponse(&letter))
 	}
 
-	AddPresignedURLToLetter(&letter)
-	return utils.Created(c, "Draft surat berhasil disimpan", letter)
+	return utils.Created(c, "Draft surat berhasil disimpan", letters.NewLetterResponse(&letter))
 }
 
 // UpdateDraftLetter - Handler untuk edit dan submit draft
@@ -254,6 +342,9 @@ func (h *LetterKeluarHandler) UpdateDraftLetter(c *fiber.Ctx) error {
 	if letter.Status != models.StatusDraft && letter.Status != models.StatusPerluRevisi {
 		return utils.Conflict(c, "Hanya surat status Draft atau Perlu Revisi yang bisa diedit")
 	}
+	if !ifMatchSatisfied(c, letter) {
+		return preconditionFailed(c, letter)
+	}
 
 	oldStatus := letter.Status
 
@@ -267,6 +358,11 @@ func (h *LetterKeluarHandler) UpdateDraftLetter(c *fiber.Ctx) error {
 	if errMap := req.Validate(); len(errMap) > 0 {
 		return utils.BadRequest(c, "Validasi gagal", errMap)
 	}
+	if req.Tembusan != nil {
+		if err := h.tembusanService.Validate(req.Tembusan.UserIDs(), req.Tembusan.Roles()); err != nil {
+			return tembusanError(c, err)
+		}
+	}
 
 	// 5. Update Field Teks (Judul, Nomor, dll)
 	// Fungsi ApplyUpdate ini ada di dto/letters/mapper.go
@@ -274,6 +370,7 @@ func (h *LetterKeluarHandler) UpdateDraftLetter(c *fiber.Ctx) error {
 
 	// 6. Handle File Upload (OPSIONAL untuk Edit)
 	// Jika user mengupload file baru, kita ganti. Jika tidak, pakai file lama.
+	previousFile := letter.FilePath
 	fileHeader, err := c.FormFile("file")
 	if err == nil {
 		// Validasi Ekstensi
@@ -294,6 +391,8 @@ func (h *LetterKeluarHandler) UpdateDraftLetter(c *fiber.Ctx) error {
 
 		// Update path di database
 		letter.FilePath = uploadedPath
+		// File manual menggantikan dokumen hasil template
+		letter.TemplateID = nil
 	}
 
 	// 7. Logic Auto-Assign Manajer (Sama seperti Create)
@@ -317,6 +416,9 @@ func (h *LetterKeluarHandler) UpdateDraftLetter(c *fiber.Ctx) error {
 		// === LOGIC MANUAL (Eksternal) ===
 		// Jika user memilih verifikator baru di dropdown
 		if req.AssignedVerifierID != nil {
+			if !h.verifierAvailable(*req.AssignedVerifierID, letter.Scope) {
+				return utils.UnprocessableEntity(c, "Verifikator tidak aktif atau tidak berwenang memverifikasi surat ini", fiber.Map{"assigned_verifier_id": "invalid"})
+			}
 			letter.AssignedVerifierID = req.AssignedVerifierID
 			// Auto ajukan ulang
 			if letter.Status == models.StatusDraft || letter.Status == models.StatusPerluRevisi {
@@ -333,22 +435,58 @@ func (h *LetterKeluarHandler) UpdateDraftLetter(c *fiber.Ctx) error {
 		}
 	}
 
+	// Surat dari template: buat ulang PDF dari data terbaru setiap revisi,
+	// setelah validasi agar request yang ditolak tidak meninggalkan file
+	if letter.TemplateID != nil {
+		tmpl, err := h.docService.FindTemplate(*letter.TemplateID)
+		if err != nil {
+			return utils.InternalServerError(c, "Template surat tidak ditemukan")
+		}
+		if err := h.docService.GenerateFromTemplate(c.Context(), letter, tmpl); err != nil {
+			return utils.InternalServerError(c, "Gagal membuat ulang dokumen dari template")
+		}
+	}
+
 	// 8. Simpan Perubahan ke DB (with nomor_agenda generation if needed)
 	err = h.db.Transaction(func(tx *gorm.DB) error {
 		// Generate nomor_agenda ONLY when transitioning draft→publish AND nomor_agenda is empty
 		if oldStatus == models.StatusDraft && letter.Status == models.StatusPerluVerifikasi && letter.NomorAgenda == "" {
-			nomorAgenda, err := utils.GenerateNomorAgenda(tx, models.LetterKeluar)
+			unit, err := h.unitService.LetterUnit(letter)
+			if err != nil {
+				return err
+			}
+			nomorAgenda, err := utils.GenerateNomorAgenda(tx, models.LetterKeluar, unit)
 			if err != nil {
 				return err
 			}
 			letter.NomorAgenda = nomorAgenda
 		}
 
-		return tx.Save(letter).Error
+		if err := services.SaveLetterIfUnchanged(tx, letter); err != nil {
+			return err
+		}
+		action := models.LetterActionUpdated
+		if statusChanged {
+			action = models.LetterActionSubmitted
+		}
+		if err := services.RecordLetterAction(tx, letter, oldStatus, letterAction(c, action, "")); err != nil {
+			return err
+		}
+		if req.Tembusan != nil {
+			return h.tembusanService.Replace(tx, letter.ID, req.Tembusan.ToModels())
+		}
+		return nil
 	})
 
 	if err != nil {
-		return utils.InternalServerError(c, "Gagal menyimpan revisi surat: "+err.Error())
+		if letter.FilePath != previousFile {
+			h.docService.DiscardGeneratedFile(c.Context(), letter.FilePath)
+		}
+		return letterSaveError(c, err, "Gagal menyimpan revisi surat: "+err.Error())
+	}
+	// PDF template lama sudah digantikan file baru
+	if letter.FilePath != previousFile {
+		h.docService.DiscardGeneratedFile(c.Context(), previousFile)
 	}
 
 	// 9. Kirim Notifikasi (Hanya jika status berubah, misal: Revisi -> Perlu Verifikasi)
@@ -363,8 +501,8 @@ func (h *LetterKeluarHandler) UpdateDraftLetter(c *fiber.Ctx) error {
 		}
 	}
 
-	AddPresignedURLToLetter(letter)
-	return utils.OK(c, "Surat berhasil diperbarui dan diajukan kembali", letter)
+	setLetterETag(c, letter)
+	return utils.OK(c, "Surat berhasil diperbarui dan diajukan kembali", letters.NewLetterResponse(letter))
 }
 
 // VerifyLetterApprove
@@ -381,11 +519,17 @@ func (h *LetterKeluarHandler) VerifyLetterApprove(c *fiber.Ctx) error {
 	if !canVerify {
 		return utils.Forbidden(c, "Anda tidak memiliki izin memverifikasi surat ini")
 	}
+	if !ifMatchSatisfied(c, letter) {
+		return preconditionFailed(c, letter)
+	}
 
 	oldStatus := letter.Status
 	letter.Status = models.StatusPerluPersetujuan
 	letter.VerifiedByID = &user.ID
-	h.db.Save(letter)
+	if err := services.TransitionLetter(h.db, letter, oldStatus, letterAction(c, models.LetterActionVerified, ""), "status", "verified_by_id"); err != nil {
+		return letterSaveError(c, err, "Gagal memverifikasi surat")
+	}
+	setLetterETag(c, letter)
 
 	events.LetterEventBus <- events.LetterEvent{
 		Type:      events.LetterStatusMoved,
@@ -410,10 +554,16 @@ func (h *LetterKeluarHandler) VerifyLetterReject(c *fiber.Ctx) error {
 	if !canVerify {
 		return utils.Forbidden(c, "Forbidden")
 	}
+	if !ifMatchSatisfied(c, letter) {
+		return preconditionFailed(c, letter)
+	}
 
 	oldStatus := letter.Status
 	letter.Status = models.StatusPerluRevisi
-	h.db.Save(letter)
+	if err := services.TransitionLetter(h.db, letter, oldStatus, letterAction(c, models.LetterActionVerificationReject, ""), "status"); err != nil {
+		return letterSaveError(c, err, "Gagal mengembalikan surat untuk revisi")
+	}
+	setLetterETag(c, letter)
 
 	events.LetterEventBus <- events.LetterEvent{
 		Type:      events.LetterStatusMoved,
@@ -438,19 +588,46 @@ func (h *LetterKeluarHandler) ApproveLetterByDirektur(c *fiber.Ctx) error {
 	if !canApprove {
 		return utils.Forbidden(c, "Anda tidak memiliki izin menyetujui surat ini")
 	}
+	if !ifMatchSatisfied(c, letter) {
+		return preconditionFailed(c, letter)
+	}
 
 	oldStatus := letter.Status
 
+	// Tempel QR verifikasi, tanda tangan Direktur + tanda tangan digital organisasi pada file final
+	verification, err := h.docService.SignApprovedLetter(c.Context(), letter, user.ID)
+	if err != nil {
+		if errors.Is(err, services.ErrLetterHasNoFile) {
+			return utils.UnprocessableEntity(c, "Surat tidak memiliki file untuk ditandatangani", nil)
+		}
+		log.Printf("⚠️ Gagal menandatangani surat ID %d: %v", letter.ID, err)
+		return utils.InternalServerError(c, "Gagal menandatangani dokumen surat")
+	}
+
 	// [PERUBAHAN DISINI]
 	// Real Case: Langsung "Diarsipkan", bukan "Disetujui" dulu.
 	letter.Status = models.StatusDiarsipkan
 	letter.DisposedByID = &user.ID // DisposedBy diisi Direktur sebagai tanda approval
 
-	if err := h.db.Save(letter).Error; err != nil {
-		return utils.InternalServerError(c, "Gagal memproses persetujuan surat")
+	// Versi dicek ulang saat simpan: jika surat berubah selama proses tanda
+	// tangan, persetujuan dibatalkan (file bertanda tangan tidak dipakai)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := services.SaveLetterIfUnchanged(tx, letter); err != nil {
+			return err
+		}
+		if err := services.RecordLetterAction(tx, letter, oldStatus, letterAction(c, models.LetterActionApproved, "")); err != nil {
+			return err
+		}
+		return tx.Create(verification).Error
+	})
+	if err != nil {
+		h.docService.DiscardSignedFile(c.Context(), letter)
+		return letterSaveError(c, err, "Gagal memproses persetujuan surat")
 	}
+	setLetterETag(c, letter)
 
-	// Kirim Event Notifikasi
+	// Kirim Event Notifikasi (termasuk ke penerima tembusan)
+	loadEventTembusan(h.tembusanService, letter)
 	events.LetterEventBus <- events.LetterEvent{
 		Type:      events.LetterStatusMoved,
 		Letter:    *letter,
@@ -474,10 +651,16 @@ func (h *LetterKeluarHandler) RejectLetterByDirektur(c *fiber.Ctx) error {
 	if !canApprove {
 		return utils.Forbidden(c, "Forbidden")
 	}
+	if !ifMatchSatisfied(c, letter) {
+		return preconditionFailed(c, letter)
+	}
 
 	oldStatus := letter.Status
 	letter.Status = models.StatusPerluRevisi
-	h.db.Save(letter)
+	if err := services.TransitionLetter(h.db, letter, oldStatus, letterAction(c, models.LetterActionRejected, ""), "status"); err != nil {
+		return letterSaveError(c, err, "Gagal menolak surat")
+	}
+	setLetterETag(c, letter)
 
 	events.LetterEventBus <- events.LetterEvent{
 		Type:      events.LetterStatusMoved,
@@ -502,11 +685,18 @@ func (h *LetterKeluarHandler) ArchiveLetter(c *fiber.Ctx) error {
 	if !canArchive {
 		return utils.Forbidden(c, "Forbidden")
 	}
+	if !ifMatchSatisfied(c, letter) {
+		return preconditionFailed(c, letter)
+	}
 
 	oldStatus := letter.Status
 	letter.Status = models.StatusDiarsipkan
-	h.db.Save(letter)
+	if err := services.TransitionLetter(h.db, letter, oldStatus, letterAction(c, models.LetterActionArchived, ""), "status"); err != nil {
+		return letterSaveError(c, err, "Gagal mengarsipkan surat")
+	}
+	setLetterETag(c, letter)
 
+	loadEventTembusan(h.tembusanService, letter)
 	events.LetterEventBus <- events.LetterEvent{
 		Type:      events.LetterStatusMoved,
 		Letter:    *letter,
@@ -520,17 +710,32 @@ func (h *LetterKeluarHandler) ArchiveLetter(c *fiber.Ctx) error {
 func (h *LetterKeluarHandler) GetAvailableVerifiers(c *fiber.Ctx) error {
 	scope := c.Query("scope")
 	var verifiers []models.User
-	query := h.db.Model(&models.User{})
+	// User pending / nonaktif tidak bisa login, jadi tidak bisa memverifikasi
+	query := h.db.Model(&models.User{}).Scopes(services.ScopeActiveUsers)
 
-	if strings.EqualFold(scope, models.ScopeEksternal) {
-		query = query.Where("role IN ?", []models.Role{models.RoleManajerKPP, models.RoleManajerPemas})
-	} else if strings.EqualFold(scope, models.ScopeInternal) {
-		query = query.Where("role = ?", models.RoleManajerPKL)
+	// Verifikator = user dengan role (utama atau penugasan yang berlaku) yang
+	// punya izin verifikasi scope tersebut
+	if perm := models.VerifyPermission(scope); perm != "" {
+		query = query.Scopes(services.ScopeUsersWithRoles(h.permService.RolesWithPermission(perm)))
 	} else {
-		query = query.Where("role IN ?", []models.Role{models.RoleManajerKPP, models.RoleManajerPemas, models.RoleManajerPKL})
+		query = query.Scopes(services.ScopeUsersWithRoles(h.permService.RolesWithPermission(models.PermLetterVerifyInternal, models.PermLetterVerifyEksternal)))
+	}
+
+	// Filter opsional per unit (unit_id), misal manajer di bidang tujuan surat
+	if unitID := c.QueryInt("unit_id"); unitID > 0 {
+		query = query.Where("unit_id = ?", unitID)
+	}
+
+	// Manajer di unit pemohon ditampilkan paling atas
+	if user, err := middleware.GetUserFromContext(c); err == nil {
+		if unit, err := h.unitService.UserUnit(user.ID); err == nil && unit != nil {
+			query = query.Order(clause.OrderBy{Expression: clause.Expr{
+				SQL: "unit_id = ? DESC", Vars: []interface{}{unit.ID}, WithoutParentheses: true,
+			}})
+		}
 	}
 
-	query.Select("id, username, email, role, jabatan").Find(&verifiers)
+	query.Select("id, username, email, role, jabatan, unit_id").Preload("Unit").Order("username ASC").Find(&verifiers)
 
 	var response []VerifierResponse
 	for _, v := range verifiers {
@@ -545,40 +750,41 @@ func (h *LetterKeluarHandler) GetAvailableVerifiers(c *fiber.Ctx) error {
 	return utils.OK(c, "Data verifikator berhasil diambil", verifiers)
 }
 
+// verifierAvailable - verifikator yang dipilih pembuat surat harus aktif dan
+// punya izin verifikasi untuk scope surat
+func (h *LetterKeluarHandler) verifierAvailable(verifierID uint, scope string) bool {
+	var count int64
+	h.db.Model(&models.User{}).
+		Scopes(services.ScopeActiveUsers, services.ScopeUsersWithRoles(h.permService.RolesWithPermission(models.VerifyPermission(scope)))).
+		Where("users.id = ?", verifierID).
+		Count(&count)
+	return count > 0
+}
+
 // GetMyLetters
 func (h *LetterKeluarHandler) GetMyLetters(c *fiber.Ctx) error {
 	user, _ := middleware.GetUserFromContext(c)
-	var letters []models.Letter
 
-	// Staf Lembaga bisa melihat SEMUA surat keluar (sebagai arsiparis)
+	// Arsiparis (bawaan: Staf Lembaga) bisa melihat SEMUA surat keluar
 	// Staf lain hanya melihat surat buatannya sendiri
-	if user.Role == models.RoleStafLembaga {
-		h.db.Where("jenis_surat = ?", models.LetterKeluar).
-			Preload("CreatedBy").
-			Order("updated_at DESC").
-			Find(&letters)
-	} else {
-		h.db.Where("created_by_id = ? AND jenis_surat = ?", user.ID, models.LetterKeluar).
-			Preload("CreatedBy").
-			Order("updated_at DESC").
-			Find(&letters)
+	query := h.db.Where("jenis_surat = ?", models.LetterKeluar)
+	if !h.permService.HasPermission(user, models.PermLetterArchiveAll) {
+		query = query.Where("created_by_id = ?", user.ID)
 	}
 
-	AddPresignedURLsToLetters(letters)
-	return utils.OK(c, "List surat keluar berhasil diambil", letters)
+	return respondLetterList(c, query, "-updated_at", "List surat keluar berhasil diambil")
 }
 
 // GetLettersNeedVerification - Menampilkan surat yang perlu diverifikasi
-// Untuk Manajer PKL: Tampilkan SEMUA surat internal (scope=Internal) + surat yang di-assign langsung
-// Untuk Manajer lain: Tampilkan surat yang di-assign ke mereka
+// Verifikator internal (bawaan: Manajer PKL): SEMUA surat internal tanpa verifikator + surat yang di-assign langsung
+// Verifikator lain (KPP, Pemas): surat yang di-assign ke mereka
 func (h *LetterKeluarHandler) GetLettersNeedVerification(c *fiber.Ctx) error {
 	user, _ := middleware.GetUserFromContext(c)
-	var letters []models.Letter
 
 	query := h.db.Where("status = ?", models.StatusPerluVerifikasi)
 
-	if user.Role == models.RoleManajerPKL {
-		// Manajer PKL melihat:
+	if h.permService.HasPermission(user, models.PermLetterVerifyInternal) {
+		// Verifikator internal melihat:
 		// 1. Semua surat INTERNAL (assigned_verifier_id IS NULL) - broadcast
 		// 2. Surat yang di-assign langsung ke mereka (jika ada)
 		query = query.Where(
@@ -586,38 +792,34 @@ func (h *LetterKeluarHandler) GetLettersNeedVerification(c *fiber.Ctx) error {
 			models.ScopeInternal, user.ID,
 		)
 	} else {
-		// Manajer lain (KPP, Pemas) hanya melihat surat yang di-assign ke mereka
+		// Verifikator eksternal hanya melihat surat yang di-assign ke mereka
 		query = query.Where("assigned_verifier_id = ?", user.ID)
 	}
 
-	query.Preload("CreatedBy").
-		Order("created_at ASC").
-		Find(&letters)
-
-	AddPresignedURLsToLetters(letters)
-	return utils.OK(c, "List surat perlu verifikasi berhasil diambil", letters)
+	return respondLetterList(c, query, "created_at", "List surat perlu verifikasi berhasil diambil")
 }
 
 // GetLettersNeedApproval
 func (h *LetterKeluarHandler) GetLettersNeedApproval(c *fiber.Ctx) error {
-	var letters []models.Letter
-	h.db.Where("status = ? AND jenis_surat = ?", models.StatusPerluPersetujuan, models.LetterKeluar).Preload("CreatedBy").Preload("VerifiedBy").Order("created_at ASC").Find(&letters)
-	AddPresignedURLsToLetters(letters)
-	return utils.OK(c, "List surat perlu persetujuan berhasil diambil", letters)
+	query := h.db.Where("status = ? AND jenis_surat = ?", models.StatusPerluPersetujuan, models.LetterKeluar)
+	return respondLetterList(c, query, "created_at", "List surat perlu persetujuan berhasil diambil")
 }
 
 // GetMyApprovals - Direktur melihat riwayat surat keluar yang sudah di-approve
 func (h *LetterKeluarHandler) GetMyApprovals(c *fiber.Ctx) error {
 	user, _ := middleware.GetUserFromContext(c)
-	var letters []models.Letter
 
 	// Surat keluar yang sudah di-approve oleh direktur ini (disposed_by_id = user.ID)
-	h.db.Where("disposed_by_id = ? AND jenis_surat = ?", user.ID, models.LetterKeluar).
-		Preload("CreatedBy").
-		Preload("VerifiedBy").
-		Order("updated_at DESC").
-		Find(&letters)
-
-	AddPresignedURLsToLetters(letters)
-	return utils.OK(c, "Riwayat surat keluar yang sudah disetujui", letters)
+	query := h.db.Where("disposed_by_id = ? AND jenis_surat = ?", user.ID, models.LetterKeluar)
+	return respondLetterList(c, query, "-updated_at", "Riwayat surat keluar yang sudah disetujui")
+}
+
+func tembusanError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrTembusanUserNotFound) {
+		return utils.UnprocessableEntity(c, "User penerima tembusan tidak ditemukan", fiber.Map{"tembusan": "invalid user_id"})
+	}
+	if errors.Is(err, services.ErrTembusanRoleNotFound) {
+		return utils.UnprocessableEntity(c, "Role penerima tembusan tidak ditemukan", fiber.Map{"tembusan": "invalid role"})
+	}
+	return utils.InternalServerError(c, "Gagal memvalidasi penerima tembusan")
 }
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	letterdto "TugasAkhir/dto/letters"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Tanggal "bisnis" surat: tanggal surat, tanggal masuk, lalu tanggal dibuat
+const letterDateExpr = "COALESCE(surat.tanggal_surat, surat.tanggal_masuk, surat.created_at)"
+
+// Kolom yang boleh dipakai di parameter sort
+var letterSortColumns = map[string]string{
+	"created_at":    "surat.created_at",
+	"updated_at":    "surat.updated_at",
+	"tanggal":       letterDateExpr,
+	"tanggal_surat": "surat.tanggal_surat",
+	"tanggal_masuk": "surat.tanggal_masuk",
+	"nomor_surat":   "surat.nomor_surat",
+	"judul_surat":   "surat.judul_surat",
+	"prioritas":     "surat.prioritas", // Enum MySQL diurutkan sesuai urutan definisi
+}
+
+// letterListParams adalah parameter umum list surat:
+// page, limit, status, prioritas, scope, unit_id, date_from, date_to, sort
+type letterListParams struct {
+	Page      int
+	Limit     int
+	Status    models.LetterStatus
+	Prioritas models.Priority
+	Scope     string
+	UnitID    uint
+	UnitIDs   []uint // UnitID beserta sub-unitnya, diisi respondLetterList
+	DateFrom  *time.Time
+	DateTo    *time.Time
+	Order     string
+}
+
+// parsePageParams membaca page & limit (default 1 & 20, maks 100)
+func parsePageParams(c *fiber.Ctx) (page, limit int) {
+	page, _ = strconv.Atoi(c.Query("page", "1"))
+	limit, _ = strconv.Atoi(c.Query("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// parseDateRange membaca date_from & date_to (YYYY-MM-DD, inklusif)
+func parseDateRange(c *fiber.Ctx, errs map[string]string) (from, to *time.Time) {
+	if raw := c.Query("date_from"); raw != "" {
+		t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			errs["date_from"] = "format tanggal harus YYYY-MM-DD"
+		} else {
+			from = &t
+		}
+	}
+	if raw := c.Query("date_to"); raw != "" {
+		t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			errs["date_to"] = "format tanggal harus YYYY-MM-DD"
+		} else {
+			// Inklusif sampai akhir hari
+			end := t.Add(24*time.Hour - time.Nanosecond)
+			to = &end
+		}
+	}
+	return from, to
+}
+
+// parseLetterListParams membaca parameter list surat. defaultSort dipakai
+// jika sort kosong, format "kolom" (ASC) atau "-kolom" (DESC).
+func parseLetterListParams(c *fiber.Ctx, defaultSort string) (letterListParams, map[string]string) {
+	errs := map[string]string{}
+	p := letterListParams{
+		Status:    models.LetterStatus(c.Query("status")),
+		Prioritas: models.Priority(c.Query("prioritas")),
+		Scope:     c.Query("scope"),
+	}
+	p.Page, p.Limit = parsePageParams(c)
+	p.DateFrom, p.DateTo = parseDateRange(c, errs)
+
+	if p.Status != "" && !p.Status.IsValid() {
+		errs["status"] = "status surat tidak dikenal"
+	}
+	if p.Prioritas != "" && !p.Prioritas.IsValid() {
+		errs["prioritas"] = "harus salah satu dari: biasa, segera, penting"
+	}
+	if p.Scope != "" && p.Scope != models.ScopeInternal && p.Scope != models.ScopeEksternal {
+		errs["scope"] = "harus salah satu dari: Internal, Eksternal"
+	}
+	if raw := c.Query("unit_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || id == 0 {
+			errs["unit_id"] = "unit_id tidak valid"
+		} else {
+			p.UnitID = uint(id)
+		}
+	}
+
+	sort := c.Query("sort", defaultSort)
+	field, desc := strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+	column, ok := letterSortColumns[field]
+	if !ok {
+		errs["sort"] = "kolom sort tidak dikenal"
+	} else {
+		dir := "ASC"
+		if desc {
+			dir = "DESC"
+		}
+		// id sebagai tie-breaker agar urutan antar halaman stabil
+		p.Order = column + " " + dir + ", surat.id " + dir
+	}
+
+	return p, errs
+}
+
+// apply menambahkan filter ke query surat
+func (p letterListParams) apply(tx *gorm.DB) *gorm.DB {
+	if p.Status != "" {
+		tx = tx.Where("surat.status = ?", p.Status)
+	}
+	if p.Prioritas != "" {
+		tx = tx.Where("surat.prioritas = ?", p.Prioritas)
+	}
+	if p.Scope != "" {
+		tx = tx.Where("surat.scope = ?", p.Scope)
+	}
+	// Surat milik unit (pembuat) atau yang didisposisikan ke unit, termasuk sub-unit
+	if len(p.UnitIDs) > 0 {
+		tx = tx.Where("(surat.unit_id IN ? OR surat.tujuan_unit_id IN ?)", p.UnitIDs, p.UnitIDs)
+	}
+	if p.DateFrom != nil {
+		tx = tx.Where(letterDateExpr+" >= ?", *p.DateFrom)
+	}
+	if p.DateTo != nil {
+		tx = tx.Where(letterDateExpr+" <= ?", *p.DateTo)
+	}
+	return tx
+}
+
+// respondLetterList menjalankan query list surat dengan filter, sort &
+// pagination lalu mengirim PaginatedResponse berisi ringkasan surat
+// (mendukung fields=)
+func respondLetterList(c *fiber.Ctx, tx *gorm.DB, defaultSort, message string) error {
+	p, errs := parseLetterListParams(c, defaultSort)
+	if len(errs) > 0 {
+		return utils.BadRequest(c, "Parameter list tidak valid", errs)
+	}
+	if p.UnitID != 0 {
+		ids, err := services.NewUnitService(tx.Session(&gorm.Session{NewDB: true})).DescendantIDs(p.UnitID)
+		if err != nil {
+			return utils.InternalServerError(c, "Gagal mengambil data unit")
+		}
+		p.UnitIDs = ids
+	}
+
+	tx = p.apply(tx.Model(&models.Letter{}))
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return utils.InternalServerError(c, "Gagal mengambil data surat")
+	}
+
+	var letters []models.Letter
+	if err := tx.Preload("CreatedBy").Order(p.Order).
+		Limit(p.Limit).Offset((p.Page - 1) * p.Limit).
+		Find(&letters).Error; err != nil {
+		return utils.InternalServerError(c, "Gagal mengambil data surat")
+	}
+
+	// List hanya berisi ringkasan, file diunduh lewat GET /letters/:id/file
+	items, err := utils.SelectFields(letterdto.NewLetterSummaryResponses(letters), utils.ParseFields(c))
+	if err != nil {
+		return fieldsError(c, err)
+	}
+
+	meta := utils.PaginationMeta{Page: p.Page, Limit: p.Limit, Total: total}
+	return utils.PaginatedResponse(c, fiber.StatusOK, message, items, meta)
+}
+
+func fieldsError(c *fiber.Ctx, err error) error {
+	return utils.BadRequest(c, "Parameter fields tidak valid", fiber.Map{"fields": err.Error()})
+}
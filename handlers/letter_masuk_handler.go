@@ -8,7 +8,9 @@ import (
 	"TugasAkhir/utils" // Imported for response helpers
 	"TugasAkhir/utils/events"
 	"TugasAkhir/utils/storage"
+	"errors"
 	"fmt"
+	"log"
 	"path/filepath"
 	"strings"
 	"time"
@@ -18,17 +20,60 @@ import (
 )
 
 type LetterMasukHandler struct {
-	db          *gorm.DB
-	permService *services.PermissionService
+	db              *gorm.DB
+	permService     *services.PermissionService
+	docService      *services.DocumentService
+	tembusanService *services.TembusanService
+	unitService     *services.UnitService
 }
 
 func NewLetterMasukHandler(db *gorm.DB) *LetterMasukHandler {
 	return &LetterMasukHandler{
-		db:          db,
-		permService: services.NewPermissionService(db),
+		db:              db,
+		permService:     services.NewPermissionService(db),
+		docService:      services.NewDocumentService(db),
+		tembusanService: services.NewTembusanService(db),
+		unitService:     services.NewUnitService(db),
 	}
 }
 
+// stampReceiptIfNeeded membuat salinan berstempel penerimaan untuk surat masuk
+// yang sudah punya nomor agenda dan file aslinya belum distempel. Jika restamp
+// true (nomor agenda / tanggal masuk berubah), stempel dibuat ulang dari file
+// asli. Kegagalan stempel tidak membatalkan pencatatan, surat tetap memakai
+// file sebelumnya.
+func (h *LetterMasukHandler) stampReceiptIfNeeded(c *fiber.Ctx, letter *models.Letter, recorderID uint, restamp bool) {
+	if letter.NomorAgenda == "" || letter.FilePath == "" || (letter.OriginalFilePath != "" && !restamp) {
+		return
+	}
+
+	previousFile, previousOriginal := letter.FilePath, letter.OriginalFilePath
+	if err := h.docService.StampIncomingReceipt(c.Context(), letter, recorderID); err != nil {
+		log.Printf("⚠️ Gagal menstempel surat masuk ID %d: %v", letter.ID, err)
+		return
+	}
+
+	if err := services.UpdateLetterFields(h.db, letter, letter.Status, "file_path", "original_file_path"); err != nil {
+		log.Printf("⚠️ Gagal menyimpan file berstempel surat masuk ID %d: %v", letter.ID, err)
+		h.docService.DiscardStampedFile(c.Context(), letter.FilePath)
+		letter.FilePath, letter.OriginalFilePath = previousFile, previousOriginal
+		return
+	}
+	// Stempel lama sudah digantikan
+	if previousOriginal != "" {
+		h.docService.DiscardStampedFile(c.Context(), previousFile)
+	}
+}
+
+// receiptStampFields - isi stempel penerimaan yang bergantung pada data surat
+func receiptStampFields(letter *models.Letter) string {
+	tanggal := ""
+	if letter.TanggalMasuk != nil {
+		tanggal = letter.TanggalMasuk.Format("02-01-2006")
+	}
+	return letter.NomorAgenda + "|" + tanggal
+}
+
 // CreateSuratMasuk - Staf input surat masuk (BYPASS manajer, langsung ke Direktur)
 func (h *LetterMasukHandler) CreateSuratMasuk(c *fiber.Ctx) error {
 	user, err := middleware.GetUserFromContext(c)
@@ -46,6 +91,9 @@ func (h *LetterMasukHandler) CreateSuratMasuk(c *fiber.Ctx) error {
 	if errMap := req.Validate(); len(errMap) > 0 {
 		return utils.BadRequest(c, "Validasi gagal", errMap)
 	}
+	if err := h.tembusanService.Validate(req.Tembusan.UserIDs(), req.Tembusan.Roles()); err != nil {
+		return tembusanError(c, err)
+	}
 
 	// 3. Cek Permission
 	canCreate, _ := h.permService.CanUserCreateLetter(user, req.Scope, models.LetterMasuk)
@@ -95,6 +143,7 @@ func (h *LetterMasukHandler) CreateSuratMasuk(c *fiber.Ctx) error {
 	// 5. Mapping ke Model
 	letter := req.ToModel(user.ID, uploadedPath)
 	letter.JenisSurat = models.LetterMasuk
+	letter.Tembusan = req.Tembusan.ToModels() // Ikut tersimpan saat Create
 
 	// Set status berdasarkan mode
 	if isDraftMode {
@@ -104,12 +153,22 @@ func (h *LetterMasukHandler) CreateSuratMasuk(c *fiber.Ctx) error {
 		letter.Status = models.StatusBelumDisposisi
 	}
 
+	// Unit pencatat, hanya untuk rekap. Nomor agenda surat masuk tetap satu
+	// urutan untuk seluruh organisasi.
+	recorderUnit, err := h.unitService.UserUnit(user.ID)
+	if err != nil {
+		return utils.InternalServerError(c, "Gagal mengambil unit pencatat surat")
+	}
+	if recorderUnit != nil {
+		letter.UnitID = &recorderUnit.ID
+	}
+
 	// Auto-Generate Nomor Agenda (Transactional)
 	// Only generate for non-draft letters (published/submitted)
 	err = h.db.Transaction(func(tx *gorm.DB) error {
 		// Only generate nomor agenda if NOT draft mode
 		if !isDraftMode {
-			nomorAgenda, err := utils.GenerateNomorAgenda(tx, models.LetterMasuk)
+			nomorAgenda, err := utils.GenerateNomorAgenda(tx, models.LetterMasuk, nil)
 			if err != nil {
 				return err
 			}
@@ -120,25 +179,26 @@ func (h *LetterMasukHandler) CreateSuratMasuk(c *fiber.Ctx) error {
 		if err := tx.Create(&letter).Error; err != nil {
 			return err
 		}
-		return nil
+		return services.RecordLetterAction(tx, &letter, "", letterAction(c, models.LetterActionCreated, ""))
 	})
 
 	if err != nil {
 		return utils.InternalServerError(c, "Gagal mencatat surat masuk: "+err.Error())
 	}
 
+	// Stempel nomor agenda & info penerimaan pada salinan file
+	h.stampReceiptIfNeeded(c, &letter, user.ID, false)
+
 	// 6. Kirim Notifikasi (HANYA jika bukan draft)
 	if !isDraftMode {
 		events.LetterEventBus <- events.LetterEvent{
 			Type:   events.LetterCreated,
 			Letter: letter,
 		}
-		AddPresignedURLToLetter(&letter)
-		return utils.Created(c, "Surat masuk berhasil dicatat dan dikirim ke Direktur", letter)
+		return utils.Created(c, "Surat masuk berhasil dicatat dan dikirim ke Direktur", letters.NewLetterResponse(&letter))
 	}
 
-	AddPresignedURLToLetter(&letter)
-	return utils.Created(c, "Draft surat masuk berhasil disimpan", letter)
+	return utils.Created(c, "Draft surat masuk berhasil disimpan", letters.NewLetterResponse(&letter))
 }
 
 // UpdateSuratMasuk - Staf edit surat masuk (hanya jika belum diarsip/disposisi final)
@@ -163,6 +223,9 @@ func (h *LetterMasukHandler) UpdateSuratMasuk(c *fiber.Ctx) error {
 	if letter.Status != models.StatusDraft && letter.Status != models.StatusBelumDisposisi {
 		return utils.Conflict(c, "Surat yang sudah didisposisi tidak dapat diedit")
 	}
+	if !ifMatchSatisfied(c, letter) {
+		return preconditionFailed(c, letter)
+	}
 
 	oldStatus := letter.Status
 
@@ -174,8 +237,14 @@ func (h *LetterMasukHandler) UpdateSuratMasuk(c *fiber.Ctx) error {
 	if errMap := req.Validate(); len(errMap) > 0 {
 		return utils.BadRequest(c, "Validasi gagal", errMap)
 	}
+	if req.Tembusan != nil {
+		if err := h.tembusanService.Validate(req.Tembusan.UserIDs(), req.Tembusan.Roles()); err != nil {
+			return tembusanError(c, err)
+		}
+	}
 
 	// Apply Update Metadata
+	stampedFields := receiptStampFields(letter)
 	letters.ApplyUpdateMasuk(letter, &req)
 
 	// Handle File Upload (Optional Replace, WAJIB jika submit draft)
@@ -191,6 +260,7 @@ func (h *LetterMasukHandler) UpdateSuratMasuk(c *fiber.Ctx) error {
 			return utils.InternalServerError(c, "Gagal mengupload file revisi")
 		}
 		letter.FilePath = uploadedPath
+		letter.OriginalFilePath = "" // File baru, stempel ulang setelah disimpan
 	}
 
 	// Jika status dikirim "belum_disposisi" DAN surat masih draft, maka ini adalah submission
@@ -209,20 +279,37 @@ func (h *LetterMasukHandler) UpdateSuratMasuk(c *fiber.Ctx) error {
 	err = h.db.Transaction(func(tx *gorm.DB) error {
 		// Generate nomor_agenda ONLY when transitioning draft→publish AND nomor_agenda is empty
 		if oldStatus == models.StatusDraft && letter.Status == models.StatusBelumDisposisi && letter.NomorAgenda == "" {
-			nomorAgenda, err := utils.GenerateNomorAgenda(tx, models.LetterMasuk)
+			nomorAgenda, err := utils.GenerateNomorAgenda(tx, models.LetterMasuk, nil)
 			if err != nil {
 				return err
 			}
 			letter.NomorAgenda = nomorAgenda
 		}
 
-		return tx.Save(letter).Error
+		if err := services.SaveLetterIfUnchanged(tx, letter); err != nil {
+			return err
+		}
+		action := models.LetterActionUpdated
+		if oldStatus != letter.Status {
+			action = models.LetterActionSubmitted
+		}
+		if err := services.RecordLetterAction(tx, letter, oldStatus, letterAction(c, action, "")); err != nil {
+			return err
+		}
+		if req.Tembusan != nil {
+			return h.tembusanService.Replace(tx, letter.ID, req.Tembusan.ToModels())
+		}
+		return nil
 	})
 
 	if err != nil {
-		return utils.InternalServerError(c, "Gagal menyimpan surat: "+err.Error())
+		return letterSaveError(c, err, "Gagal menyimpan surat: "+err.Error())
 	}
 
+	// Nomor agenda / tanggal masuk yang berubah membuat stempel lama tidak sesuai
+	h.stampReceiptIfNeeded(c, letter, user.ID, receiptStampFields(letter) != stampedFields)
+	setLetterETag(c, letter)
+
 	// Kirim notifikasi jika status berubah dari draft ke belum_disposisi
 	if oldStatus == models.StatusDraft && letter.Status == models.StatusBelumDisposisi {
 		events.LetterEventBus <- events.LetterEvent{
@@ -230,12 +317,10 @@ func (h *LetterMasukHandler) UpdateSuratMasuk(c *fiber.Ctx) error {
 			Letter:    *letter,
 			OldStatus: oldStatus,
 		}
-		AddPresignedURLToLetter(letter)
-		return utils.OK(c, "Draft surat berhasil dikirim ke Direktur", letter)
+		return utils.OK(c, "Draft surat berhasil dikirim ke Direktur", letters.NewLetterResponse(letter))
 	}
 
-	AddPresignedURLToLetter(letter)
-	return utils.OK(c, "Surat masuk berhasil diperbarui", letter)
+	return utils.OK(c, "Surat masuk berhasil diperbarui", letters.NewLetterResponse(letter))
 }
 
 // GetMySuratMasuk - List surat masuk buatan saya
@@ -245,24 +330,14 @@ func (h *LetterMasukHandler) GetMySuratMasuk(c *fiber.Ctx) error {
 		return utils.Unauthorized(c, "Unauthorized")
 	}
 
-	var letters []models.Letter
-
-	// Staf Lembaga bisa melihat SEMUA surat masuk (sebagai arsiparis)
+	// Arsiparis (bawaan: Staf Lembaga) bisa melihat SEMUA surat masuk
 	// Staf lain hanya melihat surat buatannya sendiri
-	if user.Role == models.RoleStafLembaga {
-		h.db.Where("jenis_surat = ?", models.LetterMasuk).
-			Preload("CreatedBy").
-			Order("created_at DESC").
-			Find(&letters)
-	} else {
-		h.db.Where("created_by_id = ? AND jenis_surat = ?", user.ID, models.LetterMasuk).
-			Preload("CreatedBy").
-			Order("created_at DESC").
-			Find(&letters)
+	query := h.db.Where("jenis_surat = ?", models.LetterMasuk)
+	if !h.permService.HasPermission(user, models.PermLetterArchiveAll) {
+		query = query.Where("created_by_id = ?", user.ID)
 	}
 
-	AddPresignedURLsToLetters(letters)
-	return utils.OK(c, "List surat masuk berhasil diambil", letters)
+	return respondLetterList(c, query, "-created_at", "List surat masuk berhasil diambil")
 }
 
 // DisposeSuratMasuk - Direktur memberikan instruksi disposisi
@@ -284,21 +359,48 @@ func (h *LetterMasukHandler) DisposeSuratMasuk(c *fiber.Ctx) error {
 	if !canDispose {
 		return utils.Forbidden(c, "Anda tidak berhak mendisposisi surat ini")
 	}
+	if !ifMatchSatisfied(c, letter) {
+		return preconditionFailed(c, letter)
+	}
 
 	var req struct {
 		InstruksiDisposisi string `json:"instruksi_disposisi"`
-		TujuanDisposisi    string `json:"tujuan_disposisi"` // Bidang Tujuan
+		TujuanDisposisi    string `json:"tujuan_disposisi"` // Bidang Tujuan (teks, klien lama)
+		TujuanUnitID       *uint  `json:"tujuan_unit_id"`   // Unit tujuan, diutamakan dari tujuan_disposisi
 		Catatan            string `json:"catatan"`
 		NeedsReply         bool   `json:"needs_reply"` // Flag: apakah surat ini butuh balasan?
 	}
 	c.BodyParser(&req)
 
+	// Tentukan unit tujuan: dari tujuan_unit_id, atau cocokkan teks tujuan_disposisi
+	// dengan kode/nama unit. Teks yang tidak cocok tetap disimpan apa adanya.
+	var tujuanUnit *models.Unit
+	if req.TujuanUnitID != nil {
+		tujuanUnit, err = h.unitService.GetActive(*req.TujuanUnitID)
+		if err != nil {
+			if errors.Is(err, services.ErrUnitNotFound) || errors.Is(err, services.ErrUnitInactive) {
+				return utils.UnprocessableEntity(c, "Unit tujuan disposisi tidak ditemukan atau tidak aktif", fiber.Map{"tujuan_unit_id": "invalid"})
+			}
+			return utils.InternalServerError(c, "Gagal mengambil unit tujuan")
+		}
+	} else {
+		tujuanUnit, err = h.unitService.FindByKodeOrNama(req.TujuanDisposisi)
+		if err != nil {
+			return utils.InternalServerError(c, "Gagal mengambil unit tujuan")
+		}
+	}
+
 	oldStatus := letter.Status
 	now := time.Now()
 
 	// Update Data Disposisi
 	letter.Disposisi = req.InstruksiDisposisi
 	letter.BidangTujuan = req.TujuanDisposisi
+	letter.TujuanUnitID = nil
+	if tujuanUnit != nil {
+		letter.BidangTujuan = tujuanUnit.Nama
+		letter.TujuanUnitID = &tujuanUnit.ID
+	}
 	letter.DisposedByID = &user.ID
 	letter.TanggalDisposisi = &now
 	letter.NeedsReply = req.NeedsReply // Set flag needs_reply
@@ -316,7 +418,11 @@ func (h *LetterMasukHandler) DisposeSuratMasuk(c *fiber.Ctx) error {
 		letter.Status = models.StatusDiarsipkan
 	}
 
-	h.db.Save(letter)
+	if err := services.TransitionLetter(h.db, letter, oldStatus, letterAction(c, models.LetterActionDisposed, req.Catatan),
+		"disposisi", "bidang_tujuan", "tujuan_unit_id", "disposed_by_id", "tanggal_disposisi", "needs_reply", "status"); err != nil {
+		return letterSaveError(c, err, "Gagal menyimpan disposisi")
+	}
+	setLetterETag(c, letter)
 
 	// Notif ke Staf Pembuat
 	events.LetterEventBus <- events.LetterEvent{
@@ -325,8 +431,7 @@ func (h *LetterMasukHandler) DisposeSuratMasuk(c *fiber.Ctx) error {
 		OldStatus: oldStatus,
 	}
 
-	AddPresignedURLToLetter(letter)
-	return utils.OK(c, "Disposisi berhasil disimpan", letter)
+	return utils.OK(c, "Disposisi berhasil disimpan", letters.NewLetterResponse(letter))
 }
 
 // ArchiveSuratMasuk - Staf arsip surat yang sudah didisposisi
@@ -346,11 +451,18 @@ func (h *LetterMasukHandler) ArchiveSuratMasuk(c *fiber.Ctx) error {
 	if !canArchive {
 		return utils.Forbidden(c, "Forbidden")
 	}
+	if !ifMatchSatisfied(c, letter) {
+		return preconditionFailed(c, letter)
+	}
 
 	oldStatus := letter.Status
 	letter.Status = models.StatusDiarsipkan
-	h.db.Save(letter)
+	if err := services.TransitionLetter(h.db, letter, oldStatus, letterAction(c, models.LetterActionArchived, ""), "status"); err != nil {
+		return letterSaveError(c, err, "Gagal mengarsipkan surat")
+	}
+	setLetterETag(c, letter)
 
+	loadEventTembusan(h.tembusanService, letter)
 	events.LetterEventBus <- events.LetterEvent{
 		Type:      events.LetterStatusMoved,
 		Letter:    *letter,
@@ -363,57 +475,45 @@ func (h *LetterMasukHandler) ArchiveSuratMasuk(c *fiber.Ctx) error {
 // GetLettersMasukForDisposition - Helper List untuk Direktur
 func (h *LetterMasukHandler) GetLettersMasukForDisposition(c *fiber.Ctx) error {
 	user, _ := middleware.GetUserFromContext(c)
-	if !user.IsDirektur() {
+	if !h.permService.HasPermission(user, models.PermLetterDispose) {
 		return utils.Forbidden(c, "Forbidden")
 	}
 
-	var letters []models.Letter
-	h.db.Where("jenis_surat = ? AND status = ?", models.LetterMasuk, models.StatusBelumDisposisi).
-		Preload("CreatedBy").
-		Order("created_at DESC").
-		Find(&letters)
-
-	AddPresignedURLsToLetters(letters)
-	return utils.OK(c, "List disposisi berhasil diambil", letters)
+	query := h.db.Where("jenis_surat = ? AND status = ?", models.LetterMasuk, models.StatusBelumDisposisi)
+	return respondLetterList(c, query, "-created_at", "List disposisi berhasil diambil")
 }
 
 // GetMyDispositions - Direktur melihat riwayat surat masuk yang sudah didisposisi
 func (h *LetterMasukHandler) GetMyDispositions(c *fiber.Ctx) error {
 	user, _ := middleware.GetUserFromContext(c)
-	var letters []models.Letter
 
 	// Surat masuk yang sudah didisposisi oleh direktur ini (disposed_by_id = user.ID)
-	h.db.Where("disposed_by_id = ? AND jenis_surat = ?", user.ID, models.LetterMasuk).
-		Preload("CreatedBy").
-		Order("updated_at DESC").
-		Find(&letters)
-
-	AddPresignedURLsToLetters(letters)
-	return utils.OK(c, "Riwayat surat masuk yang sudah didisposisi", letters)
+	query := h.db.Where("disposed_by_id = ? AND jenis_surat = ?", user.ID, models.LetterMasuk)
+	return respondLetterList(c, query, "-updated_at", "Riwayat surat masuk yang sudah didisposisi")
 }
 
 // GetLettersNeedingReply - List surat masuk yang butuh balasan (needs_reply = true)
-// Filter by scope: Staf Program → Eksternal, Staf Lembaga → Internal
+// Filter by scope surat keluar yang boleh dibuat user (Staf Program → Eksternal, Staf Lembaga → Internal)
 func (h *LetterMasukHandler) GetLettersNeedingReply(c *fiber.Ctx) error {
 	user, err := middleware.GetUserFromContext(c)
 	if err != nil {
 		return utils.Unauthorized(c, "Unauthorized")
 	}
 
-	// Tentukan scope berdasarkan role staf
+	// Tentukan scope berdasarkan izin membuat surat keluar
 	var scopeFilter string
-	switch user.Role {
-	case models.RoleStafProgram:
+	canEksternal := h.permService.HasPermission(user, models.PermLetterKeluarCreateEksternal)
+	canInternal := h.permService.HasPermission(user, models.PermLetterKeluarCreateInternal)
+	switch {
+	case canEksternal && !canInternal:
 		scopeFilter = models.ScopeEksternal
-	case models.RoleStafLembaga:
+	case canInternal && !canEksternal:
 		scopeFilter = models.ScopeInternal
 	default:
-		// Role lain (admin, direktur, manajer) bisa lihat semua
+		// Boleh membalas keduanya: lihat semua
 		scopeFilter = ""
 	}
 
-	var letters []models.Letter
-
 	// Build query
 	// [FIX] Exclude letter that already archived (already replied)
 	query := h.db.Where("jenis_surat = ? AND needs_reply = ? AND status != ?", models.LetterMasuk, true, models.StatusDiarsipkan)
@@ -423,20 +523,8 @@ func (h *LetterMasukHandler) GetLettersNeedingReply(c *fiber.Ctx) error {
 		query = query.Where("scope = ?", scopeFilter)
 	}
 
-	query.Preload("CreatedBy").
-		Preload("DisposedBy").
-		Preload("Replies"). // Preload surat balasan jika ada
-		Order("updated_at DESC").
-		Find(&letters)
-
-	// Filter hanya yang belum punya balasan
-	var needsReply []models.Letter
-	for _, l := range letters {
-		if len(l.Replies) == 0 {
-			needsReply = append(needsReply, l)
-		}
-	}
+	// Hanya yang belum punya balasan (dicek di SQL agar pagination tetap akurat)
+	query = query.Where("NOT EXISTS (SELECT 1 FROM surat balasan WHERE balasan.in_reply_to_id = surat.id AND balasan.deleted_at IS NULL)")
 
-	AddPresignedURLsToLetters(needsReply)
-	return utils.OK(c, "List surat masuk yang butuh balasan", needsReply)
+	return respondLetterList(c, query, "-updated_at", "List surat masuk yang butuh balasan")
 }
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"TugasAkhir/config"
+	"TugasAkhir/dto/letters"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ListActiveLetterTemplates - GET /api/letters/templates
+// Daftar template aktif untuk dipilih Staf saat membuat surat keluar
+func ListActiveLetterTemplates(c *fiber.Ctx) error {
+	var templates []models.LetterTemplate
+	if err := config.DB.Where("is_active = ?", true).Order("nama ASC").Find(&templates).Error; err != nil {
+		return utils.InternalServerError(c, "Gagal mengambil template surat")
+	}
+
+	responses := make([]letters.LetterTemplateResponse, 0, len(templates))
+	for i := range templates {
+		responses = append(responses, letters.NewLetterTemplateResponse(templates[i]))
+	}
+	return utils.OK(c, "List template surat berhasil diambil", responses)
+}
+
+// Create API
+func AdminCreateLetterTemplate(c *fiber.Ctx) error {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "Unauthorized")
+	}
+
+	var req letters.LetterTemplateCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "invalid request body", err.Error())
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", validationErrors)
+	}
+
+	tmpl := req.ToModel(user.ID)
+	if err := config.DB.Create(&tmpl).Error; err != nil {
+		if utils.IsDuplicateError(err) {
+			return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "template name already exists", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to create template", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusCreated, "template created successfully", letters.NewLetterTemplateResponse(tmpl))
+}
+
+// LIST
+func AdminListLetterTemplates(c *fiber.Ctx) error {
+	var templates []models.LetterTemplate
+	if err := config.DB.Order("id DESC").Find(&templates).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve templates", err.Error())
+	}
+
+	responses := make([]letters.LetterTemplateResponse, 0, len(templates))
+	for i := range templates {
+		responses = append(responses, letters.NewLetterTemplateResponse(templates[i]))
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "templates retrieved successfully", responses)
+}
+
+// READ ONE
+func AdminGetLetterTemplate(c *fiber.Ctx) error {
+	tmpl, err := findLetterTemplate(c.Params("id"))
+	if err != nil {
+		return letterTemplateError(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "template retrieved successfully", letters.NewLetterTemplateResponse(*tmpl))
+}
+
+// Update API (partial)
+func AdminUpdateLetterTemplate(c *fiber.Ctx) error {
+	tmpl, err := findLetterTemplate(c.Params("id"))
+	if err != nil {
+		return letterTemplateError(c, err)
+	}
+
+	var req letters.LetterTemplateUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "invalid request body", err.Error())
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "validation error", validationErrors)
+	}
+
+	letters.ApplyTemplateUpdate(tmpl, &req)
+	if err := config.DB.Save(tmpl).Error; err != nil {
+		if utils.IsDuplicateError(err) {
+			return utils.ErrorResponse(c, fiber.ErrBadRequest.Code, "template name already exists", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to update template", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "template updated successfully", letters.NewLetterTemplateResponse(*tmpl))
+}
+
+// Delete API (soft delete, draft yang sudah memakai template tetap bisa dibuat ulang)
+func AdminDeleteLetterTemplate(c *fiber.Ctx) error {
+	result := config.DB.Delete(&models.LetterTemplate{}, "id = ?", c.Params("id"))
+	if result.Error != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to delete template", result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "template not found", nil)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "template deleted successfully", nil)
+}
+
+// AdminPreviewLetterTemplate - GET /api/admin/letter-templates/:id/preview
+// Render template dengan data contoh agar Admin bisa mengecek tata letak
+func AdminPreviewLetterTemplate(c *fiber.Ctx) error {
+	tmpl, err := findLetterTemplate(c.Params("id"))
+	if err != nil {
+		return letterTemplateError(c, err)
+	}
+
+	now := time.Now()
+	sample := models.Letter{
+		NomorSurat:   "001/CONTOH/" + now.Format("2006"),
+		JudulSurat:   "Contoh Perihal Surat",
+		IsiSurat:     "Ini adalah contoh isi surat untuk melihat tata letak template.",
+		Penerima:     "Nama Penerima\nAlamat Penerima",
+		Pengirim:     "Nama Pengirim",
+		TanggalSurat: &now,
+	}
+
+	pdf, err := services.RenderLetterTemplate(tmpl, &sample)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to render template", err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="preview.pdf"`)
+	return c.Send(pdf)
+}
+
+func findLetterTemplate(id string) (*models.LetterTemplate, error) {
+	var tmpl models.LetterTemplate
+	if err := config.DB.First(&tmpl, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+func letterTemplateError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "template not found", nil)
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve template", err.Error())
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"TugasAkhir/config"
+	"TugasAkhir/dto"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+	"TugasAkhir/utils/password"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// passwordPolicyResponse - password baru ditolak kebijakan (400 dengan semua
+// aturan yang dilanggar di field terkait) atau gagal diproses (500)
+func passwordPolicyResponse(c *fiber.Ctx, field string, err error) error {
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "validation error", fiber.Map{field: policyErr.Error()})
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to process password", err.Error())
+}
+
+// passwordPolicyMessage - pesan kebijakan password untuk panel admin
+func passwordPolicyMessage(err error) string {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return "Gagal memproses password"
+	}
+
+	messages := make([]string, 0, len(policyErr.Rules))
+	for _, rule := range policyErr.Rules {
+		switch rule {
+		case password.RuleMinLength:
+			messages = append(messages, fmt.Sprintf("minimal %d karakter", policyErr.MinLength))
+		case password.RuleMaxLength:
+			messages = append(messages, fmt.Sprintf("maksimal %d karakter", password.MaxLength))
+		case password.RuleUpper:
+			messages = append(messages, "mengandung huruf besar")
+		case password.RuleLower:
+			messages = append(messages, "mengandung huruf kecil")
+		case password.RuleDigit:
+			messages = append(messages, "mengandung angka")
+		case password.RuleSymbol:
+			messages = append(messages, "mengandung simbol")
+		case password.RuleCommon:
+			messages = append(messages, "bukan password umum / pernah bocor")
+		case password.RulePersonal:
+			messages = append(messages, "tidak memuat username atau email")
+		case password.RuleReused:
+			messages = append(messages, fmt.Sprintf("berbeda dari %d password terakhir", policyErr.History))
+		}
+	}
+	return "Password harus " + strings.Join(messages, ", ")
+}
+
+// passwordPolicyHint - ringkasan aturan password aktif untuk form di panel admin
+func passwordPolicyHint() string {
+	cfg := config.LoadPasswordConfig()
+	hint := fmt.Sprintf("Minimal %d karakter", cfg.MinLength)
+	var parts []string
+	if cfg.RequireUpper {
+		parts = append(parts, "huruf besar")
+	}
+	if cfg.RequireLower {
+		parts = append(parts, "huruf kecil")
+	}
+	if cfg.RequireDigit {
+		parts = append(parts, "angka")
+	}
+	if cfg.RequireSymbol {
+		parts = append(parts, "simbol")
+	}
+	if len(parts) > 0 {
+		hint += ", mengandung " + strings.Join(parts, ", ")
+	}
+	return hint + "."
+}
+
+// passwordExpiredLogin - password benar tapi kedaluwarsa. Token reset tidak
+// pernah diberikan hanya dengan password: akun dengan 2FA harus melewati
+// challenge dulu (token reset diterbitkan di VerifyTwoFactorLogin), akun tanpa
+// 2FA menerima link reset lewat email seperti lupa password.
+func passwordExpiredLogin(c *fiber.Ctx, user *models.User) error {
+	if !user.CanLogin() {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "account is not active", nil)
+	}
+
+	enabled, err := services.NewTwoFactorService(config.DB).Enabled(user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to check two-factor status", err.Error())
+	}
+	if enabled {
+		challenge, claims, err := utils.GeneratePasswordExpiredChallenge(*user)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to generate challenge token", err.Error())
+		}
+		return utils.SuccessResponse(c, fiber.StatusOK, "two-factor authentication required", dto.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+			ExpiresAt:         claims.ExpiresAt.Time,
+			PasswordExpired:   true,
+		})
+	}
+
+	// Password terbukti benar: counter kegagalan akun direset
+	resetLoginThrottle(user)
+	if err := sendPasswordResetLink(c, user, "password expired"); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to send reset email", nil)
+	}
+	return utils.ErrorResponse(c, fiber.StatusForbidden, "password has expired", fiber.Map{
+		"password_expired": true,
+		"reset_link_sent":  true,
+		"min_length":       config.LoadPasswordConfig().MinLength,
+	})
+}
+
+// passwordExpiredResponse - kedua faktor sudah terverifikasi tetapi password
+// kedaluwarsa. Token reset diterbitkan langsung agar aplikasi bisa meminta
+// password baru lewat POST /api/auth/reset-password tanpa menunggu email.
+func passwordExpiredResponse(c *fiber.Ctx, user *models.User) error {
+	rawToken, err := issuePasswordResetToken(user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to create reset token", err.Error())
+	}
+	recordAuthEvent(c, models.AuthEventPasswordResetRequested, user, "", "password expired")
+
+	return utils.ErrorResponse(c, fiber.StatusForbidden, "password has expired", fiber.Map{
+		"password_expired": true,
+		"reset_token":      rawToken,
+		"min_length":       config.LoadPasswordConfig().MinLength,
+	})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	letterdto "TugasAkhir/dto/letters"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+	"TugasAkhir/utils/search"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type SearchHandler struct {
+	searchService *services.SearchService
+}
+
+type searchResultItem struct {
+	Letter     interface{}         `json:"letter"` // LetterSummaryResponse (atau subset dari fields=)
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+type searchResponse struct {
+	Items  []searchResultItem        `json:"items"`
+	Facets map[string]map[string]int `json:"facets"`
+}
+
+func NewSearchHandler(db *gorm.DB) *SearchHandler {
+	return &SearchHandler{
+		searchService: services.NewSearchService(db, search.DefaultIndex()),
+	}
+}
+
+// SearchLetters - Pencarian full-text surat (judul, pengirim, nomor, isi,
+// kesimpulan & teks lampiran PDF) dengan filter facet dan highlight
+func (h *SearchHandler) SearchLetters(c *fiber.Ctx) error {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "Unauthorized")
+	}
+
+	params, errs := parseSearchParams(c)
+	if len(errs) > 0 {
+		return utils.BadRequest(c, "Parameter pencarian tidak valid", errs)
+	}
+
+	result, err := h.searchService.Search(user, params)
+	if err != nil {
+		if errors.Is(err, services.ErrSearchUnavailable) {
+			return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Pencarian sedang tidak tersedia", nil)
+		}
+		log.Printf("⚠️ Pencarian gagal: %v", err)
+		return utils.InternalServerError(c, "Gagal melakukan pencarian")
+	}
+
+	fields := utils.ParseFields(c)
+	items := make([]searchResultItem, 0, len(result.Items))
+	for i := range result.Items {
+		letter, err := utils.SelectFields(letterdto.NewLetterSummaryResponse(&result.Items[i].Letter), fields)
+		if err != nil {
+			return fieldsError(c, err)
+		}
+		items = append(items, searchResultItem{
+			Letter:     letter,
+			Score:      result.Items[i].Score,
+			Highlights: result.Items[i].Highlights,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponseStruct{
+		Success: true,
+		Message: "Hasil pencarian berhasil diambil",
+		Data:    searchResponse{Items: items, Facets: result.Facets},
+		Meta:    utils.PaginationMeta{Page: params.Page, Limit: params.Limit, Total: result.Total},
+	})
+}
+
+func parseSearchParams(c *fiber.Ctx) (services.SearchParams, map[string]string) {
+	errs := map[string]string{}
+
+	page, limit := parsePageParams(c)
+
+	p := services.SearchParams{
+		Query:      c.Query("q"),
+		JenisSurat: c.Query("jenis_surat"),
+		Status:     c.Query("status"),
+		Scope:      c.Query("scope"),
+		Prioritas:  c.Query("prioritas"),
+		Page:       page,
+		Limit:      limit,
+	}
+
+	if p.JenisSurat != "" && !models.LetterType(p.JenisSurat).IsValid() {
+		errs["jenis_surat"] = "harus salah satu dari: masuk, keluar, internal"
+	}
+	if p.Status != "" && !models.LetterStatus(p.Status).IsValid() {
+		errs["status"] = "status surat tidak dikenal"
+	}
+	switch p.Scope {
+	case "", models.ScopeInternal, models.ScopeEksternal:
+	default:
+		errs["scope"] = "harus salah satu dari: Internal, Eksternal"
+	}
+	if p.Prioritas != "" && !models.Priority(p.Prioritas).IsValid() {
+		errs["prioritas"] = "harus salah satu dari: biasa, segera, penting"
+	}
+
+	if raw := c.Query("created_by_id"); raw != "" {
+		if id, err := strconv.ParseUint(raw, 10, 64); err != nil || id == 0 {
+			errs["created_by_id"] = "harus berupa ID user"
+		} else {
+			p.CreatedByID = raw
+		}
+	}
+
+	p.DateFrom, p.DateTo = parseDateRange(c, errs)
+
+	return p, errs
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"TugasAkhir/config"
+	"TugasAkhir/dto"
+	"TugasAkhir/middleware"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sessionDevice melengkapi info perangkat dari body dengan header
+// X-Device-Name / X-Platform, lalu menebak platform dari User-Agent
+func sessionDevice(c *fiber.Ctx, info dto.DeviceInfo) services.SessionDevice {
+	device := services.SessionDevice{
+		Name:      strings.TrimSpace(info.DeviceName),
+		Platform:  strings.TrimSpace(info.Platform),
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+	if device.Name == "" {
+		device.Name = strings.TrimSpace(c.Get("X-Device-Name"))
+	}
+	if device.Platform == "" {
+		device.Platform = strings.TrimSpace(c.Get("X-Platform"))
+	}
+	if device.Platform == "" {
+		device.Platform = platformFromUserAgent(device.UserAgent)
+	}
+	return device
+}
+
+func platformFromUserAgent(ua string) string {
+	ua = strings.ToLower(ua)
+	switch {
+	case strings.Contains(ua, "android"):
+		return "android"
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		return "ios"
+	case strings.Contains(ua, "windows"):
+		return "windows"
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os"):
+		return "macos"
+	case strings.Contains(ua, "linux"):
+		return "linux"
+	}
+	return ""
+}
+
+// revokeUserSessions memaksa user login ulang di semua perangkat, misal
+// setelah admin mengubah password atau role-nya. Kegagalan hanya di-log agar
+// perubahan yang sudah tersimpan tidak dilaporkan gagal.
+func revokeUserSessions(userID uint) {
+	if _, err := services.NewSessionService(config.DB).RevokeAll(userID); err != nil {
+		log.Printf("[session] gagal mencabut sesi user %d: %v", userID, err)
+	}
+}
+
+// ListMySessions - GET /api/settings/sessions
+func ListMySessions(c *fiber.Ctx) error {
+	claims, ok := middleware.GetJWTClaims(c)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	sessions, err := services.NewSessionService(config.DB).List(claims.UserID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve sessions", err.Error())
+	}
+
+	responses := make([]dto.SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		responses = append(responses, dto.NewSessionResponse(s, claims.SessionID))
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "sessions retrieved successfully", responses)
+}
+
+// RevokeMySession - DELETE /api/settings/sessions/:id
+// Mencabut sesi perangkat lain (atau sesi ini sendiri, sama dengan logout)
+func RevokeMySession(c *fiber.Ctx) error {
+	claims, ok := middleware.GetJWTClaims(c)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "session not found", nil)
+	}
+
+	if err := services.NewSessionService(config.DB).Revoke(claims.UserID, uint(id)); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "session not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to revoke session", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "session revoked successfully", nil)
+}
+
+// RevokeOtherSessions - POST /api/settings/sessions/revoke-others
+// Mencabut semua sesi kecuali sesi yang dipakai request ini
+func RevokeOtherSessions(c *fiber.Ctx) error {
+	claims, ok := middleware.GetJWTClaims(c)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	revoked, err := services.NewSessionService(config.DB).RevokeOthers(claims.UserID, claims.SessionID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to revoke sessions", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "other sessions revoked successfully", dto.RevokeSessionsResponse{Revoked: revoked})
+}
@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"TugasAkhir/config"
+	"TugasAkhir/dto"
 	userdto "TugasAkhir/dto/users"
 	"TugasAkhir/middleware"
 	"TugasAkhir/models"
+	"TugasAkhir/services"
 	"TugasAkhir/utils"
+	"TugasAkhir/utils/storage"
+	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -77,16 +83,74 @@ func ChangePassword(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "password lama salah", nil)
 	}
 
-	newHash, err := utils.HashPassword(req.NewPassword)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to hash password", err.Error())
+	if err := services.NewPasswordService(config.DB).Set(&user, req.NewPassword); err != nil {
+		return passwordPolicyResponse(c, "new_password", err)
 	}
+	recordAuthEvent(c, models.AuthEventPasswordChanged, &user, "", "")
 
-	user.PasswordHash = newHash
-	if err := config.DB.Save(&user).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to update password", err.Error())
+	// Perangkat lain harus login ulang; sesi yang dipakai saat ini tetap aktif
+	if _, err := services.NewSessionService(config.DB).RevokeOthers(user.ID, claims.SessionID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to revoke other sessions", err.Error())
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "password updated successfully", nil)
 
 }
+
+// UploadMySignature - Unggah gambar tanda tangan (PNG/JPG) yang ditempel saat menyetujui surat
+func UploadMySignature(c *fiber.Ctx) error {
+	claims, ok := middleware.GetJWTClaims(c)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "file tanda tangan wajib diunggah", nil)
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	if ext != ".png" && ext != ".jpg" && ext != ".jpeg" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "format tanda tangan harus PNG atau JPG", nil)
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "user not found", nil)
+	}
+
+	key := fmt.Sprintf("signature/user_%d_%d%s", user.ID, time.Now().UnixNano(), ext)
+	uploadedPath, err := storage.UploadFile(c.Context(), fileHeader, key)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to upload signature", err.Error())
+	}
+
+	user.SignatureImagePath = uploadedPath
+	if err := config.DB.Model(&user).Update("signature_image_path", uploadedPath).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to save signature", err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "signature uploaded successfully", toUserSummary(user))
+}
+
+// GetMyRoles - GET /api/settings/roles
+// Role yang bisa dipakai user saat ini (role utama + penugasan Plt./Plh. yang
+// berlaku) beserta role aktif di token
+func GetMyRoles(c *fiber.Ctx) error {
+	claims, ok := middleware.GetJWTClaims(c)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "user not found", nil)
+	}
+
+	roles, err := services.NewUserRoleService(config.DB).AvailableRoles(&user)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to fetch user roles", err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "roles retrieved", dto.RolesResponse{ActiveRole: claims.Role, Roles: roles})
+}
@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"TugasAkhir/config"
+	"TugasAkhir/dto"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// authenticatePassword memeriksa email & password dari form login. Tanpa
+// LDAP hanya password lokal yang dicek. Dengan LDAP:
+//   - user yang tertaut ke LDAP hanya bisa login dengan password direktori;
+//   - user lain dicek password lokalnya dulu, lalu ke direktori;
+//   - email yang belum terdaftar dicek ke direktori dan akunnya dibuat.
+//
+// reason tidak kosong berarti kredensial ditolak (untuk log audit); user tetap
+// diisi jika emailnya dikenal.
+func authenticatePassword(c *fiber.Ctx, email, password string) (*models.User, string, error) {
+	sso := services.NewSSOService(config.DB)
+
+	var user *models.User
+	var local models.User
+	err := config.DB.Where("email = ?", email).First(&local).Error
+	switch {
+	case err == nil:
+		user = &local
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, "", err
+	}
+
+	if !sso.LDAPEnabled() {
+		if user == nil {
+			return nil, "unknown email", nil
+		}
+		if !utils.CheckPassword(user.PasswordHash, password) {
+			return user, "wrong password", nil
+		}
+		return checkPasswordAge(user)
+	}
+
+	if user != nil {
+		linked, err := sso.LinkedTo(user.ID, models.IdentityProviderLDAP)
+		if err != nil {
+			return nil, "", err
+		}
+		if !linked && utils.CheckPassword(user.PasswordHash, password) {
+			return checkPasswordAge(user)
+		}
+	}
+
+	identity, err := sso.AuthenticatePassword(c.UserContext(), email, password)
+	if errors.Is(err, services.ErrSSOInvalidCredentials) {
+		if user == nil {
+			return nil, "unknown email", nil
+		}
+		return user, "wrong password", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	provisioned, err := provisionExternalUser(c, identity)
+	if err != nil {
+		return nil, "", err
+	}
+	return provisioned, "", nil
+}
+
+// checkPasswordAge - password lokal yang benar tetap ditolak dengan
+// services.ErrPasswordExpired jika melewati PASSWORD_MAX_AGE. Password
+// direktori tidak diperiksa; masa berlakunya diatur LDAP.
+func checkPasswordAge(user *models.User) (*models.User, string, error) {
+	if services.NewPasswordService(config.DB).Expired(user) {
+		return user, "", services.ErrPasswordExpired
+	}
+	return user, "", nil
+}
+
+// provisionExternalUser menautkan / membuat user lokal dari identitas
+// eksternal. Perubahan role karena grup direktori dicatat di log audit.
+func provisionExternalUser(c *fiber.Ctx, identity *services.ExternalIdentity) (*models.User, error) {
+	user, roleChanged, err := services.NewSSOService(config.DB).Provision(identity)
+	if err != nil {
+		return nil, err
+	}
+	if roleChanged {
+		recordAuthEvent(c, models.AuthEventRoleSynced, user, "", identity.Provider+" groups: "+string(user.Role))
+	}
+	return user, nil
+}
+
+// isSSOError - kegagalan SSO yang punya respons sendiri (bukan kesalahan server)
+func isSSOError(err error) bool {
+	for _, target := range []error{
+		services.ErrSSODisabled,
+		services.ErrSSOInvalidState,
+		services.ErrSSONoRole,
+		services.ErrSSOEmailMissing,
+		services.ErrSSOAccountRemoved,
+		services.ErrSSOLinkRequired,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ssoErrorResponse memetakan kegagalan SSO ke respons API
+func ssoErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrSSODisabled):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "single sign-on is not configured", nil)
+	case errors.Is(err, services.ErrSSOInvalidState):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "sso login request is invalid or has expired", nil)
+	case errors.Is(err, services.ErrSSONoRole):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "your directory groups do not grant access to this application", nil)
+	case errors.Is(err, services.ErrSSOEmailMissing):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "identity provider did not return a verified email", nil)
+	case errors.Is(err, services.ErrSSOAccountRemoved):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "account is not active", nil)
+	case errors.Is(err, services.ErrSSOLinkRequired):
+		return utils.ErrorResponse(c, fiber.StatusConflict, "an account with this email already exists; sign in and link it from account settings", nil)
+	case errors.Is(err, services.ErrSSOIdentityLinked):
+		return utils.ErrorResponse(c, fiber.StatusConflict, "this identity is already linked to another account", nil)
+	}
+	log.Printf("[sso] login failed: %v", err)
+	return utils.ErrorResponse(c, fiber.StatusBadGateway, "single sign-on failed", nil)
+}
+
+// ssoErrorMessage - pesan kegagalan SSO untuk halaman login admin
+func ssoErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, services.ErrSSODisabled):
+		return "Login SSO belum dikonfigurasi"
+	case errors.Is(err, services.ErrSSOInvalidState):
+		return "Permintaan login SSO tidak valid atau sudah kedaluwarsa. Silakan coba lagi."
+	case errors.Is(err, services.ErrSSONoRole):
+		return "Grup direktori Anda tidak memiliki akses ke aplikasi ini"
+	case errors.Is(err, services.ErrSSOEmailMissing):
+		return "Identity provider tidak mengirim email yang terverifikasi"
+	case errors.Is(err, services.ErrSSOAccountRemoved):
+		return "Akun Anda sudah dihapus"
+	case errors.Is(err, services.ErrSSOLinkRequired):
+		return "Email ini sudah terdaftar. Login dengan password lalu tautkan akun SSO dari pengaturan, atau minta admin menautkannya."
+	}
+	log.Printf("[sso] login failed: %v", err)
+	return "Login SSO gagal, silakan coba lagi"
+}
+
+// OIDCAuthorize - GET /api/auth/oidc/authorize
+// Mengembalikan URL login identity provider. Setelah login, provider
+// mengarahkan ke OIDC_REDIRECT_URL dengan code & state yang diteruskan
+// aplikasi ke /api/auth/oidc/callback.
+func OIDCAuthorize(c *fiber.Ctx) error {
+	sso := services.NewSSOService(config.DB)
+	if !sso.OIDCEnabled() || sso.MobileRedirectURL() == "" {
+		return ssoErrorResponse(c, services.ErrSSODisabled)
+	}
+
+	authURL, err := sso.BeginOIDC(c.UserContext(), sso.MobileRedirectURL(), 0)
+	if err != nil {
+		return ssoErrorResponse(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "redirect to identity provider", dto.OIDCAuthorizeResponse{
+		AuthorizationURL: authURL,
+	})
+}
+
+// OIDCCallback - POST /api/auth/oidc/callback
+// Menukar code dari identity provider; respons sama dengan /auth/login
+func OIDCCallback(c *fiber.Ctx) error {
+	var req dto.OIDCCallbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+	req.Code = strings.TrimSpace(req.Code)
+	req.State = strings.TrimSpace(req.State)
+	if req.Code == "" || req.State == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "code and state are required", nil)
+	}
+
+	identity, err := services.NewSSOService(config.DB).CompleteOIDC(c.UserContext(), req.State, req.Code, 0)
+	if err != nil {
+		recordAuthEvent(c, models.AuthEventLoginFailure, nil, "", "oidc: "+err.Error())
+		return ssoErrorResponse(c, err)
+	}
+	user, err := provisionExternalUser(c, identity)
+	if err != nil {
+		recordAuthEvent(c, models.AuthEventLoginFailure, nil, identity.Email, "oidc: "+err.Error())
+		return ssoErrorResponse(c, err)
+	}
+
+	return finishLogin(c, *user, req.DeviceInfo)
+}
+
+// =====================
+// PENAUTAN AKUN SSO
+// =====================
+
+// ListMyIdentities - GET /api/settings/sso
+func ListMyIdentities(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+	identities, err := services.NewSSOService(config.DB).Identities(user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve linked identities", nil)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "linked identities retrieved", identityResponses(identities))
+}
+
+// LinkLDAPIdentity - POST /api/settings/sso/ldap
+// User yang sudah login membuktikan akun direktorinya lalu menautkannya.
+// Setelah tertaut, login hanya bisa memakai password direktori.
+func LinkLDAPIdentity(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+	var req dto.LinkLDAPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+
+	sso := services.NewSSOService(config.DB)
+	identity, err := sso.AuthenticatePassword(c.UserContext(), strings.TrimSpace(req.Login), req.Password)
+	if errors.Is(err, services.ErrSSOInvalidCredentials) {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid directory credentials", nil)
+	}
+	if err != nil {
+		return ssoErrorResponse(c, err)
+	}
+	return linkIdentity(c, sso, user, identity)
+}
+
+// LinkOIDCAuthorize - GET /api/settings/sso/oidc/authorize
+// Sama dengan /auth/oidc/authorize, tetapi state hanya bisa diselesaikan
+// lewat /settings/sso/oidc/callback oleh user yang sama
+func LinkOIDCAuthorize(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+	sso := services.NewSSOService(config.DB)
+	if !sso.OIDCEnabled() || sso.MobileRedirectURL() == "" {
+		return ssoErrorResponse(c, services.ErrSSODisabled)
+	}
+
+	authURL, err := sso.BeginOIDC(c.UserContext(), sso.MobileRedirectURL(), user.ID)
+	if err != nil {
+		return ssoErrorResponse(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "redirect to identity provider", dto.OIDCAuthorizeResponse{
+		AuthorizationURL: authURL,
+	})
+}
+
+// LinkOIDCCallback - POST /api/settings/sso/oidc/callback
+func LinkOIDCCallback(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+	var req dto.LinkOIDCRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+	req.Code = strings.TrimSpace(req.Code)
+	req.State = strings.TrimSpace(req.State)
+	if req.Code == "" || req.State == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "code and state are required", nil)
+	}
+
+	sso := services.NewSSOService(config.DB)
+	identity, err := sso.CompleteOIDC(c.UserContext(), req.State, req.Code, user.ID)
+	if err != nil {
+		return ssoErrorResponse(c, err)
+	}
+	return linkIdentity(c, sso, user, identity)
+}
+
+func linkIdentity(c *fiber.Ctx, sso *services.SSOService, user *models.User, identity *services.ExternalIdentity) error {
+	link, err := sso.Link(user.ID, identity)
+	if err != nil {
+		return ssoErrorResponse(c, err)
+	}
+	recordAuthEvent(c, models.AuthEventIdentityLinked, user, "", identity.Provider+": "+identity.Subject)
+	return utils.SuccessResponse(c, fiber.StatusOK, "identity linked", dto.NewIdentityResponse(*link))
+}
+
+// AdminListUserIdentities - GET /api/admin/users/:id/identities
+func AdminListUserIdentities(c *fiber.Ctx) error {
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid user id", nil)
+	}
+	identities, err := services.NewSSOService(config.DB).Identities(uint(userID))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve linked identities", nil)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "linked identities retrieved", identityResponses(identities))
+}
+
+// AdminLinkUserIdentity - POST /api/admin/users/:id/identities
+// Admin menautkan akun provider ke user tanpa perlu login SSO lebih dulu
+func AdminLinkUserIdentity(c *fiber.Ctx) error {
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid user id", nil)
+	}
+	var req dto.AdminLinkIdentityRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+
+	identity := services.ExternalIdentity{Provider: strings.ToLower(strings.TrimSpace(req.Provider)), Subject: strings.TrimSpace(req.Subject)}
+	errs := map[string]string{}
+	if identity.Provider != models.IdentityProviderLDAP && identity.Provider != models.IdentityProviderOIDC {
+		errs["provider"] = "provider must be ldap or oidc"
+	}
+	if identity.Subject == "" || len(identity.Subject) > 255 {
+		errs["subject"] = "subject is required (max 255 characters)"
+	}
+	if len(errs) > 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "validation error", errs)
+	}
+	// DN LDAP disimpan huruf kecil, sama dengan LDAPProvider.Authenticate
+	if identity.Provider == models.IdentityProviderLDAP {
+		identity.Subject = strings.ToLower(identity.Subject)
+	}
+
+	link, err := services.NewSSOService(config.DB).Link(uint(userID), &identity)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "user not found", nil)
+		}
+		return ssoErrorResponse(c, err)
+	}
+	recordIdentityEvent(c, models.AuthEventIdentityLinked, uint(userID), link)
+	return utils.SuccessResponse(c, fiber.StatusCreated, "identity linked", dto.NewIdentityResponse(*link))
+}
+
+// AdminUnlinkUserIdentity - DELETE /api/admin/users/:id/identities/:identityId
+func AdminUnlinkUserIdentity(c *fiber.Ctx) error {
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid user id", nil)
+	}
+	identityID, err := c.ParamsInt("identityId")
+	if err != nil || identityID <= 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid identity id", nil)
+	}
+
+	sso := services.NewSSOService(config.DB)
+	var link models.UserIdentity
+	if err := config.DB.Where("id = ? AND user_id = ?", identityID, userID).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "linked identity not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve linked identity", nil)
+	}
+	if err := sso.Unlink(uint(userID), uint(identityID)); err != nil {
+		if errors.Is(err, services.ErrSSOIdentityNotFound) {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "linked identity not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to unlink identity", nil)
+	}
+	recordIdentityEvent(c, models.AuthEventIdentityUnlinked, uint(userID), &link)
+	return utils.SuccessResponse(c, fiber.StatusOK, "identity unlinked", nil)
+}
+
+func recordIdentityEvent(c *fiber.Ctx, event models.AuthEventType, userID uint, link *models.UserIdentity) {
+	detail := link.Provider + ": " + link.Subject
+	if admin, err := currentUser(c); err == nil {
+		detail += " by " + admin.Email
+	}
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		user.ID = userID
+	}
+	recordAuthEvent(c, event, &user, "", detail)
+}
+
+func identityResponses(identities []models.UserIdentity) []dto.IdentityResponse {
+	responses := make([]dto.IdentityResponse, 0, len(identities))
+	for _, identity := range identities {
+		responses = append(responses, dto.NewIdentityResponse(identity))
+	}
+	return responses
+}
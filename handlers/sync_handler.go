@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"TugasAkhir/dto/letters"
+	"TugasAkhir/middleware"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type SyncHandler struct {
+	syncService *services.SyncService
+}
+
+func NewSyncHandler(db *gorm.DB) *SyncHandler {
+	return &SyncHandler{
+		syncService: services.NewSyncService(db),
+	}
+}
+
+// Sync - Delta sync untuk aplikasi mobile (offline-first). Mengembalikan
+// surat, disposisi & notifikasi yang berubah sejak cursor ?since=, termasuk
+// tombstone untuk data yang dihapus.
+func (h *SyncHandler) Sync(c *fiber.Ctx) error {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "Unauthorized")
+	}
+
+	cursor, err := services.DecodeSyncCursor(c.Query("since"))
+	if err != nil {
+		return utils.BadRequest(c, "Cursor sync tidak valid", fiber.Map{"since": "cursor tidak dikenal, lakukan sync penuh tanpa since"})
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "200"))
+	if limit < 1 || limit > 500 {
+		limit = 200
+	}
+
+	result, err := h.syncService.Changes(user, cursor, limit)
+	if err != nil {
+		log.Printf("⚠️ Sync gagal untuk user %d: %v", user.ID, err)
+		return utils.InternalServerError(c, "Gagal mengambil data sync")
+	}
+
+	resp := letters.NewSyncResponse(
+		result.Cursor.Encode(), result.HasMore,
+		result.Letters, result.DeletedLetters, result.RevokedLetters,
+		result.Notifications, result.DeletedNotifications,
+	)
+	return utils.OK(c, "Data sync berhasil diambil", resp)
+}
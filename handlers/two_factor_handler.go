@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"TugasAkhir/config"
+	"TugasAkhir/dto"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+// startTwoFactorChallenge mengembalikan challenge token jika user harus
+// memasukkan kode 2FA (atau wajib mendaftarkan 2FA karena role-nya).
+// challenged false berarti login bisa langsung diselesaikan.
+func startTwoFactorChallenge(c *fiber.Ctx, user models.User) (bool, error) {
+	enabled, required, err := twoFactorState(&user)
+	if err != nil {
+		return true, utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to check two-factor status", err.Error())
+	}
+	if !enabled && !required {
+		return false, nil
+	}
+
+	challenge, claims, err := utils.GenerateTwoFactorChallenge(user)
+	if err != nil {
+		return true, utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to generate challenge token", err.Error())
+	}
+
+	return true, utils.SuccessResponse(c, fiber.StatusOK, "two-factor authentication required", dto.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		SetupRequired:     !enabled,
+		ChallengeToken:    challenge,
+		ExpiresAt:         claims.ExpiresAt.Time,
+	})
+}
+
+// twoFactorState - enabled: user sudah mengaktifkan 2FA; required: role user
+// mewajibkan 2FA (hanya diperiksa jika belum aktif)
+func twoFactorState(user *models.User) (enabled, required bool, err error) {
+	tfs := services.NewTwoFactorService(config.DB)
+	if enabled, err = tfs.Enabled(user.ID); err != nil || enabled {
+		return enabled, false, err
+	}
+	required, err = tfs.Required(user)
+	return false, required, err
+}
+
+// challengeUser memvalidasi challenge token dan memuat user-nya
+func challengeUser(token string) (*models.User, *utils.JWTClaims, error) {
+	claims, err := utils.VerifyTwoFactorChallenge(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+	if !user.CanLogin() {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	return &user, claims, nil
+}
+
+// SetupTwoFactorLogin - POST /api/auth/2fa/setup
+// Enrolment wajib saat login: role user mewajibkan 2FA tapi user belum
+// mendaftarkan authenticator. Challenge token hanya membuktikan password,
+// jadi enrolment juga butuh token dari admin. Kode pertama dikirim ke
+// /auth/2fa/verify.
+func SetupTwoFactorLogin(c *fiber.Ctx) error {
+	var req dto.TwoFactorChallengeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+	if strings.TrimSpace(req.EnrolmentToken) == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "enrolment_token is required", nil)
+	}
+
+	// Challenge password kedaluwarsa hanya untuk akun yang sudah memakai 2FA
+	user, claims, err := challengeUser(req.ChallengeToken)
+	if err != nil || claims.PasswordExpired {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid or expired challenge token", nil)
+	}
+
+	// Token enrolment yang salah dihitung sebagai login gagal
+	lockErr, err := loginLock(c, user.Email)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to check login attempts", err.Error())
+	}
+	if lockErr != nil {
+		return tooManyLoginAttempts(c, lockErr)
+	}
+
+	enrolment, err := services.NewTwoFactorService(config.DB).BeginEnrolmentWithToken(user, req.EnrolmentToken)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorEnrolmentToken) {
+			if lockErr := loginFailed(c, user.Email, user, "invalid two-factor enrolment token"); lockErr != nil {
+				return tooManyLoginAttempts(c, lockErr)
+			}
+		}
+		return twoFactorError(c, err)
+	}
+	return respondTwoFactorSetup(c, enrolment)
+}
+
+// VerifyTwoFactorLogin - POST /api/auth/2fa/verify
+// Langkah kedua login: menukar challenge token + kode 2FA (atau recovery code)
+// dengan access & refresh token. Jika user sedang enrolment wajib, kode ini
+// sekaligus mengaktifkan 2FA dan recovery code dikembalikan di respons.
+func VerifyTwoFactorLogin(c *fiber.Ctx) error {
+	var req dto.TwoFactorVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+	if strings.TrimSpace(req.Code) == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "code is required", nil)
+	}
+
+	user, claims, err := challengeUser(req.ChallengeToken)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid or expired challenge token", nil)
+	}
+
+	// Kode 2FA yang salah dihitung sebagai login gagal, sama seperti password
+	lockErr, err := loginLock(c, user.Email)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to check login attempts", err.Error())
+	}
+	if lockErr != nil {
+		return tooManyLoginAttempts(c, lockErr)
+	}
+
+	recoveryCodes, err := verifyOrEnrolTwoFactor(user, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorInvalidCode) {
+			if lockErr := loginFailed(c, user.Email, user, "invalid two-factor code"); lockErr != nil {
+				return tooManyLoginAttempts(c, lockErr)
+			}
+		}
+		return twoFactorError(c, err)
+	}
+
+	// Password kedaluwarsa: kedua faktor sudah benar, user hanya boleh
+	// mengganti password (belum ada sesi yang dibuat)
+	if claims.PasswordExpired {
+		resetLoginThrottle(user)
+		return passwordExpiredResponse(c, user)
+	}
+
+	return completeLogin(c, *user, req.DeviceInfo, recoveryCodes)
+}
+
+// verifyOrEnrolTwoFactor memverifikasi kode untuk user yang sudah memakai 2FA,
+// atau mengonfirmasi enrolment (dan mengembalikan recovery code) jika belum
+func verifyOrEnrolTwoFactor(user *models.User, code string) ([]string, error) {
+	tfs := services.NewTwoFactorService(config.DB)
+	enabled, err := tfs.Enabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, tfs.Verify(user.ID, code)
+	}
+	return tfs.ConfirmEnrolment(user.ID, code)
+}
+
+// GetTwoFactorStatus - GET /api/settings/2fa
+func GetTwoFactorStatus(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	tfs := services.NewTwoFactorService(config.DB)
+	var resp dto.TwoFactorStatusResponse
+	if resp.Enabled, err = tfs.Enabled(user.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to check two-factor status", err.Error())
+	}
+	if resp.Required, err = tfs.Required(user); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to check two-factor status", err.Error())
+	}
+	if resp.Enabled {
+		if resp.RecoveryCodesRemaining, err = tfs.RemainingRecoveryCodes(user.ID); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to count recovery codes", err.Error())
+		}
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "two-factor status retrieved", resp)
+}
+
+// SetupTwoFactor - POST /api/settings/2fa/setup
+// Menghasilkan secret & QR provisioning URI baru (secret sebelumnya yang belum
+// dikonfirmasi tidak berlaku lagi); 2FA baru aktif setelah dikonfirmasi lewat
+// /settings/2fa/enable
+func SetupTwoFactor(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	enrolment, err := services.NewTwoFactorService(config.DB).BeginEnrolment(user)
+	if err != nil {
+		return twoFactorError(c, err)
+	}
+	return respondTwoFactorSetup(c, enrolment)
+}
+
+// EnableTwoFactor - POST /api/settings/2fa/enable
+func EnableTwoFactor(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	var req dto.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+
+	codes, err := services.NewTwoFactorService(config.DB).ConfirmEnrolment(user.ID, req.Code)
+	if err != nil {
+		return twoFactorError(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "two-factor authentication enabled", dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes - POST /api/settings/2fa/recovery-codes
+// Butuh kode 2FA yang valid; recovery code lama tidak berlaku lagi
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	var req dto.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+
+	tfs := services.NewTwoFactorService(config.DB)
+	if err := tfs.Verify(user.ID, req.Code); err != nil {
+		return twoFactorError(c, err)
+	}
+	codes, err := tfs.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		return twoFactorError(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "recovery codes regenerated", dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor - POST /api/settings/2fa/disable
+// Butuh password dan kode 2FA; akun SSO tanpa password lokal cukup dengan
+// kode dari authenticator (bukan recovery code). Ditolak jika role user
+// mewajibkan 2FA.
+func DisableTwoFactor(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	var req dto.TwoFactorDisableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "invalid request body", err.Error())
+	}
+
+	tfs := services.NewTwoFactorService(config.DB)
+	verify := tfs.Verify
+	if user.PasswordHash == "" {
+		verify = tfs.VerifyTOTP
+	} else if !utils.CheckPassword(user.PasswordHash, req.Password) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "password is incorrect", nil)
+	}
+	if err := verify(user.ID, req.Code); err != nil {
+		return twoFactorError(c, err)
+	}
+	if err := tfs.Disable(user); err != nil {
+		return twoFactorError(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "two-factor authentication disabled", nil)
+}
+
+// AdminIssueTwoFactorEnrolment - POST /api/admin/users/:id/2fa-enrolment
+// Token enrolment sekali pakai (berlaku 24 jam) untuk user yang wajib 2FA tapi
+// belum mendaftarkan authenticator. Admin menyampaikan token ke user sendiri.
+func AdminIssueTwoFactorEnrolment(c *fiber.Ctx) error {
+	user, err := findAdminUser(c)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "user not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to retrieve user", err.Error())
+	}
+	admin, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "unauthorized", nil)
+	}
+
+	token, expiresAt, err := services.NewTwoFactorService(config.DB).IssueEnrolmentToken(user.ID, &admin.ID)
+	if err != nil {
+		return twoFactorError(c, err)
+	}
+	recordAuthEvent(c, models.AuthEventTwoFactorTokenIssued, user, user.Email, "by "+admin.Email)
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "enrolment token issued", dto.TwoFactorEnrolmentTokenResponse{
+		EnrolmentToken: token,
+		ExpiresAt:      expiresAt,
+	})
+}
+
+func respondTwoFactorSetup(c *fiber.Ctx, enrolment *services.TwoFactorEnrolment) error {
+	qr, err := qrDataURI(enrolment.ProvisioningURI)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "failed to generate qr code", err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "scan the qr code with an authenticator app", dto.TwoFactorSetupResponse{
+		Secret:          enrolment.Secret,
+		ProvisioningURI: enrolment.ProvisioningURI,
+		QRCode:          qr,
+	})
+}
+
+// qrDataURI merender konten sebagai PNG data URI (untuk <img src>)
+func qrDataURI(content string) (string, error) {
+	png, err := qrcode.Encode(content, qrcode.Medium, 256)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
+
+func currentUser(c *fiber.Ctx) (*models.User, error) {
+	claims, ok := middleware.GetJWTClaims(c)
+	if !ok {
+		return nil, fiber.ErrUnauthorized
+	}
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func twoFactorError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorInvalidCode):
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "invalid two-factor code", nil)
+	case errors.Is(err, services.ErrTwoFactorEnrolmentToken):
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, err.Error(), nil)
+	case errors.Is(err, services.ErrTwoFactorNotEnrolled), errors.Is(err, services.ErrTwoFactorEnrolmentMissing):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+		return utils.ErrorResponse(c, fiber.StatusConflict, err.Error(), nil)
+	case errors.Is(err, services.ErrTwoFactorRequiredByRole):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error(), nil)
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "two-factor operation failed", err.Error())
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"TugasAkhir/config"
+	"TugasAkhir/dto/letters"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+	"TugasAkhir/utils/storage"
+	"bytes"
+	"errors"
+	"html/template"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type VerificationHandler struct {
+	db                  *gorm.DB
+	permService         *services.PermissionService
+	verificationService *services.VerificationService
+	page                *template.Template
+}
+
+type verificationPageData struct {
+	Found bool
+	Data  letters.VerificationResponse
+}
+
+type RevokeVerificationRequest struct {
+	Reason string `json:"reason"`
+}
+
+func NewVerificationHandler(db *gorm.DB) *VerificationHandler {
+	return &VerificationHandler{
+		db:                  db,
+		permService:         services.NewPermissionService(db),
+		verificationService: services.NewVerificationService(db),
+		page:                template.Must(template.ParseFiles("templates/public/verify.html")),
+	}
+}
+
+// VerifyLetter - GET /verify/:token (Publik, tanpa login)
+// Mengembalikan HTML untuk browser (hasil scan QR) atau JSON jika diminta via Accept header.
+func (h *VerificationHandler) VerifyLetter(c *fiber.Ctx) error {
+	wantsJSON := c.Accepts(fiber.MIMETextHTML, fiber.MIMEApplicationJSON) == fiber.MIMEApplicationJSON
+
+	v, err := h.verificationService.FindByToken(c.Params("token"))
+	if err != nil {
+		if !errors.Is(err, services.ErrVerificationNotFound) {
+			log.Printf("verify letter: %v", err)
+			return utils.InternalServerError(c, "Gagal memeriksa verifikasi surat")
+		}
+		if wantsJSON {
+			return utils.NotFound(c, "Kode verifikasi tidak dikenal")
+		}
+		return h.renderPage(c, fiber.StatusNotFound, verificationPageData{})
+	}
+
+	cfg := config.LoadVerificationConfig()
+	resp := letters.NewVerificationResponse(v, cfg.ExposeBody)
+	if cfg.ExposeBody && resp.Status == letters.VerificationValid && v.Letter.FilePath != "" {
+		if url, err := storage.GetPresignedURL(v.Letter.FilePath); err == nil {
+			resp.FileURL = url
+		}
+	}
+
+	if wantsJSON {
+		return utils.OK(c, "Data verifikasi surat", resp)
+	}
+	return h.renderPage(c, fiber.StatusOK, verificationPageData{Found: true, Data: resp})
+}
+
+func (h *VerificationHandler) renderPage(c *fiber.Ctx, status int, data verificationPageData) error {
+	var buf bytes.Buffer
+	if err := h.page.Execute(&buf, data); err != nil {
+		log.Printf("Template error: %v", err)
+		return c.Status(500).SendString("Template error")
+	}
+
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Status(status).Send(buf.Bytes())
+}
+
+// RevokeLetterVerification - POST /api/letters/keluar/:id/verification/revoke
+// Mencabut QR verifikasi surat (Direktur / Admin). Halaman verifikasi akan
+// menampilkan status dicabut beserta alasannya.
+func (h *VerificationHandler) RevokeLetterVerification(c *fiber.Ctx) error {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		return utils.Unauthorized(c, "Unauthorized")
+	}
+
+	letterID, _ := c.ParamsInt("id")
+	letter, err := h.permService.GetLetterByID(uint(letterID))
+	if err != nil {
+		return utils.NotFound(c, "Surat tidak ditemukan")
+	}
+	if !letter.IsSuratKeluar() {
+		return utils.BadRequest(c, "Hanya surat keluar yang memiliki verifikasi", nil)
+	}
+
+	var req RevokeVerificationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.BadRequest(c, "Format data tidak valid", nil)
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return utils.UnprocessableEntity(c, "Alasan pencabutan wajib diisi", fiber.Map{"reason": "required"})
+	}
+
+	// Status surat tidak berubah; riwayat hanya mencatat pencabutannya
+	var v *models.LetterVerification
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if v, err = services.NewVerificationService(tx).Revoke(letter.ID, user.ID, req.Reason); err != nil {
+			return err
+		}
+		return services.RecordLetterAction(tx, letter, letter.Status, letterAction(c, models.LetterActionVerificationRevoked, req.Reason))
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrVerificationNotFound):
+			return utils.NotFound(c, "Surat belum memiliki QR verifikasi")
+		case errors.Is(err, services.ErrVerificationRevoked):
+			return utils.Conflict(c, "Verifikasi surat sudah dicabut sebelumnya")
+		}
+		return utils.InternalServerError(c, "Gagal mencabut verifikasi surat")
+	}
+
+	return utils.OK(c, "Verifikasi surat berhasil dicabut", v)
+}
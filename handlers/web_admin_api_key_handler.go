@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"TugasAkhir/config"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyFormData - isian form pembuatan API key
+type APIKeyFormData struct {
+	Name      string
+	OwnerID   string
+	Scopes    map[string]bool
+	ExpiresAt string // yyyy-mm-dd
+}
+
+// =====================
+// API KEY HANDLERS
+// =====================
+
+// ShowAPIKeys - GET /admin/api-keys
+func (h *WebAdminHandler) ShowAPIKeys(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	return h.renderAPIKeys(c, PageData{
+		User:       user,
+		APIKeyForm: APIKeyFormData{Scopes: map[string]bool{}},
+		Success:    c.Query("success"),
+		Error:      c.Query("error"),
+	})
+}
+
+// HandleCreateAPIKey - POST /admin/api-keys
+// Key mentah ditampilkan sekali di halaman hasil, tidak bisa dilihat lagi
+func (h *WebAdminHandler) HandleCreateAPIKey(c *fiber.Ctx) error {
+	admin, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	form := APIKeyFormData{
+		Name:      strings.TrimSpace(c.FormValue("name")),
+		OwnerID:   strings.TrimSpace(c.FormValue("owner_id")),
+		Scopes:    map[string]bool{},
+		ExpiresAt: strings.TrimSpace(c.FormValue("expires_at")),
+	}
+	var scopes []string
+	for _, code := range c.Context().PostArgs().PeekMulti("scopes") {
+		form.Scopes[string(code)] = true
+		scopes = append(scopes, string(code))
+	}
+	data := PageData{User: admin, APIKeyForm: form}
+
+	errs := map[string]string{}
+	if form.Name == "" || len(form.Name) > 100 {
+		errs["name"] = "Nama wajib diisi (maks. 100 karakter)"
+	}
+	ownerID, err := strconv.ParseUint(form.OwnerID, 10, 64)
+	if err != nil || ownerID == 0 {
+		errs["owner_id"] = "Pilih pemilik key"
+	}
+	var expiresAt *time.Time
+	if form.ExpiresAt != "" {
+		// Berlaku sampai akhir hari yang dipilih
+		day, err := time.ParseInLocation("2006-01-02", form.ExpiresAt, time.Local)
+		if end := day.AddDate(0, 0, 1); err != nil || !end.After(time.Now()) {
+			errs["expires_at"] = "Tanggal kedaluwarsa harus hari ini atau setelahnya"
+		} else {
+			expiresAt = &end
+		}
+	}
+	if len(scopes) == 0 {
+		errs["scopes"] = "Pilih minimal satu scope"
+	}
+	if len(errs) > 0 {
+		data.Errors = errs
+		return h.renderAPIKeys(c, data)
+	}
+
+	key, raw, err := services.NewAPIKeyService(config.DB).Create(services.APIKeyInput{
+		Name:      form.Name,
+		OwnerID:   uint(ownerID),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}, &admin.ID)
+	if err != nil {
+		var scopeErr *services.APIKeyScopeError
+		switch {
+		case errors.As(err, &scopeErr):
+			data.Errors = map[string]string{"scopes": "Scope " + scopeErr.Scope + " tidak dimiliki role pemilik key"}
+		case errors.Is(err, services.ErrAPIKeyNoOwner):
+			data.Errors = map[string]string{"owner_id": "User tidak ditemukan"}
+		default:
+			data.Error = "Gagal membuat API key"
+		}
+		return h.renderAPIKeys(c, data)
+	}
+	recordAPIKeyEvent(c, models.AuthEventAPIKeyCreated, key, admin)
+
+	return h.renderAPIKeys(c, PageData{
+		User:       admin,
+		APIKeyForm: APIKeyFormData{Scopes: map[string]bool{}},
+		NewAPIKey:  raw,
+		Success:    "API key " + key.Name + " dibuat. Salin key sekarang, key tidak bisa ditampilkan lagi.",
+	})
+}
+
+// HandleRevokeAPIKey - POST /admin/api-keys/:id/revoke
+func (h *WebAdminHandler) HandleRevokeAPIKey(c *fiber.Ctx) error {
+	admin, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return c.Redirect("/admin/api-keys?error=API key tidak ditemukan")
+	}
+	key, err := services.NewAPIKeyService(config.DB).Revoke(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			return c.Redirect("/admin/api-keys?error=API key tidak ditemukan")
+		}
+		return c.Redirect("/admin/api-keys?error=Gagal mencabut API key")
+	}
+	recordAPIKeyEvent(c, models.AuthEventAPIKeyRevoked, key, admin)
+
+	return c.Redirect("/admin/api-keys?success=API key dicabut")
+}
+
+// renderAPIKeys melengkapi data halaman (daftar key, calon pemilik, katalog
+// scope) lalu merender
+func (h *WebAdminHandler) renderAPIKeys(c *fiber.Ctx, data PageData) error {
+	keys, err := services.NewAPIKeyService(config.DB).List()
+	if err != nil {
+		return c.Redirect("/admin?error=Gagal memuat API key")
+	}
+	data.Title = "API Key"
+	data.Active = "api_keys"
+	data.APIKeys = keys
+	config.DB.Where("status = ?", models.UserStatusActive).Order("username ASC").Find(&data.Users)
+	data.Permissions, _ = services.NewRBACService(config.DB).ListPermissions()
+	return h.render(c, "api_keys", data)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"TugasAkhir/config"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ShowAuthEvents - GET /admin/auth-events
+// Log audit autentikasi beserta daftar akun / IP yang sedang dikunci
+func (h *WebAdminHandler) ShowAuthEvents(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit := 25
+
+	filter := services.AuthEventFilter{
+		Event: models.AuthEventType(strings.TrimSpace(c.Query("event"))),
+		Query: strings.TrimSpace(c.Query("q")),
+		Page:  page,
+		Limit: limit,
+	}
+
+	events, total, err := services.NewAuthEventService(config.DB).List(filter)
+	if err != nil {
+		return c.Redirect("/admin?error=Gagal memuat log autentikasi")
+	}
+	locked, err := services.NewLoginThrottleService(config.DB).Locked()
+	if err != nil {
+		return c.Redirect("/admin?error=Gagal memuat daftar akun terkunci")
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	var pages []int
+	for i := 1; i <= totalPages; i++ {
+		if i <= 5 || i > totalPages-2 || (i >= page-1 && i <= page+1) {
+			pages = append(pages, i)
+		}
+	}
+
+	return h.render(c, "auth_events", PageData{
+		Title:        "Log Autentikasi",
+		Active:       "auth_events",
+		User:         user,
+		Query:        filter.Query,
+		EventFilter:  string(filter.Event),
+		Page:         page,
+		TotalPages:   totalPages,
+		Pages:        pages,
+		AuthEvents:   events,
+		EventTypes:   models.AuthEventTypes,
+		LockedLogins: locked,
+		Success:      c.Query("success"),
+		Error:        c.Query("error"),
+	})
+}
+
+// HandleUnlockLogin - POST /admin/auth-events/unlock
+func (h *WebAdminHandler) HandleUnlockLogin(c *fiber.Ctx) error {
+	admin, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	scope := models.LoginThrottleScope(c.FormValue("scope"))
+	key := strings.TrimSpace(c.FormValue("key"))
+	if (scope != models.LoginThrottleAccount && scope != models.LoginThrottleIP) || key == "" {
+		return c.Redirect("/admin/auth-events?error=Data penguncian tidak valid")
+	}
+
+	if err := services.NewLoginThrottleService(config.DB).Unlock(scope, key); err != nil {
+		if errors.Is(err, services.ErrLoginThrottleMissing) {
+			return c.Redirect("/admin/auth-events?error=Penguncian sudah berakhir")
+		}
+		return c.Redirect("/admin/auth-events?error=Gagal membuka penguncian")
+	}
+
+	// Dicatat atas nama akun yang dibuka; admin yang membuka ada di detail
+	detail := "unlocked by " + admin.Email
+	if scope == models.LoginThrottleIP {
+		detail = "ip " + key + " " + detail
+		recordAuthEvent(c, models.AuthEventAccountUnlocked, nil, "", detail)
+	} else {
+		var target models.User
+		if err := config.DB.Where("email = ?", key).First(&target).Error; err == nil {
+			recordAuthEvent(c, models.AuthEventAccountUnlocked, &target, "", detail)
+		} else {
+			recordAuthEvent(c, models.AuthEventAccountUnlocked, nil, key, detail)
+		}
+	}
+
+	return c.Redirect("/admin/auth-events?success=Penguncian login dibuka")
+}
@@ -2,16 +2,21 @@ package handlers
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
+	"net/url"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"TugasAkhir/config"
+	userdto "TugasAkhir/dto/users"
 	"TugasAkhir/middleware"
 	"TugasAkhir/models"
+	"TugasAkhir/services"
 	"TugasAkhir/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -41,6 +46,36 @@ type PageData struct {
 	TotalPages int
 	Pages      []int
 	Stats      DashboardStats
+	Units      []models.Unit
+	EditUnit   *models.Unit
+	UnitForm   UnitFormData
+	UnitReport []services.UnitReportRow
+
+	Roles          []models.RoleDefinition
+	EditRole       *models.RoleDefinition
+	RoleForm       RoleFormData
+	Permissions    []models.Permission
+	RoleUserCounts map[models.Role]int64
+
+	Assigned []userdto.RoleAssignmentResponse // Penugasan role tambahan user yang diedit
+
+	StatusFilter string
+	Deactivate   DeactivateFormData
+
+	TwoFactor *TwoFactorPageData
+
+	SSOEnabled bool // Tombol "Login dengan SSO" di halaman login
+
+	PasswordHint string // Ringkasan kebijakan password di form ganti password
+
+	APIKeys    []models.APIKey
+	APIKeyForm APIKeyFormData
+	NewAPIKey  string // Key mentah, ditampilkan sekali setelah dibuat
+
+	AuthEvents   []models.AuthEvent
+	EventTypes   []models.AuthEventType
+	EventFilter  string
+	LockedLogins []models.LoginThrottle
 }
 
 type UserFormData struct {
@@ -51,6 +86,7 @@ type UserFormData struct {
 	Role      string
 	Jabatan   string
 	Atribut   string
+	UnitID    string
 }
 
 type DashboardStats struct {
@@ -72,6 +108,13 @@ func NewWebAdminHandler() *WebAdminHandler {
 		"eq": func(a, b interface{}) bool {
 			return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
 		},
+		// deref untuk membandingkan ID opsional (*uint) di template, nil = 0
+		"deref": func(id *uint) uint {
+			if id == nil {
+				return 0
+			}
+			return *id
+		},
 	}
 
 	templates := make(map[string]*template.Template)
@@ -79,12 +122,20 @@ func NewWebAdminHandler() *WebAdminHandler {
 
 	// Parse each page template with the base layout
 	pages := map[string]string{
-		"login":        "templates/admin/login.html",
-		"dashboard":    "templates/admin/dashboard.html",
-		"users_list":   "templates/admin/users/list.html",
-		"users_create": "templates/admin/users/create.html",
-		"users_edit":   "templates/admin/users/edit.html",
-		"settings":     "templates/admin/settings.html",
+		"login":            "templates/admin/login.html",
+		"dashboard":        "templates/admin/dashboard.html",
+		"users_list":       "templates/admin/users/list.html",
+		"users_create":     "templates/admin/users/create.html",
+		"users_edit":       "templates/admin/users/edit.html",
+		"users_deactivate": "templates/admin/users/deactivate.html",
+		"settings":         "templates/admin/settings.html",
+		"units_list":       "templates/admin/units/list.html",
+		"units_form":       "templates/admin/units/form.html",
+		"roles_list":       "templates/admin/roles/list.html",
+		"roles_form":       "templates/admin/roles/form.html",
+		"login_2fa":        "templates/admin/login_2fa.html",
+		"auth_events":      "templates/admin/auth_events.html",
+		"api_keys":         "templates/admin/api_keys.html",
 	}
 
 	for name, pageFile := range pages {
@@ -105,6 +156,14 @@ func (h *WebAdminHandler) render(c *fiber.Ctx, templateName string, data PageDat
 		return c.Status(500).SendString("Template not found: " + templateName)
 	}
 
+	// Semua render halaman login (termasuk pesan error) menampilkan tombol SSO
+	if templateName == "login" {
+		data.SSOEnabled = services.NewSSOService(config.DB).AdminSSOEnabled()
+	}
+	if templateName == "settings" || templateName == "users_edit" {
+		data.PasswordHint = passwordPolicyHint()
+	}
+
 	var buf bytes.Buffer
 	if err := t.ExecuteTemplate(&buf, "base", data); err != nil {
 		log.Printf("Template error: %v", err)
@@ -149,19 +208,45 @@ func (h *WebAdminHandler) HandleLogin(c *fiber.Ctx) error {
 		})
 	}
 
-	// Cari user
-	var user models.User
-	if err := config.DB.Where("email = ?", email).First(&user).Error; err != nil {
+	// Akun / IP yang terlalu sering gagal dikunci sementara
+	lockErr, err := loginLock(c, email)
+	if err != nil {
 		return h.render(c, "login", PageData{
 			Title:  "Login",
-			Error:  "Email atau password salah",
+			Error:  "Gagal memeriksa percobaan login",
 			Email:  email,
 			Active: "login",
 		})
 	}
+	if lockErr != nil {
+		recordAuthEvent(c, models.AuthEventLoginFailure, nil, email, "locked")
+		return h.renderLoginLocked(c, email, lockErr)
+	}
 
-	// Cek password
-	if !utils.CheckPassword(user.PasswordHash, password) {
+	// Password lokal atau direktori LDAP (lihat authenticatePassword)
+	user, reason, err := authenticatePassword(c, email, password)
+	if errors.Is(err, services.ErrPasswordExpired) && user.CanLogin() {
+		return h.passwordExpiredLogin(c, user, email)
+	}
+	if err != nil {
+		msg := "Gagal memeriksa kredensial"
+		if errors.Is(err, services.ErrPasswordExpired) {
+			msg = "Password Anda kedaluwarsa. Gunakan fitur lupa password untuk mengganti."
+		}
+		if isSSOError(err) {
+			msg = ssoErrorMessage(err)
+		}
+		return h.render(c, "login", PageData{
+			Title:  "Login",
+			Error:  msg,
+			Email:  email,
+			Active: "login",
+		})
+	}
+	if reason != "" {
+		if lockErr := loginFailed(c, email, user, reason); lockErr != nil {
+			return h.renderLoginLocked(c, email, lockErr)
+		}
 		return h.render(c, "login", PageData{
 			Title:  "Login",
 			Error:  "Email atau password salah",
@@ -170,11 +255,30 @@ func (h *WebAdminHandler) HandleLogin(c *fiber.Ctx) error {
 		})
 	}
 
-	// Cek role admin
-	if user.Role != models.RoleAdmin {
+	return h.finishLogin(c, *user, email)
+}
+
+// finishLogin - langkah setelah kredensial (password, LDAP, atau OIDC) valid:
+// cek status & izin panel admin, lalu 2FA atau langsung buat session
+func (h *WebAdminHandler) finishLogin(c *fiber.Ctx, user models.User, email string) error {
+	if !user.CanLogin() {
+		msg := "Akun Anda belum aktif."
+		if user.Status == models.UserStatusInactive {
+			msg = "Akun Anda telah dinonaktifkan."
+		}
+		return h.render(c, "login", PageData{
+			Title:  "Login",
+			Error:  msg,
+			Email:  email,
+			Active: "login",
+		})
+	}
+
+	// Cek izin akses panel admin
+	if !services.NewRBACService(config.DB).HasPermission(user.Role, models.PermAdminPanel) {
 		return h.render(c, "login", PageData{
 			Title:  "Login",
-			Error:  "Akses ditolak. Hanya admin yang dapat login.",
+			Error:  "Akses ditolak. Role Anda tidak memiliki akses panel admin.",
 			Email:  email,
 			Active: "login",
 		})
@@ -191,6 +295,25 @@ func (h *WebAdminHandler) HandleLogin(c *fiber.Ctx) error {
 		})
 	}
 
+	// Akun dengan 2FA: session admin baru dibuat setelah kode diverifikasi
+	if enabled, required, err := twoFactorState(&user); err != nil || enabled || required {
+		if err == nil {
+			sess.Set(middleware.SessionTwoFactorUserKey, user.ID)
+			sess.Set(middleware.SessionTwoFactorAtKey, time.Now().Unix())
+			sess.Delete(middleware.SessionTwoFactorPasswordExpiredKey)
+			err = sess.Save()
+		}
+		if err != nil {
+			return h.render(c, "login", PageData{
+				Title:  "Login",
+				Error:  "Gagal memeriksa 2FA",
+				Email:  email,
+				Active: "login",
+			})
+		}
+		return c.Redirect("/admin/login/2fa")
+	}
+
 	sess.Set(middleware.SessionAdminIDKey, user.ID)
 	sess.Set(middleware.SessionAdminRoleKey, string(user.Role))
 	if err := sess.Save(); err != nil {
@@ -201,12 +324,64 @@ func (h *WebAdminHandler) HandleLogin(c *fiber.Ctx) error {
 			Active: "login",
 		})
 	}
+	loginSucceeded(c, &user, authEventWebDetail)
 
 	return c.Redirect("/admin")
 }
 
+// passwordExpiredLogin - password benar tapi kedaluwarsa. Akun dengan 2FA
+// memasukkan kode dulu lalu diarahkan ke form reset (HandleTwoFactorLogin);
+// akun tanpa 2FA menerima link reset lewat email.
+func (h *WebAdminHandler) passwordExpiredLogin(c *fiber.Ctx, user *models.User, email string) error {
+	renderError := func(msg string) error {
+		return h.render(c, "login", PageData{Title: "Login", Error: msg, Email: email, Active: "login"})
+	}
+
+	enabled, err := services.NewTwoFactorService(config.DB).Enabled(user.ID)
+	if err != nil {
+		return renderError("Gagal memeriksa 2FA")
+	}
+	if enabled {
+		sess, err := middleware.AdminSessionStore.Get(c)
+		if err == nil {
+			sess.Set(middleware.SessionTwoFactorUserKey, user.ID)
+			sess.Set(middleware.SessionTwoFactorAtKey, time.Now().Unix())
+			sess.Set(middleware.SessionTwoFactorPasswordExpiredKey, true)
+			err = sess.Save()
+		}
+		if err != nil {
+			return renderError("Gagal memeriksa 2FA")
+		}
+		return c.Redirect("/admin/login/2fa")
+	}
+
+	resetLoginThrottle(user)
+	if err := sendPasswordResetLink(c, user, "password expired"); err != nil {
+		log.Printf("[auth] gagal mengirim link reset user %d: %v", user.ID, err)
+		return renderError("Password Anda kedaluwarsa dan link reset gagal dikirim. Gunakan fitur lupa password.")
+	}
+	return renderError("Password Anda kedaluwarsa. Link untuk mengganti password telah dikirim ke email Anda.")
+}
+
+// renderLoginLocked - halaman login dengan status 429 saat akun / IP dikunci
+func (h *WebAdminHandler) renderLoginLocked(c *fiber.Ctx, email string, lockErr *services.LoginLockError) error {
+	seconds := retryAfterSeconds(lockErr)
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	c.Status(fiber.StatusTooManyRequests)
+	return h.render(c, "login", PageData{
+		Title:  "Login",
+		Error:  fmt.Sprintf("Terlalu banyak percobaan login gagal. Coba lagi dalam %d menit.", (seconds+59)/60),
+		Email:  email,
+		Active: "login",
+	})
+}
+
 // HandleLogout - POST /admin/logout
 func (h *WebAdminHandler) HandleLogout(c *fiber.Ctx) error {
+	if user, err := middleware.GetAdminFromSession(c); err == nil {
+		recordAuthEvent(c, models.AuthEventLogout, user, "", authEventWebDetail)
+	}
+
 	sess, err := middleware.AdminSessionStore.Get(c)
 	if err == nil {
 		sess.Destroy()
@@ -237,6 +412,7 @@ func (h *WebAdminHandler) ShowDashboard(c *fiber.Ctx) error {
 		Active: "dashboard",
 		User:   user,
 		Stats:  stats,
+		Error:  c.Query("error"),
 	})
 }
 
@@ -264,6 +440,7 @@ func (h *WebAdminHandler) ShowUserList(c *fiber.Ctx) error {
 	offset := (page - 1) * limit
 
 	roleFilter := strings.TrimSpace(c.Query("role"))
+	statusFilter := strings.TrimSpace(c.Query("status"))
 	query := strings.TrimSpace(c.Query("q"))
 
 	// Build query
@@ -271,6 +448,9 @@ func (h *WebAdminHandler) ShowUserList(c *fiber.Ctx) error {
 	if roleFilter != "" {
 		tx = tx.Where("role = ?", roleFilter)
 	}
+	if statusFilter != "" {
+		tx = tx.Where("status = ?", statusFilter)
+	}
 	if query != "" {
 		like := "%" + query + "%"
 		tx = tx.Where(
@@ -287,7 +467,7 @@ func (h *WebAdminHandler) ShowUserList(c *fiber.Ctx) error {
 
 	// Get users
 	var users []models.User
-	tx.Order("id DESC").Limit(limit).Offset(offset).Find(&users)
+	tx.Preload("Unit").Order("id DESC").Limit(limit).Offset(offset).Find(&users)
 
 	// Calculate pages
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
@@ -299,17 +479,19 @@ func (h *WebAdminHandler) ShowUserList(c *fiber.Ctx) error {
 	}
 
 	return h.render(c, "users_list", PageData{
-		Title:      "Manajemen User",
-		Active:     "users",
-		User:       user,
-		Users:      users,
-		Query:      query,
-		RoleFilter: roleFilter,
-		Page:       page,
-		TotalPages: totalPages,
-		Pages:      pages,
-		Success:    success,
-		Error:      errorMsg,
+		Title:        "Manajemen User",
+		Active:       "users",
+		User:         user,
+		Users:        users,
+		Query:        query,
+		RoleFilter:   roleFilter,
+		StatusFilter: statusFilter,
+		Page:         page,
+		TotalPages:   totalPages,
+		Pages:        pages,
+		Roles:        roleOptions(),
+		Success:      success,
+		Error:        errorMsg,
 	})
 }
 
@@ -324,6 +506,8 @@ func (h *WebAdminHandler) ShowCreateUserForm(c *fiber.Ctx) error {
 		Title:  "Tambah User",
 		Active: "users",
 		User:   user,
+		Units:  activeUnits(),
+		Roles:  roleOptions(),
 		Errors: make(map[string]string),
 	})
 }
@@ -343,9 +527,8 @@ func (h *WebAdminHandler) HandleCreateUser(c *fiber.Ctx) error {
 		Role:      c.FormValue("role"),
 		Jabatan:   strings.TrimSpace(c.FormValue("jabatan")),
 		Atribut:   strings.TrimSpace(c.FormValue("atribut")),
+		UnitID:    c.FormValue("unit_id"),
 	}
-	password := c.FormValue("password")
-	confirmPassword := c.FormValue("confirm_password")
 
 	errors := make(map[string]string)
 
@@ -361,13 +544,11 @@ func (h *WebAdminHandler) HandleCreateUser(c *fiber.Ctx) error {
 	}
 	if form.Role == "" {
 		errors["role"] = "Role harus dipilih"
+	} else if !services.NewRBACService(config.DB).RoleExists(models.Role(form.Role)) {
+		errors["role"] = "Role tidak ditemukan"
 	}
-	if password == "" {
-		errors["password"] = "Password harus diisi"
-	} else if len(password) < 6 {
-		errors["password"] = "Password minimal 6 karakter"
-	} else if password != confirmPassword {
-		errors["password"] = "Password tidak sama"
+	if msg := validateUserUnit(form.UnitID); msg != "" {
+		errors["unit_id"] = msg
 	}
 
 	if len(errors) > 0 {
@@ -376,36 +557,34 @@ func (h *WebAdminHandler) HandleCreateUser(c *fiber.Ctx) error {
 			Active: "users",
 			User:   user,
 			Form:   form,
+			Units:  activeUnits(),
+			Roles:  roleOptions(),
 			Errors: errors,
 		})
 	}
 
-	// Hash password
-	passwordHash, err := utils.HashPassword(password)
-	if err != nil {
-		errors["password"] = "Gagal memproses password"
-		return h.render(c, "users_create", PageData{
-			Title:  "Tambah User",
-			Active: "users",
-			User:   user,
-			Form:   form,
-			Errors: errors,
-		})
-	}
-
-	// Create user
+	// Create user (pending) beserta undangannya; password diatur user sendiri
 	newUser := models.User{
-		Username:     form.Username,
-		Email:        form.Email,
-		FirstName:    form.FirstName,
-		LastName:     form.LastName,
-		Role:         models.Role(form.Role),
-		Jabatan:      form.Jabatan,
-		Atribut:      form.Atribut,
-		PasswordHash: passwordHash,
-	}
-
-	if err := config.DB.Create(&newUser).Error; err != nil {
+		Username:  form.Username,
+		Email:     form.Email,
+		FirstName: form.FirstName,
+		LastName:  form.LastName,
+		Role:      models.Role(form.Role),
+		Jabatan:   form.Jabatan,
+		Atribut:   form.Atribut,
+		UnitID:    parseOptionalID(form.UnitID),
+		Status:    models.UserStatusPending,
+	}
+
+	var invitation *services.Invitation
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		var err error
+		invitation, err = services.NewInvitationService(tx).Issue(&newUser, &user.ID)
+		return err
+	}); err != nil {
 		if utils.IsDuplicateError(err) {
 			errors["username"] = "Username atau email sudah digunakan"
 			return h.render(c, "users_create", PageData{
@@ -413,6 +592,8 @@ func (h *WebAdminHandler) HandleCreateUser(c *fiber.Ctx) error {
 				Active: "users",
 				User:   user,
 				Form:   form,
+				Units:  activeUnits(),
+				Roles:  roleOptions(),
 				Errors: errors,
 			})
 		}
@@ -421,12 +602,17 @@ func (h *WebAdminHandler) HandleCreateUser(c *fiber.Ctx) error {
 			Active: "users",
 			User:   user,
 			Form:   form,
+			Units:  activeUnits(),
+			Roles:  roleOptions(),
 			Error:  "Gagal membuat user: " + err.Error(),
 			Errors: make(map[string]string),
 		})
 	}
 
-	return c.Redirect("/admin/users?success=User berhasil dibuat")
+	if !deliverInvitation(&newUser, invitation).Sent {
+		return c.Redirect(fmt.Sprintf("/admin/users/%d/edit?error=User dibuat, tetapi email undangan gagal dikirim. Periksa konfigurasi email lalu kirim ulang undangan.", newUser.ID))
+	}
+	return c.Redirect("/admin/users?success=User berhasil dibuat dan undangan telah dikirim")
 }
 
 // ShowEditUserForm - GET /admin/users/:id/edit
@@ -445,13 +631,25 @@ func (h *WebAdminHandler) ShowEditUserForm(c *fiber.Ctx) error {
 		return c.Redirect("/admin/users?error=Gagal mengambil data user")
 	}
 
-	return h.render(c, "users_edit", PageData{
-		Title:    "Edit User",
-		Active:   "users",
-		User:     user,
-		EditUser: &editUser,
-		Errors:   make(map[string]string),
-	})
+	return h.render(c, "users_edit", editUserPageData(c, user, &editUser))
+}
+
+// editUserPageData - data halaman edit user, dipakai juga oleh aksi yang
+// menampilkan hasil sekali lihat (token enrolment 2FA)
+func editUserPageData(c *fiber.Ctx, admin, editUser *models.User) PageData {
+	return PageData{
+		Title:     "Edit User",
+		Active:    "users",
+		User:      admin,
+		EditUser:  editUser,
+		Units:     activeUnits(),
+		Roles:     roleOptions(),
+		Assigned:  userRoleAssignments(editUser.ID),
+		TwoFactor: settingsTwoFactorData(editUser),
+		Success:   c.Query("success"),
+		Error:     c.Query("error"),
+		Errors:    make(map[string]string),
+	}
 }
 
 // HandleUpdateUser - POST /admin/users/:id
@@ -467,6 +665,9 @@ func (h *WebAdminHandler) HandleUpdateUser(c *fiber.Ctx) error {
 		return c.Redirect("/admin/users?error=User tidak ditemukan")
 	}
 
+	previousRole := editUser.Role
+	previousUnitID := editUser.UnitID
+
 	// Update fields
 	editUser.Username = strings.TrimSpace(c.FormValue("username"))
 	editUser.Email = strings.TrimSpace(c.FormValue("email"))
@@ -475,6 +676,8 @@ func (h *WebAdminHandler) HandleUpdateUser(c *fiber.Ctx) error {
 	editUser.Role = models.Role(c.FormValue("role"))
 	editUser.Jabatan = strings.TrimSpace(c.FormValue("jabatan"))
 	editUser.Atribut = strings.TrimSpace(c.FormValue("atribut"))
+	editUser.UnitID = parseOptionalID(c.FormValue("unit_id"))
+	editUser.Unit = nil
 
 	errors := make(map[string]string)
 
@@ -488,18 +691,32 @@ func (h *WebAdminHandler) HandleUpdateUser(c *fiber.Ctx) error {
 	if editUser.FirstName == "" {
 		errors["first_name"] = "Nama depan harus diisi"
 	}
+	if !services.NewRBACService(config.DB).RoleExists(editUser.Role) {
+		errors["role"] = "Role tidak ditemukan"
+	}
+	// Unit lama yang sudah nonaktif boleh tetap dipakai selama tidak diganti
+	if editUser.UnitID == nil || previousUnitID == nil || *editUser.UnitID != *previousUnitID {
+		if msg := validateUserUnit(c.FormValue("unit_id")); msg != "" {
+			errors["unit_id"] = msg
+		}
+	}
 
 	// Update password jika diisi
 	newPassword := c.FormValue("password")
+	passwordChanged := false
+	passwords := services.NewPasswordService(config.DB)
 	if newPassword != "" {
-		if len(newPassword) < 6 {
-			errors["password"] = "Password minimal 6 karakter"
+		if err := passwords.Validate(&editUser, newPassword); err != nil {
+			errors["password"] = passwordPolicyMessage(err)
 		} else {
 			hash, err := utils.HashPassword(newPassword)
 			if err != nil {
 				errors["password"] = "Gagal memproses password"
 			} else {
+				now := time.Now()
 				editUser.PasswordHash = hash
+				editUser.PasswordChangedAt = &now
+				passwordChanged = true
 			}
 		}
 	}
@@ -510,6 +727,9 @@ func (h *WebAdminHandler) HandleUpdateUser(c *fiber.Ctx) error {
 			Active:   "users",
 			User:     user,
 			EditUser: &editUser,
+			Units:    activeUnits(),
+			Roles:    roleOptions(),
+			Assigned: userRoleAssignments(editUser.ID),
 			Errors:   errors,
 		})
 	}
@@ -522,6 +742,9 @@ func (h *WebAdminHandler) HandleUpdateUser(c *fiber.Ctx) error {
 				Active:   "users",
 				User:     user,
 				EditUser: &editUser,
+				Units:    activeUnits(),
+				Roles:    roleOptions(),
+				Assigned: userRoleAssignments(editUser.ID),
 				Errors:   errors,
 			})
 		}
@@ -530,38 +753,52 @@ func (h *WebAdminHandler) HandleUpdateUser(c *fiber.Ctx) error {
 			Active:   "users",
 			User:     user,
 			EditUser: &editUser,
+			Units:    activeUnits(),
+			Roles:    roleOptions(),
+			Assigned: userRoleAssignments(editUser.ID),
 			Error:    "Gagal update user: " + err.Error(),
 			Errors:   make(map[string]string),
 		})
 	}
 
+	if passwordChanged {
+		if err := passwords.Record(editUser.ID, editUser.PasswordHash); err != nil {
+			log.Printf("[password] failed to record history for user %d: %v", editUser.ID, err)
+		}
+		recordAuthEvent(c, models.AuthEventPasswordChanged, &editUser, "", "set by admin")
+	}
+	// Password atau role diganti admin: semua perangkat user harus login ulang
+	if passwordChanged || editUser.Role != previousRole {
+		revokeUserSessions(editUser.ID)
+	}
+
 	return c.Redirect("/admin/users?success=User berhasil diupdate")
 }
 
-// HandleDeleteUser - POST /admin/users/:id/delete
-func (h *WebAdminHandler) HandleDeleteUser(c *fiber.Ctx) error {
-	_, err := middleware.GetAdminFromSession(c)
+// HandleResendInvitation - POST /admin/users/:id/invitation
+func (h *WebAdminHandler) HandleResendInvitation(c *fiber.Ctx) error {
+	admin, err := middleware.GetAdminFromSession(c)
 	if err != nil {
 		return c.Redirect("/admin/login")
 	}
 
-	id := c.Params("id")
-
-	// Cegah hapus diri sendiri
-	adminID := c.Locals(middleware.SessionAdminIDKey)
-	if fmt.Sprintf("%v", adminID) == id {
-		return c.Redirect("/admin/users?error=Tidak dapat menghapus akun sendiri")
+	user, err := findAdminUser(c)
+	if err != nil {
+		return c.Redirect("/admin/users?error=User tidak ditemukan")
 	}
+	back := fmt.Sprintf("/admin/users/%d/edit", user.ID)
 
-	result := config.DB.Delete(&models.User{}, "id = ?", id)
-	if result.Error != nil {
-		return c.Redirect("/admin/users?error=Gagal menghapus user")
+	status, err := sendInvitation(user, &admin.ID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvitationNotPending) {
+			return c.Redirect(back + "?error=User sudah mengaktifkan akunnya")
+		}
+		return c.Redirect(back + "?error=Gagal membuat undangan")
 	}
-	if result.RowsAffected == 0 {
-		return c.Redirect("/admin/users?error=User tidak ditemukan")
+	if !status.Sent {
+		return c.Redirect(back + "?error=Email undangan gagal dikirim. Periksa konfigurasi email.")
 	}
-
-	return c.Redirect("/admin/users?success=User berhasil dihapus")
+	return c.Redirect(back + "?success=Undangan baru telah dikirim")
 }
 
 // =====================
@@ -579,11 +816,12 @@ func (h *WebAdminHandler) ShowSettings(c *fiber.Ctx) error {
 	errorMsg := c.Query("error")
 
 	return h.render(c, "settings", PageData{
-		Title:   "Settings",
-		Active:  "settings",
-		User:    user,
-		Success: success,
-		Error:   errorMsg,
+		Title:     "Settings",
+		Active:    "settings",
+		User:      user,
+		Success:   success,
+		Error:     errorMsg,
+		TwoFactor: settingsTwoFactorData(user),
 	})
 }
 
@@ -621,24 +859,45 @@ func (h *WebAdminHandler) HandleChangePassword(c *fiber.Ctx) error {
 		return c.Redirect("/admin/settings?error=Password lama salah")
 	}
 
-	if len(newPassword) < 6 {
-		return c.Redirect("/admin/settings?error=Password baru minimal 6 karakter")
-	}
-
 	if newPassword != confirmPassword {
 		return c.Redirect("/admin/settings?error=Konfirmasi password tidak sama")
 	}
 
-	// Hash password baru
-	hash, err := utils.HashPassword(newPassword)
-	if err != nil {
-		return c.Redirect("/admin/settings?error=Gagal memproses password")
-	}
-
-	user.PasswordHash = hash
-	if err := config.DB.Save(user).Error; err != nil {
-		return c.Redirect("/admin/settings?error=Gagal update password")
+	if err := services.NewPasswordService(config.DB).Set(user, newPassword); err != nil {
+		return c.Redirect("/admin/settings?error=" + url.QueryEscape(passwordPolicyMessage(err)))
 	}
+	recordAuthEvent(c, models.AuthEventPasswordChanged, user, "", authEventWebDetail)
+	revokeUserSessions(user.ID)
 
 	return c.Redirect("/admin/settings?success=Password berhasil diubah")
 }
+
+// activeUnits - pilihan unit di form user
+func activeUnits() []models.Unit {
+	var units []models.Unit
+	config.DB.Where("is_active = ?", true).Order("kode ASC").Find(&units)
+	return units
+}
+
+// validateUserUnit memeriksa unit yang dipilih di form user: kosong berarti
+// tanpa unit, selain itu harus unit yang ada dan masih aktif. Mengembalikan
+// pesan error field (kosong jika valid).
+func validateUserUnit(raw string) string {
+	if strings.TrimSpace(raw) == "" {
+		return ""
+	}
+	id := parseOptionalID(raw)
+	if id == nil {
+		return "Unit tidak ditemukan"
+	}
+	_, err := services.NewUnitService(config.DB).GetActive(*id)
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, services.ErrUnitInactive):
+		return "Unit sudah nonaktif"
+	case errors.Is(err, services.ErrUnitNotFound):
+		return "Unit tidak ditemukan"
+	}
+	return "Gagal memeriksa unit"
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"TugasAkhir/config"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RoleFormData struct {
+	Name             string
+	DisplayName      string
+	Description      string
+	Permissions      map[string]bool
+	RequireTwoFactor bool
+}
+
+// =====================
+// ROLE & PERMISSION HANDLERS
+// =====================
+
+// ShowRoleList - GET /admin/roles
+func (h *WebAdminHandler) ShowRoleList(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	roles, err := services.NewRBACService(config.DB).ListRoles()
+	if err != nil {
+		return c.Redirect("/admin?error=Gagal mengambil data role")
+	}
+
+	var counts []struct {
+		Role  models.Role
+		Total int64
+	}
+	config.DB.Model(&models.User{}).Select("role, COUNT(*) AS total").Group("role").Scan(&counts)
+	userCounts := make(map[models.Role]int64, len(counts))
+	for _, row := range counts {
+		userCounts[row.Role] = row.Total
+	}
+
+	return h.render(c, "roles_list", PageData{
+		Title:          "Role & Permission",
+		Active:         "roles",
+		User:           user,
+		Roles:          roles,
+		RoleUserCounts: userCounts,
+		Success:        c.Query("success"),
+		Error:          c.Query("error"),
+	})
+}
+
+// ShowCreateRoleForm - GET /admin/roles/create
+func (h *WebAdminHandler) ShowCreateRoleForm(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	return h.renderRoleForm(c, PageData{
+		Title:    "Tambah Role",
+		User:     user,
+		RoleForm: RoleFormData{Permissions: map[string]bool{}},
+		Errors:   make(map[string]string),
+	})
+}
+
+// HandleCreateRole - POST /admin/roles
+func (h *WebAdminHandler) HandleCreateRole(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	form := readRoleForm(c)
+	errors := validateRoleForm(form, true)
+	if len(errors) > 0 {
+		return h.renderRoleForm(c, PageData{Title: "Tambah Role", User: user, RoleForm: form, Errors: errors})
+	}
+
+	role := models.RoleDefinition{
+		Name:             models.Role(form.Name),
+		DisplayName:      form.DisplayName,
+		Description:      form.Description,
+		RequireTwoFactor: form.RequireTwoFactor,
+	}
+	if err := services.NewRBACService(config.DB).SaveRole(&role, form.permissionCodes(), ""); err != nil {
+		if utils.IsDuplicateError(err) {
+			errors["name"] = "Nama role sudah digunakan"
+			return h.renderRoleForm(c, PageData{Title: "Tambah Role", User: user, RoleForm: form, Errors: errors})
+		}
+		return h.renderRoleForm(c, PageData{
+			Title: "Tambah Role", User: user, RoleForm: form,
+			Error:  "Gagal membuat role: " + err.Error(),
+			Errors: make(map[string]string),
+		})
+	}
+
+	return c.Redirect("/admin/roles?success=Role berhasil dibuat")
+}
+
+// ShowEditRoleForm - GET /admin/roles/:id/edit
+func (h *WebAdminHandler) ShowEditRoleForm(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	editRole, err := loadWebRole(c)
+	if err != nil {
+		return c.Redirect("/admin/roles?error=Role tidak ditemukan")
+	}
+
+	return h.renderRoleForm(c, PageData{
+		Title:    "Edit Role",
+		User:     user,
+		EditRole: editRole,
+		RoleForm: roleFormFromModel(editRole),
+		Errors:   make(map[string]string),
+	})
+}
+
+// HandleUpdateRole - POST /admin/roles/:id
+func (h *WebAdminHandler) HandleUpdateRole(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	editRole, err := loadWebRole(c)
+	if err != nil {
+		return c.Redirect("/admin/roles?error=Role tidak ditemukan")
+	}
+
+	form := readRoleForm(c)
+	form.Name = string(editRole.Name) // Nama role tidak bisa diubah
+	errors := validateRoleForm(form, false)
+	if len(errors) > 0 {
+		return h.renderRoleForm(c, PageData{Title: "Edit Role", User: user, EditRole: editRole, RoleForm: form, Errors: errors})
+	}
+
+	editRole.DisplayName = form.DisplayName
+	editRole.Description = form.Description
+	editRole.RequireTwoFactor = form.RequireTwoFactor
+	actingRole, _ := c.Locals(middleware.SessionAdminRoleKey).(string)
+	if err := services.NewRBACService(config.DB).SaveRole(editRole, form.permissionCodes(), models.Role(actingRole)); err != nil {
+		msg := "Gagal update role: " + err.Error()
+		if err == services.ErrRoleLockout { // errors dibayangi variabel validasi di atas
+			msg = "Permission admin.panel dan admin.roles.manage tidak bisa dicabut dari role admin atau role yang sedang Anda pakai"
+		}
+		return h.renderRoleForm(c, PageData{
+			Title: "Edit Role", User: user, EditRole: editRole, RoleForm: form,
+			Error:  msg,
+			Errors: make(map[string]string),
+		})
+	}
+
+	return c.Redirect("/admin/roles?success=Role berhasil diupdate")
+}
+
+// HandleDeleteRole - POST /admin/roles/:id/delete
+func (h *WebAdminHandler) HandleDeleteRole(c *fiber.Ctx) error {
+	if _, err := middleware.GetAdminFromSession(c); err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return c.Redirect("/admin/roles?error=Role tidak ditemukan")
+	}
+
+	if err := services.NewRBACService(config.DB).DeleteRole(uint(id)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrRoleNotFound):
+			return c.Redirect("/admin/roles?error=Role tidak ditemukan")
+		case errors.Is(err, services.ErrRoleSystem):
+			return c.Redirect("/admin/roles?error=Role bawaan tidak bisa dihapus")
+		case errors.Is(err, services.ErrRoleInUse):
+			return c.Redirect("/admin/roles?error=Role masih dipakai user")
+		}
+		return c.Redirect("/admin/roles?error=Gagal menghapus role")
+	}
+
+	return c.Redirect("/admin/roles?success=Role berhasil dihapus")
+}
+
+// renderRoleForm mengisi katalog permission lalu merender form role
+func (h *WebAdminHandler) renderRoleForm(c *fiber.Ctx, data PageData) error {
+	data.Active = "roles"
+	data.Permissions, _ = services.NewRBACService(config.DB).ListPermissions()
+	return h.render(c, "roles_form", data)
+}
+
+func loadWebRole(c *fiber.Ctx) (*models.RoleDefinition, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return nil, services.ErrRoleNotFound
+	}
+	return services.NewRBACService(config.DB).GetRole(uint(id))
+}
+
+func readRoleForm(c *fiber.Ctx) RoleFormData {
+	form := RoleFormData{
+		Name:        strings.ToLower(strings.TrimSpace(c.FormValue("name"))),
+		DisplayName: strings.TrimSpace(c.FormValue("display_name")),
+		Description: strings.TrimSpace(c.FormValue("description")),
+		Permissions: map[string]bool{},
+
+		RequireTwoFactor: c.FormValue("require_two_factor") == "1",
+	}
+	// Checkbox permission dikirim dengan nama yang sama ("permissions")
+	for _, code := range c.Context().PostArgs().PeekMulti("permissions") {
+		form.Permissions[string(code)] = true
+	}
+	return form
+}
+
+func roleFormFromModel(role *models.RoleDefinition) RoleFormData {
+	form := RoleFormData{
+		Name:        string(role.Name),
+		DisplayName: role.DisplayName,
+		Description: role.Description,
+		Permissions: make(map[string]bool, len(role.Permissions)),
+
+		RequireTwoFactor: role.RequireTwoFactor,
+	}
+	for _, p := range role.Permissions {
+		form.Permissions[p.Code] = true
+	}
+	return form
+}
+
+func (f RoleFormData) permissionCodes() []string {
+	codes := make([]string, 0, len(f.Permissions))
+	for code := range f.Permissions {
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+func validateRoleForm(form RoleFormData, isNew bool) map[string]string {
+	errors := make(map[string]string)
+	if isNew && !models.Role(form.Name).IsValid() {
+		errors["name"] = "Nama role harus diisi: huruf kecil, angka dan '_', maksimal 50 karakter"
+	}
+	if form.DisplayName == "" {
+		errors["display_name"] = "Nama tampilan harus diisi"
+	}
+	return errors
+}
+
+// roleOptions - pilihan role di form user
+func roleOptions() []models.RoleDefinition {
+	roles, _ := services.NewRBACService(config.DB).ListRoles()
+	return roles
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"strings"
+
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =====================
+// LOGIN SSO (OIDC)
+// =====================
+
+// StartSSOLogin - GET /admin/login/sso
+// Mengarahkan admin ke halaman login identity provider
+func (h *WebAdminHandler) StartSSOLogin(c *fiber.Ctx) error {
+	sso := services.NewSSOService(config.DB)
+	if !sso.AdminSSOEnabled() {
+		return h.renderSSOError(c, services.ErrSSODisabled)
+	}
+
+	authURL, err := sso.BeginOIDC(c.UserContext(), sso.AdminRedirectURL(), 0)
+	if err != nil {
+		return h.renderSSOError(c, err)
+	}
+	return c.Redirect(authURL)
+}
+
+// HandleSSOCallback - GET /admin/login/sso/callback
+// Identity provider kembali ke sini dengan code & state (atau error jika
+// user membatalkan login)
+func (h *WebAdminHandler) HandleSSOCallback(c *fiber.Ctx) error {
+	if providerErr := strings.TrimSpace(c.Query("error")); providerErr != "" {
+		recordAuthEvent(c, models.AuthEventLoginFailure, nil, "", "oidc: "+providerErr+", "+authEventWebDetail)
+		return h.render(c, "login", PageData{
+			Title:  "Login",
+			Error:  "Login SSO dibatalkan atau ditolak identity provider",
+			Active: "login",
+		})
+	}
+
+	identity, err := services.NewSSOService(config.DB).CompleteOIDC(c.UserContext(), c.Query("state"), c.Query("code"), 0)
+	if err != nil {
+		recordAuthEvent(c, models.AuthEventLoginFailure, nil, "", "oidc: "+err.Error()+", "+authEventWebDetail)
+		return h.renderSSOError(c, err)
+	}
+	user, err := provisionExternalUser(c, identity)
+	if err != nil {
+		recordAuthEvent(c, models.AuthEventLoginFailure, nil, identity.Email, "oidc: "+err.Error()+", "+authEventWebDetail)
+		return h.renderSSOError(c, err)
+	}
+
+	return h.finishLogin(c, *user, user.Email)
+}
+
+func (h *WebAdminHandler) renderSSOError(c *fiber.Ctx, err error) error {
+	return h.render(c, "login", PageData{
+		Title:  "Login",
+		Error:  ssoErrorMessage(err),
+		Active: "login",
+	})
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+	"time"
+
+	"TugasAkhir/config"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+// TwoFactorPageData - data 2FA untuk halaman login langkah kedua & settings
+type TwoFactorPageData struct {
+	Enabled       bool
+	Required      bool
+	Remaining     int64
+	Pending       bool         // Enrolment dimulai tapi kode pertama belum dikonfirmasi
+	NoPassword    bool         // Akun SSO: kode authenticator menggantikan password
+	Secret        string       // Diisi hanya pada respons yang membuat secret
+	QRCode        template.URL // data URI PNG, aman dipakai di <img src>
+	RecoveryCodes []string     // Ditampilkan sekali setelah enrolment
+
+	EnrolmentToken string // Token enrolment dari admin, ditampilkan sekali setelah dibuat
+	TokenExpiresAt time.Time
+}
+
+// =====================
+// LOGIN 2FA
+// =====================
+
+// ShowTwoFactorLogin - GET /admin/login/2fa
+func (h *WebAdminHandler) ShowTwoFactorLogin(c *fiber.Ctx) error {
+	user, _, err := pendingTwoFactorUser(c)
+	if err != nil {
+		return c.Redirect("/admin/login?error=Sesi login berakhir, silakan login ulang")
+	}
+
+	data, err := twoFactorLoginData(user)
+	if err != nil {
+		return c.Redirect("/admin/login?error=Gagal menyiapkan 2FA")
+	}
+	return h.render(c, "login_2fa", PageData{Title: "Verifikasi 2FA", Active: "login", TwoFactor: data})
+}
+
+// HandleTwoFactorLogin - POST /admin/login/2fa
+func (h *WebAdminHandler) HandleTwoFactorLogin(c *fiber.Ctx) error {
+	user, sess, err := pendingTwoFactorUser(c)
+	if err != nil {
+		return c.Redirect("/admin/login?error=Sesi login berakhir, silakan login ulang")
+	}
+
+	// Kode 2FA yang salah dihitung sebagai login gagal, sama seperti password
+	lockErr, err := loginLock(c, user.Email)
+	if err != nil {
+		return c.Redirect("/admin/login?error=Gagal memeriksa percobaan login")
+	}
+	if lockErr != nil {
+		sess.Destroy()
+		return h.renderLoginLocked(c, user.Email, lockErr)
+	}
+
+	recoveryCodes, err := verifyOrEnrolTwoFactor(user, c.FormValue("code"))
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorInvalidCode) {
+			if lockErr := loginFailed(c, user.Email, user, "invalid two-factor code"); lockErr != nil {
+				sess.Destroy()
+				return h.renderLoginLocked(c, user.Email, lockErr)
+			}
+		}
+		data, dataErr := twoFactorLoginData(user)
+		if dataErr != nil {
+			return c.Redirect("/admin/login?error=Gagal menyiapkan 2FA")
+		}
+		msg := "Kode tidak valid"
+		if !errors.Is(err, services.ErrTwoFactorInvalidCode) {
+			msg = "Gagal memverifikasi kode"
+		}
+		return h.render(c, "login_2fa", PageData{Title: "Verifikasi 2FA", Active: "login", TwoFactor: data, Error: msg})
+	}
+
+	sess.Delete(middleware.SessionTwoFactorUserKey)
+	sess.Delete(middleware.SessionTwoFactorAtKey)
+
+	// Password kedaluwarsa: kedua faktor benar, lanjut ke form reset password
+	if expired, _ := sess.Get(middleware.SessionTwoFactorPasswordExpiredKey).(bool); expired {
+		sess.Delete(middleware.SessionTwoFactorPasswordExpiredKey)
+		if err := sess.Save(); err != nil {
+			return c.Redirect("/admin/login?error=Gagal menyimpan session")
+		}
+		resetLoginThrottle(user)
+		rawToken, err := issuePasswordResetToken(user.ID)
+		if err != nil {
+			return c.Redirect("/admin/login?error=Gagal membuat token reset password")
+		}
+		recordAuthEvent(c, models.AuthEventPasswordResetRequested, user, "", "password expired")
+		return c.Redirect("/api/auth/reset-password?token=" + url.QueryEscape(rawToken))
+	}
+
+	sess.Set(middleware.SessionAdminIDKey, user.ID)
+	sess.Set(middleware.SessionAdminRoleKey, string(user.Role))
+	if err := sess.Save(); err != nil {
+		return c.Redirect("/admin/login?error=Gagal menyimpan session")
+	}
+	loginSucceeded(c, user, authEventWebDetail)
+
+	// Enrolment baru: tampilkan recovery code sekali sebelum masuk dashboard
+	if len(recoveryCodes) > 0 {
+		return h.render(c, "login_2fa", PageData{
+			Title:     "Recovery Code",
+			Active:    "login",
+			TwoFactor: &TwoFactorPageData{Enabled: true, RecoveryCodes: recoveryCodes},
+		})
+	}
+	return c.Redirect("/admin")
+}
+
+// HandleTwoFactorLoginSetup - POST /admin/login/2fa/setup
+// Enrolment wajib saat login memakai token enrolment dari admin; password
+// saja tidak cukup untuk mendaftarkan authenticator
+func (h *WebAdminHandler) HandleTwoFactorLoginSetup(c *fiber.Ctx) error {
+	user, sess, err := pendingTwoFactorUser(c)
+	if err != nil {
+		return c.Redirect("/admin/login?error=Sesi login berakhir, silakan login ulang")
+	}
+
+	lockErr, err := loginLock(c, user.Email)
+	if err != nil {
+		return c.Redirect("/admin/login?error=Gagal memeriksa percobaan login")
+	}
+	if lockErr != nil {
+		sess.Destroy()
+		return h.renderLoginLocked(c, user.Email, lockErr)
+	}
+
+	data, err := twoFactorLoginData(user)
+	if err != nil {
+		return c.Redirect("/admin/login?error=Gagal menyiapkan 2FA")
+	}
+	enrolment, err := services.NewTwoFactorService(config.DB).BeginEnrolmentWithToken(user, c.FormValue("enrolment_token"))
+	if err != nil {
+		msg := "Gagal menyiapkan 2FA"
+		if errors.Is(err, services.ErrTwoFactorEnrolmentToken) {
+			if lockErr := loginFailed(c, user.Email, user, "invalid two-factor enrolment token"); lockErr != nil {
+				sess.Destroy()
+				return h.renderLoginLocked(c, user.Email, lockErr)
+			}
+			msg = "Token enrolment tidak valid atau sudah kedaluwarsa"
+		}
+		return h.render(c, "login_2fa", PageData{Title: "Verifikasi 2FA", Active: "login", TwoFactor: data, Error: msg})
+	}
+	if err := fillTwoFactorEnrolment(data, enrolment); err != nil {
+		return c.Redirect("/admin/login?error=Gagal menyiapkan 2FA")
+	}
+	return h.render(c, "login_2fa", PageData{Title: "Verifikasi 2FA", Active: "login", TwoFactor: data})
+}
+
+// pendingTwoFactorUser mengambil user yang password-nya sudah benar dan sedang
+// menunggu verifikasi kode. Batas waktunya sama dengan challenge token API.
+func pendingTwoFactorUser(c *fiber.Ctx) (*models.User, *session.Session, error) {
+	sess, err := middleware.AdminSessionStore.Get(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userID := sess.Get(middleware.SessionTwoFactorUserKey)
+	startedAt, _ := sess.Get(middleware.SessionTwoFactorAtKey).(int64)
+	if userID == nil || time.Since(time.Unix(startedAt, 0)) > utils.TwoFactorChallengeTTL {
+		return nil, nil, fiber.ErrUnauthorized
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, nil, err
+	}
+	if !user.CanLogin() {
+		return nil, nil, fiber.ErrUnauthorized
+	}
+	return &user, sess, nil
+}
+
+// twoFactorLoginData - untuk user yang belum enrolment (wajib karena role),
+// halaman login langkah kedua meminta token enrolment dari admin
+func twoFactorLoginData(user *models.User) (*TwoFactorPageData, error) {
+	enabled, required, err := twoFactorState(user)
+	if err != nil {
+		return nil, err
+	}
+	data := &TwoFactorPageData{Enabled: enabled, Required: required}
+	if !enabled {
+		if data.Pending, err = services.NewTwoFactorService(config.DB).Pending(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func fillTwoFactorEnrolment(data *TwoFactorPageData, enrolment *services.TwoFactorEnrolment) error {
+	qr, err := qrDataURI(enrolment.ProvisioningURI)
+	if err != nil {
+		return err
+	}
+	data.Secret = enrolment.Secret
+	data.QRCode = template.URL(qr)
+	return nil
+}
+
+// HandleIssueTwoFactorEnrolment - POST /admin/users/:id/2fa-enrolment
+// Token ditampilkan sekali di halaman edit user untuk disampaikan ke user
+func (h *WebAdminHandler) HandleIssueTwoFactorEnrolment(c *fiber.Ctx) error {
+	admin, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	user, err := findAdminUser(c)
+	if err != nil {
+		return c.Redirect("/admin/users?error=User tidak ditemukan")
+	}
+
+	token, expiresAt, err := services.NewTwoFactorService(config.DB).IssueEnrolmentToken(user.ID, &admin.ID)
+	if err != nil {
+		back := fmt.Sprintf("/admin/users/%d/edit", user.ID)
+		if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+			return c.Redirect(back + "?error=User sudah mengaktifkan 2FA")
+		}
+		return c.Redirect(back + "?error=Gagal membuat token enrolment 2FA")
+	}
+	recordAuthEvent(c, models.AuthEventTwoFactorTokenIssued, user, user.Email, "by "+admin.Email)
+
+	data := editUserPageData(c, admin, user)
+	data.TwoFactor.EnrolmentToken = token
+	data.TwoFactor.TokenExpiresAt = expiresAt
+	return h.render(c, "users_edit", data)
+}
+
+// =====================
+// SETTINGS 2FA
+// =====================
+
+// settingsTwoFactorData - status 2FA admin untuk halaman settings
+func settingsTwoFactorData(user *models.User) *TwoFactorPageData {
+	tfs := services.NewTwoFactorService(config.DB)
+	data := &TwoFactorPageData{NoPassword: user.PasswordHash == ""}
+	data.Enabled, _ = tfs.Enabled(user.ID)
+	data.Required, _ = tfs.Required(user)
+	if data.Enabled {
+		data.Remaining, _ = tfs.RemainingRecoveryCodes(user.ID)
+	} else {
+		data.Pending, _ = tfs.Pending(user.ID)
+	}
+	return data
+}
+
+// HandleSetupTwoFactor - POST /admin/settings/2fa/setup
+func (h *WebAdminHandler) HandleSetupTwoFactor(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	data := settingsTwoFactorData(user)
+	if data.Enabled {
+		return c.Redirect("/admin/settings?error=2FA sudah aktif")
+	}
+	enrolment, err := services.NewTwoFactorService(config.DB).BeginEnrolment(user)
+	if err != nil || fillTwoFactorEnrolment(data, enrolment) != nil {
+		return c.Redirect("/admin/settings?error=Gagal menyiapkan 2FA")
+	}
+
+	return h.render(c, "settings", PageData{Title: "Settings", Active: "settings", User: user, TwoFactor: data})
+}
+
+// HandleEnableTwoFactor - POST /admin/settings/2fa/enable
+func (h *WebAdminHandler) HandleEnableTwoFactor(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	codes, err := services.NewTwoFactorService(config.DB).ConfirmEnrolment(user.ID, c.FormValue("code"))
+	if err != nil {
+		// Secret yang sudah dipindai tetap berlaku, tapi tidak ditampilkan lagi
+		data := settingsTwoFactorData(user)
+		if !data.Pending {
+			return c.Redirect("/admin/settings?error=Gagal mengaktifkan 2FA")
+		}
+		return h.render(c, "settings", PageData{
+			Title: "Settings", Active: "settings", User: user, TwoFactor: data,
+			Error: "Kode tidak valid, periksa jam perangkat lalu coba lagi",
+		})
+	}
+
+	data := settingsTwoFactorData(user)
+	data.RecoveryCodes = codes
+	return h.render(c, "settings", PageData{
+		Title: "Settings", Active: "settings", User: user, TwoFactor: data,
+		Success: "2FA berhasil diaktifkan. Simpan recovery code di bawah ini.",
+	})
+}
+
+// HandleDisableTwoFactor - POST /admin/settings/2fa/disable
+func (h *WebAdminHandler) HandleDisableTwoFactor(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	// Akun SSO tanpa password lokal: kode authenticator menggantikan password
+	tfs := services.NewTwoFactorService(config.DB)
+	verify := tfs.Verify
+	if user.PasswordHash == "" {
+		verify = tfs.VerifyTOTP
+	} else if !utils.CheckPassword(user.PasswordHash, c.FormValue("password")) {
+		return c.Redirect("/admin/settings?error=Password salah")
+	}
+	if err := verify(user.ID, strings.TrimSpace(c.FormValue("code"))); err != nil {
+		return c.Redirect("/admin/settings?error=Kode 2FA tidak valid")
+	}
+	if err := tfs.Disable(user); err != nil {
+		if errors.Is(err, services.ErrTwoFactorRequiredByRole) {
+			return c.Redirect("/admin/settings?error=Role Anda mewajibkan 2FA")
+		}
+		return c.Redirect("/admin/settings?error=Gagal menonaktifkan 2FA")
+	}
+
+	return c.Redirect("/admin/settings?success=2FA dinonaktifkan")
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"TugasAkhir/config"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UnitFormData struct {
+	Kode       string
+	Nama       string
+	ParentID   string
+	HeadUserID string
+	IsActive   bool
+}
+
+// =====================
+// UNIT CRUD HANDLERS
+// =====================
+
+// ShowUnitList - GET /admin/units (beserta rekap surat per unit)
+func (h *WebAdminHandler) ShowUnitList(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	var units []models.Unit
+	config.DB.Preload("Parent").Preload("HeadUser").Order("kode ASC").Find(&units)
+
+	report, err := services.NewUnitService(config.DB).Report(nil, nil)
+	if err != nil {
+		return c.Redirect("/admin?error=Gagal mengambil rekap unit")
+	}
+
+	return h.render(c, "units_list", PageData{
+		Title:      "Manajemen Unit",
+		Active:     "units",
+		User:       user,
+		Units:      units,
+		UnitReport: report,
+		Success:    c.Query("success"),
+		Error:      c.Query("error"),
+	})
+}
+
+// ShowCreateUnitForm - GET /admin/units/create
+func (h *WebAdminHandler) ShowCreateUnitForm(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	return h.renderUnitForm(c, PageData{
+		Title:    "Tambah Unit",
+		User:     user,
+		UnitForm: UnitFormData{IsActive: true},
+		Errors:   make(map[string]string),
+	})
+}
+
+// HandleCreateUnit - POST /admin/units
+func (h *WebAdminHandler) HandleCreateUnit(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	form := readUnitForm(c)
+	unit := models.Unit{}
+	errors := applyUnitForm(&unit, form)
+	if len(errors) > 0 {
+		return h.renderUnitForm(c, PageData{Title: "Tambah Unit", User: user, UnitForm: form, Errors: errors})
+	}
+
+	if err := config.DB.Create(&unit).Error; err != nil {
+		if utils.IsDuplicateError(err) {
+			errors["kode"] = "Kode unit sudah digunakan"
+			return h.renderUnitForm(c, PageData{Title: "Tambah Unit", User: user, UnitForm: form, Errors: errors})
+		}
+		return h.renderUnitForm(c, PageData{
+			Title: "Tambah Unit", User: user, UnitForm: form,
+			Error:  "Gagal membuat unit: " + err.Error(),
+			Errors: make(map[string]string),
+		})
+	}
+
+	return c.Redirect("/admin/units?success=Unit berhasil dibuat")
+}
+
+// ShowEditUnitForm - GET /admin/units/:id/edit
+func (h *WebAdminHandler) ShowEditUnitForm(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	editUnit, err := loadWebUnit(c)
+	if err != nil {
+		return c.Redirect("/admin/units?error=Unit tidak ditemukan")
+	}
+
+	return h.renderUnitForm(c, PageData{
+		Title:    "Edit Unit",
+		User:     user,
+		EditUnit: editUnit,
+		UnitForm: unitFormFromModel(editUnit),
+		Errors:   make(map[string]string),
+	})
+}
+
+// HandleUpdateUnit - POST /admin/units/:id
+func (h *WebAdminHandler) HandleUpdateUnit(c *fiber.Ctx) error {
+	user, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	editUnit, err := loadWebUnit(c)
+	if err != nil {
+		return c.Redirect("/admin/units?error=Unit tidak ditemukan")
+	}
+
+	form := readUnitForm(c)
+	errors := applyUnitForm(editUnit, form)
+	if len(errors) > 0 {
+		return h.renderUnitForm(c, PageData{Title: "Edit Unit", User: user, EditUnit: editUnit, UnitForm: form, Errors: errors})
+	}
+
+	if err := config.DB.Omit("Parent", "HeadUser", "Members").Save(editUnit).Error; err != nil {
+		if utils.IsDuplicateError(err) {
+			errors["kode"] = "Kode unit sudah digunakan"
+			return h.renderUnitForm(c, PageData{Title: "Edit Unit", User: user, EditUnit: editUnit, UnitForm: form, Errors: errors})
+		}
+		return h.renderUnitForm(c, PageData{
+			Title: "Edit Unit", User: user, EditUnit: editUnit, UnitForm: form,
+			Error:  "Gagal update unit: " + err.Error(),
+			Errors: make(map[string]string),
+		})
+	}
+
+	return c.Redirect("/admin/units?success=Unit berhasil diupdate")
+}
+
+// HandleDeleteUnit - POST /admin/units/:id/delete
+func (h *WebAdminHandler) HandleDeleteUnit(c *fiber.Ctx) error {
+	if _, err := middleware.GetAdminFromSession(c); err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return c.Redirect("/admin/units?error=Unit tidak ditemukan")
+	}
+
+	if err := services.NewUnitService(config.DB).Delete(uint(id)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnitNotFound):
+			return c.Redirect("/admin/units?error=Unit tidak ditemukan")
+		case errors.Is(err, services.ErrUnitHasChildren):
+			return c.Redirect("/admin/units?error=Unit masih memiliki sub-unit")
+		}
+		return c.Redirect("/admin/units?error=Gagal menghapus unit")
+	}
+
+	return c.Redirect("/admin/units?success=Unit berhasil dihapus")
+}
+
+// renderUnitForm mengisi pilihan parent & kepala unit lalu merender form unit
+func (h *WebAdminHandler) renderUnitForm(c *fiber.Ctx, data PageData) error {
+	data.Active = "units"
+
+	query := config.DB.Order("kode ASC")
+	if data.EditUnit != nil {
+		query = query.Where("id <> ?", data.EditUnit.ID)
+	}
+	query.Find(&data.Units)
+	config.DB.Order("username ASC").Find(&data.Users)
+
+	return h.render(c, "units_form", data)
+}
+
+func loadWebUnit(c *fiber.Ctx) (*models.Unit, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return nil, services.ErrUnitNotFound
+	}
+	unit, err := services.NewUnitService(config.DB).Get(uint(id))
+	if err != nil {
+		return nil, err
+	}
+	config.DB.Where("unit_id = ?", unit.ID).Order("username ASC").Find(&unit.Members)
+	return unit, nil
+}
+
+func readUnitForm(c *fiber.Ctx) UnitFormData {
+	return UnitFormData{
+		Kode:       strings.ToUpper(strings.TrimSpace(c.FormValue("kode"))),
+		Nama:       strings.TrimSpace(c.FormValue("nama")),
+		ParentID:   c.FormValue("parent_id"),
+		HeadUserID: c.FormValue("head_user_id"),
+		IsActive:   c.FormValue("is_active") != "",
+	}
+}
+
+func unitFormFromModel(unit *models.Unit) UnitFormData {
+	form := UnitFormData{Kode: unit.Kode, Nama: unit.Nama, IsActive: unit.IsActive}
+	if unit.ParentID != nil {
+		form.ParentID = strconv.FormatUint(uint64(*unit.ParentID), 10)
+	}
+	if unit.HeadUserID != nil {
+		form.HeadUserID = strconv.FormatUint(uint64(*unit.HeadUserID), 10)
+	}
+	return form
+}
+
+// applyUnitForm memvalidasi form dan menyalinnya ke unit. Mengembalikan error
+// per field (kosong jika valid).
+func applyUnitForm(unit *models.Unit, form UnitFormData) map[string]string {
+	errors := make(map[string]string)
+
+	if form.Kode == "" {
+		errors["kode"] = "Kode harus diisi"
+	} else if len(form.Kode) > 20 || strings.ContainsAny(form.Kode, "/ ") {
+		errors["kode"] = "Kode maksimal 20 karakter, tanpa spasi dan '/'"
+	}
+	if form.Nama == "" {
+		errors["nama"] = "Nama unit harus diisi"
+	}
+
+	unit.Kode = form.Kode
+	unit.Nama = form.Nama
+	unit.IsActive = form.IsActive
+	unit.ParentID = parseOptionalID(form.ParentID)
+	unit.HeadUserID = parseOptionalID(form.HeadUserID)
+	unit.Parent, unit.HeadUser = nil, nil
+
+	unitService := services.NewUnitService(config.DB)
+	if err := unitService.ValidateParent(unit.ID, unit.ParentID); err != nil {
+		if err == services.ErrUnitCycle {
+			errors["parent_id"] = "Unit induk tidak boleh unit ini sendiri atau sub-unitnya"
+		} else {
+			errors["parent_id"] = "Unit induk tidak ditemukan"
+		}
+	}
+	if err := unitService.ValidateHead(unit.HeadUserID); err != nil {
+		errors["head_user_id"] = "Kepala unit tidak ditemukan"
+	}
+
+	return errors
+}
+
+func parseOptionalID(raw string) *uint {
+	id, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+	if err != nil || id == 0 {
+		return nil
+	}
+	v := uint(id)
+	return &v
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"TugasAkhir/config"
+	userdto "TugasAkhir/dto/users"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Format input datetime-local di form penugasan role
+const assignmentTimeLayout = "2006-01-02T15:04"
+
+// =====================
+// PENUGASAN ROLE (Plt./Plh.)
+// =====================
+
+// HandleAssignUserRole - POST /admin/users/:id/roles
+func (h *WebAdminHandler) HandleAssignUserRole(c *fiber.Ctx) error {
+	admin, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	user, err := findAdminUser(c)
+	if err != nil {
+		return c.Redirect("/admin/users?error=User tidak ditemukan")
+	}
+	back := fmt.Sprintf("/admin/users/%d/edit", user.ID)
+
+	req := userdto.RoleAssignmentRequest{
+		Role:  models.Role(strings.TrimSpace(c.FormValue("role"))),
+		Label: strings.TrimSpace(c.FormValue("label")),
+	}
+	if req.ValidFrom, err = parseAssignmentTime(c.FormValue("valid_from")); err != nil {
+		return c.Redirect(back + "?error=Format tanggal mulai tidak valid")
+	}
+	if req.ValidUntil, err = parseAssignmentTime(c.FormValue("valid_until")); err != nil {
+		return c.Redirect(back + "?error=Format tanggal selesai tidak valid")
+	}
+	if len(req.Validate()) > 0 {
+		return c.Redirect(back + "?error=Data penugasan tidak valid")
+	}
+
+	assignment := req.ToModel(user.ID, &admin.ID)
+	if err := services.NewUserRoleService(config.DB).Assign(&assignment); err != nil {
+		switch {
+		case errors.Is(err, services.ErrRoleNotFound):
+			return c.Redirect(back + "?error=Role tidak ditemukan")
+		case errors.Is(err, services.ErrRoleAssignmentPrimary):
+			return c.Redirect(back + "?error=Role tersebut sudah menjadi role utama user")
+		case errors.Is(err, services.ErrRoleAssignmentInvalidSpan):
+			return c.Redirect(back + "?error=Tanggal selesai harus setelah tanggal mulai")
+		}
+		return c.Redirect(back + "?error=Gagal menambah penugasan role")
+	}
+
+	return c.Redirect(back + "?success=Penugasan role berhasil ditambahkan")
+}
+
+// HandleRevokeUserRole - POST /admin/users/:id/roles/:assignmentId/delete
+func (h *WebAdminHandler) HandleRevokeUserRole(c *fiber.Ctx) error {
+	if _, err := middleware.GetAdminFromSession(c); err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	user, err := findAdminUser(c)
+	if err != nil {
+		return c.Redirect("/admin/users?error=User tidak ditemukan")
+	}
+	back := fmt.Sprintf("/admin/users/%d/edit", user.ID)
+
+	assignmentID, err := c.ParamsInt("assignmentId")
+	if err != nil || assignmentID < 1 {
+		return c.Redirect(back + "?error=Penugasan tidak ditemukan")
+	}
+	if err := services.NewUserRoleService(config.DB).DeleteAssignment(user.ID, uint(assignmentID)); err != nil {
+		if errors.Is(err, services.ErrRoleAssignmentNotFound) {
+			return c.Redirect(back + "?error=Penugasan tidak ditemukan")
+		}
+		return c.Redirect(back + "?error=Gagal mencabut penugasan role")
+	}
+
+	return c.Redirect(back + "?success=Penugasan role berhasil dicabut")
+}
+
+// userRoleAssignments - daftar penugasan untuk halaman edit user
+func userRoleAssignments(userID uint) []userdto.RoleAssignmentResponse {
+	assignments, _ := services.NewUserRoleService(config.DB).ListAssignments(userID)
+	responses := make([]userdto.RoleAssignmentResponse, 0, len(assignments))
+	for _, a := range assignments {
+		responses = append(responses, userdto.NewRoleAssignmentResponse(a))
+	}
+	return responses
+}
+
+// parseAssignmentTime membaca input datetime-local (waktu server); kosong = tanpa batas
+func parseAssignmentTime(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation(assignmentTimeLayout, value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"TugasAkhir/config"
+	userdto "TugasAkhir/dto/users"
+	"TugasAkhir/middleware"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// DeactivateFormData - wizard penonaktifan user: surat yang menunggu user
+// sebagai verifikator dan pilihan pengganti (per surat atau satu untuk semua)
+type DeactivateFormData struct {
+	Letters     []userdto.AdminPendingLetterResponse
+	Candidates  []userdto.VerifierCandidate // Gabungan kandidat semua scope, untuk pilihan default
+	ReassignTo  string
+	Assignments map[uint]string
+}
+
+// =====================
+// USER STATUS HANDLERS
+// =====================
+
+// ShowDeactivateUser - GET /admin/users/:id/deactivate
+func (h *WebAdminHandler) ShowDeactivateUser(c *fiber.Ctx) error {
+	admin, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	target, redirect := h.deactivateTarget(c, admin)
+	if target == nil {
+		return redirect
+	}
+
+	form, err := deactivateForm(target.ID)
+	if err != nil {
+		return c.Redirect("/admin/users?error=Gagal mengambil surat user")
+	}
+	return h.renderDeactivate(c, PageData{User: admin, EditUser: target, Deactivate: form, Error: c.Query("error")})
+}
+
+// HandleDeactivateUser - POST /admin/users/:id/deactivate
+func (h *WebAdminHandler) HandleDeactivateUser(c *fiber.Ctx) error {
+	admin, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	target, redirect := h.deactivateTarget(c, admin)
+	if target == nil {
+		return redirect
+	}
+
+	form, err := deactivateForm(target.ID)
+	if err != nil {
+		return c.Redirect("/admin/users?error=Gagal mengambil surat user")
+	}
+	form.ReassignTo = strings.TrimSpace(c.FormValue("reassign_to"))
+	plan := services.ReassignPlan{Default: parseFormID(form.ReassignTo), Letters: map[uint]uint{}}
+	for _, letter := range form.Letters {
+		value := strings.TrimSpace(c.FormValue(fmt.Sprintf("reassign_%d", letter.ID)))
+		form.Assignments[letter.ID] = value
+		if id := parseFormID(value); id != 0 {
+			plan.Letters[letter.ID] = id
+		}
+	}
+	data := PageData{User: admin, EditUser: target, Deactivate: form}
+
+	deactivated, reassigned, err := services.NewUserDeactivationService(config.DB).Deactivate(target.ID, plan, admin)
+	if err != nil {
+		var unassigned *services.UnassignedLettersError
+		var replacement *services.ReplacementError
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			return c.Redirect("/admin/users?error=User tidak ditemukan")
+		case errors.Is(err, services.ErrUserAlreadyInactive):
+			return c.Redirect("/admin/users?error=User sudah nonaktif")
+		case errors.As(err, &unassigned):
+			data.Error = fmt.Sprintf("%d surat belum punya verifikator pengganti", len(unassigned.LetterIDs))
+		case errors.As(err, &replacement):
+			data.Error = fmt.Sprintf("Verifikator pengganti untuk surat #%d tidak aktif atau tidak berwenang memverifikasi surat tersebut", replacement.LetterID)
+		default:
+			data.Error = "Gagal menonaktifkan user"
+		}
+		// Daftar surat bisa berubah sejak halaman dibuka, jadi form dirender ulang
+		return h.renderDeactivate(c, data)
+	}
+
+	ids := publishVerifierReassigned(reassigned)
+	recordUserStatusEvent(c, models.AuthEventUserDeactivated, deactivated, admin, len(ids))
+
+	msg := "User berhasil dinonaktifkan"
+	if len(ids) > 0 {
+		msg = fmt.Sprintf("User berhasil dinonaktifkan, %d surat dialihkan", len(ids))
+	}
+	return c.Redirect("/admin/users?success=" + msg)
+}
+
+// HandleActivateUser - POST /admin/users/:id/activate
+func (h *WebAdminHandler) HandleActivateUser(c *fiber.Ctx) error {
+	admin, err := middleware.GetAdminFromSession(c)
+	if err != nil {
+		return c.Redirect("/admin/login")
+	}
+
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		return c.Redirect("/admin/users?error=User tidak ditemukan")
+	}
+	user, err := services.NewUserDeactivationService(config.DB).Reactivate(uint(userID))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			return c.Redirect("/admin/users?error=User tidak ditemukan")
+		case errors.Is(err, services.ErrUserNotInactive):
+			return c.Redirect("/admin/users?error=User tidak sedang nonaktif")
+		}
+		return c.Redirect("/admin/users?error=Gagal mengaktifkan user")
+	}
+	recordUserStatusEvent(c, models.AuthEventUserActivated, user, admin, 0)
+
+	return c.Redirect("/admin/users?success=User " + user.Username + " berhasil diaktifkan kembali")
+}
+
+// deactivateTarget memuat user yang akan dinonaktifkan; admin tidak boleh
+// menonaktifkan akunnya sendiri
+func (h *WebAdminHandler) deactivateTarget(c *fiber.Ctx, admin *models.User) (*models.User, error) {
+	var target models.User
+	if err := config.DB.First(&target, "id = ?", c.Params("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, c.Redirect("/admin/users?error=User tidak ditemukan")
+		}
+		return nil, c.Redirect("/admin/users?error=Gagal mengambil data user")
+	}
+	if target.ID == admin.ID {
+		return nil, c.Redirect("/admin/users?error=Tidak dapat menonaktifkan akun sendiri")
+	}
+	if target.Status == models.UserStatusInactive {
+		return nil, c.Redirect("/admin/users?error=User sudah nonaktif")
+	}
+	return &target, nil
+}
+
+func deactivateForm(userID uint) (DeactivateFormData, error) {
+	letters, err := pendingLetterResponses(services.NewUserDeactivationService(config.DB), userID)
+	if err != nil {
+		return DeactivateFormData{}, err
+	}
+
+	form := DeactivateFormData{Letters: letters, Assignments: map[uint]string{}}
+	seen := map[uint]bool{}
+	for _, letter := range letters {
+		for _, candidate := range letter.Candidates {
+			if !seen[candidate.ID] {
+				seen[candidate.ID] = true
+				form.Candidates = append(form.Candidates, candidate)
+			}
+		}
+	}
+	return form, nil
+}
+
+func parseFormID(value string) uint {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
+
+func (h *WebAdminHandler) renderDeactivate(c *fiber.Ctx, data PageData) error {
+	data.Title = "Nonaktifkan User"
+	data.Active = "users"
+	return h.render(c, "users_deactivate", data)
+}
@@ -1,8 +1,12 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
 
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
 	"TugasAkhir/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,8 +18,15 @@ const (
 	ContextUserRoleKey = "userRole"
 )
 
+// HeaderAPIKey - header API key untuk integrasi mesin (pengganti Authorization)
+const HeaderAPIKey = "X-API-Key"
+
 func RequireAuth() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		if rawKey := strings.TrimSpace(c.Get(HeaderAPIKey)); rawKey != "" {
+			return authenticateAPIKey(c, rawKey)
+		}
+
 		header := c.Get("Authorization")
 		if header == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "missing Authorization header"})
@@ -32,6 +43,15 @@ func RequireAuth() fiber.Handler {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or expired token"})
 		}
 
+		// Token milik user yang dihapus / dinonaktifkan, atau terbit sebelum
+		// role diganti / sesi dicabut, ditolak tanpa menunggu kedaluwarsa
+		if err := services.NewTokenVersionService(config.DB).Check(claims.UserID, claims.Version); err != nil {
+			if errors.Is(err, services.ErrTokenRevoked) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "token has been revoked"})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to validate token"})
+		}
+
 		c.Locals(ContextClaimsKey, claims)
 		c.Locals(ContextUserIDKey, claims.UserID)
 		c.Locals(ContextUserRoleKey, claims.Role)
@@ -40,6 +60,61 @@ func RequireAuth() fiber.Handler {
 	}
 }
 
+// authenticateAPIKey - request bertindak sebagai owner key dengan role utamanya;
+// permission dibatasi ke scope key lewat claims.Scopes
+func authenticateAPIKey(c *fiber.Ctx, rawKey string) error {
+	key, err := services.NewAPIKeyService(config.DB).Authenticate(rawKey, c.IP())
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyInvalid) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or expired API key"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to validate API key"})
+	}
+
+	claims := &utils.JWTClaims{
+		UserID:   key.Owner.ID,
+		Role:     key.Owner.Role,
+		Email:    key.Owner.Email,
+		Username: key.Owner.Username,
+		APIKeyID: key.ID,
+		Scopes:   key.ScopeList(),
+	}
+	c.Locals(ContextClaimsKey, claims)
+	c.Locals(ContextUserIDKey, claims.UserID)
+	c.Locals(ContextUserRoleKey, claims.Role)
+
+	return c.Next()
+}
+
+// RejectAPIKey - endpoint akun (password, sesi, 2FA, ganti role, kelola API
+// key) hanya untuk user yang login sendiri
+func RejectAPIKey() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if claims, ok := GetJWTClaims(c); ok && claims.APIKeyID != 0 {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "endpoint is not available for API keys"})
+		}
+		return c.Next()
+	}
+}
+
+// RequireAPIKeyScope - endpoint yang aksesnya diatur per surat (bukan oleh
+// RequirePermission) hanya bisa dipakai API key yang scope-nya memuat salah
+// satu perms. Login biasa tidak terpengaruh. Endpoint tanpa scope eksplisit
+// memakai RejectAPIKey.
+func RequireAPIKeyScope(perms ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := GetJWTClaims(c)
+		if !ok || claims.APIKeyID == 0 {
+			return c.Next()
+		}
+		allowed := models.ScopedPermissions(claims.Scopes, perms)
+		if !services.NewRBACService(config.DB).HasPermission(claims.Role, allowed...) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "API key scope does not allow this endpoint"})
+		}
+		return c.Next()
+	}
+}
+
 func GetJWTClaims(c *fiber.Ctx) (*utils.JWTClaims, bool) {
 	claims, ok := c.Locals(ContextClaimsKey).(*utils.JWTClaims)
 	return claims, ok
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+	"TugasAkhir/utils/dbtest"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequireAuthRejectsTokenIssuedBeforeRevokeAll(t *testing.T) {
+	t.Setenv("JWT_SECRET", "middleware-test-secret")
+	db := dbtest.Open(t, &models.User{}, &models.UserSession{}, &models.RefreshToken{})
+	previousDB := config.DB
+	config.DB = db
+	t.Cleanup(func() { config.DB = previousDB })
+
+	user := models.User{Username: "dewi", Email: "dewi@yayasan.org", Role: models.RoleStafProgram, Status: models.UserStatusActive}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	services.InvalidateTokenState(user.ID)
+	t.Cleanup(func() { services.InvalidateTokenState(user.ID) })
+
+	oldToken, _, err := utils.GenerateAccessToken(user)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	app := fiber.New()
+	app.Get("/", RequireAuth(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	call := func(token string) int {
+		req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Request pertama mengisi cache versi token
+	if status := call(oldToken); status != fiber.StatusOK {
+		t.Fatalf("status before revoke = %d, want 200", status)
+	}
+
+	if _, err := services.NewSessionService(db).RevokeAll(user.ID); err != nil {
+		t.Fatalf("RevokeAll: %v", err)
+	}
+	// Ditolak langsung, tanpa menunggu cache 5 detik kedaluwarsa
+	if status := call(oldToken); status != fiber.StatusUnauthorized {
+		t.Fatalf("status after revoke = %d, want 401", status)
+	}
+
+	if err := db.First(&user, user.ID).Error; err != nil {
+		t.Fatalf("reload user: %v", err)
+	}
+	newToken, _, err := utils.GenerateAccessToken(user)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	if status := call(newToken); status != fiber.StatusOK {
+		t.Fatalf("status with new token = %d, want 200", status)
+	}
+}
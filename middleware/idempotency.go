@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"TugasAkhir/models"
+	"TugasAkhir/utils"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+)
+
+// Idempotency membuat request mutasi (POST/PUT/PATCH/DELETE) dengan header
+// Idempotency-Key hanya dieksekusi sekali per user selama 24 jam:
+//   - key sama & body sama: response pertama dikirim ulang
+//   - key sama & body beda: 422
+//   - key sama & request pertama belum selesai: 409
+//   - response selain 2xx tidak disimpan, request boleh diulang dengan key sama
+//
+// Harus dipasang setelah RequireAuth. Request tanpa header tidak terpengaruh.
+func Idempotency(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := strings.TrimSpace(c.Get(HeaderIdempotencyKey))
+		if key == "" || !isMutatingMethod(c.Method()) {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return utils.BadRequest(c, "Idempotency-Key terlalu panjang", fiber.Map{"idempotency_key": "maksimal 255 karakter"})
+		}
+
+		claims, ok := GetJWTClaims(c)
+		if !ok {
+			return c.Next()
+		}
+
+		requestHash, err := hashIdempotentRequest(c)
+		if err != nil {
+			return utils.BadRequest(c, "Body request tidak valid", nil)
+		}
+
+		now := time.Now()
+		var existing models.IdempotencyKey
+		err = db.Where("user_id = ? AND `key` = ?", claims.UserID, key).First(&existing).Error
+		switch {
+		case err == nil && existing.IsExpired(now):
+			db.Unscoped().Delete(&existing)
+		case err == nil:
+			return replayIdempotent(c, &existing, requestHash)
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return utils.InternalServerError(c, "Gagal memeriksa Idempotency-Key")
+		}
+
+		record := models.IdempotencyKey{
+			UserID:      claims.UserID,
+			Key:         key,
+			Method:      c.Method(),
+			Path:        c.Path(),
+			RequestHash: requestHash,
+			ExpiresAt:   now.Add(models.IdempotencyKeyTTL),
+		}
+		// Unique index (user_id, key) mencegah dua request paralel sama-sama lolos
+		if err := db.Create(&record).Error; err != nil {
+			if utils.IsDuplicateError(err) {
+				return utils.Conflict(c, "Request dengan Idempotency-Key ini sedang diproses")
+			}
+			log.Printf("⚠️ Gagal menyimpan idempotency key user %d: %v", claims.UserID, err)
+			return utils.InternalServerError(c, "Gagal menyimpan Idempotency-Key")
+		}
+
+		if err := c.Next(); err != nil {
+			db.Unscoped().Delete(&record)
+			return err
+		}
+
+		// Response gagal (validasi, konflik, error server) tidak disimpan agar
+		// klien bisa mencoba lagi dengan key yang sama
+		status := c.Response().StatusCode()
+		if status < fiber.StatusOK || status >= fiber.StatusMultipleChoices {
+			db.Unscoped().Delete(&record)
+			return nil
+		}
+
+		completedAt := time.Now()
+		if err := db.Model(&record).Updates(map[string]interface{}{
+			"completed_at":  &completedAt,
+			"status_code":   status,
+			"content_type":  string(c.Response().Header.ContentType()),
+			"response_body": append([]byte(nil), c.Response().Body()...),
+		}).Error; err != nil {
+			log.Printf("⚠️ Gagal menyimpan response idempotency key %d: %v", record.ID, err)
+		}
+
+		// Bersihkan key kedaluwarsa milik user ini
+		db.Unscoped().Where("user_id = ? AND expires_at < ?", claims.UserID, now).Delete(&models.IdempotencyKey{})
+		return nil
+	}
+}
+
+func replayIdempotent(c *fiber.Ctx, record *models.IdempotencyKey, requestHash string) error {
+	if record.RequestHash != requestHash || record.Method != c.Method() || record.Path != c.Path() {
+		return utils.UnprocessableEntity(c, "Idempotency-Key sudah dipakai untuk request yang berbeda", nil)
+	}
+	if record.CompletedAt == nil {
+		return utils.Conflict(c, "Request dengan Idempotency-Key ini sedang diproses")
+	}
+
+	c.Set(HeaderIdempotencyReplayed, "true")
+	if record.ContentType != "" {
+		c.Set(fiber.HeaderContentType, record.ContentType)
+	}
+	return c.Status(record.StatusCode).Send(record.ResponseBody)
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// hashIdempotentRequest menghitung hash isi request. Multipart di-hash per
+// field & isi file karena boundary berubah setiap kali klien mengirim ulang.
+func hashIdempotentRequest(c *fiber.Ctx) (string, error) {
+	h := sha256.New()
+	io.WriteString(h, c.Method()+" "+c.Path()+"\n")
+
+	if !strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+		h.Write(c.Body())
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(form.Value))
+	for name := range form.Value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range form.Value[name] {
+			io.WriteString(h, "v:"+name+"="+v+"\n")
+		}
+	}
+
+	names = names[:0]
+	for name := range form.File {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, fh := range form.File[name] {
+			io.WriteString(h, "f:"+name+"="+fh.Filename+"\n")
+			f, err := fh.Open()
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils"
+	"TugasAkhir/utils/dbtest"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func idempotencyTestApp(db *gorm.DB, handlerCalls *int) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(ContextClaimsKey, &utils.JWTClaims{UserID: 1, Role: models.RoleStafProgram})
+		return c.Next()
+	})
+	app.Post("/letters", Idempotency(db), func(c *fiber.Ctx) error {
+		*handlerCalls++
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": *handlerCalls})
+	})
+	app.Post("/letters/rejected", Idempotency(db), func(c *fiber.Ctx) error {
+		*handlerCalls++
+		return utils.BadRequest(c, "Validasi gagal", nil)
+	})
+	app.Post("/letters/broken", Idempotency(db), func(c *fiber.Ctx) error {
+		*handlerCalls++
+		return utils.InternalServerError(c, "Gagal menyimpan surat")
+	})
+	return app
+}
+
+const idempotencyTestBody = `{"judul_surat":"Undangan"}`
+
+func postIdempotent(t *testing.T, app *fiber.App, key string) int {
+	t.Helper()
+	return postIdempotentTo(t, app, "/letters", key, idempotencyTestBody)
+}
+
+func postIdempotentTo(t *testing.T, app *fiber.App, target, key, body string) int {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodPost, target, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(HeaderIdempotencyKey, key)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestIdempotencyReplaysCompletedRequest(t *testing.T) {
+	db := dbtest.Open(t, &models.IdempotencyKey{})
+	calls := 0
+	app := idempotencyTestApp(db, &calls)
+
+	for i := 0; i < 2; i++ {
+		if status := postIdempotent(t, app, "abc"); status != fiber.StatusCreated {
+			t.Fatalf("attempt %d status = %d, want 201", i+1, status)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestIdempotencyRetryWithSameKey(t *testing.T) {
+	type request struct{ target, body string }
+	cases := []struct {
+		name   string
+		first  request
+		second request
+		// between dijalankan di antara kedua request
+		between   func(t *testing.T, db *gorm.DB)
+		want      int
+		wantCalls int
+	}{
+		{
+			name:      "body berbeda",
+			first:     request{"/letters", idempotencyTestBody},
+			second:    request{"/letters", `{"judul_surat":"Undangan Rapat"}`},
+			want:      fiber.StatusUnprocessableEntity,
+			wantCalls: 1,
+		},
+		{
+			name:      "endpoint berbeda",
+			first:     request{"/letters", idempotencyTestBody},
+			second:    request{"/letters/rejected", idempotencyTestBody},
+			want:      fiber.StatusUnprocessableEntity,
+			wantCalls: 1,
+		},
+		{
+			name:   "request pertama masih diproses",
+			first:  request{"/letters", idempotencyTestBody},
+			second: request{"/letters", idempotencyTestBody},
+			between: func(t *testing.T, db *gorm.DB) {
+				// Sama seperti saat handler request pertama belum selesai
+				if err := db.Model(&models.IdempotencyKey{}).Where("`key` = ?", "abc").Update("completed_at", nil).Error; err != nil {
+					t.Fatalf("reset completed_at: %v", err)
+				}
+			},
+			want:      fiber.StatusConflict,
+			wantCalls: 1,
+		},
+		{
+			name:      "response 4xx tidak disimpan",
+			first:     request{"/letters/rejected", idempotencyTestBody},
+			second:    request{"/letters/rejected", idempotencyTestBody},
+			want:      fiber.StatusBadRequest,
+			wantCalls: 2,
+		},
+		{
+			name:      "response 5xx tidak disimpan",
+			first:     request{"/letters/broken", idempotencyTestBody},
+			second:    request{"/letters/broken", idempotencyTestBody},
+			want:      fiber.StatusInternalServerError,
+			wantCalls: 2,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := dbtest.Open(t, &models.IdempotencyKey{})
+			calls := 0
+			app := idempotencyTestApp(db, &calls)
+
+			postIdempotentTo(t, app, tc.first.target, "abc", tc.first.body)
+			if tc.between != nil {
+				tc.between(t, db)
+			}
+			if status := postIdempotentTo(t, app, tc.second.target, "abc", tc.second.body); status != tc.want {
+				t.Fatalf("retry status = %d, want %d", status, tc.want)
+			}
+			if calls != tc.wantCalls {
+				t.Fatalf("handler called %d times, want %d", calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyDatabaseErrorIsNotConflict(t *testing.T) {
+	db := dbtest.Open(t, &models.IdempotencyKey{})
+	// Simulasikan error database selain pelanggaran unique index
+	if err := db.Callback().Create().Before("gorm:create").Register("test:fail_create", func(tx *gorm.DB) {
+		tx.AddError(errors.New("database is locked"))
+	}); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	calls := 0
+	app := idempotencyTestApp(db, &calls)
+
+	if status := postIdempotent(t, app, "abc"); status != fiber.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", status)
+	}
+	if calls != 0 {
+		t.Fatalf("handler called %d times, want 0", calls)
+	}
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"TugasAkhir/config"
 	"TugasAkhir/models"
+	"TugasAkhir/services"
 	"TugasAkhir/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -23,14 +25,23 @@ func RequireRole(allowedRoles ...models.Role) fiber.Handler {
 	}
 }
 
-func RequireStaf() fiber.Handler {
-	return RequireRole(models.RoleStafProgram, models.RoleStafLembaga)
-}
-func RequireManajer() fiber.Handler {
-	return RequireRole(models.RoleManajerKPP, models.RoleManajerPemas, models.RoleManajerPKL)
+// RequirePermission - lolos jika role user memiliki salah satu permission yang
+// disebut (misal RequirePermission(models.PermLetterApprove)). Pemetaan
+// role→permission diambil dari tabel roles & role_permissions (di-cache).
+// Request dengan API key hanya bisa memakai permission yang ada di scope key.
+func RequirePermission(perms ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("jwtClaims").(*utils.JWTClaims)
+		if !ok {
+			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+		allowed := models.ScopedPermissions(claims.Scopes, perms)
+		if !services.NewRBACService(config.DB).HasPermission(claims.Role, allowed...) {
+			return c.Status(403).JSON(fiber.Map{"error": "Forbidden"})
+		}
+		return c.Next()
+	}
 }
-func RequireDirektur() fiber.Handler { return RequireRole(models.RoleDirektur) }
-func RequireAdmin() fiber.Handler    { return RequireRole(models.RoleAdmin) }
 
 func GetUserFromContext(c *fiber.Ctx) (*models.User, error) {
 	claims, ok := c.Locals("jwtClaims").(*utils.JWTClaims)
@@ -38,8 +49,9 @@ func GetUserFromContext(c *fiber.Ctx) (*models.User, error) {
 		return nil, fiber.ErrUnauthorized
 	}
 	return &models.User{
-		Model: gorm.Model{ID: claims.UserID},
-		Role:  claims.Role,
-		Email: claims.Email,
+		Model:  gorm.Model{ID: claims.UserID},
+		Role:   claims.Role,
+		Email:  claims.Email,
+		Scopes: claims.Scopes,
 	}, nil
 }
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+	"TugasAkhir/utils/dbtest"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequirePermission(t *testing.T) {
+	db := dbtest.Open(t, &models.RoleDefinition{}, &models.Permission{})
+	if err := services.SeedRBAC(db); err != nil {
+		t.Fatalf("SeedRBAC: %v", err)
+	}
+	previousDB := config.DB
+	config.DB = db
+	services.InvalidatePermissionCache()
+	t.Cleanup(func() {
+		config.DB = previousDB
+		services.InvalidatePermissionCache()
+	})
+
+	tests := []struct {
+		name   string
+		claims *utils.JWTClaims
+		want   int
+	}{
+		{"no claims", nil, fiber.StatusUnauthorized},
+		{"role with permission", &utils.JWTClaims{Role: models.RoleDirektur}, fiber.StatusOK},
+		{"role without permission", &utils.JWTClaims{Role: models.RoleStafProgram}, fiber.StatusForbidden},
+		{"unknown role", &utils.JWTClaims{Role: "tamu"}, fiber.StatusForbidden},
+		{"API key with scope", &utils.JWTClaims{Role: models.RoleDirektur, APIKeyID: 1, Scopes: []string{models.PermLetterApprove}}, fiber.StatusOK},
+		{"API key without scope", &utils.JWTClaims{Role: models.RoleDirektur, APIKeyID: 1, Scopes: []string{models.PermLetterViewAll}}, fiber.StatusForbidden},
+		{"API key with no scopes", &utils.JWTClaims{Role: models.RoleDirektur, APIKeyID: 1, Scopes: []string{}}, fiber.StatusForbidden},
+		// Scope tidak menambah izin yang tidak dimiliki role pemilik
+		{"API key scope outside role", &utils.JWTClaims{Role: models.RoleStafProgram, APIKeyID: 1, Scopes: []string{models.PermLetterApprove}}, fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				if tt.claims != nil {
+					c.Locals(ContextClaimsKey, tt.claims)
+				}
+				return c.Next()
+			})
+			app.Get("/", RequirePermission(models.PermLetterApprove, models.PermLetterDispose), func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil), -1)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"TugasAkhir/config"
 	"TugasAkhir/models"
+	"TugasAkhir/services"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
@@ -23,6 +24,13 @@ func init() {
 const (
 	SessionAdminIDKey   = "admin_id"
 	SessionAdminRoleKey = "admin_role"
+
+	// Password benar tapi kode 2FA belum diverifikasi; session admin belum dibuat
+	SessionTwoFactorUserKey = "two_factor_user_id"
+	SessionTwoFactorAtKey   = "two_factor_started_at"
+	// Password benar tapi kedaluwarsa: setelah kode 2FA benar user diarahkan
+	// ke form reset password, bukan ke dashboard
+	SessionTwoFactorPasswordExpiredKey = "two_factor_password_expired"
 )
 
 // RequireAdminSession - Middleware untuk cek session admin
@@ -40,8 +48,9 @@ func RequireAdminSession() fiber.Handler {
 			return c.Redirect("/admin/login")
 		}
 
-		// Cek apakah role adalah admin
-		if adminRole != string(models.RoleAdmin) {
+		// Cek apakah role masih boleh mengakses panel admin
+		role, _ := adminRole.(string)
+		if !services.NewRBACService(config.DB).HasPermission(models.Role(role), models.PermAdminPanel) {
 			sess.Destroy()
 			return c.Redirect("/admin/login")
 		}
@@ -54,6 +63,18 @@ func RequireAdminSession() fiber.Handler {
 	}
 }
 
+// RequireSessionPermission - Dipasang setelah RequireAdminSession untuk halaman
+// yang butuh permission tambahan selain akses panel
+func RequireSessionPermission(perms ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals(SessionAdminRoleKey).(string)
+		if !services.NewRBACService(config.DB).HasPermission(models.Role(role), perms...) {
+			return c.Redirect("/admin?error=Akses ditolak")
+		}
+		return c.Next()
+	}
+}
+
 // GetAdminFromSession - Helper untuk mendapatkan admin user dari session
 func GetAdminFromSession(c *fiber.Ctx) (*models.User, error) {
 	adminID := c.Locals(SessionAdminIDKey)
@@ -65,6 +86,10 @@ func GetAdminFromSession(c *fiber.Ctx) (*models.User, error) {
 	if err := config.DB.First(&user, "id = ?", adminID).Error; err != nil {
 		return nil, err
 	}
+	// Admin yang dinonaktifkan kehilangan session panel yang masih tersimpan
+	if !user.CanLogin() {
+		return nil, fiber.ErrUnauthorized
+	}
 
 	return &user, nil
 }
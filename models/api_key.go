@@ -0,0 +1,66 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey - kredensial integrasi mesin (skrip impor surat, penarik laporan).
+// Request dengan API key bertindak sebagai Owner, tetapi permission-nya
+// dibatasi ke Scopes. Hanya hash key yang disimpan; Prefix ditampilkan agar
+// key bisa dikenali. Key yang dicabut tidak dihapus supaya riwayat surat
+// tetap bisa menunjuk ke key tersebut.
+type APIKey struct {
+	gorm.Model
+	Name        string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix      string     `gorm:"type:varchar(16);not null" json:"prefix"`
+	KeyHash     string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	OwnerID     uint       `gorm:"not null;index" json:"owner_id"`
+	Scopes      string     `gorm:"type:text;not null" json:"-"` // Kode permission dipisah spasi
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `gorm:"type:varchar(45)" json:"last_used_ip,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedByID *uint      `json:"created_by_id"`
+
+	Owner User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList - daftar permission key. Tidak pernah nil, sehingga key tanpa
+// scope tetap dibatasi (tidak punya permission apa pun).
+func (k APIKey) ScopeList() []string {
+	scopes := strings.Fields(k.Scopes)
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return scopes
+}
+
+// Usable - key belum dicabut dan belum kedaluwarsa
+func (k APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// ScopedPermissions menyaring perms ke yang ada di scopes. scopes nil berarti
+// request tanpa API key (tidak dibatasi).
+func ScopedPermissions(scopes []string, perms []string) []string {
+	if scopes == nil {
+		return perms
+	}
+	var allowed []string
+	for _, perm := range perms {
+		for _, scope := range scopes {
+			if perm == scope {
+				allowed = append(allowed, perm)
+				break
+			}
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// AuthEventType - jenis kejadian autentikasi yang dicatat di auth_events
+type AuthEventType string
+
+const (
+	AuthEventLoginSuccess           AuthEventType = "login_success"
+	AuthEventLoginFailure           AuthEventType = "login_failure"
+	AuthEventLoginLocked            AuthEventType = "login_locked"
+	AuthEventTokenRefresh           AuthEventType = "token_refresh"
+	AuthEventLogout                 AuthEventType = "logout"
+	AuthEventPasswordResetRequested AuthEventType = "password_reset_requested"
+	AuthEventPasswordResetCompleted AuthEventType = "password_reset_completed"
+	AuthEventPasswordChanged        AuthEventType = "password_changed"
+	AuthEventAccountUnlocked        AuthEventType = "account_unlocked"
+	AuthEventRoleSynced             AuthEventType = "role_synced" // Role diganti mengikuti grup direktori saat login SSO
+	AuthEventAPIKeyCreated          AuthEventType = "api_key_created"
+	AuthEventAPIKeyRevoked          AuthEventType = "api_key_revoked"
+	AuthEventUserDeactivated        AuthEventType = "user_deactivated"
+	AuthEventUserActivated          AuthEventType = "user_activated"
+	AuthEventIdentityLinked         AuthEventType = "identity_linked"
+	AuthEventIdentityUnlinked       AuthEventType = "identity_unlinked"
+	AuthEventTwoFactorTokenIssued   AuthEventType = "two_factor_token_issued" // Admin membuat token enrolment 2FA
+)
+
+// AuthEventTypes - urutan untuk filter di panel admin
+var AuthEventTypes = []AuthEventType{
+	AuthEventLoginSuccess,
+	AuthEventLoginFailure,
+	AuthEventLoginLocked,
+	AuthEventTokenRefresh,
+	AuthEventLogout,
+	AuthEventPasswordResetRequested,
+	AuthEventPasswordResetCompleted,
+	AuthEventPasswordChanged,
+	AuthEventAccountUnlocked,
+	AuthEventRoleSynced,
+	AuthEventAPIKeyCreated,
+	AuthEventAPIKeyRevoked,
+	AuthEventUserDeactivated,
+	AuthEventUserActivated,
+	AuthEventIdentityLinked,
+	AuthEventIdentityUnlinked,
+	AuthEventTwoFactorTokenIssued,
+}
+
+// AuthEvent adalah log audit autentikasi. Hanya ditambah, tidak pernah diubah,
+// jadi tidak memakai gorm.Model. UserID kosong jika email yang dicoba tidak
+// terdaftar; Email tetap disimpan agar percobaan tersebut bisa dilacak.
+type AuthEvent struct {
+	ID        uint          `gorm:"primarykey"`
+	CreatedAt time.Time     `gorm:"index"`
+	UserID    *uint         `gorm:"index"`
+	Email     string        `gorm:"type:varchar(255);index"`
+	Event     AuthEventType `gorm:"type:varchar(40);not null;index"`
+	IP        string        `gorm:"type:varchar(64)"`
+	UserAgent string        `gorm:"type:varchar(255)"`
+	Detail    string        `gorm:"type:varchar(255)"`
+
+	User *User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+}
+
+func (AuthEvent) TableName() string {
+	return "auth_events"
+}
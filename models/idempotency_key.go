@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyKey menyimpan hasil request mutasi yang dikirim dengan header
+// Idempotency-Key, agar request ulang (misal dari antrean offline aplikasi
+// mobile) mendapat response yang sama tanpa dieksekusi dua kali.
+type IdempotencyKey struct {
+	gorm.Model
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_idempotency_user_key"`
+	Key         string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_user_key"`
+	Method      string    `gorm:"type:varchar(10);not null"`
+	Path        string    `gorm:"type:varchar(255);not null"`
+	RequestHash string    `gorm:"type:varchar(64);not null"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+
+	// Diisi setelah request selesai; CompletedAt nil berarti masih diproses
+	CompletedAt  *time.Time
+	StatusCode   int
+	ContentType  string `gorm:"type:varchar(100)"`
+	ResponseBody []byte `gorm:"type:longblob"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+func (k IdempotencyKey) IsExpired(reference time.Time) bool {
+	return !reference.Before(k.ExpiresAt)
+}
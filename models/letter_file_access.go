@@ -0,0 +1,28 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	FileVariantCurrent  = "current"  // FilePath: versi terbaru (distempel / ditandatangani)
+	FileVariantOriginal = "original" // OriginalFilePath: file asli hasil upload
+)
+
+const (
+	FileAccessRedirect = "redirect"
+	FileAccessStream   = "stream"
+)
+
+// LetterFileAccess mencatat setiap akses file surat lewat endpoint proxy
+type LetterFileAccess struct {
+	gorm.Model
+	LetterID  uint   `json:"letter_id" gorm:"not null;index"`
+	UserID    uint   `json:"user_id" gorm:"not null;index"`
+	Variant   string `json:"variant" gorm:"type:varchar(20);not null"`
+	Mode      string `json:"mode" gorm:"type:varchar(10);not null"`
+	IPAddress string `json:"ip_address" gorm:"type:varchar(45)"`
+	UserAgent string `json:"user_agent" gorm:"type:varchar(255)"`
+}
+
+func (LetterFileAccess) TableName() string {
+	return "letter_file_access_logs"
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Aksi workflow yang dicatat di riwayat surat
+const (
+	LetterActionCreated             = "created"
+	LetterActionSubmitted           = "submitted"
+	LetterActionUpdated             = "updated"
+	LetterActionVerified            = "verified"
+	LetterActionVerificationReject  = "verification_rejected"
+	LetterActionApproved            = "approved"
+	LetterActionRejected            = "rejected"
+	LetterActionDisposed            = "disposed"
+	LetterActionArchived            = "archived"
+	LetterActionDeleted             = "deleted"
+	LetterActionVerificationRevoked = "verification_revoked"
+	LetterActionVerifierReassigned  = "verifier_reassigned" // Verifikator dinonaktifkan, surat dialihkan admin
+)
+
+// LetterHistory mencatat setiap aksi workflow surat beserta role yang dipakai
+// pelaku saat itu (role aktif di token), sehingga aksi seorang Manajer PKL
+// sebagai Plt. Direktur tetap terbaca sebagai aksi Direktur. Hanya ditambah,
+// tidak pernah diubah.
+type LetterHistory struct {
+	ID         uint         `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LetterID   uint         `json:"letter_id" gorm:"not null;index"`
+	Action     string       `json:"action" gorm:"type:varchar(30);not null"`
+	FromStatus LetterStatus `json:"from_status" gorm:"type:varchar(30)"`
+	ToStatus   LetterStatus `json:"to_status" gorm:"type:varchar(30)"`
+	ActorID    uint         `json:"actor_id" gorm:"not null;index"`
+	ActorRole  Role         `json:"actor_role" gorm:"type:varchar(50);not null"`
+	ActorLabel string       `json:"actor_label" gorm:"type:varchar(100)"` // Label penugasan, misal "Plt. Direktur"
+	Catatan    string       `json:"catatan" gorm:"type:text"`
+	APIKeyID   *uint        `json:"api_key_id" gorm:"index"` // Diisi jika aksi dilakukan lewat API key milik actor
+
+	Actor  *User   `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+	APIKey *APIKey `json:"-" gorm:"foreignKey:APIKeyID"`
+}
+
+func (LetterHistory) TableName() string {
+	return "letter_histories"
+}
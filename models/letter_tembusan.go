@@ -0,0 +1,26 @@
+package models
+
+import "gorm.io/gorm"
+
+// LetterTembusan adalah penerima tembusan (carbon copy) surat. Setiap baris
+// berisi tepat satu dari: user internal, unit internal (role) atau nama pihak
+// eksternal yang hanya dicetak di daftar tembusan.
+type LetterTembusan struct {
+	gorm.Model
+	LetterID      uint   `json:"letter_id" gorm:"not null;index"`
+	UserID        *uint  `json:"user_id,omitempty" gorm:"index"`
+	User          *User  `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Role          *Role  `json:"role,omitempty" gorm:"type:varchar(50);index"` // Unit internal
+	NamaEksternal string `json:"nama_eksternal,omitempty" gorm:"type:varchar(200)"`
+	Urutan        int    `json:"urutan" gorm:"not null;default:0"`
+
+	Letter *Letter `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (LetterTembusan) TableName() string {
+	return "surat_tembusan"
+}
+
+func (t LetterTembusan) IsInternal() bool {
+	return t.UserID != nil || t.Role != nil
+}
@@ -0,0 +1,23 @@
+package models
+
+import "gorm.io/gorm"
+
+// LetterTemplate adalah template surat keluar yang dikelola Admin. Body dan
+// SignatureBlock boleh berisi placeholder {{nama_field}} yang diisi dari data
+// surat saat PDF dibuat di server.
+type LetterTemplate struct {
+	gorm.Model
+	Nama           string `json:"nama" gorm:"type:varchar(150);not null;uniqueIndex"`
+	Deskripsi      string `json:"deskripsi" gorm:"type:text"`
+	KopSurat       string `json:"kop_surat" gorm:"type:text"` // Baris pertama dicetak tebal (nama organisasi)
+	Body           string `json:"body" gorm:"type:longtext"`
+	SignatureBlock string `json:"signature_block" gorm:"type:text"`
+	IsActive       bool   `json:"is_active" gorm:"not null;default:true;index"`
+
+	CreatedByID uint  `json:"created_by_id" gorm:"not null;index"`
+	CreatedBy   *User `json:"created_by,omitempty" gorm:"foreignKey:CreatedByID"`
+}
+
+func (LetterTemplate) TableName() string {
+	return "letter_templates"
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LetterVerification menyimpan token verifikasi publik (QR code) untuk surat
+// keluar yang sudah disetujui. Token asli hanya ada di QR code pada PDF,
+// database hanya menyimpan hash-nya.
+type LetterVerification struct {
+	gorm.Model
+	LetterID  uint   `json:"letter_id" gorm:"not null;index"`
+	TokenHash string `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`
+	FileHash  string `json:"file_hash" gorm:"type:varchar(64);not null"` // SHA-256 file final yang disetujui
+
+	RevokedAt        *time.Time `json:"revoked_at,omitempty" gorm:"type:datetime"`
+	RevokedByID      *uint      `json:"revoked_by_id,omitempty" gorm:"index"`
+	RevokedBy        *User      `json:"revoked_by,omitempty" gorm:"foreignKey:RevokedByID"`
+	RevocationReason string     `json:"revocation_reason,omitempty" gorm:"type:text"`
+
+	Letter *Letter `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (LetterVerification) TableName() string {
+	return "letter_verifications"
+}
+
+func (v LetterVerification) IsRevoked() bool {
+	return v.RevokedAt != nil
+}
@@ -47,12 +47,16 @@ type Letter struct {
 	Disposisi        string     `gorm:"type:text"`
 	TanggalDisposisi *time.Time `gorm:"type:datetime"`
 	BidangTujuan     string     `gorm:"type:varchar(150);index"`
+	TujuanUnitID     *uint      `gorm:"index"` // Unit tujuan disposisi, BidangTujuan berisi namanya
+	TujuanUnit       *Unit      `gorm:"foreignKey:TujuanUnitID"`
 
 	JenisSurat LetterType `gorm:"type:enum('masuk','keluar','internal');not null;index"`
 	Prioritas  Priority   `gorm:"type:enum('biasa','segera','penting');default:'biasa';not null;index"`
 
 	// NEW FIELDS
 	Scope              string `json:"scope" gorm:"type:enum('Internal','Eksternal');not null;index"`
+	UnitID             *uint  `json:"unit_id" gorm:"index"` // Unit pembuat surat (unit user pembuat saat surat dibuat)
+	Unit               *Unit  `json:"unit,omitempty" gorm:"foreignKey:UnitID"`
 	AssignedVerifierID *uint  `json:"assigned_verifier_id" gorm:"index"`
 	AssignedVerifier   *User  `json:"assigned_verifier,omitempty" gorm:"foreignKey:AssignedVerifierID"`
 
@@ -63,8 +67,21 @@ type Letter struct {
 	Kesimpulan   string     `gorm:"type:text"`
 	FilePath     string     `gorm:"type:varchar(255)"`
 
+	// File asli hasil upload sebelum diproses (distempel agenda / ditandatangani).
+	// FilePath selalu menunjuk ke versi terbaru yang dipakai sebagai default.
+	// Keduanya berisi key S3 dan diakses klien lewat GET /letters/:id/file.
+	OriginalFilePath string `json:"-" gorm:"type:varchar(255)"`
+
+	// Surat yang dibuat dari template: PDF dibuat ulang di server setiap revisi
+	TemplateID *uint           `json:"template_id,omitempty" gorm:"index"`
+	Template   *LetterTemplate `json:"template,omitempty" gorm:"foreignKey:TemplateID"`
+	Penerima   string          `json:"penerima,omitempty" gorm:"type:text"` // Tujuan surat (Kepada Yth.)
+
 	Status LetterStatus `gorm:"type:enum('draft','perlu_verifikasi','belum_disposisi','sudah_disposisi','perlu_persetujuan','perlu_revisi','disetujui','diarsipkan');default:'draft';not null;index"`
 
+	// Naik setiap kali surat diubah, dipakai untuk ETag / If-Match (optimistic locking)
+	Version uint `json:"version" gorm:"not null;default:1"`
+
 	CreatedByID  uint  `gorm:"not null;index"`
 	CreatedBy    *User `gorm:"foreignkey:CreatedByID"`
 	VerifiedByID *uint `gorm:"index"`
@@ -72,6 +89,17 @@ type Letter struct {
 	DisposedByID *uint `gorm:"index"`
 	DisposedBy   *User `gorm:"foreignkey:DisposedByID"`
 
+	// Tanda Tangan Digital (diisi saat Direktur menyetujui surat keluar)
+	SignedAt             *time.Time          `json:"signed_at,omitempty" gorm:"type:datetime"`
+	SignedByID           *uint               `json:"signed_by_id,omitempty" gorm:"index"`
+	SignedBy             *User               `json:"signed_by,omitempty" gorm:"foreignKey:SignedByID"`
+	SignatureCertSubject string              `json:"signature_cert_subject,omitempty" gorm:"type:varchar(255)"`
+	SignatureFingerprint string              `json:"signature_fingerprint,omitempty" gorm:"type:varchar(64)"` // SHA-256 sertifikat organisasi
+	Verification         *LetterVerification `json:"verification,omitempty" gorm:"foreignKey:LetterID"`
+
+	// Tembusan (CC) internal & eksternal
+	Tembusan []LetterTembusan `json:"tembusan,omitempty" gorm:"foreignKey:LetterID"`
+
 	// Reply Linking Fields
 	NeedsReply  bool     `gorm:"default:false;index" json:"needs_reply"` // Flag: surat masuk ini butuh balasan?
 	InReplyToID *uint    `gorm:"index" json:"in_reply_to_id,omitempty"`  // FK: surat ini adalah balasan dari surat mana?
@@ -81,9 +109,31 @@ type Letter struct {
 
 func (Letter) TableName() string { return "surat" }
 
+// BeforeCreate mengisi Version di struct, karena default kolom tidak dibaca
+// ulang oleh MySQL setelah insert
+func (l *Letter) BeforeCreate(tx *gorm.DB) error {
+	if l.Version == 0 {
+		l.Version = 1
+	}
+	return nil
+}
+
 func (l *Letter) IsSuratKeluar() bool { return l.JenisSurat == LetterKeluar }
 func (l *Letter) IsSuratMasuk() bool  { return l.JenisSurat == LetterMasuk }
 
+// FileKey mengembalikan key S3 untuk varian file (lihat FileVariant*).
+// String kosong jika surat tidak punya file untuk varian tersebut.
+func (l *Letter) FileKey(variant string) string {
+	switch variant {
+	case FileVariantCurrent:
+		return l.FilePath
+	case FileVariantOriginal:
+		return l.OriginalFilePath
+	default:
+		return ""
+	}
+}
+
 func (l *Letter) CanTransitionTo(newStatus LetterStatus) bool {
 	validTransitions := map[LetterStatus][]LetterStatus{
 		StatusDraft:            {StatusPerluVerifikasi, StatusBelumDisposisi},
@@ -107,9 +157,47 @@ func (l *Letter) CanTransitionTo(newStatus LetterStatus) bool {
 	return false
 }
 
+// IsVisibleToTembusan menentukan kapan penerima tembusan internal boleh membaca
+// surat: surat keluar setelah final (disetujui/diarsipkan), surat masuk setelah
+// dicatat (bukan draft).
+func (l *Letter) IsVisibleToTembusan() bool {
+	if l.IsSuratMasuk() {
+		return l.Status != StatusDraft
+	}
+	return l.Status == StatusDisetujui || l.Status == StatusDiarsipkan
+}
+
 func (l *Letter) Validate() error {
 	if l.IsSuratKeluar() && l.AssignedVerifierID == nil {
 		return errors.New("surat keluar harus memiliki assigned verifier")
 	}
 	return nil
 }
+
+func (t LetterType) IsValid() bool {
+	switch t {
+	case LetterMasuk, LetterKeluar, LetterInternal:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p Priority) IsValid() bool {
+	switch p {
+	case PriorityBiasa, PrioritySegera, PriorityPenting:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s LetterStatus) IsValid() bool {
+	switch s {
+	case StatusDraft, StatusPerluVerifikasi, StatusBelumDisposisi, StatusSudahDisposisi,
+		StatusPerluPersetujuan, StatusPerluRevisi, StatusDisetujui, StatusDiarsipkan:
+		return true
+	default:
+		return false
+	}
+}
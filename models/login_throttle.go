@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// LoginThrottleScope - counter kegagalan login dihitung per akun dan per IP
+type LoginThrottleScope string
+
+const (
+	LoginThrottleAccount LoginThrottleScope = "account"
+	LoginThrottleIP      LoginThrottleScope = "ip"
+)
+
+// LoginThrottle mencatat kegagalan login beruntun untuk satu akun (email,
+// huruf kecil) atau satu alamat IP. LockCount dipakai untuk memperpanjang
+// penguncian berikutnya dan baru direset setelah login berhasil.
+type LoginThrottle struct {
+	ID            uint               `gorm:"primarykey"`
+	Scope         LoginThrottleScope `gorm:"type:varchar(10);not null;uniqueIndex:idx_login_throttle_key"`
+	Key           string             `gorm:"column:throttle_key;type:varchar(255);not null;uniqueIndex:idx_login_throttle_key"`
+	Failures      int                `gorm:"not null;default:0"`
+	LockCount     int                `gorm:"not null;default:0"`
+	LastFailureAt *time.Time
+	LockedUntil   *time.Time
+	UpdatedAt     time.Time
+}
+
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
+
+// Locked - masih dalam masa penguncian pada waktu reference
+func (t LoginThrottle) Locked(reference time.Time) bool {
+	return t.LockedUntil != nil && reference.Before(*t.LockedUntil)
+}
@@ -0,0 +1,20 @@
+package models
+
+import "gorm.io/gorm"
+
+// Notification adalah salinan notifikasi push yang disimpan agar aplikasi
+// mobile bisa mengambilnya lewat sinkronisasi (misal saat offline). Target
+// sama dengan topic FCM: satu user (UserID) atau semua user dengan Role.
+type Notification struct {
+	gorm.Model
+	UserID   *uint  `json:"user_id,omitempty" gorm:"index"`
+	Role     *Role  `json:"role,omitempty" gorm:"type:varchar(50);index"`
+	LetterID *uint  `json:"letter_id,omitempty" gorm:"index"`
+	Title    string `json:"title" gorm:"type:varchar(255);not null"`
+	Body     string `json:"body" gorm:"type:text"`
+	Status   string `json:"status" gorm:"type:varchar(50)"` // Status surat saat notifikasi dikirim
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// PasswordHistory menyimpan hash password yang pernah dipakai user agar
+// password lama tidak dipakai ulang (lihat PASSWORD_HISTORY). Baris terbaru
+// sama dengan password saat ini; baris lama dipangkas saat password diganti.
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uint      `gorm:"not null;index"`
+	PasswordHash string    `gorm:"type:varchar(255);not null"`
+	CreatedAt    time.Time `gorm:"index"`
+
+	User User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
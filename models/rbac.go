@@ -0,0 +1,138 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// RoleDefinition adalah data role di tabel roles. Kolom users.role menyimpan
+// Name, sehingga role baru bisa ditambahkan admin tanpa migrasi skema.
+type RoleDefinition struct {
+	gorm.Model
+	Name        Role   `json:"name" gorm:"type:varchar(50);not null;uniqueIndex"`
+	DisplayName string `json:"display_name" gorm:"type:varchar(100);not null"`
+	Description string `json:"description" gorm:"type:text"`
+	IsSystem    bool   `json:"is_system" gorm:"not null;default:false"` // Role bawaan: tidak bisa dihapus
+
+	RequireTwoFactor bool `json:"require_two_factor" gorm:"not null;default:false"` // User dengan role ini wajib memakai 2FA
+
+	Permissions []Permission `json:"permissions,omitempty" gorm:"many2many:role_permissions;joinForeignKey:RoleID;joinReferences:PermissionID"`
+}
+
+func (RoleDefinition) TableName() string {
+	return "roles"
+}
+
+// Permission adalah satu izin granular, misal "letter.keluar.create:eksternal".
+// Bagian setelah ':' (jika ada) membatasi izin ke scope tertentu.
+type Permission struct {
+	gorm.Model
+	Code        string `json:"code" gorm:"type:varchar(100);not null;uniqueIndex"`
+	Description string `json:"description" gorm:"type:varchar(255)"`
+}
+
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+// Kode permission yang dicek oleh aplikasi
+const (
+	PermLetterKeluarCreateInternal  = "letter.keluar.create:internal"
+	PermLetterKeluarCreateEksternal = "letter.keluar.create:eksternal"
+	PermLetterMasukCreate           = "letter.masuk.create"
+	PermLetterVerifyInternal        = "letter.verify:internal"
+	PermLetterVerifyEksternal       = "letter.verify:eksternal"
+	PermLetterApprove               = "letter.approve"
+	PermLetterDispose               = "letter.dispose"
+	PermLetterArchiveAll            = "letter.archive:all"
+	PermLetterViewAll               = "letter.view:all"
+	PermLetterViewEksternal         = "letter.view:eksternal"
+	PermLetterMasukView             = "letter.masuk.view"
+	PermLetterDeleteAll             = "letter.delete:all"
+	PermLetterVerificationRevoke    = "letter.verification.revoke"
+
+	PermAdminPanel     = "admin.panel"
+	PermAdminUsers     = "admin.users.manage"
+	PermAdminUnits     = "admin.units.manage"
+	PermAdminTemplates = "admin.templates.manage"
+	PermAdminRoles     = "admin.roles.manage"
+	PermAdminAPIKeys   = "admin.api_keys.manage"
+)
+
+// AdminPermissions - permission yang memberi akses administrasi aplikasi
+var AdminPermissions = []string{
+	PermAdminPanel, PermAdminUsers, PermAdminUnits, PermAdminTemplates, PermAdminRoles, PermAdminAPIKeys,
+}
+
+// DefaultPermissions adalah katalog permission yang di-seed ke tabel permissions
+var DefaultPermissions = []Permission{
+	{Code: PermLetterKeluarCreateInternal, Description: "Membuat & mengelola surat keluar internal miliknya"},
+	{Code: PermLetterKeluarCreateEksternal, Description: "Membuat & mengelola surat keluar eksternal miliknya"},
+	{Code: PermLetterMasukCreate, Description: "Mencatat & mengelola surat masuk"},
+	{Code: PermLetterVerifyInternal, Description: "Memverifikasi surat keluar internal"},
+	{Code: PermLetterVerifyEksternal, Description: "Memverifikasi surat keluar eksternal"},
+	{Code: PermLetterApprove, Description: "Menyetujui / menolak surat keluar (tanda tangan)"},
+	{Code: PermLetterDispose, Description: "Mendisposisikan surat masuk"},
+	{Code: PermLetterArchiveAll, Description: "Mengarsipkan surat milik siapa pun (arsiparis)"},
+	{Code: PermLetterViewAll, Description: "Melihat semua surat"},
+	{Code: PermLetterViewEksternal, Description: "Melihat semua surat keluar eksternal"},
+	{Code: PermLetterMasukView, Description: "Melihat semua surat masuk (untuk dibalas)"},
+	{Code: PermLetterDeleteAll, Description: "Menghapus surat milik siapa pun"},
+	{Code: PermLetterVerificationRevoke, Description: "Mencabut verifikasi QR surat keluar"},
+	{Code: PermAdminPanel, Description: "Login ke panel admin web"},
+	{Code: PermAdminUsers, Description: "Mengelola user"},
+	{Code: PermAdminUnits, Description: "Mengelola unit / bidang"},
+	{Code: PermAdminTemplates, Description: "Mengelola template surat"},
+	{Code: PermAdminRoles, Description: "Mengelola role & permission"},
+	{Code: PermAdminAPIKeys, Description: "Mengelola API key integrasi"},
+}
+
+// DefaultRoles adalah delapan role bawaan beserta permission awalnya. Dipakai
+// saat seeding dan sebagai fallback selama tabel roles belum di-seed, sehingga
+// perilakunya sama dengan pengecekan role lama.
+var DefaultRoles = []struct {
+	Name        Role
+	DisplayName string
+	Permissions []string
+}{
+	{RoleAdmin, "Admin", []string{
+		PermLetterViewAll, PermLetterDeleteAll, PermLetterVerificationRevoke,
+		PermAdminPanel, PermAdminUsers, PermAdminUnits, PermAdminTemplates, PermAdminRoles, PermAdminAPIKeys,
+	}},
+	{RoleDirektur, "Direktur", []string{
+		PermLetterViewAll, PermLetterApprove, PermLetterDispose, PermLetterVerificationRevoke,
+	}},
+	{RolePengurus, "Pengurus (Board)", []string{PermLetterViewAll}},
+	{RoleManajerKPP, "Manajer KPP", []string{PermLetterVerifyEksternal}},
+	{RoleManajerPemas, "Manajer Pemas", []string{PermLetterVerifyEksternal}},
+	{RoleManajerPKL, "Manajer PKL", []string{PermLetterVerifyInternal}},
+	{RoleStafProgram, "Staf Program", []string{
+		PermLetterKeluarCreateEksternal, PermLetterViewEksternal, PermLetterMasukView,
+	}},
+	{RoleStafLembaga, "Staf Lembaga", []string{
+		PermLetterKeluarCreateInternal, PermLetterMasukCreate, PermLetterArchiveAll, PermLetterViewAll,
+	}},
+}
+
+// KeluarCreatePermission - permission membuat surat keluar dengan scope tertentu
+func KeluarCreatePermission(scope string) string {
+	return scopedPermission(scope, PermLetterKeluarCreateInternal, PermLetterKeluarCreateEksternal)
+}
+
+// VerifyPermission - permission memverifikasi surat keluar dengan scope tertentu
+func VerifyPermission(scope string) string {
+	return scopedPermission(scope, PermLetterVerifyInternal, PermLetterVerifyEksternal)
+}
+
+// scopedPermission memilih permission sesuai scope surat; scope tidak dikenal
+// menghasilkan string kosong yang tidak dimiliki role mana pun
+func scopedPermission(scope, internal, eksternal string) string {
+	switch {
+	case strings.EqualFold(scope, ScopeInternal):
+		return internal
+	case strings.EqualFold(scope, ScopeEksternal):
+		return eksternal
+	}
+	return ""
+}
@@ -6,12 +6,17 @@ import (
 	"gorm.io/gorm"
 )
 
+// RefreshToken - yang disimpan hanya hash SHA-256 token. Setiap refresh token
+// milik satu UserSession dan dirotasi setiap kali dipakai; SessionID kosong
+// hanya untuk token lama sebelum sesi dicatat.
 type RefreshToken struct {
 	gorm.Model
-	Token     string    `gorm:"type:varchar(512);uniqueIndex;not null"`
-	UserID    uint      `gorm:"not null;index"`
-	User      User      `gorm:"constraint:OnDelete:CASCADE;"`
-	ExpiresAt time.Time `gorm:"not null;index"`
+	TokenHash string       `gorm:"type:varchar(64);uniqueIndex;not null"`
+	UserID    uint         `gorm:"not null;index"`
+	User      User         `gorm:"constraint:OnDelete:CASCADE;"`
+	SessionID *uint        `gorm:"index"`
+	Session   *UserSession `gorm:"constraint:OnDelete:CASCADE;"`
+	ExpiresAt time.Time    `gorm:"not null;index"`
 }
 
 func (RefreshToken) TableName() string {
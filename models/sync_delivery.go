@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SyncLetterDelivery mencatat surat yang sudah dikirim ke cache aplikasi user
+// lewat /sync. Jika user kehilangan akses ke surat tersebut (dialihkan, pindah
+// unit, tembusan dicabut, dll), RevokedAt diisi dan sync berikutnya mengirim
+// tombstone agar surat dibuang dari cache.
+type SyncLetterDelivery struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"not null;uniqueIndex:idx_sync_delivery_user_letter"`
+	LetterID  uint       `gorm:"not null;uniqueIndex:idx_sync_delivery_user_letter"`
+	RevokedAt *time.Time `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (SyncLetterDelivery) TableName() string {
+	return "sync_letter_deliveries"
+}
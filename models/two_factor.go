@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserTwoFactor menyimpan secret TOTP user. Selama EnabledAt masih nil,
+// enrolment belum dikonfirmasi dengan kode dari aplikasi authenticator dan
+// login belum meminta kode.
+type UserTwoFactor struct {
+	gorm.Model
+	UserID       uint   `gorm:"not null;uniqueIndex"`
+	Secret       string `gorm:"type:varchar(64);not null" json:"-"`
+	EnabledAt    *time.Time
+	LastUsedStep int64 `gorm:"not null;default:0"` // Time step kode terakhir, kode yang sama tidak bisa dipakai ulang
+
+	User User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (UserTwoFactor) TableName() string {
+	return "user_two_factors"
+}
+
+func (t UserTwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
+
+// UserRecoveryCode - kode cadangan sekali pakai jika perangkat authenticator
+// hilang. Yang disimpan hanya hash-nya.
+type UserRecoveryCode struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index"`
+	CodeHash string `gorm:"type:varchar(64);not null;index"`
+	UsedAt   *time.Time
+
+	User User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+// TwoFactorEnrolmentToken - token sekali pakai dari admin agar user yang role-nya
+// mewajibkan 2FA bisa mendaftarkan authenticator saat login. Password saja
+// tidak cukup untuk enrolment pertama. Yang disimpan hanya hash-nya.
+type TwoFactorEnrolmentToken struct {
+	gorm.Model
+	UserID     uint      `gorm:"not null;index"`
+	TokenHash  string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	IssuedByID *uint
+	UsedAt     *time.Time
+
+	User User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (TwoFactorEnrolmentToken) TableName() string {
+	return "two_factor_enrolment_tokens"
+}
@@ -0,0 +1,25 @@
+package models
+
+import "gorm.io/gorm"
+
+// Unit adalah bidang / unit kerja organisasi. Unit bisa bertingkat (ParentID)
+// dan punya satu kepala unit. Anggota unit adalah user dengan UnitID = ID unit.
+type Unit struct {
+	gorm.Model
+	Kode     string `json:"kode" gorm:"type:varchar(20);not null;uniqueIndex"` // Dipakai di penomoran surat, misal "KPP"
+	Nama     string `json:"nama" gorm:"type:varchar(150);not null"`
+	ParentID *uint  `json:"parent_id" gorm:"index"`
+	Parent   *Unit  `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
+	IsActive bool   `json:"is_active" gorm:"not null;default:true;index"`
+
+	// Kepala unit. Tanpa FK constraint karena users juga menunjuk ke units
+	// (siklus akan membuat AutoMigrate gagal di database kosong)
+	HeadUserID *uint `json:"head_user_id" gorm:"index"`
+	HeadUser   *User `json:"head_user,omitempty" gorm:"foreignKey:HeadUserID;constraint:-"`
+
+	Members []User `json:"members,omitempty" gorm:"foreignKey:UnitID"`
+}
+
+func (Unit) TableName() string {
+	return "units"
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Provider autentikasi eksternal
+const (
+	IdentityProviderLDAP = "ldap"
+	IdentityProviderOIDC = "oidc"
+)
+
+// UserIdentity menautkan user ke akun di direktori / identity provider.
+// Subject adalah DN (LDAP) atau klaim sub (OIDC). User yang tertaut ke LDAP
+// hanya bisa login dengan password direktori.
+type UserIdentity struct {
+	gorm.Model
+	UserID      uint   `gorm:"not null;index"`
+	Provider    string `gorm:"type:varchar(20);not null;uniqueIndex:idx_user_identity_subject"`
+	Subject     string `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identity_subject"`
+	LastLoginAt *time.Time
+
+	User User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// SSOLoginState - permintaan login OIDC yang sedang berjalan. Dibuat saat
+// user diarahkan ke identity provider dan dihapus saat callback diproses.
+// LinkUserID terisi jika permintaan dimulai user yang sudah login untuk
+// menautkan akun provider ke akunnya, bukan untuk login.
+type SSOLoginState struct {
+	ID           uint `gorm:"primarykey"`
+	CreatedAt    time.Time
+	StateHash    string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Nonce        string `gorm:"type:varchar(64);not null"`
+	CodeVerifier string `gorm:"type:varchar(64);not null"`
+	RedirectURL  string `gorm:"type:varchar(255);not null"`
+	LinkUserID   *uint
+	ExpiresAt    time.Time `gorm:"not null;index"`
+}
+
+func (SSOLoginState) TableName() string {
+	return "sso_login_states"
+}
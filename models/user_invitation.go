@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvitationExpired = errors.New("invitation expired")
+	ErrInvitationUsed    = errors.New("invitation already used")
+)
+
+// UserInvitation adalah link sekali pakai untuk user pending mengatur password
+// pertamanya. Sama seperti PasswordResetToken, yang disimpan hanya hash token.
+type UserInvitation struct {
+	gorm.Model
+	UserID      uint      `gorm:"not null;index"`
+	TokenHash   string    `gorm:"type:varchar(255);not null;uniqueIndex"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	InvitedByID *uint
+	Used        bool `gorm:"not null;default:false"`
+	UsedAt      *time.Time
+
+	User User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (UserInvitation) TableName() string {
+	return "user_invitations"
+}
+
+func (i UserInvitation) IsExpired(reference time.Time) bool {
+	if reference.IsZero() {
+		reference = time.Now()
+	}
+	return !reference.Before(i.ExpiresAt)
+}
+
+func (i UserInvitation) Validate(reference time.Time) error {
+	if i.Used {
+		return ErrInvitationUsed
+	}
+	if i.IsExpired(reference) {
+		return ErrInvitationExpired
+	}
+	return nil
+}
+
+// Consume menandai undangan terpakai. Update bersyarat agar dua request
+// bersamaan tidak sama-sama berhasil memakai undangan yang sama.
+func (i *UserInvitation) Consume(tx *gorm.DB, reference time.Time) error {
+	if reference.IsZero() {
+		reference = time.Now()
+	}
+	if err := i.Validate(reference); err != nil {
+		return err
+	}
+
+	usedAt := reference
+	res := tx.Model(&UserInvitation{}).
+		Where("id = ? AND used = ? AND expires_at > ?", i.ID, false, reference).
+		Updates(map[string]any{"used": true, "used_at": &usedAt})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvitationUsed
+	}
+
+	i.Used = true
+	i.UsedAt = &usedAt
+	return nil
+}
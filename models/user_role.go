@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserRoleAssignment memberi user role tambahan di luar role utamanya
+// (users.role), misal Manajer PKL yang sekaligus Plt. Direktur. ValidFrom /
+// ValidUntil kosong berarti penugasan tidak dibatasi waktu.
+type UserRoleAssignment struct {
+	gorm.Model
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	Role         Role       `json:"role" gorm:"type:varchar(50);not null;index"`
+	Label        string     `json:"label" gorm:"type:varchar(100)"` // Misal "Plt. Direktur" / "Plh. Direktur"
+	ValidFrom    *time.Time `json:"valid_from"`
+	ValidUntil   *time.Time `json:"valid_until"`
+	AssignedByID *uint      `json:"assigned_by_id"`
+}
+
+func (UserRoleAssignment) TableName() string {
+	return "user_roles"
+}
+
+// ActiveAt - penugasan berlaku pada waktu t
+func (a UserRoleAssignment) ActiveAt(t time.Time) bool {
+	if a.ValidFrom != nil && t.Before(*a.ValidFrom) {
+		return false
+	}
+	if a.ValidUntil != nil && !t.Before(*a.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+// ActingRole adalah role yang bisa dipakai user saat ini: role utamanya atau
+// penugasan yang sedang berlaku. Role yang dipilih dibawa token (klaim role).
+type ActingRole struct {
+	Role       Role       `json:"role"`
+	Label      string     `json:"label,omitempty"`
+	Primary    bool       `json:"primary"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserSession - satu perangkat yang login lewat API. Sesi bertahan selama
+// refresh token-nya dirotasi dan berakhir saat logout, dicabut, atau refresh
+// token terakhirnya kedaluwarsa.
+type UserSession struct {
+	gorm.Model
+	UserID     uint      `gorm:"not null;index"`
+	DeviceName string    `gorm:"type:varchar(100)"`
+	Platform   string    `gorm:"type:varchar(50)"`
+	IP         string    `gorm:"type:varchar(64)"`
+	UserAgent  string    `gorm:"type:varchar(255)"`
+	LastUsedAt time.Time `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+
+	User User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
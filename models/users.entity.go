@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Role string
 
@@ -15,6 +19,18 @@ const (
 	RoleManajerPKL   Role = "manajer_pkl"
 )
 
+// UserStatus - status akun. User undangan berstatus pending sampai mengatur
+// password sendiri lewat link undangan; hanya user active yang bisa login.
+// User yang berhenti dinonaktifkan (inactive), tidak dihapus, agar namanya
+// tetap tampil di riwayat surat.
+type UserStatus string
+
+const (
+	UserStatusPending  UserStatus = "pending"
+	UserStatusActive   UserStatus = "active"
+	UserStatusInactive UserStatus = "inactive"
+)
+
 type User struct {
 	gorm.Model
 	Username     string `gorm:"type:varchar(100);uniqueIndex;not null" json:"username"`
@@ -22,43 +38,55 @@ type User struct {
 	LastName     string `gorm:"type:varchar(100)" json:"last_name"`
 	Email        string `gorm:"type:varchar(191);uniqueIndex;not null" json:"email"` // Email Boleh ditampilkan untuk kontak, tapi Password JANGAN
 	PasswordHash string `gorm:"type:varchar(255);not null" json:"-"`                 // [FIX] Hide PasswordHash
-	Role         Role   `gorm:"type:enum('admin','direktur','pengurus','staf_program','staf_lembaga','manajer_kpp','manajer_pemas','manajer_pkl');not null;index" json:"role"`
+	Role         Role   `gorm:"type:varchar(50);not null;index" json:"role"`         // Nama role di tabel roles
 	Jabatan      string `gorm:"type:varchar(150)" json:"jabatan"`
 	Atribut      string `gorm:"type:text" json:"atribut"`
-}
 
-func (User) TableName() string {
-	return "users"
-}
+	UnitID *uint `gorm:"index" json:"unit_id"`
+	Unit   *Unit `gorm:"foreignKey:UnitID" json:"unit,omitempty"`
 
-// --- Helper Methods ---
+	SignatureImagePath string `gorm:"type:varchar(255)" json:"-"` // Key S3 gambar tanda tangan (dipakai Direktur)
 
-func (u *User) IsStaf() bool {
-	return u.Role == RoleStafProgram || u.Role == RoleStafLembaga
-}
+	Status        UserStatus `gorm:"type:varchar(20);not null;default:'active';index" json:"status"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+
+	// PasswordChangedAt - waktu password terakhir diatur, dasar masa berlaku
+	// password (PASSWORD_MAX_AGE). Kosong untuk akun lama; dihitung dari CreatedAt.
+	PasswordChangedAt *time.Time `json:"-"`
+
+	// ProvisionedBy - provider SSO (ldap / oidc) yang membuat akun; kosong
+	// untuk akun yang dibuat lokal. Hanya role akun SSO yang mengikuti
+	// pemetaan grup direktori.
+	ProvisionedBy string `gorm:"type:varchar(20)" json:"-"`
 
-func (u *User) IsManajer() bool {
-	return u.Role == RoleManajerKPP || u.Role == RoleManajerPemas || u.Role == RoleManajerPKL
+	// TokenVersion dinaikkan untuk membatalkan semua access token user yang
+	// sudah terbit (role diganti, sesi dicabut, password direset)
+	TokenVersion uint `gorm:"not null;default:0" json:"-"`
+
+	// Scopes membatasi permission saat request memakai API key (lihat
+	// APIKey.ScopeList); nil untuk login biasa. Tidak disimpan.
+	Scopes []string `gorm:"-" json:"-"`
 }
 
-func (u *User) CanVerifyScope(scope string) bool {
-	if scope == "Eksternal" {
-		return u.Role == RoleManajerKPP || u.Role == RoleManajerPemas
-	}
-	if scope == "Internal" {
-		return u.Role == RoleManajerPKL
-	}
-	return false
+// CanLogin - hanya akun aktif yang boleh login / memperbarui token
+func (u User) CanLogin() bool {
+	return u.Status == UserStatusActive
 }
 
-func (u *User) IsDirektur() bool { return u.Role == RoleDirektur }
-func (u *User) IsAdmin() bool    { return u.Role == RoleAdmin }
+func (User) TableName() string {
+	return "users"
+}
 
+// IsValid hanya memeriksa format nama role (huruf kecil, angka, '_', maks. 50
+// karakter). Keberadaan role dicek ke tabel roles lewat RBACService.RoleExists.
 func (r Role) IsValid() bool {
-	switch r {
-	case RoleAdmin, RoleDirektur, RolePengurus, RoleStafProgram, RoleStafLembaga, RoleManajerKPP, RoleManajerPemas, RoleManajerPKL:
-		return true
-	default:
+	if r == "" || len(r) > 50 {
 		return false
 	}
+	for _, ch := range r {
+		if (ch < 'a' || ch > 'z') && (ch < '0' || ch > '9') && ch != '_' {
+			return false
+		}
+	}
+	return true
 }
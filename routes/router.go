@@ -3,6 +3,7 @@ package routes
 import (
 	"TugasAkhir/handlers"
 	"TugasAkhir/middleware"
+	"TugasAkhir/models"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -14,6 +15,15 @@ func SetupRoutes(app *fiber.App, db *gorm.DB) {
 	lkHandler := handlers.NewLetterKeluarHandler(db)
 	lmHandler := handlers.NewLetterMasukHandler(db)
 	commonHandler := handlers.NewLetterCommonHandler(db) //
+	verificationHandler := handlers.NewVerificationHandler(db)
+	searchHandler := handlers.NewSearchHandler(db)
+	syncHandler := handlers.NewSyncHandler(db)
+
+	// Verifikasi publik surat keluar (target QR code pada PDF)
+	app.Get("/verify/:token", verificationHandler.VerifyLetter)
+
+	// Public key penandatangan JWT untuk layanan internal lain
+	app.Get("/.well-known/jwks.json", handlers.JWKS)
 
 	api := app.Group("/api")
 
@@ -26,73 +36,171 @@ func SetupRoutes(app *fiber.App, db *gorm.DB) {
 	auth.Post("/forgot-password", handlers.RequestPasswordReset)
 	auth.Get("/reset-password", handlers.ShowResetPasswordForm)
 	auth.Post("/reset-password", handlers.ResetPassword)
+	auth.Get("/accept-invitation", handlers.ShowAcceptInvitationForm)
+	auth.Post("/accept-invitation", handlers.AcceptInvitation)
+	// Langkah kedua login untuk akun dengan 2FA (pakai challenge token dari /login)
+	auth.Post("/2fa/setup", handlers.SetupTwoFactorLogin)
+	auth.Post("/2fa/verify", handlers.VerifyTwoFactorLogin)
+	// Login lewat identity provider (OIDC authorization code + PKCE)
+	auth.Get("/oidc/authorize", handlers.OIDCAuthorize)
+	auth.Post("/oidc/callback", handlers.OIDCCallback)
 
 	// 3. MIDDLEWARE & UTILITY
 	api.Use(middleware.RequireAuth())
+	// Request mutasi dengan header Idempotency-Key hanya dieksekusi sekali
+	api.Use(middleware.Idempotency(db))
+
+	// API key ditolak kecuali di route yang menyebut scope-nya (RequirePermission
+	// atau RequireAPIKeyScope)
+	noAPIKey := middleware.RejectAPIKey()
+	canCreateLetter := middleware.RequireAPIKeyScope(models.PermLetterKeluarCreateInternal, models.PermLetterKeluarCreateEksternal, models.PermLetterMasukCreate)
+	canReadLetters := middleware.RequireAPIKeyScope(models.PermLetterViewAll, models.PermLetterViewEksternal, models.PermLetterMasukView)
+
+	// Ganti role aktif (penugasan Plt./Plh.), butuh access token
+	api.Post("/auth/switch-role", noAPIKey, handlers.SwitchRole)
 
 	// Route Upload File (PDF/Gambar)
-	api.Post("/upload", handlers.UploadFileHandler)
+	api.Post("/upload", canCreateLetter, handlers.UploadFileHandler)
+
+	// Delta sync aplikasi mobile (offline-first)
+	api.Get("/sync", noAPIKey, syncHandler.Sync)
+
+	// Daftar unit aktif (tujuan disposisi, filter unit_id)
+	api.Get("/units", noAPIKey, handlers.ListActiveUnits)
 
 	// 4. PROFILE & SETTINGS
-	settings := api.Group("/settings")
+	// Pengaturan akun hanya untuk user yang login sendiri, bukan API key
+	settings := api.Group("/settings", noAPIKey)
 	settings.Get("/profile", handlers.GetMyProfile)
 	settings.Put("/profile", handlers.UpdateMyProfile)
 	settings.Put("/change-password", handlers.ChangePassword)
+	settings.Put("/signature", handlers.UploadMySignature)
+	settings.Get("/roles", handlers.GetMyRoles)
+	settings.Get("/2fa", handlers.GetTwoFactorStatus)
+	settings.Post("/2fa/setup", handlers.SetupTwoFactor)
+	settings.Post("/2fa/enable", handlers.EnableTwoFactor)
+	settings.Post("/2fa/recovery-codes", handlers.RegenerateRecoveryCodes)
+	settings.Post("/2fa/disable", handlers.DisableTwoFactor)
+	settings.Get("/sessions", handlers.ListMySessions)
+	settings.Post("/sessions/revoke-others", handlers.RevokeOtherSessions)
+	settings.Delete("/sessions/:id", handlers.RevokeMySession)
+	// Penautan akun SSO ke akun yang sedang login
+	settings.Get("/sso", handlers.ListMyIdentities)
+	settings.Post("/sso/ldap", handlers.LinkLDAPIdentity)
+	settings.Get("/sso/oidc/authorize", handlers.LinkOIDCAuthorize)
+	settings.Post("/sso/oidc/callback", handlers.LinkOIDCCallback)
 
 	// 5. MANAJEMEN SURAT (Group: /api/letters)
 	letters := api.Group("/letters")
 
 	// --- A. HELPER ROUTES (must be before :id routes) ---
-	letters.Get("/verifiers", lkHandler.GetAvailableVerifiers)
+	letters.Get("/verifiers", noAPIKey, lkHandler.GetAvailableVerifiers)
+	letters.Get("/templates", noAPIKey, handlers.ListActiveLetterTemplates)
+	letters.Get("/tembusan/my", noAPIKey, commonHandler.GetMyTembusan)
+	letters.Get("/search", canReadLetters, searchHandler.SearchLetters)
+
+	// Permission per kelompok aksi (katalog di models/rbac.go, dikelola admin)
+	canCreateKeluar := middleware.RequirePermission(models.PermLetterKeluarCreateInternal, models.PermLetterKeluarCreateEksternal)
+	canVerify := middleware.RequirePermission(models.PermLetterVerifyInternal, models.PermLetterVerifyEksternal)
+	canApprove := middleware.RequirePermission(models.PermLetterApprove)
+	canManageMasuk := middleware.RequirePermission(models.PermLetterMasukCreate)
+	canDispose := middleware.RequirePermission(models.PermLetterDispose)
 
 	// --- B. WORKFLOW SURAT KELUAR ---
 
 	// Dashboard & Aksi STAF
-	letters.Get("/keluar/my", middleware.RequireStaf(), lkHandler.GetMyLetters)
-	letters.Post("/keluar", middleware.RequireStaf(), lkHandler.CreateSuratKeluar)
-	letters.Put("/keluar/:id", middleware.RequireStaf(), lkHandler.UpdateDraftLetter)
-	letters.Post("/keluar/:id/archive", middleware.RequireStaf(), lkHandler.ArchiveLetter)
+	letters.Get("/keluar/my", canCreateKeluar, lkHandler.GetMyLetters)
+	letters.Post("/keluar", canCreateKeluar, lkHandler.CreateSuratKeluar)
+	letters.Post("/keluar/from-template", canCreateKeluar, lkHandler.CreateSuratKeluarFromTemplate)
+	letters.Put("/keluar/:id", canCreateKeluar, lkHandler.UpdateDraftLetter)
+	letters.Post("/keluar/:id/archive", canCreateKeluar, lkHandler.ArchiveLetter)
 
 	// Dashboard & Aksi MANAJER
-	letters.Get("/keluar/need-verification", middleware.RequireManajer(), lkHandler.GetLettersNeedVerification)
-	letters.Post("/keluar/:id/verify/approve", middleware.RequireManajer(), lkHandler.VerifyLetterApprove)
-	letters.Post("/keluar/:id/verify/reject", middleware.RequireManajer(), lkHandler.VerifyLetterReject)
+	letters.Get("/keluar/need-verification", canVerify, lkHandler.GetLettersNeedVerification)
+	letters.Post("/keluar/:id/verify/approve", canVerify, lkHandler.VerifyLetterApprove)
+	letters.Post("/keluar/:id/verify/reject", canVerify, lkHandler.VerifyLetterReject)
 
 	// Dashboard & Aksi DIREKTUR
-	letters.Get("/keluar/need-approval", middleware.RequireDirektur(), lkHandler.GetLettersNeedApproval)
-	letters.Get("/keluar/my-approvals", middleware.RequireDirektur(), lkHandler.GetMyApprovals)
-	letters.Post("/keluar/:id/approve", middleware.RequireDirektur(), lkHandler.ApproveLetterByDirektur)
-	letters.Post("/keluar/:id/reject", middleware.RequireDirektur(), lkHandler.RejectLetterByDirektur)
+	letters.Get("/keluar/need-approval", canApprove, lkHandler.GetLettersNeedApproval)
+	letters.Get("/keluar/my-approvals", canApprove, lkHandler.GetMyApprovals)
+	letters.Post("/keluar/:id/approve", canApprove, lkHandler.ApproveLetterByDirektur)
+	letters.Post("/keluar/:id/reject", canApprove, lkHandler.RejectLetterByDirektur)
+	letters.Post("/keluar/:id/verification/revoke", middleware.RequirePermission(models.PermLetterVerificationRevoke), verificationHandler.RevokeLetterVerification)
 
 	// --- C. WORKFLOW SURAT MASUK ---
 
 	// Aksi STAF
-	letters.Get("/masuk/my", middleware.RequireStaf(), lmHandler.GetMySuratMasuk)
-	letters.Post("/masuk", middleware.RequireStaf(), lmHandler.CreateSuratMasuk)
-	letters.Put("/masuk/:id", middleware.RequireStaf(), lmHandler.UpdateSuratMasuk)
-	letters.Post("/masuk/:id/archive", middleware.RequireStaf(), lmHandler.ArchiveSuratMasuk)
+	letters.Get("/masuk/my", canManageMasuk, lmHandler.GetMySuratMasuk)
+	letters.Post("/masuk", canManageMasuk, lmHandler.CreateSuratMasuk)
+	letters.Put("/masuk/:id", canManageMasuk, lmHandler.UpdateSuratMasuk)
+	letters.Post("/masuk/:id/archive", canManageMasuk, lmHandler.ArchiveSuratMasuk)
 
 	// Dashboard & Aksi DIREKTUR (Disposisi)
-	letters.Get("/masuk/need-disposition", middleware.RequireDirektur(), lmHandler.GetLettersMasukForDisposition)
-	letters.Get("/masuk/my-dispositions", middleware.RequireDirektur(), lmHandler.GetMyDispositions)
-	letters.Post("/masuk/:id/dispose", middleware.RequireDirektur(), lmHandler.DisposeSuratMasuk)
+	letters.Get("/masuk/need-disposition", canDispose, lmHandler.GetLettersMasukForDisposition)
+	letters.Get("/masuk/my-dispositions", canDispose, lmHandler.GetMyDispositions)
+	letters.Post("/masuk/:id/dispose", canDispose, lmHandler.DisposeSuratMasuk)
 
 	// Reply Linking - Surat masuk yang butuh balasan
-	letters.Get("/masuk/needs-reply", middleware.RequireStaf(), lmHandler.GetLettersNeedingReply)
+	letters.Get("/masuk/needs-reply", canCreateKeluar, lmHandler.GetLettersNeedingReply)
 
 	// --- D. GENERIC ROUTES (must be LAST to avoid catching specific routes) ---
 	// Melihat Detail Surat (any letter by ID)
-	letters.Get("/:id", commonHandler.GetLetterByID)
-	// Menghapus/Membatalkan Surat (Soft Delete / Cancel)
+	letters.Get("/:id", canReadLetters, commonHandler.GetLetterByID)
+	// Akses file surat (redirect presigned URL / stream) + log akses
+	letters.Get("/:id/file", canReadLetters, commonHandler.GetLetterFile)
+	letters.Get("/:id/file/:variant", canReadLetters, commonHandler.GetLetterFile)
+	// Riwayat aksi workflow beserta role yang dipakai pelakunya
+	letters.Get("/:id/history", canReadLetters, commonHandler.GetLetterHistory)
+	// Menghapus/Membatalkan Surat (Soft Delete / Cancel); scope API key dicek di handler
 	letters.Delete("/:id", commonHandler.DeleteLetter)
 
 	// 6. ADMIN ZONE (API)
-	admin := api.Group("/admin", middleware.RequireAdmin())
-	admin.Post("/users", handlers.AdminCreateUser)
-	admin.Get("/users", handlers.AdminListUsers)
-	admin.Get("/users/:id", handlers.AdminGetUserByID)
-	admin.Put("/users/:id", handlers.AdminUpdateUser)
-	admin.Delete("/users/:id", handlers.AdminDeleteUser)
+	// Tiap kelompok route admin punya permission sendiri
+	admin := api.Group("/admin")
+	adminUsers := admin.Group("/users", middleware.RequirePermission(models.PermAdminUsers))
+	adminUsers.Post("/", handlers.AdminCreateUser)
+	adminUsers.Get("/", handlers.AdminListUsers)
+	adminUsers.Get("/:id", handlers.AdminGetUserByID)
+	adminUsers.Put("/:id", handlers.AdminUpdateUser)
+	adminUsers.Delete("/:id", handlers.AdminDeactivateUser)
+	adminUsers.Get("/:id/pending-letters", handlers.AdminUserPendingLetters)
+	adminUsers.Post("/:id/activate", handlers.AdminActivateUser)
+	adminUsers.Post("/:id/invitation", handlers.AdminResendInvitation)
+	adminUsers.Post("/:id/2fa-enrolment", handlers.AdminIssueTwoFactorEnrolment)
+	adminUsers.Get("/:id/roles", handlers.AdminListUserRoles)
+	adminUsers.Post("/:id/roles", handlers.AdminAssignUserRole)
+	adminUsers.Delete("/:id/roles/:assignmentId", handlers.AdminRevokeUserRole)
+	adminUsers.Get("/:id/identities", handlers.AdminListUserIdentities)
+	adminUsers.Post("/:id/identities", handlers.AdminLinkUserIdentity)
+	adminUsers.Delete("/:id/identities/:identityId", handlers.AdminUnlinkUserIdentity)
+	adminTemplates := admin.Group("/letter-templates", middleware.RequirePermission(models.PermAdminTemplates))
+	adminTemplates.Post("/", handlers.AdminCreateLetterTemplate)
+	adminTemplates.Get("/", handlers.AdminListLetterTemplates)
+	adminTemplates.Get("/:id", handlers.AdminGetLetterTemplate)
+	adminTemplates.Get("/:id/preview", handlers.AdminPreviewLetterTemplate)
+	adminTemplates.Put("/:id", handlers.AdminUpdateLetterTemplate)
+	adminTemplates.Delete("/:id", handlers.AdminDeleteLetterTemplate)
+	adminUnits := admin.Group("/units", middleware.RequirePermission(models.PermAdminUnits))
+	adminUnits.Post("/", handlers.AdminCreateUnit)
+	adminUnits.Get("/", handlers.AdminListUnits)
+	adminUnits.Get("/report", handlers.AdminUnitReport)
+	adminUnits.Get("/:id", handlers.AdminGetUnit)
+	adminUnits.Put("/:id", handlers.AdminUpdateUnit)
+	adminUnits.Delete("/:id", handlers.AdminDeleteUnit)
+	adminUnits.Post("/:id/members", handlers.AdminAddUnitMembers)
+	adminUnits.Delete("/:id/members/:userId", handlers.AdminRemoveUnitMember)
+	admin.Get("/permissions", middleware.RequirePermission(models.PermAdminRoles), handlers.AdminListPermissions)
+	adminRoles := admin.Group("/roles", middleware.RequirePermission(models.PermAdminRoles))
+	adminRoles.Post("/", handlers.AdminCreateRole)
+	adminRoles.Get("/", handlers.AdminListRoles)
+	adminRoles.Get("/:id", handlers.AdminGetRole)
+	adminRoles.Put("/:id", handlers.AdminUpdateRole)
+	adminRoles.Delete("/:id", handlers.AdminDeleteRole)
+	// API key tidak bisa membuat / mencabut API key lain
+	adminAPIKeys := admin.Group("/api-keys", noAPIKey, middleware.RequirePermission(models.PermAdminAPIKeys))
+	adminAPIKeys.Get("/", handlers.AdminListAPIKeys)
+	adminAPIKeys.Post("/", handlers.AdminCreateAPIKey)
+	adminAPIKeys.Delete("/:id", handlers.AdminRevokeAPIKey)
 
 	// 7. ADMIN WEB PANEL (Session-based auth)
 	webHandler := handlers.NewWebAdminHandler()
@@ -104,18 +212,53 @@ func SetupRoutes(app *fiber.App, db *gorm.DB) {
 	adminWeb := app.Group("/admin")
 	adminWeb.Get("/login", webHandler.ShowLoginPage)
 	adminWeb.Post("/login", webHandler.HandleLogin)
+	adminWeb.Get("/login/2fa", webHandler.ShowTwoFactorLogin)
+	adminWeb.Post("/login/2fa", webHandler.HandleTwoFactorLogin)
+	adminWeb.Post("/login/2fa/setup", webHandler.HandleTwoFactorLoginSetup)
+	adminWeb.Get("/login/sso", webHandler.StartSSOLogin)
+	adminWeb.Get("/login/sso/callback", webHandler.HandleSSOCallback)
 
 	// Protected routes (require session)
 	adminWebAuth := adminWeb.Group("", middleware.RequireAdminSession())
+	webUsers := middleware.RequireSessionPermission(models.PermAdminUsers)
+	webUnits := middleware.RequireSessionPermission(models.PermAdminUnits)
+	webRoles := middleware.RequireSessionPermission(models.PermAdminRoles)
+	webAPIKeys := middleware.RequireSessionPermission(models.PermAdminAPIKeys)
 	adminWebAuth.Post("/logout", webHandler.HandleLogout)
 	adminWebAuth.Get("/", webHandler.ShowDashboard)
-	adminWebAuth.Get("/users", webHandler.ShowUserList)
-	adminWebAuth.Get("/users/create", webHandler.ShowCreateUserForm)
-	adminWebAuth.Post("/users", webHandler.HandleCreateUser)
-	adminWebAuth.Get("/users/:id/edit", webHandler.ShowEditUserForm)
-	adminWebAuth.Post("/users/:id", webHandler.HandleUpdateUser)
-	adminWebAuth.Post("/users/:id/delete", webHandler.HandleDeleteUser)
+	adminWebAuth.Get("/users", webUsers, webHandler.ShowUserList)
+	adminWebAuth.Get("/users/create", webUsers, webHandler.ShowCreateUserForm)
+	adminWebAuth.Post("/users", webUsers, webHandler.HandleCreateUser)
+	adminWebAuth.Get("/users/:id/edit", webUsers, webHandler.ShowEditUserForm)
+	adminWebAuth.Post("/users/:id", webUsers, webHandler.HandleUpdateUser)
+	adminWebAuth.Get("/users/:id/deactivate", webUsers, webHandler.ShowDeactivateUser)
+	adminWebAuth.Post("/users/:id/deactivate", webUsers, webHandler.HandleDeactivateUser)
+	adminWebAuth.Post("/users/:id/activate", webUsers, webHandler.HandleActivateUser)
+	adminWebAuth.Post("/users/:id/invitation", webUsers, webHandler.HandleResendInvitation)
+	adminWebAuth.Post("/users/:id/2fa-enrolment", webUsers, webHandler.HandleIssueTwoFactorEnrolment)
+	adminWebAuth.Post("/users/:id/roles", webUsers, webHandler.HandleAssignUserRole)
+	adminWebAuth.Post("/users/:id/roles/:assignmentId/delete", webUsers, webHandler.HandleRevokeUserRole)
+	adminWebAuth.Get("/auth-events", webUsers, webHandler.ShowAuthEvents)
+	adminWebAuth.Post("/auth-events/unlock", webUsers, webHandler.HandleUnlockLogin)
+	adminWebAuth.Get("/units", webUnits, webHandler.ShowUnitList)
+	adminWebAuth.Get("/units/create", webUnits, webHandler.ShowCreateUnitForm)
+	adminWebAuth.Post("/units", webUnits, webHandler.HandleCreateUnit)
+	adminWebAuth.Get("/units/:id/edit", webUnits, webHandler.ShowEditUnitForm)
+	adminWebAuth.Post("/units/:id", webUnits, webHandler.HandleUpdateUnit)
+	adminWebAuth.Post("/units/:id/delete", webUnits, webHandler.HandleDeleteUnit)
+	adminWebAuth.Get("/roles", webRoles, webHandler.ShowRoleList)
+	adminWebAuth.Get("/roles/create", webRoles, webHandler.ShowCreateRoleForm)
+	adminWebAuth.Post("/roles", webRoles, webHandler.HandleCreateRole)
+	adminWebAuth.Get("/roles/:id/edit", webRoles, webHandler.ShowEditRoleForm)
+	adminWebAuth.Post("/roles/:id", webRoles, webHandler.HandleUpdateRole)
+	adminWebAuth.Post("/roles/:id/delete", webRoles, webHandler.HandleDeleteRole)
+	adminWebAuth.Get("/api-keys", webAPIKeys, webHandler.ShowAPIKeys)
+	adminWebAuth.Post("/api-keys", webAPIKeys, webHandler.HandleCreateAPIKey)
+	adminWebAuth.Post("/api-keys/:id/revoke", webAPIKeys, webHandler.HandleRevokeAPIKey)
 	adminWebAuth.Get("/settings", webHandler.ShowSettings)
 	adminWebAuth.Post("/settings/profile", webHandler.HandleUpdateProfile)
 	adminWebAuth.Post("/settings/password", webHandler.HandleChangePassword)
+	adminWebAuth.Post("/settings/2fa/setup", webHandler.HandleSetupTwoFactor)
+	adminWebAuth.Post("/settings/2fa/enable", webHandler.HandleEnableTwoFactor)
+	adminWebAuth.Post("/settings/2fa/disable", webHandler.HandleDisableTwoFactor)
 }
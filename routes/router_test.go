@@ -0,0 +1,229 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/services"
+	"TugasAkhir/utils"
+	"TugasAkhir/utils/dbtest"
+	"TugasAkhir/utils/totp"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// newRouteTestDB menyiapkan database test sebagai config.DB dan mengembalikan
+// owner (staf_program) beserta API key miliknya dengan scope yang diberikan
+func newRouteTestDB(t *testing.T, scopes ...string) (*gorm.DB, models.User, string) {
+	t.Helper()
+	// Template panel admin dibaca relatif terhadap root repo
+	t.Chdir("..")
+
+	db := dbtest.Open(t,
+		&models.Unit{}, &models.User{}, &models.Letter{}, &models.LetterHistory{}, &models.LetterTembusan{},
+		&models.IdempotencyKey{}, &models.Permission{}, &models.RoleDefinition{}, &models.UserRoleAssignment{},
+		&models.APIKey{}, &models.AuthEvent{}, &models.LoginThrottle{}, &models.PasswordResetToken{},
+		&models.UserSession{}, &models.RefreshToken{}, &models.UserTwoFactor{}, &models.UserRecoveryCode{},
+	)
+	if err := services.SeedRBAC(db); err != nil {
+		t.Fatalf("SeedRBAC: %v", err)
+	}
+	services.InvalidatePermissionCache()
+	previousDB := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = previousDB
+		services.InvalidatePermissionCache()
+	})
+
+	owner := models.User{Username: "rina", Email: "rina@yayasan.org", Role: models.RoleStafProgram, Status: models.UserStatusActive}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatalf("create owner: %v", err)
+	}
+	_, rawKey, err := services.NewAPIKeyService(db).Create(services.APIKeyInput{
+		Name:    "Impor",
+		OwnerID: owner.ID,
+		Scopes:  scopes,
+	}, nil)
+	if err != nil {
+		t.Fatalf("create API key: %v", err)
+	}
+	return db, owner, rawKey
+}
+
+func TestNarrowAPIKeyIsRejectedOnUnscopedRoutes(t *testing.T) {
+	// Scope hanya untuk membuat surat keluar eksternal
+	db, owner, rawKey := newRouteTestDB(t, models.PermLetterKeluarCreateEksternal)
+
+	draft := func(scope string) models.Letter {
+		letter := models.Letter{JenisSurat: models.LetterKeluar, Scope: scope, Status: models.StatusDraft, CreatedByID: owner.ID}
+		if err := db.Create(&letter).Error; err != nil {
+			t.Fatalf("create letter: %v", err)
+		}
+		return letter
+	}
+	internalDraft := draft(models.ScopeInternal)
+	eksternalDraft := draft(models.ScopeEksternal)
+
+	app := fiber.New()
+	SetupRoutes(app, db)
+
+	call := func(method, target string) int {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("X-API-Key", rawKey)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, target, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	forbidden := []struct{ method, target string }{
+		{fiber.MethodGet, "/api/sync"},
+		{fiber.MethodGet, "/api/units"},
+		{fiber.MethodGet, "/api/letters/verifiers"},
+		{fiber.MethodGet, "/api/letters/templates"},
+		{fiber.MethodGet, "/api/letters/tembusan/my"},
+		{fiber.MethodGet, "/api/letters/search?q=rapat"},
+		{fiber.MethodGet, fmt.Sprintf("/api/letters/%d", eksternalDraft.ID)},
+		{fiber.MethodGet, fmt.Sprintf("/api/letters/%d/file", eksternalDraft.ID)},
+		{fiber.MethodGet, fmt.Sprintf("/api/letters/%d/history", eksternalDraft.ID)},
+		{fiber.MethodGet, "/api/settings/profile"},
+		// Draft milik owner, tapi scope key tidak mencakup surat internal
+		{fiber.MethodDelete, fmt.Sprintf("/api/letters/%d", internalDraft.ID)},
+	}
+	for _, tc := range forbidden {
+		if status := call(tc.method, tc.target); status != fiber.StatusForbidden {
+			t.Errorf("%s %s = %d, want 403", tc.method, tc.target, status)
+		}
+	}
+
+	if status := call(fiber.MethodDelete, fmt.Sprintf("/api/letters/%d", eksternalDraft.ID)); status != fiber.StatusOK {
+		t.Errorf("DELETE own eksternal draft = %d, want 200", status)
+	}
+}
+
+func TestDeleteLetterWithStaleIfMatchReturns412(t *testing.T) {
+	db, owner, rawKey := newRouteTestDB(t, models.PermLetterKeluarCreateEksternal)
+
+	letter := models.Letter{JenisSurat: models.LetterKeluar, Scope: models.ScopeEksternal, Status: models.StatusDraft, CreatedByID: owner.ID}
+	if err := db.Create(&letter).Error; err != nil {
+		t.Fatalf("create letter: %v", err)
+	}
+	staleETag := fmt.Sprintf(`"%d-%d"`, letter.ID, letter.Version)
+	// Surat diubah request lain setelah klien memuatnya
+	if err := services.SaveLetterIfUnchanged(db, &letter); err != nil {
+		t.Fatalf("SaveLetterIfUnchanged: %v", err)
+	}
+
+	app := fiber.New()
+	SetupRoutes(app, db)
+	remove := func(ifMatch string) int {
+		req := httptest.NewRequest(fiber.MethodDelete, fmt.Sprintf("/api/letters/%d", letter.ID), nil)
+		req.Header.Set("X-API-Key", rawKey)
+		req.Header.Set(fiber.HeaderIfMatch, ifMatch)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("DELETE: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := remove(staleETag); status != fiber.StatusPreconditionFailed {
+		t.Fatalf("DELETE with stale If-Match = %d, want 412", status)
+	}
+	if err := db.First(&models.Letter{}, letter.ID).Error; err != nil {
+		t.Fatalf("letter deleted despite failed precondition: %v", err)
+	}
+	if status := remove(fmt.Sprintf(`"%d-%d"`, letter.ID, letter.Version)); status != fiber.StatusOK {
+		t.Fatalf("DELETE with current If-Match = %d, want 200", status)
+	}
+}
+
+func TestExpiredPasswordWithTwoFactorRequiresCodeBeforeResetToken(t *testing.T) {
+	t.Setenv("JWT_SECRET", "route-test-secret")
+	t.Setenv("PASSWORD_MAX_AGE", "720h")
+	db, user, _ := newRouteTestDB(t, models.PermLetterKeluarCreateEksternal)
+
+	hash, err := utils.HashPassword("Rahasia123!")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	changedAt := time.Now().Add(-60 * 24 * time.Hour)
+	if err := db.Model(&user).Updates(map[string]interface{}{"password_hash": hash, "password_changed_at": changedAt}).Error; err != nil {
+		t.Fatalf("expire password: %v", err)
+	}
+	const secret = "JBSWY3DPEHPK3PXP"
+	enabledAt := time.Now()
+	if err := db.Create(&models.UserTwoFactor{UserID: user.ID, Secret: secret, EnabledAt: &enabledAt}).Error; err != nil {
+		t.Fatalf("enable 2FA: %v", err)
+	}
+
+	app := fiber.New()
+	SetupRoutes(app, db)
+	post := func(target string, body interface{}) (int, map[string]interface{}) {
+		raw, _ := json.Marshal(body)
+		req := httptest.NewRequest(fiber.MethodPost, target, strings.NewReader(string(raw)))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("POST %s: %v", target, err)
+		}
+		defer resp.Body.Close()
+		var out map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("decode %s: %v", target, err)
+		}
+		return resp.StatusCode, out
+	}
+
+	// Password benar saja belum cukup untuk mendapat token reset
+	status, body := post("/api/auth/login", fiber.Map{"email": user.Email, "password": "Rahasia123!"})
+	if status != fiber.StatusOK {
+		t.Fatalf("login = %d %v, want 200 challenge", status, body)
+	}
+	data, _ := body["data"].(map[string]interface{})
+	if data["password_expired"] != true || data["challenge_token"] == nil {
+		t.Fatalf("login data = %v, want password_expired challenge", data)
+	}
+	if strings.Contains(fmt.Sprint(body), "reset_token") {
+		t.Fatalf("login response leaked reset_token: %v", body)
+	}
+	challenge := data["challenge_token"].(string)
+
+	// Challenge password kedaluwarsa tidak bisa dipakai untuk enrolment
+	if status, body := post("/api/auth/2fa/setup", fiber.Map{"challenge_token": challenge, "enrolment_token": "00000-00000-00000-00000"}); status != fiber.StatusUnauthorized {
+		t.Fatalf("2fa setup = %d %v, want 401", status, body)
+	}
+
+	if status, body := post("/api/auth/2fa/verify", fiber.Map{"challenge_token": challenge, "code": "000000"}); status == fiber.StatusForbidden {
+		t.Fatalf("wrong code = %d %v, want rejection without reset token", status, body)
+	}
+
+	code, err := totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatalf("totp.Code: %v", err)
+	}
+	status, body = post("/api/auth/2fa/verify", fiber.Map{"challenge_token": challenge, "code": code})
+	if status != fiber.StatusForbidden {
+		t.Fatalf("2fa verify = %d %v, want 403", status, body)
+	}
+	errs, _ := body["errors"].(map[string]interface{})
+	if errs["password_expired"] != true || errs["reset_token"] == nil || errs["reset_token"] == "" {
+		t.Fatalf("2fa verify errors = %v, want reset_token", errs)
+	}
+	var sessions int64
+	db.Model(&models.UserSession{}).Where("user_id = ?", user.ID).Count(&sessions)
+	if sessions != 0 {
+		t.Fatalf("sessions = %d, want none for expired password", sessions)
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"TugasAkhir/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAPIKeyInvalid  = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyNoScopes = errors.New("at least one scope is required")
+	ErrAPIKeyNoOwner  = errors.New("api key owner not found")
+)
+
+// APIKeyScopeError - scope tidak ada di katalog atau tidak dimiliki role owner
+type APIKeyScopeError struct {
+	Scope string
+}
+
+func (e *APIKeyScopeError) Error() string {
+	return fmt.Sprintf("scope %q is not granted to the key owner's role", e.Scope)
+}
+
+// apiKeyPrefix membedakan API key dari token lain di log / secret scanner
+const apiKeyPrefix = "dmk_"
+
+// lastUsedInterval - last_used_at cukup diperbarui sekali per menit agar
+// skrip yang sering memanggil API tidak menulis ke database di setiap request
+const lastUsedInterval = time.Minute
+
+type APIKeyService struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+// APIKeyInput - data key baru dari admin
+type APIKeyInput struct {
+	Name      string
+	OwnerID   uint
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create membuat key baru dan mengembalikan key mentah, yang hanya bisa
+// dilihat sekali ini. Scope harus dimiliki role owner saat ini.
+func (s *APIKeyService) Create(input APIKeyInput, createdByID *uint) (*models.APIKey, string, error) {
+	var owner models.User
+	if err := s.db.First(&owner, input.OwnerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrAPIKeyNoOwner
+		}
+		return nil, "", err
+	}
+
+	scopes := make([]string, 0, len(input.Scopes))
+	for code := range uniqueStrings(input.Scopes) {
+		if code = strings.TrimSpace(code); code != "" {
+			scopes = append(scopes, code)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, "", ErrAPIKeyNoScopes
+	}
+	sort.Strings(scopes)
+
+	rbac := NewRBACService(s.db)
+	for _, scope := range scopes {
+		if !rbac.HasPermission(owner.Role, scope) {
+			return nil, "", &APIKeyScopeError{Scope: scope}
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	raw := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := models.APIKey{
+		Name:        input.Name,
+		Prefix:      raw[:12],
+		KeyHash:     hashAPIKey(raw),
+		OwnerID:     owner.ID,
+		Scopes:      strings.Join(scopes, " "),
+		ExpiresAt:   input.ExpiresAt,
+		CreatedByID: createdByID,
+	}
+	if err := s.db.Create(&key).Error; err != nil {
+		return nil, "", err
+	}
+	key.Owner = owner
+	return &key, raw, nil
+}
+
+// List - semua key (termasuk yang dicabut) beserta owner, terbaru dulu
+func (s *APIKeyService) List() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := s.db.Preload("Owner").Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (s *APIKeyService) Get(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := s.db.Preload("Owner").First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Revoke mencabut key. Mencabut ulang key yang sudah dicabut tidak mengubah
+// waktu pencabutan.
+func (s *APIKeyService) Revoke(id uint) (*models.APIKey, error) {
+	key, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		if err := s.db.Model(key).Update("revoked_at", &now).Error; err != nil {
+			return nil, err
+		}
+		key.RevokedAt = &now
+	}
+	return key, nil
+}
+
+// Authenticate mencari key aktif beserta owner-nya. Owner yang dihapus atau
+// tidak aktif membuat key ikut tidak berlaku.
+func (s *APIKeyService) Authenticate(raw, ip string) (*models.APIKey, error) {
+	if !strings.HasPrefix(raw, apiKeyPrefix) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	var key models.APIKey
+	if err := s.db.Preload("Owner").Where("key_hash = ?", hashAPIKey(raw)).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !key.Usable(now) || key.Owner.ID == 0 || !key.Owner.CanLogin() {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval || key.LastUsedIP != ip {
+		if err := s.db.Model(&key).UpdateColumns(map[string]any{
+			"last_used_at": &now,
+			"last_used_ip": truncate(ip, 45),
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &key, nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"TugasAkhir/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuthEventService menulis dan membaca log audit autentikasi
+type AuthEventService struct {
+	db *gorm.DB
+}
+
+func NewAuthEventService(db *gorm.DB) *AuthEventService {
+	return &AuthEventService{db: db}
+}
+
+// AuthEventFilter - filter halaman log di panel admin
+type AuthEventFilter struct {
+	Event models.AuthEventType
+	Query string // email atau IP (LIKE)
+	Page  int
+	Limit int
+}
+
+// Record menyimpan satu kejadian. Kegagalan hanya di-log: audit tidak boleh
+// membuat login / logout user ikut gagal.
+func (s *AuthEventService) Record(event models.AuthEvent) {
+	if len(event.UserAgent) > 255 {
+		event.UserAgent = event.UserAgent[:255]
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if err := s.db.Create(&event).Error; err != nil {
+		log.Printf("[auth-event] gagal mencatat %s untuk %q: %v", event.Event, event.Email, err)
+	}
+}
+
+// List mengambil log terbaru sesuai filter beserta total barisnya
+func (s *AuthEventService) List(filter AuthEventFilter) ([]models.AuthEvent, int64, error) {
+	tx := s.db.Model(&models.AuthEvent{})
+	if filter.Event != "" {
+		tx = tx.Where("event = ?", filter.Event)
+	}
+	if filter.Query != "" {
+		like := "%" + filter.Query + "%"
+		tx = tx.Where(s.db.Where("email LIKE ?", like).Or("ip LIKE ?", like))
+	}
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	var events []models.AuthEvent
+	err := tx.Order("id DESC").
+		Limit(filter.Limit).
+		Offset((filter.Page - 1) * filter.Limit).
+		Find(&events).Error
+	return events, total, err
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/utils/document"
+	"TugasAkhir/utils/storage"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLetterHasNoFile  = errors.New("letter has no document file")
+	ErrTemplateNotFound = errors.New("letter template not found")
+)
+
+// Stempel teks pengganti jika Direktur belum mengunggah gambar tanda tangan.
+// Posisinya sama dengan document.SignatureStampDesc.
+const signatureTextStampDesc = "fontname:Helvetica, points:9, position:br, offset:-70 60, scalefactor:1 abs, rotation:0, backgroundcolor:#FFFFFF, border:1 #000000, margins:4"
+
+type DocumentService struct {
+	db *gorm.DB
+}
+
+func NewDocumentService(db *gorm.DB) *DocumentService {
+	return &DocumentService{db: db}
+}
+
+// SignApprovedLetter menghasilkan PDF final untuk surat keluar yang disetujui:
+// menempelkan QR code verifikasi dan tanda tangan Direktur, menandatangani secara
+// digital dengan key organisasi, lalu mengganti FilePath surat dengan versi
+// bertanda tangan. Perubahan field surat dan LetterVerification yang dikembalikan
+// belum disimpan, caller yang menyimpan keduanya; jika penyimpanan gagal, caller
+// memanggil DiscardSignedFile agar file bertanda tangan tidak tertinggal di S3.
+func (ds *DocumentService) SignApprovedLetter(ctx context.Context, letter *models.Letter, signerID uint) (*models.LetterVerification, error) {
+	if letter.FilePath == "" {
+		return nil, ErrLetterHasNoFile
+	}
+
+	var signer models.User
+	if err := ds.db.First(&signer, signerID).Error; err != nil {
+		return nil, fmt.Errorf("load signer: %w", err)
+	}
+
+	data, err := storage.DownloadFile(ctx, letter.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf, err := document.EnsurePDF(data, letter.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	signerName := displayName(signer)
+
+	token, tokenHash, err := newVerificationToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate verification token: %w", err)
+	}
+	verifyURL := config.LoadVerificationConfig().URLFor(token)
+	if pdf, err = document.StampQRCode(pdf, verifyURL, document.QRCodeStampDesc); err != nil {
+		return nil, err
+	}
+
+	if signer.SignatureImagePath != "" {
+		image, err := storage.DownloadFile(ctx, signer.SignatureImagePath)
+		if err != nil {
+			return nil, err
+		}
+		if pdf, err = document.StampImage(pdf, image, document.SignatureStampDesc); err != nil {
+			return nil, err
+		}
+	} else {
+		text := fmt.Sprintf("Disetujui secara elektronik oleh\n%s\n%s", signerName, now.Format("02-01-2006 15:04"))
+		if pdf, err = document.StampText(pdf, text, signatureTextStampDesc); err != nil {
+			return nil, err
+		}
+	}
+
+	orgSigner := document.DefaultSigner()
+	if orgSigner != nil {
+		pdf, err = orgSigner.SignPDF(pdf, document.SignatureInfo{
+			Name:     signerName,
+			Reason:   orgSigner.Reason(),
+			Location: orgSigner.Location(),
+			Time:     now,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		log.Printf("⚠️ Surat ID %d disetujui tanpa tanda tangan digital: signer belum dikonfigurasi", letter.ID)
+	}
+
+	key := fmt.Sprintf("surat/signed_%d_%d.pdf", letter.ID, now.UnixNano())
+	if _, err := storage.UploadBytes(ctx, pdf, key, "application/pdf"); err != nil {
+		return nil, err
+	}
+
+	if letter.OriginalFilePath == "" {
+		letter.OriginalFilePath = letter.FilePath
+	}
+	letter.FilePath = key
+	letter.SignedAt = &now
+	letter.SignedByID = &signer.ID
+	if orgSigner != nil {
+		letter.SignatureCertSubject = orgSigner.Subject()
+		letter.SignatureFingerprint = orgSigner.Fingerprint()
+	}
+
+	fileHash := sha256.Sum256(pdf)
+	return &models.LetterVerification{
+		LetterID:  letter.ID,
+		TokenHash: tokenHash,
+		FileHash:  hex.EncodeToString(fileHash[:]),
+	}, nil
+}
+
+// DiscardSignedFile menghapus file hasil SignApprovedLetter yang batal disimpan.
+// Kegagalan hanya dicatat di log karena surat di database tetap menunjuk ke file lama.
+func (ds *DocumentService) DiscardSignedFile(ctx context.Context, letter *models.Letter) {
+	if letter.FilePath == "" || letter.FilePath == letter.OriginalFilePath {
+		return
+	}
+	if err := storage.DeleteFile(ctx, letter.FilePath); err != nil {
+		log.Printf("⚠️ Gagal menghapus file tanda tangan surat ID %d yang batal disimpan: %v", letter.ID, err)
+	}
+}
+
+// stampedFilePrefix - awalan key salinan berstempel hasil StampIncomingReceipt
+const stampedFilePrefix = "surat/stamped_masuk_"
+
+// StampIncomingReceipt membuat salinan surat masuk dengan stempel penerimaan
+// (nomor agenda, tanggal diterima, pencatat) di pojok kanan atas halaman pertama.
+// File asli tetap disimpan di OriginalFilePath, FilePath diganti ke salinan
+// berstempel. Surat yang sudah distempel distempel ulang dari file aslinya.
+// Perubahan field surat belum disimpan, caller yang menyimpan; jika penyimpanan
+// gagal, caller memanggil DiscardStampedFile.
+func (ds *DocumentService) StampIncomingReceipt(ctx context.Context, letter *models.Letter, recorderID uint) error {
+	source := letter.OriginalFilePath
+	if source == "" {
+		source = letter.FilePath
+	}
+	if source == "" {
+		return ErrLetterHasNoFile
+	}
+
+	var recorder models.User
+	if err := ds.db.First(&recorder, recorderID).Error; err != nil {
+		return fmt.Errorf("load recorder: %w", err)
+	}
+
+	data, err := storage.DownloadFile(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	pdf, err := document.EnsurePDF(data, source)
+	if err != nil {
+		return err
+	}
+
+	received := time.Now()
+	if letter.TanggalMasuk != nil {
+		received = *letter.TanggalMasuk
+	}
+	text := fmt.Sprintf("DITERIMA\nNo. Agenda: %s\nTanggal: %s\nDicatat oleh: %s",
+		letter.NomorAgenda, received.Format("02-01-2006"), displayName(recorder))
+
+	if pdf, err = document.StampTextFirstPage(pdf, text, document.ReceiptStampDesc); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s%d_%d.pdf", stampedFilePrefix, letter.ID, time.Now().UnixNano())
+	if _, err := storage.UploadBytes(ctx, pdf, key, "application/pdf"); err != nil {
+		return err
+	}
+
+	letter.OriginalFilePath = source
+	letter.FilePath = key
+	return nil
+}
+
+// DiscardStampedFile menghapus salinan berstempel yang batal disimpan atau
+// sudah digantikan stempel baru. Path selain hasil StampIncomingReceipt
+// diabaikan. Kegagalan hanya dicatat di log.
+func (ds *DocumentService) DiscardStampedFile(ctx context.Context, path string) {
+	if !strings.HasPrefix(path, stampedFilePrefix) {
+		return
+	}
+	if err := storage.DeleteFile(ctx, path); err != nil {
+		log.Printf("⚠️ Gagal menghapus salinan berstempel %s: %v", path, err)
+	}
+}
+
+// FindTemplate mengambil template surat, termasuk yang sudah dihapus agar
+// draft lama tetap bisa dibuat ulang
+func (ds *DocumentService) FindTemplate(id uint) (*models.LetterTemplate, error) {
+	var tmpl models.LetterTemplate
+	if err := ds.db.Unscoped().First(&tmpl, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// generatedFilePrefix - awalan key file hasil GenerateFromTemplate
+const generatedFilePrefix = "surat/generated_"
+
+// GenerateFromTemplate mengisi template dengan data surat, merender PDF di
+// server lalu mengganti FilePath surat dengan hasilnya. Dipanggil saat surat
+// dibuat dan setiap kali draft/revisi diperbarui. Caller yang menyimpan surat;
+// jika penyimpanan gagal, caller memanggil DiscardGeneratedFile.
+func (ds *DocumentService) GenerateFromTemplate(ctx context.Context, letter *models.Letter, tmpl *models.LetterTemplate) error {
+	pdf, err := RenderLetterTemplate(tmpl, letter)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s%d.pdf", generatedFilePrefix, time.Now().UnixNano())
+	if _, err := storage.UploadBytes(ctx, pdf, key, "application/pdf"); err != nil {
+		return err
+	}
+
+	letter.FilePath = key
+	return nil
+}
+
+// DiscardGeneratedFile menghapus PDF hasil GenerateFromTemplate yang batal
+// disimpan atau sudah digantikan file baru. Path selain hasil template
+// (upload user, file bertanda tangan) diabaikan. Kegagalan hanya dicatat di log.
+func (ds *DocumentService) DiscardGeneratedFile(ctx context.Context, path string) {
+	if !strings.HasPrefix(path, generatedFilePrefix) {
+		return
+	}
+	if err := storage.DeleteFile(ctx, path); err != nil {
+		log.Printf("⚠️ Gagal menghapus file hasil template %s: %v", path, err)
+	}
+}
+
+// RenderLetterTemplate mengisi placeholder template dengan data surat lalu
+// merender PDF-nya
+func RenderLetterTemplate(tmpl *models.LetterTemplate, letter *models.Letter) ([]byte, error) {
+	tanggal := time.Now()
+	if letter.TanggalSurat != nil {
+		tanggal = *letter.TanggalSurat
+	}
+
+	values := map[string]string{
+		document.PlaceholderNomorSurat:   letter.NomorSurat,
+		document.PlaceholderJudulSurat:   letter.JudulSurat,
+		document.PlaceholderIsiSurat:     letter.IsiSurat,
+		document.PlaceholderPenerima:     letter.Penerima,
+		document.PlaceholderTanggalSurat: document.FormatTanggal(tanggal),
+		document.PlaceholderPengirim:     letter.Pengirim,
+	}
+
+	body := tmpl.Body
+	if strings.TrimSpace(body) == "" {
+		body = "{{" + document.PlaceholderIsiSurat + "}}"
+	}
+
+	return document.RenderLetter(document.LetterContent{
+		KopSurat:       tmpl.KopSurat,
+		NomorSurat:     letter.NomorSurat,
+		Perihal:        letter.JudulSurat,
+		Tanggal:        values[document.PlaceholderTanggalSurat],
+		Penerima:       letter.Penerima,
+		Body:           document.FillPlaceholders(body, values),
+		SignatureBlock: document.FillPlaceholders(tmpl.SignatureBlock, values),
+	})
+}
+
+func displayName(u models.User) string {
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if name == "" {
+		name = u.Username
+	}
+	return name
+}
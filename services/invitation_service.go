@@ -0,0 +1,157 @@
+package services
+
+import (
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvitationNotFound   = errors.New("invitation not found")
+	ErrInvitationNotPending = errors.New("user has already activated the account")
+)
+
+type InvitationService struct {
+	db  *gorm.DB
+	cfg config.AuthConfig
+}
+
+func NewInvitationService(db *gorm.DB) *InvitationService {
+	return &InvitationService{db: db, cfg: config.LoadAuthConfig()}
+}
+
+// Invitation - hasil Issue; Link berisi token mentah dan hanya ada di memori
+type Invitation struct {
+	Link      string
+	ExpiresAt time.Time
+}
+
+func newInvitationToken() (string, string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", "", err
+	}
+	raw := hex.EncodeToString(tokenBytes)
+	return raw, hashInvitationToken(raw), nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue membuat undangan baru untuk user pending. Undangan sebelumnya yang
+// belum terpakai dibatalkan sehingga hanya link terakhir yang berlaku.
+func (s *InvitationService) Issue(user *models.User, invitedByID *uint) (*Invitation, error) {
+	if user.Status != models.UserStatusPending {
+		return nil, ErrInvitationNotPending
+	}
+
+	rawToken, tokenHash, err := newInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invitation := models.UserInvitation{
+		UserID:      user.ID,
+		TokenHash:   tokenHash,
+		ExpiresAt:   now.Add(s.cfg.InvitationTTL),
+		InvitedByID: invitedByID,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserInvitation{}).
+			Where("user_id = ? AND used = ?", user.ID, false).
+			Updates(map[string]any{"used": true, "used_at": &now}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&invitation).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Invitation{Link: s.linkFor(rawToken), ExpiresAt: invitation.ExpiresAt}, nil
+}
+
+func (s *InvitationService) linkFor(token string) string {
+	base := s.cfg.InvitationURL
+	escapedToken := url.QueryEscape(token)
+	if strings.Contains(base, "?") {
+		if strings.HasSuffix(base, "?") || strings.HasSuffix(base, "&") {
+			return base + "token=" + escapedToken
+		}
+		return base + "&token=" + escapedToken
+	}
+	return base + "?token=" + escapedToken
+}
+
+// Lookup mencari undangan yang masih berlaku beserta user-nya (untuk form)
+func (s *InvitationService) Lookup(token string) (*models.UserInvitation, error) {
+	var invitation models.UserInvitation
+	err := s.db.Preload("User").Where("token_hash = ?", hashInvitationToken(token)).First(&invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := invitation.Validate(time.Now()); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// Accept memakai undangan: password user di-set dan akun menjadi aktif.
+// passwordHash harus sudah lolos PasswordService.Validate.
+func (s *InvitationService) Accept(token, passwordHash string) (*models.User, error) {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var invitation models.UserInvitation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashInvitationToken(token)).
+			First(&invitation).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvitationNotFound
+			}
+			return err
+		}
+
+		if err := invitation.Consume(tx, time.Now()); err != nil {
+			return err
+		}
+
+		res := tx.Model(&models.User{}).
+			Where("id = ? AND status = ?", invitation.UserID, models.UserStatusPending).
+			Updates(map[string]any{
+				"password_hash":       passwordHash,
+				"password_changed_at": time.Now(),
+				"status":              models.UserStatusActive,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvitationNotPending
+		}
+		if err := NewPasswordService(tx).Record(invitation.UserID, passwordHash); err != nil {
+			return err
+		}
+
+		return tx.First(&user, invitation.UserID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
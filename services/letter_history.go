@@ -0,0 +1,40 @@
+package services
+
+import (
+	"TugasAkhir/models"
+
+	"gorm.io/gorm"
+)
+
+// RecordLetterAction menambah satu baris riwayat surat. entry cukup berisi
+// aksi, pelaku (beserta role aktifnya) dan catatan; surat & status diisi dari
+// letter. Panggil di transaksi yang sama dengan perubahan surat agar riwayat
+// tidak tercatat untuk perubahan yang gagal disimpan.
+func RecordLetterAction(tx *gorm.DB, letter *models.Letter, fromStatus models.LetterStatus, entry models.LetterHistory) error {
+	entry.LetterID = letter.ID
+	entry.FromStatus = fromStatus
+	entry.ToStatus = letter.Status
+	return tx.Create(&entry).Error
+}
+
+// TransitionLetter menjalankan UpdateLetterFields dan mencatat riwayatnya
+// dalam satu transaksi
+func TransitionLetter(db *gorm.DB, letter *models.Letter, fromStatus models.LetterStatus, entry models.LetterHistory, columns ...string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := UpdateLetterFields(tx, letter, fromStatus, columns...); err != nil {
+			return err
+		}
+		return RecordLetterAction(tx, letter, fromStatus, entry)
+	})
+}
+
+// ListLetterHistory mengambil riwayat surat dari yang paling lama
+func ListLetterHistory(db *gorm.DB, letterID uint) ([]models.LetterHistory, error) {
+	var history []models.LetterHistory
+	err := db.Preload("Actor", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Select("id, username, role, jabatan")
+	}).Preload("APIKey", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Select("id, name, prefix")
+	}).Where("letter_id = ?", letterID).Order("id ASC").Find(&history).Error
+	return history, err
+}
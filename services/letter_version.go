@@ -0,0 +1,58 @@
+package services
+
+import (
+	"TugasAkhir/models"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrLetterVersionConflict berarti surat sudah diubah request lain sejak dimuat
+var ErrLetterVersionConflict = errors.New("letter was modified by another request")
+
+// SaveLetterIfUnchanged menyimpan seluruh kolom surat (pengganti Save) hanya
+// jika versi di database masih sama dengan versi saat surat dimuat
+func SaveLetterIfUnchanged(tx *gorm.DB, letter *models.Letter) error {
+	expected := letter.Version
+	letter.Version = expected + 1
+
+	res := tx.Model(letter).
+		Where("version = ?", expected).
+		Select("*").Omit(clause.Associations, "created_at").
+		Updates(letter)
+	return checkVersionedUpdate(res, letter, expected)
+}
+
+// UpdateLetterFields menyimpan kolom tertentu untuk transisi workflow. Update
+// hanya terjadi jika versi & status di database masih sama dengan saat surat
+// dimuat, sehingga dua aksi bersamaan tidak saling menimpa.
+func UpdateLetterFields(tx *gorm.DB, letter *models.Letter, fromStatus models.LetterStatus, columns ...string) error {
+	expected := letter.Version
+	letter.Version = expected + 1
+
+	res := tx.Model(letter).
+		Where("version = ? AND status = ?", expected, fromStatus).
+		Select(append(columns, "version")).
+		Updates(letter)
+	return checkVersionedUpdate(res, letter, expected)
+}
+
+// DeleteLetterIfUnchanged menghapus (soft delete) surat hanya jika versi di
+// database masih sama dengan versi saat surat dimuat
+func DeleteLetterIfUnchanged(tx *gorm.DB, letter *models.Letter) error {
+	res := tx.Where("version = ?", letter.Version).Delete(letter)
+	return checkVersionedUpdate(res, letter, letter.Version)
+}
+
+func checkVersionedUpdate(res *gorm.DB, letter *models.Letter, expected uint) error {
+	if res.Error != nil {
+		letter.Version = expected
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		letter.Version = expected
+		return ErrLetterVersionConflict
+	}
+	return nil
+}
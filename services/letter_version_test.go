@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils/dbtest"
+
+	"gorm.io/gorm"
+)
+
+func newTestLetter(t *testing.T) (*gorm.DB, *models.Letter) {
+	t.Helper()
+	db := dbtest.Open(t, &models.Unit{}, &models.User{}, &models.Letter{})
+	letter := models.Letter{JenisSurat: models.LetterKeluar, Scope: models.ScopeInternal, Status: models.StatusDraft, JudulSurat: "Undangan"}
+	if err := db.Create(&letter).Error; err != nil {
+		t.Fatalf("create letter: %v", err)
+	}
+	return db, &letter
+}
+
+// loadLetter memuat salinan surat seperti request lain yang berjalan bersamaan
+func loadLetter(t *testing.T, db *gorm.DB, id uint) *models.Letter {
+	t.Helper()
+	var letter models.Letter
+	if err := db.First(&letter, id).Error; err != nil {
+		t.Fatalf("load letter: %v", err)
+	}
+	return &letter
+}
+
+func TestSaveLetterIfUnchanged(t *testing.T) {
+	db, letter := newTestLetter(t)
+	first := loadLetter(t, db, letter.ID)
+	second := loadLetter(t, db, letter.ID)
+
+	first.JudulSurat = "Undangan rapat"
+	if err := SaveLetterIfUnchanged(db, first); err != nil {
+		t.Fatalf("SaveLetterIfUnchanged: %v", err)
+	}
+	if first.Version != letter.Version+1 {
+		t.Fatalf("version = %d, want %d", first.Version, letter.Version+1)
+	}
+
+	// Salinan lama tidak boleh menimpa perubahan yang sudah tersimpan
+	second.JudulSurat = "Undangan lama"
+	if err := SaveLetterIfUnchanged(db, second); !errors.Is(err, ErrLetterVersionConflict) {
+		t.Fatalf("stale save err = %v, want ErrLetterVersionConflict", err)
+	}
+	if second.Version != letter.Version {
+		t.Fatalf("stale copy version = %d, want unchanged %d", second.Version, letter.Version)
+	}
+	if got := loadLetter(t, db, letter.ID); got.JudulSurat != "Undangan rapat" || got.Version != first.Version {
+		t.Fatalf("stored letter = %q v%d, want %q v%d", got.JudulSurat, got.Version, "Undangan rapat", first.Version)
+	}
+}
+
+func TestUpdateLetterFieldsChecksStatus(t *testing.T) {
+	db, letter := newTestLetter(t)
+
+	// Versi sama tapi status di database sudah berbeda
+	db.Model(&models.Letter{}).Where("id = ?", letter.ID).Update("status", models.StatusPerluVerifikasi)
+	letter.Status = models.StatusDiarsipkan
+	if err := UpdateLetterFields(db, letter, models.StatusDraft, "status"); !errors.Is(err, ErrLetterVersionConflict) {
+		t.Fatalf("UpdateLetterFields err = %v, want ErrLetterVersionConflict", err)
+	}
+}
+
+func TestDeleteLetterIfUnchanged(t *testing.T) {
+	db, letter := newTestLetter(t)
+	stale := loadLetter(t, db, letter.ID)
+
+	letter.JudulSurat = "Undangan rapat"
+	if err := SaveLetterIfUnchanged(db, letter); err != nil {
+		t.Fatalf("SaveLetterIfUnchanged: %v", err)
+	}
+	if err := DeleteLetterIfUnchanged(db, stale); !errors.Is(err, ErrLetterVersionConflict) {
+		t.Fatalf("stale delete err = %v, want ErrLetterVersionConflict", err)
+	}
+	loadLetter(t, db, letter.ID)
+
+	if err := DeleteLetterIfUnchanged(db, letter); err != nil {
+		t.Fatalf("DeleteLetterIfUnchanged: %v", err)
+	}
+	if err := db.First(&models.Letter{}, letter.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("letter still visible after delete: %v", err)
+	}
+}
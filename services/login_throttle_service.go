@@ -0,0 +1,206 @@
+package services
+
+import (
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrLoginLocked          = errors.New("too many failed login attempts")
+	ErrLoginThrottleMissing = errors.New("login lock not found")
+)
+
+// LoginThrottleService membatasi percobaan login per akun dan per IP.
+// Setiap kali batas tercapai login dikunci, dan penguncian berikutnya dua
+// kali lebih lama (dibatasi LOGIN_MAX_LOCKOUT). Counter akun direset saat login
+// berhasil; counter IP hanya kedaluwarsa sendiri agar penyerang tidak bisa
+// meresetnya dengan login ke akunnya sendiri.
+type LoginThrottleService struct {
+	db  *gorm.DB
+	cfg config.AuthConfig
+}
+
+func NewLoginThrottleService(db *gorm.DB) *LoginThrottleService {
+	return &LoginThrottleService{db: db, cfg: config.LoadAuthConfig()}
+}
+
+// LoginLockError membawa sisa waktu penguncian untuk header Retry-After
+type LoginLockError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockError) Error() string {
+	return ErrLoginLocked.Error()
+}
+
+func (e *LoginLockError) Unwrap() error {
+	return ErrLoginLocked
+}
+
+// NormalizeLoginKey - email dibandingkan tanpa memperhatikan huruf besar/kecil
+func NormalizeLoginKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check mengembalikan *LoginLockError jika akun atau IP sedang dikunci.
+// Dipanggil sebelum password diperiksa.
+func (s *LoginThrottleService) Check(email, ip string) error {
+	var records []models.LoginThrottle
+	if err := s.scopeQuery(s.db, email, ip).Find(&records).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var retryAfter time.Duration
+	for _, r := range records {
+		if r.Locked(now) && r.LockedUntil.Sub(now) > retryAfter {
+			retryAfter = r.LockedUntil.Sub(now)
+		}
+	}
+	if retryAfter > 0 {
+		return &LoginLockError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RecordFailure menambah counter kegagalan akun dan IP. Jika salah satu
+// mencapai batas, dikembalikan *LoginLockError (login baru saja dikunci).
+func (s *LoginThrottleService) RecordFailure(email, ip string) error {
+	var lockErr *LoginLockError
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		targets := []struct {
+			scope models.LoginThrottleScope
+			key   string
+			limit int
+		}{
+			{models.LoginThrottleAccount, NormalizeLoginKey(email), s.cfg.LoginMaxAttempts},
+			{models.LoginThrottleIP, ip, s.cfg.LoginMaxAttemptsPerIP},
+		}
+
+		now := time.Now()
+		for _, t := range targets {
+			if t.key == "" {
+				continue
+			}
+			record, err := lockThrottle(tx, t.scope, t.key)
+			if err != nil {
+				return err
+			}
+
+			// Kegagalan lama (di luar window sejak kegagalan / penguncian
+			// terakhir) tidak dihitung lagi
+			var last time.Time
+			if record.LastFailureAt != nil {
+				last = *record.LastFailureAt
+			}
+			if record.LockedUntil != nil && record.LockedUntil.After(last) {
+				last = *record.LockedUntil
+			}
+			if now.Sub(last) > s.cfg.LoginAttemptWindow {
+				record.Failures = 0
+				record.LockCount = 0
+			}
+
+			record.Failures++
+			record.LastFailureAt = &now
+			if record.Failures >= t.limit {
+				lockFor := s.lockDuration(record.LockCount)
+				until := now.Add(lockFor)
+				record.LockedUntil = &until
+				record.LockCount++
+				record.Failures = 0
+				if lockErr == nil || lockFor > lockErr.RetryAfter {
+					lockErr = &LoginLockError{RetryAfter: lockFor}
+				}
+			}
+
+			if err := tx.Save(record).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if lockErr != nil {
+		return lockErr
+	}
+	return nil
+}
+
+// RecordSuccess mereset counter akun setelah login berhasil
+func (s *LoginThrottleService) RecordSuccess(email string) error {
+	return s.reset(models.LoginThrottleAccount, NormalizeLoginKey(email))
+}
+
+// Unlock membuka penguncian akun atau IP dari panel admin
+func (s *LoginThrottleService) Unlock(scope models.LoginThrottleScope, key string) error {
+	if scope == models.LoginThrottleAccount {
+		key = NormalizeLoginKey(key)
+	}
+	res := s.db.Model(&models.LoginThrottle{}).
+		Where("scope = ? AND throttle_key = ? AND locked_until > ?", scope, key, time.Now()).
+		Updates(map[string]any{"failures": 0, "lock_count": 0, "locked_until": nil})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLoginThrottleMissing
+	}
+	return nil
+}
+
+// Locked mengambil semua akun & IP yang saat ini terkunci
+func (s *LoginThrottleService) Locked() ([]models.LoginThrottle, error) {
+	var records []models.LoginThrottle
+	err := s.db.Where("locked_until > ?", time.Now()).
+		Order("scope ASC, locked_until DESC").
+		Find(&records).Error
+	return records, err
+}
+
+func (s *LoginThrottleService) reset(scope models.LoginThrottleScope, key string) error {
+	return s.db.Model(&models.LoginThrottle{}).
+		Where("scope = ? AND throttle_key = ? AND (failures > 0 OR lock_count > 0)", scope, key).
+		Updates(map[string]any{"failures": 0, "lock_count": 0, "locked_until": nil}).Error
+}
+
+// lockDuration - LOGIN_LOCKOUT dikali dua untuk setiap penguncian sebelumnya
+func (s *LoginThrottleService) lockDuration(previousLocks int) time.Duration {
+	d := s.cfg.LoginLockout
+	for i := 0; i < previousLocks && d < s.cfg.LoginMaxLockout; i++ {
+		d *= 2
+	}
+	if d > s.cfg.LoginMaxLockout {
+		d = s.cfg.LoginMaxLockout
+	}
+	return d
+}
+
+func (s *LoginThrottleService) scopeQuery(db *gorm.DB, email, ip string) *gorm.DB {
+	return db.Where("(scope = ? AND throttle_key = ?) OR (scope = ? AND throttle_key = ?)",
+		models.LoginThrottleAccount, NormalizeLoginKey(email),
+		models.LoginThrottleIP, ip)
+}
+
+// lockThrottle membuat baris counter jika belum ada lalu menguncinya (FOR
+// UPDATE) agar percobaan paralel tidak saling menimpa
+func lockThrottle(tx *gorm.DB, scope models.LoginThrottleScope, key string) (*models.LoginThrottle, error) {
+	seed := models.LoginThrottle{Scope: scope, Key: key}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+		return nil, err
+	}
+
+	var record models.LoginThrottle
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("scope = ? AND throttle_key = ?", scope, key).
+		First(&record).Error
+	return &record, err
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils/dbtest"
+
+	"gorm.io/gorm"
+)
+
+const throttleTestIP = "10.0.0.7"
+
+func newTestLoginThrottleService(t *testing.T, env map[string]string) (*LoginThrottleService, *gorm.DB) {
+	t.Helper()
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	db := dbtest.Open(t, &models.LoginThrottle{})
+	return NewLoginThrottleService(db), db
+}
+
+// failLogin mencatat n kegagalan dan mengembalikan lama penguncian dari
+// kegagalan terakhir (0 jika belum terkunci)
+func failLogin(t *testing.T, s *LoginThrottleService, email, ip string, n int) time.Duration {
+	t.Helper()
+	var lockFor time.Duration
+	for i := 0; i < n; i++ {
+		err := s.RecordFailure(email, ip)
+		var lockErr *LoginLockError
+		switch {
+		case errors.As(err, &lockErr):
+			lockFor = lockErr.RetryAfter
+		case err != nil:
+			t.Fatalf("RecordFailure: %v", err)
+		default:
+			lockFor = 0
+		}
+	}
+	return lockFor
+}
+
+// ageThrottles memundurkan waktu kegagalan & penguncian semua counter,
+// seolah-olah by sudah berlalu
+func ageThrottles(t *testing.T, db *gorm.DB, by time.Duration) {
+	t.Helper()
+	var records []models.LoginThrottle
+	if err := db.Find(&records).Error; err != nil {
+		t.Fatalf("load throttles: %v", err)
+	}
+	for _, r := range records {
+		if r.LastFailureAt != nil {
+			at := r.LastFailureAt.Add(-by)
+			r.LastFailureAt = &at
+		}
+		if r.LockedUntil != nil {
+			until := r.LockedUntil.Add(-by)
+			r.LockedUntil = &until
+		}
+		if err := db.Save(&r).Error; err != nil {
+			t.Fatalf("age throttle: %v", err)
+		}
+	}
+}
+
+func TestLoginLockoutDoublesUpToMaxLockout(t *testing.T) {
+	s, db := newTestLoginThrottleService(t, map[string]string{
+		"LOGIN_MAX_ATTEMPTS":        "2",
+		"LOGIN_MAX_ATTEMPTS_PER_IP": "100",
+		"LOGIN_ATTEMPT_WINDOW":      "1h",
+		"LOGIN_LOCKOUT":             "1m",
+		"LOGIN_MAX_LOCKOUT":         "5m",
+	})
+
+	// 1m, 2m, 4m lalu dibatasi LOGIN_MAX_LOCKOUT
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		if got := failLogin(t, s, "dewi@yayasan.org", throttleTestIP, 2); got != want {
+			t.Fatalf("lock %d = %v, want %v", i+1, got, want)
+		}
+		var lockErr *LoginLockError
+		if err := s.Check("DEWI@yayasan.org", "10.0.0.8"); !errors.As(err, &lockErr) {
+			t.Fatalf("Check during lock %d err = %v, want LoginLockError", i+1, err)
+		}
+		// Penguncian selesai, kegagalan berikutnya masih di dalam window
+		ageThrottles(t, db, want+time.Second)
+		if err := s.Check("dewi@yayasan.org", throttleTestIP); err != nil {
+			t.Fatalf("Check after lock %d expired: %v", i+1, err)
+		}
+	}
+}
+
+func TestLoginFailuresOutsideWindowAreForgotten(t *testing.T) {
+	s, db := newTestLoginThrottleService(t, map[string]string{
+		"LOGIN_MAX_ATTEMPTS":        "3",
+		"LOGIN_MAX_ATTEMPTS_PER_IP": "100",
+		"LOGIN_ATTEMPT_WINDOW":      "15m",
+		"LOGIN_LOCKOUT":             "1m",
+		"LOGIN_MAX_LOCKOUT":         "1h",
+	})
+
+	if got := failLogin(t, s, "dewi@yayasan.org", throttleTestIP, 2); got != 0 {
+		t.Fatalf("locked after 2 failures for %v", got)
+	}
+	ageThrottles(t, db, 16*time.Minute)
+	// Kegagalan lama tidak dihitung: dua kegagalan baru belum mengunci
+	if got := failLogin(t, s, "dewi@yayasan.org", throttleTestIP, 2); got != 0 {
+		t.Fatalf("old failures counted, locked for %v", got)
+	}
+	if got := failLogin(t, s, "dewi@yayasan.org", throttleTestIP, 1); got != time.Minute {
+		t.Fatalf("lock = %v, want 1m", got)
+	}
+
+	// Penguncian yang sudah lewat window juga tidak memperpanjang penguncian berikutnya
+	ageThrottles(t, db, time.Minute+16*time.Minute)
+	if got := failLogin(t, s, "dewi@yayasan.org", throttleTestIP, 3); got != time.Minute {
+		t.Fatalf("lock after window = %v, want 1m", got)
+	}
+}
+
+func TestRecordSuccessKeepsIPCounter(t *testing.T) {
+	s, db := newTestLoginThrottleService(t, map[string]string{
+		"LOGIN_MAX_ATTEMPTS":        "3",
+		"LOGIN_MAX_ATTEMPTS_PER_IP": "4",
+		"LOGIN_ATTEMPT_WINDOW":      "15m",
+		"LOGIN_LOCKOUT":             "1m",
+		"LOGIN_MAX_LOCKOUT":         "1h",
+	})
+
+	failLogin(t, s, "dewi@yayasan.org", throttleTestIP, 2)
+	failLogin(t, s, "budi@yayasan.org", throttleTestIP, 1)
+	// Login berhasil ke akun sendiri tidak mereset counter IP
+	if err := s.RecordSuccess("Dewi@yayasan.org"); err != nil {
+		t.Fatalf("RecordSuccess: %v", err)
+	}
+
+	var account, ip models.LoginThrottle
+	db.Where("scope = ? AND throttle_key = ?", models.LoginThrottleAccount, "dewi@yayasan.org").First(&account)
+	db.Where("scope = ? AND throttle_key = ?", models.LoginThrottleIP, throttleTestIP).First(&ip)
+	if account.Failures != 0 {
+		t.Fatalf("account failures = %d, want 0 after success", account.Failures)
+	}
+	if ip.Failures != 3 {
+		t.Fatalf("ip failures = %d, want 3", ip.Failures)
+	}
+
+	if got := failLogin(t, s, "sari@yayasan.org", throttleTestIP, 1); got != time.Minute {
+		t.Fatalf("IP lock = %v, want 1m", got)
+	}
+	var lockErr *LoginLockError
+	if err := s.Check("dewi@yayasan.org", throttleTestIP); !errors.As(err, &lockErr) {
+		t.Fatalf("Check from locked IP err = %v, want LoginLockError", err)
+	}
+	if err := s.Check("dewi@yayasan.org", "10.0.0.8"); err != nil {
+		t.Fatalf("Check from other IP: %v", err)
+	}
+}
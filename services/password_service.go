@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/utils"
+	"TugasAkhir/utils/password"
+
+	"gorm.io/gorm"
+)
+
+// ErrPasswordExpired - password benar tapi sudah melewati PASSWORD_MAX_AGE;
+// user harus menggantinya sebelum bisa login
+var ErrPasswordExpired = errors.New("password has expired")
+
+// Daftar password bocor tambahan cukup dibaca sekali per proses
+var blocklistOnce sync.Once
+
+// PasswordService - satu-satunya jalur untuk mengatur password lokal:
+// kebijakan komposisi, daftar password umum, riwayat, dan masa berlaku
+type PasswordService struct {
+	db  *gorm.DB
+	cfg config.PasswordConfig
+}
+
+func NewPasswordService(db *gorm.DB) *PasswordService {
+	cfg := config.LoadPasswordConfig()
+	blocklistOnce.Do(func() {
+		if cfg.BlocklistFile == "" {
+			return
+		}
+		if err := password.LoadBlocklist(cfg.BlocklistFile); err != nil {
+			log.Printf("password blocklist not loaded: %v", err)
+		}
+	})
+	return &PasswordService{db: db, cfg: cfg}
+}
+
+// Validate memeriksa password baru milik user. Mengembalikan
+// *password.PolicyError jika melanggar kebijakan atau memakai ulang salah
+// satu dari PASSWORD_HISTORY password terakhir.
+func (s *PasswordService) Validate(user *models.User, plain string) error {
+	if err := s.cfg.Policy().Check(plain, user.Username, user.Email, user.FirstName, user.LastName); err != nil {
+		return err
+	}
+	if s.cfg.History == 0 || user.ID == 0 {
+		return nil
+	}
+
+	var hashes []string
+	if err := s.db.Model(&models.PasswordHistory{}).
+		Where("user_id = ?", user.ID).
+		Order("id DESC").
+		Limit(s.cfg.History).
+		Pluck("password_hash", &hashes).Error; err != nil {
+		return err
+	}
+	// Akun lama belum punya riwayat; password saat ini tetap tidak boleh dipakai ulang
+	if user.PasswordHash != "" {
+		hashes = append(hashes, user.PasswordHash)
+	}
+	for _, hash := range hashes {
+		if utils.CheckPassword(hash, plain) {
+			return &password.PolicyError{Rules: []string{password.RuleReused}, MinLength: s.cfg.MinLength, History: s.cfg.History}
+		}
+	}
+	return nil
+}
+
+// Set memvalidasi, meng-hash, lalu menyimpan password baru user
+func (s *PasswordService) Set(user *models.User, plain string) error {
+	if err := s.Validate(user, plain); err != nil {
+		return err
+	}
+	hash, err := utils.HashPassword(plain)
+	if err != nil {
+		return err
+	}
+	return s.Apply(user, hash)
+}
+
+// Apply menyimpan hash password yang sudah divalidasi lalu mencatatnya di
+// riwayat. Panggil dengan NewPasswordService(tx) agar ikut transaksi pemanggil.
+func (s *PasswordService) Apply(user *models.User, hash string) error {
+	now := time.Now()
+	if err := s.db.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]any{
+		"password_hash":       hash,
+		"password_changed_at": &now,
+	}).Error; err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	user.PasswordChangedAt = &now
+	return s.Record(user.ID, hash)
+}
+
+// Record mencatat hash password yang baru disimpan pemanggil (misalnya saat
+// user dibuat) ke riwayat dan memangkas riwayat yang melebihi PASSWORD_HISTORY
+func (s *PasswordService) Record(userID uint, hash string) error {
+	if s.cfg.History > 0 {
+		if err := s.db.Create(&models.PasswordHistory{UserID: userID, PasswordHash: hash}).Error; err != nil {
+			return err
+		}
+	}
+
+	var stale []uint
+	if err := s.db.Model(&models.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Offset(s.cfg.History).
+		Limit(1000).
+		Pluck("id", &stale).Error; err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return s.db.Where("id IN ?", stale).Delete(&models.PasswordHistory{}).Error
+}
+
+// Expired - password lokal user sudah melewati PASSWORD_MAX_AGE
+func (s *PasswordService) Expired(user *models.User) bool {
+	return s.cfg.Expired(user.PasswordChangedAt, user.CreatedAt, time.Now())
+}
+
+// MinLength - untuk atribut minlength di form HTML
+func (s *PasswordService) MinLength() int {
+	return s.cfg.MinLength
+}
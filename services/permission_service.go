@@ -3,6 +3,7 @@ package services
 import (
 	"TugasAkhir/models"
 	"errors"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -14,11 +15,23 @@ var (
 )
 
 type PermissionService struct {
-	db *gorm.DB
+	db   *gorm.DB
+	rbac *RBACService
 }
 
 func NewPermissionService(db *gorm.DB) *PermissionService {
-	return &PermissionService{db: db}
+	return &PermissionService{db: db, rbac: NewRBACService(db)}
+}
+
+// HasPermission - role user memiliki salah satu permission yang disebut. Untuk
+// request dengan API key, hanya permission di scope key yang dihitung.
+func (ps *PermissionService) HasPermission(user *models.User, perms ...string) bool {
+	return user != nil && ps.rbac.HasPermission(user.Role, models.ScopedPermissions(user.Scopes, perms)...)
+}
+
+// RolesWithPermission - role yang memiliki salah satu permission
+func (ps *PermissionService) RolesWithPermission(perms ...string) []models.Role {
+	return ps.rbac.RolesWithPermission(perms...)
 }
 
 // CanUserCreateLetter - Cek izin membuat surat
@@ -27,28 +40,12 @@ func (ps *PermissionService) CanUserCreateLetter(user *models.User, scope string
 		return false, ErrUnauthorized
 	}
 
-	// ATURAN 1: Staf Program
-	if user.Role == models.RoleStafProgram {
-		if letterType == models.LetterKeluar && scope == models.ScopeEksternal {
-			return true, nil
-		}
-		return false, nil
-	}
-
-	// ATURAN 2: Staf Lembaga
-	if user.Role == models.RoleStafLembaga {
-		if letterType == models.LetterMasuk {
-			return true, nil
-		}
-
-		if letterType == models.LetterKeluar && scope == models.ScopeInternal {
-			return true, nil
-		}
-
-		return false, nil
+	// Surat masuk dicatat tanpa memandang scope; surat keluar per scope
+	// (bawaan: Staf Program → Eksternal, Staf Lembaga → Internal)
+	if letterType == models.LetterMasuk {
+		return ps.HasPermission(user, models.PermLetterMasukCreate), nil
 	}
-
-	return false, ErrForbidden
+	return ps.HasPermission(user, models.KeluarCreatePermission(scope)), nil
 }
 
 // CanUserVerifyLetter - Cek apakah Manajer boleh verifikasi
@@ -60,13 +57,8 @@ func (ps *PermissionService) CanUserVerifyLetter(user *models.User, letter *mode
 		return false, ErrNotFound
 	}
 
-	// 1. User harus Manajer
-	if !user.IsManajer() {
-		return false, nil
-	}
-
-	// 2. Scope sesuai
-	if !user.CanVerifyScope(letter.Scope) {
+	// 1 & 2. Role punya izin verifikasi untuk scope surat ini
+	if !ps.HasPermission(user, models.VerifyPermission(letter.Scope)) {
 		return false, nil
 	}
 
@@ -86,13 +78,13 @@ func (ps *PermissionService) CanUserVerifyLetter(user *models.User, letter *mode
 	return true, nil
 }
 
-// CanUserApproveLetter - Cek apakah Direktur boleh approve
+// CanUserApproveLetter - Cek apakah user (bawaan: Direktur) boleh approve
 func (ps *PermissionService) CanUserApproveLetter(user *models.User, letter *models.Letter) (bool, error) {
 	if user == nil {
 		return false, ErrUnauthorized
 	}
 
-	if !user.IsDirektur() {
+	if !ps.HasPermission(user, models.PermLetterApprove) {
 		return false, nil
 	}
 
@@ -103,15 +95,14 @@ func (ps *PermissionService) CanUserApproveLetter(user *models.User, letter *mod
 	return true, nil
 }
 
-// === BAGIAN INI YANG TADI MUNGKIN HILANG/ERROR ===
-// CanUserDisposeLetter - Cek apakah Direktur boleh disposisi surat masuk
+// CanUserDisposeLetter - Cek apakah user (bawaan: Direktur) boleh disposisi surat masuk
 func (ps *PermissionService) CanUserDisposeLetter(user *models.User, letter *models.Letter) (bool, error) {
 	if user == nil {
 		return false, ErrUnauthorized
 	}
 
-	// 1. Hanya Direktur
-	if !user.IsDirektur() {
+	// 1. Punya izin disposisi
+	if !ps.HasPermission(user, models.PermLetterDispose) {
 		return false, nil
 	}
 
@@ -128,16 +119,14 @@ func (ps *PermissionService) CanUserDisposeLetter(user *models.User, letter *mod
 	return true, nil
 }
 
-// =================================================
-
-// CanUserArchiveLetter - Cek apakah Staf boleh arsip
+// CanUserArchiveLetter - Cek apakah pembuat / arsiparis boleh arsip
 func (ps *PermissionService) CanUserArchiveLetter(user *models.User, letter *models.Letter) (bool, error) {
 	if user == nil {
 		return false, ErrUnauthorized
 	}
 
 	isCreator := letter.CreatedByID == user.ID
-	isArchivist := user.Role == models.RoleStafLembaga
+	isArchivist := ps.HasPermission(user, models.PermLetterArchiveAll)
 
 	if !isCreator && !isArchivist {
 		return false, nil
@@ -149,6 +138,29 @@ func (ps *PermissionService) CanUserArchiveLetter(user *models.User, letter *mod
 	return true, nil
 }
 
+// CanUserDeleteLetter - Cek izin hapus: role dengan izin hapus semua surat
+// (bawaan: Admin), atau pembuat surat selama masih Draft. API key pembuat
+// juga harus punya scope membuat surat jenis tersebut.
+func (ps *PermissionService) CanUserDeleteLetter(user *models.User, letter *models.Letter) (bool, error) {
+	if user == nil {
+		return false, ErrUnauthorized
+	}
+	if letter == nil {
+		return false, ErrNotFound
+	}
+
+	if ps.HasPermission(user, models.PermLetterDeleteAll) {
+		return true, nil
+	}
+	if letter.CreatedByID != user.ID || letter.Status != models.StatusDraft {
+		return false, nil
+	}
+	if user.Scopes != nil {
+		return ps.CanUserCreateLetter(user, letter.Scope, letter.JenisSurat)
+	}
+	return true, nil
+}
+
 // GetLetterByID - Helper fetch letter
 func (ps *PermissionService) GetLetterByID(id uint) (*models.Letter, error) {
 	var letter models.Letter
@@ -171,13 +183,9 @@ func (ps *PermissionService) CanUserViewLetter(user *models.User, letter *models
 		return false, ErrUnauthorized
 	}
 
-	// 1. Admin & Direktur bisa lihat semua
-	if user.Role == models.RoleAdmin || user.Role == models.RoleDirektur || user.Role == models.RolePengurus {
-		return true, nil
-	}
-
-	// 2. Staf Lembaga (Archiver) bisa lihat SEMUA surat (internal & eksternal, masuk & keluar)
-	if user.Role == models.RoleStafLembaga {
+	// 1 & 2. Role dengan izin lihat semua (bawaan: Admin, Direktur, Pengurus,
+	// Staf Lembaga sebagai arsiparis) bisa lihat SEMUA surat
+	if ps.HasPermission(user, models.PermLetterViewAll) {
 		return true, nil
 	}
 
@@ -191,21 +199,87 @@ func (ps *PermissionService) CanUserViewLetter(user *models.User, letter *models
 		return true, nil
 	}
 
-	// 5. Manajer bisa lihat surat di Scope-nya (meski bukan verifier langsung, opsional)
-	if user.IsManajer() {
-		return user.CanVerifyScope(letter.Scope), nil
+	// 5. Verifikator bisa lihat surat di Scope-nya (meski bukan verifier langsung, opsional).
+	// Di luar scope tetap lanjut ke pengecekan tembusan.
+	if ps.HasPermission(user, models.VerifyPermission(letter.Scope)) {
+		return true, nil
+	}
+
+	// 6. Bawaan Staf Program: bisa lihat surat Eksternal ATAU Surat Masuk (untuk dibalas)
+	if letter.Scope == models.ScopeEksternal && ps.HasPermission(user, models.PermLetterViewEksternal) {
+		return true, nil
+	}
+	if letter.IsSuratMasuk() && ps.HasPermission(user, models.PermLetterMasukView) {
+		return true, nil
 	}
 
-	// 6. Staf Program bisa lihat surat Eksternal (scope bidangnya) ATAU Surat Masuk (untuk dibalas)
-	if user.Role == models.RoleStafProgram {
-		if letter.Scope == models.ScopeEksternal {
-			return true, nil
+	// 7. Anggota / kepala unit tujuan disposisi (atau unit induknya) bisa lihat
+	// surat yang didisposisikan ke unit tersebut
+	if letter.TujuanUnitID != nil {
+		member, err := NewUnitService(ps.db).IsMember(user.ID, *letter.TujuanUnitID)
+		if err != nil {
+			return false, err
 		}
-		// [FEATURE] Allow Staf Program to view ALL Incoming Letters so they can reply if disposed to them
-		if letter.IsSuratMasuk() {
+		if member {
 			return true, nil
 		}
 	}
 
+	// 8. Penerima tembusan (langsung atau lewat unit/role) bisa lihat setelah surat final
+	if letter.IsVisibleToTembusan() {
+		return NewTembusanService(ps.db).IsRecipient(user, letter.ID)
+	}
+
 	return false, nil
 }
+
+// ScopeViewableLetters adalah versi SQL dari CanUserViewLetter, dipakai untuk
+// menyaring daftar surat (misal hasil pencarian). Setiap perubahan aturan di
+// CanUserViewLetter harus ikut diubah di sini.
+func (ps *PermissionService) ScopeViewableLetters(user *models.User) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if ps.HasPermission(user, models.PermLetterViewAll) {
+			return db
+		}
+
+		conds := []string{"surat.created_by_id = ?", "surat.assigned_verifier_id = ?"}
+		args := []interface{}{user.ID, user.ID}
+
+		for _, scope := range []string{models.ScopeInternal, models.ScopeEksternal} {
+			if ps.HasPermission(user, models.VerifyPermission(scope)) {
+				conds = append(conds, "surat.scope = ?")
+				args = append(args, scope)
+			}
+		}
+
+		if ps.HasPermission(user, models.PermLetterViewEksternal) {
+			conds = append(conds, "surat.scope = ?")
+			args = append(args, models.ScopeEksternal)
+		}
+		if ps.HasPermission(user, models.PermLetterMasukView) {
+			conds = append(conds, "surat.jenis_surat = ?")
+			args = append(args, models.LetterMasuk)
+		}
+
+		unitIDs, err := NewUnitService(ps.db.Session(&gorm.Session{NewDB: true})).MemberUnitIDs(user.ID)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		if len(unitIDs) > 0 {
+			conds = append(conds, "surat.tujuan_unit_id IN ?")
+			args = append(args, unitIDs)
+		}
+
+		// Unscoped agar surat yang sudah dihapus tetap cocok (dipakai sync untuk tombstone)
+		tembusan := ps.db.Session(&gorm.Session{NewDB: true}).
+			Unscoped().
+			Model(&models.Letter{}).
+			Select("surat.id").
+			Scopes(ScopeTembusanRecipient(user))
+		conds = append(conds, "surat.id IN (?)")
+		args = append(args, tembusan)
+
+		return db.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"TugasAkhir/models"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrRoleSystem         = errors.New("system role cannot be deleted")
+	ErrRoleInUse          = errors.New("role is still assigned to users")
+	ErrPermissionNotFound = errors.New("permission not found")
+	ErrRoleLockout        = errors.New("admin.panel and admin.roles.manage cannot be removed from this role")
+)
+
+// lockoutPermissions tidak boleh dicabut dari role admin bawaan maupun role
+// yang sedang dipakai admin yang mengubahnya, agar pengelolaan role tidak
+// terkunci dari panel
+var lockoutPermissions = []string{models.PermAdminPanel, models.PermAdminRoles}
+
+// permissionCacheTTL - pemetaan role→permission dibaca ulang dari database
+// paling lambat setelah selang ini (perubahan dari admin langsung meng-invalidate)
+const permissionCacheTTL = time.Minute
+
+var permissionCache struct {
+	sync.RWMutex
+	roles    map[models.Role]map[string]bool
+	loadedAt time.Time
+}
+
+// InvalidatePermissionCache memaksa pengecekan permission berikutnya membaca
+// ulang tabel roles & role_permissions
+func InvalidatePermissionCache() {
+	permissionCache.Lock()
+	permissionCache.roles = nil
+	permissionCache.Unlock()
+}
+
+type RBACService struct {
+	db *gorm.DB
+}
+
+func NewRBACService(db *gorm.DB) *RBACService {
+	return &RBACService{db: db}
+}
+
+// HasPermission - true jika role memiliki salah satu permission yang diminta
+func (rs *RBACService) HasPermission(role models.Role, perms ...string) bool {
+	granted := rs.rolePermissions()[role]
+	for _, perm := range perms {
+		if granted[perm] {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions mengembalikan daftar permission role (terurut)
+func (rs *RBACService) Permissions(role models.Role) []string {
+	granted := rs.rolePermissions()[role]
+	perms := make([]string, 0, len(granted))
+	for perm := range granted {
+		perms = append(perms, perm)
+	}
+	sort.Strings(perms)
+	return perms
+}
+
+// RolesWithPermission mengembalikan semua role yang memiliki salah satu
+// permission, dipakai untuk mencari user berdasarkan kemampuan (misal daftar
+// verifikator)
+func (rs *RBACService) RolesWithPermission(perms ...string) []models.Role {
+	var roles []models.Role
+	for role, granted := range rs.rolePermissions() {
+		for _, perm := range perms {
+			if granted[perm] {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles
+}
+
+// RoleExists - role terdaftar di tabel roles
+func (rs *RBACService) RoleExists(role models.Role) bool {
+	_, ok := rs.rolePermissions()[role]
+	return ok
+}
+
+func (rs *RBACService) rolePermissions() map[models.Role]map[string]bool {
+	permissionCache.RLock()
+	roles, loadedAt := permissionCache.roles, permissionCache.loadedAt
+	permissionCache.RUnlock()
+	if roles != nil && time.Since(loadedAt) < permissionCacheTTL {
+		return roles
+	}
+
+	loaded, err := rs.loadRolePermissions()
+	if err != nil {
+		log.Printf("[rbac] gagal memuat permission: %v", err)
+		if roles != nil {
+			return roles // Pakai data lama sampai database bisa dibaca lagi
+		}
+		// Fail closed: tanpa data dari database tidak ada permission yang diberikan
+		return map[models.Role]map[string]bool{}
+	}
+
+	permissionCache.Lock()
+	permissionCache.roles = loaded
+	permissionCache.loadedAt = time.Now()
+	permissionCache.Unlock()
+	return loaded
+}
+
+func (rs *RBACService) loadRolePermissions() (map[models.Role]map[string]bool, error) {
+	var roles []models.RoleDefinition
+	if err := rs.db.Preload("Permissions").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	// Tabel roles belum di-seed (migrate belum dijalankan): pakai role bawaan
+	if len(roles) == 0 {
+		return defaultRolePermissions(), nil
+	}
+
+	result := make(map[models.Role]map[string]bool, len(roles))
+	for _, role := range roles {
+		granted := make(map[string]bool, len(role.Permissions))
+		for _, perm := range role.Permissions {
+			granted[perm.Code] = true
+		}
+		result[role.Name] = granted
+	}
+	return result, nil
+}
+
+func defaultRolePermissions() map[models.Role]map[string]bool {
+	result := make(map[models.Role]map[string]bool, len(models.DefaultRoles))
+	for _, role := range models.DefaultRoles {
+		granted := make(map[string]bool, len(role.Permissions))
+		for _, perm := range role.Permissions {
+			granted[perm] = true
+		}
+		result[role.Name] = granted
+	}
+	return result
+}
+
+// ListRoles mengambil semua role beserta permission-nya
+func (rs *RBACService) ListRoles() ([]models.RoleDefinition, error) {
+	var roles []models.RoleDefinition
+	err := rs.db.Preload("Permissions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("code ASC")
+	}).Order("is_system DESC, name ASC").Find(&roles).Error
+	return roles, err
+}
+
+// GetRole mengambil satu role beserta permission-nya
+func (rs *RBACService) GetRole(id uint) (*models.RoleDefinition, error) {
+	var role models.RoleDefinition
+	err := rs.db.Preload("Permissions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("code ASC")
+	}).First(&role, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// ListPermissions mengambil katalog permission
+func (rs *RBACService) ListPermissions() ([]models.Permission, error) {
+	var perms []models.Permission
+	err := rs.db.Order("code ASC").Find(&perms).Error
+	return perms, err
+}
+
+// SaveRole membuat / mengubah role dan mengganti seluruh permission-nya.
+// permissionCodes nil berarti permission tidak diubah. actingRole adalah role
+// yang dipakai admin saat mengubah; lockoutPermissions tidak bisa dicabut dari
+// role tersebut maupun dari role admin bawaan.
+func (rs *RBACService) SaveRole(role *models.RoleDefinition, permissionCodes []string, actingRole models.Role) error {
+	err := rs.db.Transaction(func(tx *gorm.DB) error {
+		if permissionCodes != nil && role.ID != 0 && (role.Name == models.RoleAdmin || role.Name == actingRole) {
+			if err := checkRoleLockout(tx, role, permissionCodes); err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit("Permissions").Save(role).Error; err != nil {
+			return err
+		}
+		if permissionCodes == nil {
+			return nil
+		}
+
+		var perms []models.Permission
+		if len(permissionCodes) > 0 {
+			if err := tx.Where("code IN ?", permissionCodes).Find(&perms).Error; err != nil {
+				return err
+			}
+			if len(perms) != len(uniqueStrings(permissionCodes)) {
+				return ErrPermissionNotFound
+			}
+		}
+		return tx.Model(role).Association("Permissions").Replace(perms)
+	})
+	if err == nil {
+		InvalidatePermissionCache()
+	}
+	return err
+}
+
+// checkRoleLockout - ErrRoleLockout jika permissionCodes mencabut salah satu
+// lockoutPermissions yang saat ini dimiliki role
+func checkRoleLockout(tx *gorm.DB, role *models.RoleDefinition, permissionCodes []string) error {
+	var current []models.Permission
+	if err := tx.Model(&models.RoleDefinition{Model: gorm.Model{ID: role.ID}}).Association("Permissions").Find(&current); err != nil {
+		return err
+	}
+	requested := uniqueStrings(permissionCodes)
+	for _, perm := range current {
+		for _, code := range lockoutPermissions {
+			if _, kept := requested[code]; perm.Code == code && !kept {
+				return ErrRoleLockout
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteRole menghapus role non-sistem yang tidak lagi dipakai user mana pun
+// (baik sebagai role utama maupun penugasan tambahan).
+// Hard delete agar nama role bisa dipakai lagi (kolom name unik).
+func (rs *RBACService) DeleteRole(id uint) error {
+	err := rs.db.Transaction(func(tx *gorm.DB) error {
+		var role models.RoleDefinition
+		if err := tx.First(&role, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRoleNotFound
+			}
+			return err
+		}
+		if role.IsSystem {
+			return ErrRoleSystem
+		}
+
+		var users int64
+		if err := tx.Model(&models.User{}).Where("role = ?", role.Name).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return ErrRoleInUse
+		}
+		if err := tx.Model(&models.UserRoleAssignment{}).Where("role = ?", role.Name).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return ErrRoleInUse
+		}
+
+		if err := tx.Model(&role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&role).Error
+	})
+	if err == nil {
+		InvalidatePermissionCache()
+	}
+	return err
+}
+
+func uniqueStrings(values []string) map[string]struct{} {
+	unique := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		unique[v] = struct{}{}
+	}
+	return unique
+}
+
+// SeedRBAC mengisi katalog permission dan delapan role bawaan. Aman dijalankan
+// berulang: role yang sudah ada tidak diubah, hanya permission yang baru
+// ditambahkan ke katalog yang diberikan ke role bawaan sesuai DefaultRoles.
+func SeedRBAC(db *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		byCode := make(map[string]models.Permission, len(models.DefaultPermissions))
+		newCodes := make(map[string]bool)
+		for _, def := range models.DefaultPermissions {
+			var perm models.Permission
+			err := tx.Where("code = ?", def.Code).First(&perm).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				perm = models.Permission{Code: def.Code, Description: def.Description}
+				if err := tx.Create(&perm).Error; err != nil {
+					return err
+				}
+				newCodes[def.Code] = true
+			} else if err != nil {
+				return err
+			}
+			byCode[def.Code] = perm
+		}
+
+		for _, def := range models.DefaultRoles {
+			var role models.RoleDefinition
+			created := false
+			err := tx.Where("name = ?", def.Name).First(&role).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				role = models.RoleDefinition{Name: def.Name, DisplayName: def.DisplayName, IsSystem: true}
+				if err := tx.Create(&role).Error; err != nil {
+					return err
+				}
+				created = true
+			} else if err != nil {
+				return err
+			}
+
+			var grant []models.Permission
+			for _, code := range def.Permissions {
+				if created || newCodes[code] {
+					grant = append(grant, byCode[code])
+				}
+			}
+			if len(grant) > 0 {
+				if err := tx.Model(&role).Association("Permissions").Append(grant); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		InvalidatePermissionCache()
+	}
+	return err
+}
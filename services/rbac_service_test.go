@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils/dbtest"
+
+	"gorm.io/gorm"
+)
+
+func newTestRBACService(t *testing.T) (*RBACService, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{}, &models.RoleDefinition{}, &models.Permission{}, &models.UserRoleAssignment{})
+	if err := SeedRBAC(db); err != nil {
+		t.Fatalf("SeedRBAC: %v", err)
+	}
+	// Cache permission bersifat global; jangan bawa data dari test lain
+	InvalidatePermissionCache()
+	t.Cleanup(InvalidatePermissionCache)
+	return NewRBACService(db), db
+}
+
+func roleByName(t *testing.T, db *gorm.DB, name models.Role) *models.RoleDefinition {
+	t.Helper()
+	var role models.RoleDefinition
+	if err := db.Where("name = ?", name).First(&role).Error; err != nil {
+		t.Fatalf("load role %s: %v", name, err)
+	}
+	return &role
+}
+
+func TestSeedRBACCreatesDefaultRoles(t *testing.T) {
+	rbac, db := newTestRBACService(t)
+
+	var roles, perms int64
+	db.Model(&models.RoleDefinition{}).Count(&roles)
+	db.Model(&models.Permission{}).Count(&perms)
+	if roles != int64(len(models.DefaultRoles)) || perms != int64(len(models.DefaultPermissions)) {
+		t.Fatalf("seeded %d roles / %d permissions, want %d / %d", roles, perms, len(models.DefaultRoles), len(models.DefaultPermissions))
+	}
+
+	for _, def := range models.DefaultRoles {
+		got := rbac.Permissions(def.Name)
+		if len(got) != len(def.Permissions) {
+			t.Errorf("%s permissions = %v, want %v", def.Name, got, def.Permissions)
+		}
+		if !roleByName(t, db, def.Name).IsSystem {
+			t.Errorf("%s is not marked as system role", def.Name)
+		}
+	}
+}
+
+func TestSeedRBACKeepsAdminChanges(t *testing.T) {
+	rbac, db := newTestRBACService(t)
+
+	// Admin mencabut izin dari role bawaan; seed ulang tidak mengembalikannya
+	staf := roleByName(t, db, models.RoleStafProgram)
+	if err := rbac.SaveRole(staf, []string{models.PermLetterKeluarCreateEksternal}, ""); err != nil {
+		t.Fatalf("SaveRole: %v", err)
+	}
+	if err := SeedRBAC(db); err != nil {
+		t.Fatalf("SeedRBAC again: %v", err)
+	}
+	InvalidatePermissionCache()
+
+	if rbac.HasPermission(models.RoleStafProgram, models.PermLetterMasukView) {
+		t.Fatal("SeedRBAC re-granted a permission removed by an admin")
+	}
+	var roles int64
+	db.Model(&models.RoleDefinition{}).Count(&roles)
+	if roles != int64(len(models.DefaultRoles)) {
+		t.Fatalf("SeedRBAC duplicated roles: %d", roles)
+	}
+}
+
+func TestSeedRBACGrantsNewCatalogPermissions(t *testing.T) {
+	rbac, db := newTestRBACService(t)
+
+	// Permission yang belum ada di katalog (misal dari versi baru) diberikan ke
+	// role bawaan sesuai DefaultRoles
+	if err := db.Unscoped().Where("code = ?", models.PermAdminAPIKeys).Delete(&models.Permission{}).Error; err != nil {
+		t.Fatalf("delete permission: %v", err)
+	}
+	InvalidatePermissionCache()
+	if rbac.HasPermission(models.RoleAdmin, models.PermAdminAPIKeys) {
+		t.Fatal("precondition: admin still has the deleted permission")
+	}
+
+	if err := SeedRBAC(db); err != nil {
+		t.Fatalf("SeedRBAC: %v", err)
+	}
+	InvalidatePermissionCache()
+	if !rbac.HasPermission(models.RoleAdmin, models.PermAdminAPIKeys) {
+		t.Fatal("new catalog permission was not granted to the admin role")
+	}
+	if rbac.HasPermission(models.RoleStafProgram, models.PermAdminAPIKeys) {
+		t.Fatal("new catalog permission was granted to a role outside DefaultRoles")
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	rbac, db := newTestRBACService(t)
+
+	tests := []struct {
+		role  models.Role
+		perms []string
+		want  bool
+	}{
+		{models.RoleAdmin, []string{models.PermAdminRoles}, true},
+		{models.RoleStafProgram, []string{models.PermAdminRoles}, false},
+		{models.RoleStafProgram, []string{models.PermLetterKeluarCreateInternal, models.PermLetterKeluarCreateEksternal}, true},
+		{models.RoleDirektur, nil, false},
+		{"tidak_ada", []string{models.PermLetterViewAll}, false},
+	}
+	for _, tt := range tests {
+		if got := rbac.HasPermission(tt.role, tt.perms...); got != tt.want {
+			t.Errorf("HasPermission(%s, %v) = %v, want %v", tt.role, tt.perms, got, tt.want)
+		}
+	}
+
+	// Role baru dari admin langsung berlaku (cache di-invalidate oleh SaveRole)
+	custom := &models.RoleDefinition{Name: "sekretaris", DisplayName: "Sekretaris"}
+	if err := rbac.SaveRole(custom, []string{models.PermLetterMasukCreate}, ""); err != nil {
+		t.Fatalf("SaveRole: %v", err)
+	}
+	if !rbac.HasPermission("sekretaris", models.PermLetterMasukCreate) {
+		t.Fatal("custom role permission not visible after SaveRole")
+	}
+	if err := rbac.SaveRole(roleByName(t, db, "sekretaris"), []string{}, ""); err != nil {
+		t.Fatalf("SaveRole (clear): %v", err)
+	}
+	if rbac.HasPermission("sekretaris", models.PermLetterMasukCreate) {
+		t.Fatal("removed permission still granted")
+	}
+}
+
+func TestHasPermissionFailsClosedOnDatabaseError(t *testing.T) {
+	rbac, db := newTestRBACService(t)
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db.DB: %v", err)
+	}
+	sqlDB.Close()
+	InvalidatePermissionCache()
+
+	if rbac.HasPermission(models.RoleAdmin, models.PermAdminPanel) {
+		t.Fatal("permission granted while the roles table could not be read")
+	}
+}
+
+func TestSaveRoleRejectsAdminLockout(t *testing.T) {
+	rbac, db := newTestRBACService(t)
+	withoutRoles := []string{models.PermAdminPanel, models.PermAdminUsers}
+
+	// Role admin bawaan, siapa pun yang mengubah
+	if err := rbac.SaveRole(roleByName(t, db, models.RoleAdmin), withoutRoles, models.RoleDirektur); !errors.Is(err, ErrRoleLockout) {
+		t.Fatalf("SaveRole(admin) err = %v, want ErrRoleLockout", err)
+	}
+	if !rbac.HasPermission(models.RoleAdmin, models.PermAdminRoles) {
+		t.Fatal("admin role lost admin.roles.manage")
+	}
+
+	// Role yang sedang dipakai admin
+	operator := &models.RoleDefinition{Name: "operator", DisplayName: "Operator"}
+	if err := rbac.SaveRole(operator, []string{models.PermAdminPanel, models.PermAdminRoles}, ""); err != nil {
+		t.Fatalf("SaveRole (create): %v", err)
+	}
+	if err := rbac.SaveRole(operator, []string{models.PermAdminRoles}, "operator"); !errors.Is(err, ErrRoleLockout) {
+		t.Fatalf("SaveRole(acting role) err = %v, want ErrRoleLockout", err)
+	}
+	// Admin lain (role admin) boleh mengubahnya; permission lain tetap bebas diubah
+	if err := rbac.SaveRole(operator, []string{models.PermAdminRoles}, models.RoleAdmin); err != nil {
+		t.Fatalf("SaveRole by another role: %v", err)
+	}
+	if err := rbac.SaveRole(roleByName(t, db, models.RoleAdmin), []string{models.PermAdminPanel, models.PermAdminRoles}, models.RoleAdmin); err != nil {
+		t.Fatalf("SaveRole(admin) keeping lockout permissions: %v", err)
+	}
+}
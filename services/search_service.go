@@ -0,0 +1,316 @@
+package services
+
+import (
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/utils/search"
+	"TugasAkhir/utils/storage"
+	"context"
+	"errors"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrSearchUnavailable = errors.New("search index unavailable")
+
+const (
+	searchCheckpointKey  = "indexer:checkpoint"
+	searchAttachmentKey  = "attachment:"
+	searchIndexBatchSize = 200
+	searchCandidateBatch = 1000 // Hit index yang dicek permission-nya per query database
+	// Overlap agar surat yang berubah tepat di batas checkpoint tidak terlewat
+	searchCheckpointSkew = 2 * time.Second
+)
+
+type SearchService struct {
+	db          *gorm.DB
+	index       *search.Index
+	permService *PermissionService
+}
+
+func NewSearchService(db *gorm.DB, index *search.Index) *SearchService {
+	return &SearchService{
+		db:          db,
+		index:       index,
+		permService: NewPermissionService(db),
+	}
+}
+
+// SearchParams adalah parameter pencarian dari query string
+type SearchParams struct {
+	Query       string
+	JenisSurat  string
+	Status      string
+	Scope       string
+	Prioritas   string
+	CreatedByID string
+	DateFrom    *time.Time
+	DateTo      *time.Time
+	Page        int
+	Limit       int
+}
+
+// SearchResultItem adalah satu surat hasil pencarian beserta potongan teks
+// yang cocok (kata yang cocok dibungkus <mark>)
+type SearchResultItem struct {
+	Letter     models.Letter       `json:"letter"`
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+// SearchResult berisi satu halaman hasil, total dan facet. Facet dihitung
+// dari seluruh hasil yang boleh dilihat user, bukan hanya halaman ini.
+type SearchResult struct {
+	Items  []SearchResultItem        `json:"items"`
+	Facets map[string]map[string]int `json:"facets"`
+	Total  int64                     `json:"-"`
+}
+
+// Search menjalankan pencarian full-text lalu menyaring hasilnya dengan
+// aturan yang sama seperti CanUserViewLetter. Semua hit index diperiksa per
+// batch, sehingga Total & facet mencakup seluruh surat yang boleh dilihat.
+func (s *SearchService) Search(user *models.User, p SearchParams) (*SearchResult, error) {
+	if s.index == nil {
+		return nil, ErrSearchUnavailable
+	}
+
+	result := &SearchResult{
+		Items:  []SearchResultItem{},
+		Facets: make(map[string]map[string]int, len(search.FacetFields)),
+	}
+	for _, field := range search.FacetFields {
+		result.Facets[field] = map[string]int{}
+	}
+
+	q := search.Query{
+		Text: strings.TrimSpace(p.Query),
+		Filters: map[string]string{
+			"jenis_surat":   p.JenisSurat,
+			"status":        p.Status,
+			"scope":         p.Scope,
+			"prioritas":     p.Prioritas,
+			"created_by_id": p.CreatedByID,
+		},
+		DateFrom:  p.DateFrom,
+		DateTo:    p.DateTo,
+		MaxResult: searchCandidateBatch,
+	}
+	var visible []search.Hit
+	for {
+		hits, err := s.index.Search(q)
+		if err != nil {
+			return nil, err
+		}
+		allowed, err := s.viewableHits(user, hits)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range allowed {
+			for _, field := range search.FacetFields {
+				if v := h.Fields[field]; v != "" {
+					result.Facets[field][v]++
+				}
+			}
+		}
+		visible = append(visible, allowed...)
+		if len(hits) < searchCandidateBatch {
+			break
+		}
+		q.From += len(hits)
+	}
+	result.Total = int64(len(visible))
+
+	start := (p.Page - 1) * p.Limit
+	if start >= len(visible) {
+		return result, nil
+	}
+	end := start + p.Limit
+	if end > len(visible) {
+		end = len(visible)
+	}
+	pageHits := visible[start:end]
+
+	pageIDs := make([]uint, len(pageHits))
+	for i, h := range pageHits {
+		pageIDs[i] = h.ID
+	}
+	var letters []models.Letter
+	if err := s.db.Preload("CreatedBy").Where("id IN ?", pageIDs).Find(&letters).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Letter, len(letters))
+	for _, l := range letters {
+		byID[l.ID] = l
+	}
+
+	for _, h := range pageHits {
+		letter, ok := byID[h.ID]
+		if !ok {
+			continue
+		}
+		result.Items = append(result.Items, SearchResultItem{
+			Letter:     letter,
+			Score:      h.Score,
+			Highlights: h.Highlights,
+		})
+	}
+	return result, nil
+}
+
+// viewableHits menyaring hits ke surat yang boleh dilihat user. Index bisa
+// sedikit tertinggal dari database, jadi permission & soft delete selalu dicek
+// ulang ke database.
+func (s *SearchService) viewableHits(user *models.User, hits []search.Hit) ([]search.Hit, error) {
+	if len(hits) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	var allowedIDs []uint
+	if err := s.db.Model(&models.Letter{}).
+		Scopes(s.permService.ScopeViewableLetters(user)).
+		Where("surat.id IN ?", ids).
+		Pluck("surat.id", &allowedIDs).Error; err != nil {
+		return nil, err
+	}
+	allowed := make(map[uint]struct{}, len(allowedIDs))
+	for _, id := range allowedIDs {
+		allowed[id] = struct{}{}
+	}
+
+	visible := hits[:0]
+	for _, h := range hits {
+		if _, ok := allowed[h.ID]; ok {
+			visible = append(visible, h)
+		}
+	}
+	return visible, nil
+}
+
+// StartIndexer menjalankan indexing berkala sampai ctx selesai. Surat yang
+// berubah sejak checkpoint terakhir di-index ulang, surat yang dihapus
+// dikeluarkan dari index.
+func (s *SearchService) StartIndexer(ctx context.Context) {
+	if s.index == nil {
+		return
+	}
+
+	interval := config.LoadSearchConfig().IndexInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.IndexChanged(ctx); err != nil {
+			log.Printf("⚠️ Search indexer gagal: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// IndexChanged meng-index semua surat yang berubah sejak checkpoint terakhir
+func (s *SearchService) IndexChanged(ctx context.Context) error {
+	since := s.checkpoint()
+	now := time.Now()
+
+	var letters []models.Letter
+	err := s.db.Unscoped().
+		Where("updated_at > ? OR deleted_at > ?", since, since).
+		FindInBatches(&letters, searchIndexBatchSize, func(tx *gorm.DB, batch int) error {
+			for i := range letters {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := s.indexLetter(ctx, &letters[i]); err != nil {
+					log.Printf("⚠️ Gagal index surat %d: %v", letters[i].ID, err)
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	return s.index.SetInternal(searchCheckpointKey, []byte(now.Add(-searchCheckpointSkew).Format(time.RFC3339Nano)))
+}
+
+func (s *SearchService) checkpoint() time.Time {
+	raw, err := s.index.GetInternal(searchCheckpointKey)
+	if err != nil || len(raw) == 0 {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(raw))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func (s *SearchService) indexLetter(ctx context.Context, letter *models.Letter) error {
+	if letter.DeletedAt.Valid {
+		return s.index.Delete(letter.ID)
+	}
+
+	tanggal := letter.CreatedAt
+	if letter.TanggalSurat != nil {
+		tanggal = *letter.TanggalSurat
+	} else if letter.TanggalMasuk != nil {
+		tanggal = *letter.TanggalMasuk
+	}
+
+	return s.index.Upsert(letter.ID, search.LetterDocument{
+		JudulSurat:  letter.JudulSurat,
+		NomorSurat:  letter.NomorSurat,
+		Pengirim:    letter.Pengirim,
+		IsiSurat:    letter.IsiSurat,
+		Kesimpulan:  letter.Kesimpulan,
+		Lampiran:    s.attachmentText(ctx, letter),
+		JenisSurat:  string(letter.JenisSurat),
+		Status:      string(letter.Status),
+		Scope:       letter.Scope,
+		Prioritas:   string(letter.Prioritas),
+		CreatedByID: strconv.FormatUint(uint64(letter.CreatedByID), 10),
+		Tanggal:     tanggal,
+	})
+}
+
+// attachmentText mengambil teks lampiran PDF. Hasil ekstraksi disimpan per
+// FilePath agar file tidak diunduh ulang setiap kali metadata surat berubah.
+func (s *SearchService) attachmentText(ctx context.Context, letter *models.Letter) string {
+	if letter.FilePath == "" || !strings.EqualFold(path.Ext(letter.FilePath), ".pdf") {
+		return ""
+	}
+
+	cacheKey := searchAttachmentKey + strconv.FormatUint(uint64(letter.ID), 10)
+	if cached, err := s.index.GetInternal(cacheKey); err == nil && len(cached) > 0 {
+		if key, text, ok := strings.Cut(string(cached), "\x00"); ok && key == letter.FilePath {
+			return text
+		}
+	}
+
+	data, err := storage.DownloadFile(ctx, letter.FilePath)
+	if err != nil {
+		log.Printf("⚠️ Gagal unduh lampiran surat %d untuk index: %v", letter.ID, err)
+		return ""
+	}
+	text, err := search.ExtractPDFText(data)
+	if err != nil {
+		log.Printf("⚠️ Gagal ekstrak teks lampiran surat %d: %v", letter.ID, err)
+	}
+
+	if err := s.index.SetInternal(cacheKey, []byte(letter.FilePath+"\x00"+text)); err != nil {
+		log.Printf("⚠️ Gagal simpan cache lampiran surat %d: %v", letter.ID, err)
+	}
+	return text
+}
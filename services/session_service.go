@@ -0,0 +1,213 @@
+package services
+
+import (
+	"TugasAkhir/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionDevice - informasi perangkat yang ditampilkan di daftar sesi
+type SessionDevice struct {
+	Name      string
+	Platform  string
+	IP        string
+	UserAgent string
+}
+
+// SessionService mengelola sesi login API beserta refresh token-nya
+type SessionService struct {
+	db *gorm.DB
+}
+
+func NewSessionService(db *gorm.DB) *SessionService {
+	return &SessionService{db: db}
+}
+
+// HashRefreshToken - refresh token hanya disimpan dalam bentuk hash
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Start membuat sesi baru saat login. Refresh token pertamanya disimpan lewat
+// Attach setelah token dibuat (token membawa ID sesi).
+func (s *SessionService) Start(userID uint, device SessionDevice) (*models.UserSession, error) {
+	now := time.Now()
+	session := models.UserSession{
+		UserID:     userID,
+		DeviceName: truncate(device.Name, 100),
+		Platform:   truncate(device.Platform, 50),
+		IP:         device.IP,
+		UserAgent:  truncate(device.UserAgent, 255),
+		LastUsedAt: now,
+		ExpiresAt:  now,
+	}
+	if err := s.db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Attach menyimpan hash refresh token untuk sesi dan memperpanjang masa
+// berlaku sesi mengikuti token tersebut
+func (s *SessionService) Attach(session *models.UserSession, token string, expiresAt time.Time) error {
+	record := models.RefreshToken{
+		TokenHash: HashRefreshToken(token),
+		UserID:    session.UserID,
+		SessionID: &session.ID,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		return err
+	}
+
+	session.ExpiresAt = expiresAt
+	session.LastUsedAt = time.Now()
+	return s.db.Model(session).Updates(map[string]any{
+		"expires_at":   session.ExpiresAt,
+		"last_used_at": session.LastUsedAt,
+	}).Error
+}
+
+// LockRefreshToken mencari refresh token (FOR UPDATE) agar satu token tidak
+// bisa dirotasi dua kali secara bersamaan. Dipanggil di dalam transaksi.
+func (s *SessionService) LockRefreshToken(token string) (*models.RefreshToken, error) {
+	var stored models.RefreshToken
+	err := s.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", HashRefreshToken(token)).
+		First(&stored).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// Resume mengambil sesi milik refresh token. Token lama yang belum punya sesi
+// dibuatkan sesi baru dari perangkat yang sedang me-refresh.
+func (s *SessionService) Resume(stored *models.RefreshToken, device SessionDevice) (*models.UserSession, error) {
+	if stored.SessionID != nil {
+		var session models.UserSession
+		err := s.db.Where("id = ? AND user_id = ?", *stored.SessionID, stored.UserID).First(&session).Error
+		if err == nil {
+			return &session, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, ErrSessionNotFound
+	}
+	return s.Start(stored.UserID, device)
+}
+
+// Rotate mengganti refresh token lama dengan yang baru di sesi yang sama
+func (s *SessionService) Rotate(stored *models.RefreshToken, session *models.UserSession, token string, expiresAt time.Time, ip string) error {
+	if err := s.db.Delete(stored).Error; err != nil {
+		return err
+	}
+	if ip != "" && ip != session.IP {
+		session.IP = ip
+		if err := s.db.Model(session).Update("ip", ip).Error; err != nil {
+			return err
+		}
+	}
+	return s.Attach(session, token, expiresAt)
+}
+
+// List mengambil sesi user yang masih berlaku, terbaru dipakai lebih dulu
+func (s *SessionService) List(userID uint) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	err := s.db.Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Revoke mencabut satu sesi milik user beserta refresh token-nya
+func (s *SessionService) Revoke(userID, sessionID uint) error {
+	revoked, err := s.revoke("user_id = ? AND id = ?", userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOthers mencabut semua sesi user kecuali keepID (sesi yang sedang dipakai)
+func (s *SessionService) RevokeOthers(userID, keepID uint) (int64, error) {
+	return s.revoke("user_id = ? AND id <> ?", userID, keepID)
+}
+
+// RevokeAll mencabut semua sesi user, misal setelah password atau role
+// diubah. Access token yang sudah terbit ikut dibatalkan lewat versi token.
+func (s *SessionService) RevokeAll(userID uint) (int64, error) {
+	revoked, err := s.revoke("user_id = ?", userID)
+	if err != nil {
+		return revoked, err
+	}
+	// Token lama tanpa sesi ikut dihapus
+	if err := s.db.Unscoped().Where("user_id = ?", userID).Delete(&models.RefreshToken{}).Error; err != nil {
+		return revoked, err
+	}
+	return revoked, NewTokenVersionService(s.db).Bump(userID)
+}
+
+// RevokeToken dipakai saat logout: sesi pemilik token dicabut. Mengembalikan
+// nil jika token tidak ditemukan (sudah logout atau sudah dirotasi).
+func (s *SessionService) RevokeToken(token string) (*models.RefreshToken, error) {
+	var stored models.RefreshToken
+	err := s.db.Where("token_hash = ?", HashRefreshToken(token)).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.SessionID != nil {
+		_, err = s.revoke("id = ?", *stored.SessionID)
+	} else {
+		err = s.db.Unscoped().Delete(&stored).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// revoke menghapus permanen sesi yang cocok dengan kondisi beserta refresh
+// token-nya
+func (s *SessionService) revoke(query string, args ...any) (int64, error) {
+	var revoked int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&models.UserSession{}).Where(query, args...).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Unscoped().Where("session_id IN ?", ids).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Where("id IN ?", ids).Delete(&models.UserSession{})
+		revoked = res.RowsAffected
+		return res.Error
+	})
+	return revoked, err
+}
+
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
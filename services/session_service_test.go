@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils/dbtest"
+
+	"gorm.io/gorm"
+)
+
+func newTestSessionService(t *testing.T) (*SessionService, *gorm.DB, models.User) {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{}, &models.UserSession{}, &models.RefreshToken{})
+	user := createTestUser(t, db, models.User{Username: "dewi", Email: "dewi@yayasan.org", Role: models.RoleStafProgram})
+	InvalidateTokenState(user.ID)
+	t.Cleanup(func() { InvalidateTokenState(user.ID) })
+	return NewSessionService(db), db, user
+}
+
+// startTestSession membuat sesi dengan refresh token pertamanya
+func startTestSession(t *testing.T, sessions *SessionService, userID uint, token string) *models.UserSession {
+	t.Helper()
+	session, err := sessions.Start(userID, SessionDevice{Name: "Pixel 8", IP: "10.0.0.7"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := sessions.Attach(session, token, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	return session
+}
+
+func TestRefreshTokenIsStoredHashed(t *testing.T) {
+	sessions, db, user := newTestSessionService(t)
+	session := startTestSession(t, sessions, user.ID, "refresh-token-1")
+
+	var stored models.RefreshToken
+	if err := db.Where("session_id = ?", session.ID).First(&stored).Error; err != nil {
+		t.Fatalf("load refresh token: %v", err)
+	}
+	if stored.TokenHash != HashRefreshToken("refresh-token-1") || stored.TokenHash == "refresh-token-1" {
+		t.Fatalf("token_hash = %q, want SHA-256 of the token", stored.TokenHash)
+	}
+
+	found, err := sessions.LockRefreshToken("refresh-token-1")
+	if err != nil {
+		t.Fatalf("LockRefreshToken: %v", err)
+	}
+	if found.ID != stored.ID || found.SessionID == nil || *found.SessionID != session.ID {
+		t.Fatalf("LockRefreshToken = %+v, want token %d of session %d", found, stored.ID, session.ID)
+	}
+	// Hash yang bocor dari database tidak bisa dipakai sebagai token
+	if _, err := sessions.LockRefreshToken(stored.TokenHash); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("lookup by hash err = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestRotatedRefreshTokenIsRejectedOnReuse(t *testing.T) {
+	sessions, _, user := newTestSessionService(t)
+	session := startTestSession(t, sessions, user.ID, "refresh-token-1")
+
+	stored, err := sessions.LockRefreshToken("refresh-token-1")
+	if err != nil {
+		t.Fatalf("LockRefreshToken: %v", err)
+	}
+	resumed, err := sessions.Resume(stored, SessionDevice{})
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if resumed.ID != session.ID {
+		t.Fatalf("Resume session = %d, want %d", resumed.ID, session.ID)
+	}
+	if err := sessions.Rotate(stored, resumed, "refresh-token-2", time.Now().Add(2*time.Hour), "10.0.0.8"); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	if _, err := sessions.LockRefreshToken("refresh-token-1"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("reused token err = %v, want ErrRecordNotFound", err)
+	}
+	rotated, err := sessions.LockRefreshToken("refresh-token-2")
+	if err != nil {
+		t.Fatalf("LockRefreshToken rotated: %v", err)
+	}
+	if rotated.SessionID == nil || *rotated.SessionID != session.ID {
+		t.Fatalf("rotated token session = %v, want %d", rotated.SessionID, session.ID)
+	}
+
+	list, err := sessions.List(user.ID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 1 || list[0].IP != "10.0.0.8" {
+		t.Fatalf("sessions = %+v, want the same session with updated IP", list)
+	}
+
+	// Logout dengan token lama tidak mencabut sesi
+	if revoked, err := sessions.RevokeToken("refresh-token-1"); err != nil || revoked != nil {
+		t.Fatalf("RevokeToken old = %+v, %v, want nil", revoked, err)
+	}
+	if _, err := sessions.RevokeToken("refresh-token-2"); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if _, err := sessions.LockRefreshToken("refresh-token-2"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("token after logout err = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/oauth2"
+)
+
+// ExternalIdentity - user yang sudah diautentikasi provider eksternal
+type ExternalIdentity struct {
+	Provider string // models.IdentityProviderLDAP / models.IdentityProviderOIDC
+	Subject  string // ID stabil di provider: DN (LDAP) atau sub (OIDC)
+	Email    string
+	// EmailVerified - provider menjamin email milik user. Hanya email
+	// terverifikasi yang boleh dipakai untuk menautkan akun.
+	EmailVerified bool
+	Username      string
+	FirstName     string
+	LastName      string
+	Groups        []string
+}
+
+// PasswordProvider memeriksa email & password langsung ke provider (LDAP bind)
+type PasswordProvider interface {
+	Authenticate(ctx context.Context, login, password string) (*ExternalIdentity, error)
+}
+
+// RedirectProvider - login terjadi di halaman provider lalu kembali dengan
+// authorization code (OIDC)
+type RedirectProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier, redirectURL string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, redirectURL, nonce string) (*ExternalIdentity, error)
+}
+
+// =====================
+// LDAP
+// =====================
+
+type LDAPProvider struct {
+	cfg       config.LDAPConfig
+	tlsConfig *tls.Config
+}
+
+// NewLDAPProvider menyiapkan provider LDAP. LDAP_CA_FILE (opsional) ditambahkan
+// ke CA sistem untuk koneksi ldaps://.
+func NewLDAPProvider(cfg config.LDAPConfig) (*LDAPProvider, error) {
+	p := &LDAPProvider{cfg: cfg}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read LDAP CA: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificate found in LDAP CA file")
+		}
+		p.tlsConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return p, nil
+}
+
+// Authenticate mencari entry user dengan akun layanan, lalu bind sebagai user
+// tersebut dengan password yang dimasukkan
+func (p *LDAPProvider) Authenticate(_ context.Context, login, password string) (*ExternalIdentity, error) {
+	// Simple bind dengan password kosong adalah unauthenticated bind yang
+	// diterima banyak server, jadi ditolak sebelum menghubungi server
+	if login == "" || password == "" {
+		return nil, ErrSSOInvalidCredentials
+	}
+
+	opts := []ldap.DialOpt{ldap.DialWithDialer(&net.Dialer{Timeout: p.cfg.Timeout})}
+	if p.tlsConfig != nil {
+		opts = append(opts, ldap.DialWithTLSConfig(p.tlsConfig))
+	}
+	conn, err := ldap.DialURL(p.cfg.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: connect: %w", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(p.cfg.Timeout)
+
+	if p.cfg.BindDN != "" {
+		if err := conn.Bind(p.cfg.BindDN, p.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap: service bind: %w", err)
+		}
+	}
+
+	// Dua hasil cukup untuk mendeteksi login yang tidak unik
+	res, err := conn.Search(ldap.NewSearchRequest(
+		p.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(p.cfg.Timeout/time.Second), false,
+		fmt.Sprintf("(%s=%s)", ldap.EscapeFilter(p.cfg.LoginAttribute), ldap.EscapeFilter(login)),
+		[]string{"mail", "givenName", "sn", p.cfg.UsernameAttribute, p.cfg.GroupAttribute},
+		nil,
+	))
+	switch {
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject):
+		return nil, ErrSSOInvalidCredentials
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded):
+		// Hasil yang sudah diterima tetap dikembalikan; lebih dari satu ditolak di bawah
+	case err != nil:
+		return nil, fmt.Errorf("ldap: search: %w", err)
+	}
+	if res == nil || len(res.Entries) != 1 {
+		return nil, ErrSSOInvalidCredentials
+	}
+	entry := res.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrSSOInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap: user bind: %w", err)
+	}
+
+	email := entry.GetAttributeValue("mail")
+	if email == "" && strings.EqualFold(p.cfg.LoginAttribute, "mail") {
+		email = login
+	}
+	// Atribut mail dikelola administrator direktori, bukan oleh user sendiri
+	return &ExternalIdentity{
+		Provider:      models.IdentityProviderLDAP,
+		Subject:       strings.ToLower(entry.DN),
+		Email:         email,
+		EmailVerified: email != "",
+		Username:      entry.GetEqualFoldAttributeValue(p.cfg.UsernameAttribute),
+		FirstName:     entry.GetEqualFoldAttributeValue("givenName"),
+		LastName:      entry.GetEqualFoldAttributeValue("sn"),
+		Groups:        entry.GetEqualFoldAttributeValues(p.cfg.GroupAttribute),
+	}, nil
+}
+
+// =====================
+// OIDC
+// =====================
+
+type OIDCProvider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewOIDCProvider(cfg config.OIDCConfig) *OIDCProvider {
+	return &OIDCProvider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// discover memuat metadata provider sekali. Jika gagal (provider sedang
+// tidak bisa dihubungi), dicoba lagi pada permintaan berikutnya.
+func (p *OIDCProvider) discover(ctx context.Context) (*oidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.provider != nil {
+		return p.provider, nil
+	}
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, p.client), p.cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", err)
+	}
+	p.provider = provider
+	return provider, nil
+}
+
+func (p *OIDCProvider) oauth2Config(provider *oidc.Provider, redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURL,
+		Scopes:       p.cfg.Scopes,
+	}
+}
+
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier, redirectURL string) (string, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauth2Config(provider, redirectURL).AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(codeVerifier),
+	), nil
+}
+
+// Exchange menukar code dan memverifikasi ID token. Email hanya dianggap
+// terverifikasi jika klaim email_verified bernilai true; klaim yang tidak ada
+// dianggap belum terverifikasi.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, redirectURL, nonce string) (*ExternalIdentity, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = oidc.ClientContext(ctx, p.client)
+
+	token, err := p.oauth2Config(provider, redirectURL).Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: token request: %w", err)
+	}
+	raw, ok := token.Extra("id_token").(string)
+	if !ok || raw == "" {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID}).Verify(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid id_token: %w", err)
+	}
+	// go-oidc tidak memeriksa nonce maupun azp
+	if idToken.Nonce == "" || idToken.Nonce != nonce {
+		return nil, errors.New("oidc: nonce mismatch")
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("oidc: read claims: %w", err)
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != p.cfg.ClientID {
+		return nil, errors.New("oidc: id_token authorized party mismatch")
+	}
+	verified, _ := claims["email_verified"].(bool)
+
+	return &ExternalIdentity{
+		Provider:      models.IdentityProviderOIDC,
+		Subject:       idToken.Subject,
+		Email:         stringClaim(claims, "email"),
+		EmailVerified: verified,
+		Username:      stringClaim(claims, "preferred_username"),
+		FirstName:     stringClaim(claims, "given_name"),
+		LastName:      stringClaim(claims, "family_name"),
+		Groups:        stringsClaim(claims, p.cfg.GroupsClaim),
+	}, nil
+}
+
+func stringClaim(claims map[string]any, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+// stringsClaim membaca klaim berisi string atau array string (misal "groups")
+func stringsClaim(claims map[string]any, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/utils/ldap/ldaptest"
+	"TugasAkhir/utils/oidc/oidctest"
+)
+
+const (
+	testBaseDN   = "ou=people,dc=yayasan,dc=org"
+	testGroupDir = "cn=direksi,ou=groups,dc=yayasan,dc=org"
+)
+
+func newTestLDAPProvider(t *testing.T) *LDAPProvider {
+	t.Helper()
+	server := ldaptest.NewServer(
+		ldaptest.Entry{DN: "cn=reader,dc=yayasan,dc=org", Password: "reader-secret"},
+		ldaptest.Entry{
+			DN:       "uid=sari," + testBaseDN,
+			Password: "rahasia-sari",
+			Attributes: map[string][]string{
+				"uid":       {"sari"},
+				"mail":      {"sari@yayasan.org"},
+				"givenName": {"Sari"},
+				"sn":        {"Wulandari"},
+				"memberOf":  {testGroupDir},
+			},
+		},
+	)
+	t.Cleanup(server.Close)
+
+	provider, err := NewLDAPProvider(config.LDAPConfig{
+		URL:               server.URL,
+		BindDN:            "cn=reader,dc=yayasan,dc=org",
+		BindPassword:      "reader-secret",
+		BaseDN:            testBaseDN,
+		LoginAttribute:    "mail",
+		UsernameAttribute: "uid",
+		GroupAttribute:    "memberOf",
+		Timeout:           2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewLDAPProvider: %v", err)
+	}
+	return provider
+}
+
+func TestLDAPProviderAuthenticate(t *testing.T) {
+	provider := newTestLDAPProvider(t)
+
+	identity, err := provider.Authenticate(context.Background(), "sari@yayasan.org", "rahasia-sari")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if identity.Provider != models.IdentityProviderLDAP || identity.Subject != "uid=sari,"+testBaseDN {
+		t.Fatalf("identity = %s %q", identity.Provider, identity.Subject)
+	}
+	if !identity.EmailVerified || identity.Email != "sari@yayasan.org" || identity.Username != "sari" || identity.FirstName != "Sari" || identity.LastName != "Wulandari" {
+		t.Fatalf("unexpected profile: %+v", identity)
+	}
+	if len(identity.Groups) != 1 || identity.Groups[0] != testGroupDir {
+		t.Fatalf("groups = %v", identity.Groups)
+	}
+}
+
+func TestLDAPProviderRejectsBadCredentials(t *testing.T) {
+	provider := newTestLDAPProvider(t)
+
+	cases := map[string][2]string{
+		"wrong password": {"sari@yayasan.org", "salah"},
+		"empty password": {"sari@yayasan.org", ""},
+		"unknown user":   {"tidak.ada@yayasan.org", "rahasia-sari"},
+	}
+	for name, creds := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := provider.Authenticate(context.Background(), creds[0], creds[1])
+			if !errors.Is(err, ErrSSOInvalidCredentials) {
+				t.Fatalf("err = %v, want ErrSSOInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderCodeFlow(t *testing.T) {
+	idp := oidctest.NewServer("digital-mail", "client-secret")
+	t.Cleanup(idp.Close)
+	idp.SignIn(map[string]any{
+		"sub":                "0f6c2a",
+		"email":              "dewi@yayasan.org",
+		"email_verified":     true,
+		"preferred_username": "dewi",
+		"groups":             []string{"direksi", "semua-staf"},
+	})
+
+	provider := NewOIDCProvider(config.OIDCConfig{
+		IssuerURL:    idp.Issuer,
+		ClientID:     "digital-mail",
+		ClientSecret: "client-secret",
+		Scopes:       []string{"openid", "email", "profile"},
+		GroupsClaim:  "groups",
+	})
+	const redirectURL = "https://mail.yayasan.org/admin/login/sso/callback"
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-verifier-verifier-verifier-verifier", redirectURL)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code := authorize(t, authURL, "state-1")
+
+	identity, err := provider.Exchange(ctx, code, "verifier-verifier-verifier-verifier-verifier", redirectURL, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Provider != models.IdentityProviderOIDC || identity.Subject != "0f6c2a" || identity.Email != "dewi@yayasan.org" {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+	if len(identity.Groups) != 2 || identity.Groups[0] != "direksi" {
+		t.Fatalf("groups = %v", identity.Groups)
+	}
+
+	// Nonce milik permintaan lain ditolak
+	code = authorize(t, authURL, "state-1")
+	if _, err := provider.Exchange(ctx, code, "verifier-verifier-verifier-verifier-verifier", redirectURL, "nonce-lain"); err == nil {
+		t.Fatal("expected nonce mismatch to be rejected")
+	}
+}
+
+func TestOIDCProviderEmailVerifiedClaim(t *testing.T) {
+	cases := map[string]struct {
+		claims map[string]any
+		want   bool
+	}{
+		"verified":     {map[string]any{"sub": "1", "email": "dewi@yayasan.org", "email_verified": true}, true},
+		"not verified": {map[string]any{"sub": "2", "email": "palsu@yayasan.org", "email_verified": false}, false},
+		// Klaim yang tidak dikirim provider tidak dianggap terverifikasi
+		"claim missing": {map[string]any{"sub": "3", "email": "palsu@yayasan.org"}, false},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			idp := oidctest.NewServer("digital-mail", "client-secret")
+			t.Cleanup(idp.Close)
+			idp.SignIn(tc.claims)
+
+			provider := NewOIDCProvider(config.OIDCConfig{IssuerURL: idp.Issuer, ClientID: "digital-mail", ClientSecret: "client-secret"})
+			const redirectURL = "https://mail.yayasan.org/callback"
+			authURL, err := provider.AuthCodeURL(context.Background(), "s", "n", "verifier-verifier-verifier-verifier-verifier", redirectURL)
+			if err != nil {
+				t.Fatalf("AuthCodeURL: %v", err)
+			}
+
+			identity, err := provider.Exchange(context.Background(), authorize(t, authURL, "s"), "verifier-verifier-verifier-verifier-verifier", redirectURL, "n")
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if identity.EmailVerified != tc.want {
+				t.Fatalf("EmailVerified = %v, want %v", identity.EmailVerified, tc.want)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderChecksCodeAndKeys(t *testing.T) {
+	idp := oidctest.NewServer("digital-mail", "client-secret")
+	t.Cleanup(idp.Close)
+	idp.SignIn(map[string]any{"sub": "0f6c2a"})
+	const (
+		redirectURL = "https://mail.yayasan.org/callback"
+		verifier    = "verifier-verifier-verifier-verifier-verifier"
+	)
+	ctx := context.Background()
+	login := func(provider *OIDCProvider) string {
+		authURL, err := provider.AuthCodeURL(ctx, "s", "n", verifier, redirectURL)
+		if err != nil {
+			t.Fatalf("AuthCodeURL: %v", err)
+		}
+		return authorize(t, authURL, "s")
+	}
+
+	provider := NewOIDCProvider(config.OIDCConfig{IssuerURL: idp.Issuer, ClientID: "digital-mail", ClientSecret: "client-secret"})
+	if _, err := provider.Exchange(ctx, login(provider), "verifier-lain-verifier-lain-verifier-lain", redirectURL, "n"); err == nil {
+		t.Fatal("code exchanged without the matching PKCE verifier")
+	}
+	code := login(provider)
+	if _, err := provider.Exchange(ctx, code, verifier, redirectURL, "n"); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := provider.Exchange(ctx, code, verifier, redirectURL, "n"); err == nil {
+		t.Fatal("authorization code accepted twice")
+	}
+
+	// Key penandatangan dirotasi provider: JWKS diambil ulang
+	idp.RotateKey()
+	if _, err := provider.Exchange(ctx, login(provider), verifier, redirectURL, "n"); err != nil {
+		t.Fatalf("Exchange after key rotation: %v", err)
+	}
+}
+
+// authorize membuka URL login provider tiruan dan mengambil code dari redirect
+func authorize(t *testing.T, authURL, wantState string) string {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("parse redirect: %v", err)
+	}
+	if got := location.Query().Get("state"); got != wantState {
+		t.Fatalf("state = %q, want %q", got, wantState)
+	}
+	return location.Query().Get("code")
+}
+
+func TestMatchGroupRole(t *testing.T) {
+	mappings, err := config.ParseGroupRoles("cn=Direksi,ou=groups,dc=yayasan,dc=org=direktur;staf=staf_program;semua=pengurus")
+	if err != nil {
+		t.Fatalf("ParseGroupRoles: %v", err)
+	}
+
+	cases := []struct {
+		groups []string
+		want   models.Role
+		ok     bool
+	}{
+		{[]string{testGroupDir}, models.RoleDirektur, true},       // DN tidak case-sensitive
+		{[]string{"semua", "staf"}, models.RoleStafProgram, true}, // urutan pemetaan = prioritas
+		{[]string{"tamu"}, "", false},
+		{nil, "", false},
+	}
+	for _, tc := range cases {
+		got, ok := matchGroupRole(mappings, tc.groups)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("matchGroupRole(%v) = %q, %v; want %q, %v", tc.groups, got, ok, tc.want, tc.ok)
+		}
+	}
+}
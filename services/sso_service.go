@@ -0,0 +1,452 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSSODisabled           = errors.New("single sign-on is not configured")
+	ErrSSOInvalidCredentials = errors.New("invalid directory credentials")
+	ErrSSOInvalidState       = errors.New("sso login request is invalid or has expired")
+	ErrSSOEmailMissing       = errors.New("identity provider did not return a verified email")
+	ErrSSONoRole             = errors.New("no role is mapped to the directory groups of this user")
+	ErrSSOAccountRemoved     = errors.New("account has been removed")
+	ErrSSOLinkRequired       = errors.New("an account with this email already exists and must be linked explicitly")
+	ErrSSOIdentityLinked     = errors.New("identity is already linked to another account")
+	ErrSSOIdentityNotFound   = errors.New("linked identity not found")
+)
+
+// Batas waktu user menyelesaikan login di halaman identity provider
+const ssoStateTTL = 10 * time.Minute
+
+// Provider dibuat sekali per proses: metadata OIDC & CA LDAP tidak perlu
+// dimuat ulang di setiap request
+var ssoProviders struct {
+	once sync.Once
+	ldap PasswordProvider
+	oidc RedirectProvider
+}
+
+func loadSSOProviders(cfg config.SSOConfig) (PasswordProvider, RedirectProvider) {
+	ssoProviders.once.Do(func() {
+		if cfg.LDAP.Enabled() {
+			provider, err := NewLDAPProvider(cfg.LDAP)
+			if err != nil {
+				log.Printf("LDAP login disabled: %v", err)
+			} else {
+				ssoProviders.ldap = provider
+			}
+		}
+		if cfg.OIDC.Enabled() {
+			ssoProviders.oidc = NewOIDCProvider(cfg.OIDC)
+		}
+	})
+	return ssoProviders.ldap, ssoProviders.oidc
+}
+
+type SSOService struct {
+	db   *gorm.DB
+	cfg  config.SSOConfig
+	ldap PasswordProvider
+	oidc RedirectProvider
+}
+
+func NewSSOService(db *gorm.DB) *SSOService {
+	cfg := config.LoadSSOConfig()
+	ldapProvider, oidcProvider := loadSSOProviders(cfg)
+	return &SSOService{db: db, cfg: cfg, ldap: ldapProvider, oidc: oidcProvider}
+}
+
+func (s *SSOService) LDAPEnabled() bool {
+	return s.ldap != nil
+}
+
+func (s *SSOService) OIDCEnabled() bool {
+	return s.oidc != nil
+}
+
+// MobileRedirectURL - callback OIDC aplikasi mobile; kosong jika OIDC hanya
+// dipakai panel admin
+func (s *SSOService) MobileRedirectURL() string {
+	return s.cfg.OIDC.RedirectURL
+}
+
+// AdminSSOEnabled - login OIDC tersedia di panel admin
+func (s *SSOService) AdminSSOEnabled() bool {
+	return s.oidc != nil && s.cfg.OIDC.AdminRedirectURL != ""
+}
+
+func (s *SSOService) AdminRedirectURL() string {
+	return s.cfg.OIDC.AdminRedirectURL
+}
+
+// LinkedTo - apakah user sudah tertaut ke provider tertentu
+func (s *SSOService) LinkedTo(userID uint, provider string) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.UserIdentity{}).
+		Where("user_id = ? AND provider = ?", userID, provider).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// AuthenticatePassword memeriksa email & password ke direktori LDAP
+func (s *SSOService) AuthenticatePassword(ctx context.Context, login, password string) (*ExternalIdentity, error) {
+	if s.ldap == nil {
+		return nil, ErrSSODisabled
+	}
+	return s.ldap.Authenticate(ctx, login, password)
+}
+
+// BeginOIDC menyimpan state login baru dan mengembalikan URL halaman login
+// identity provider. Hanya hash state yang disimpan. linkUserID bukan 0 jika
+// user yang sudah login ingin menautkan akun provider ke akunnya.
+func (s *SSOService) BeginOIDC(ctx context.Context, redirectURL string, linkUserID uint) (string, error) {
+	if s.oidc == nil || redirectURL == "" {
+		return "", ErrSSODisabled
+	}
+
+	var values [2]string
+	for i := range values {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(buf)
+	}
+	state, nonce, verifier := values[0], values[1], oauth2.GenerateVerifier()
+
+	authURL, err := s.oidc.AuthCodeURL(ctx, state, nonce, verifier, redirectURL)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	// Permintaan yang ditinggalkan user dibersihkan di sini
+	if err := s.db.Where("expires_at < ?", now).Delete(&models.SSOLoginState{}).Error; err != nil {
+		return "", err
+	}
+	login := models.SSOLoginState{
+		StateHash:    hashSSOState(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		RedirectURL:  redirectURL,
+		ExpiresAt:    now.Add(ssoStateTTL),
+	}
+	if linkUserID != 0 {
+		login.LinkUserID = &linkUserID
+	}
+	if err := s.db.Create(&login).Error; err != nil {
+		return "", err
+	}
+	return authURL, nil
+}
+
+// CompleteOIDC memproses callback: state hanya bisa dipakai sekali, lalu code
+// ditukar dengan redirect URL, verifier, dan nonce milik state tersebut.
+// linkUserID harus sama dengan saat BeginOIDC: state penautan tidak bisa
+// dipakai untuk login, dan sebaliknya.
+func (s *SSOService) CompleteOIDC(ctx context.Context, state, code string, linkUserID uint) (*ExternalIdentity, error) {
+	if s.oidc == nil {
+		return nil, ErrSSODisabled
+	}
+	if state == "" || code == "" {
+		return nil, ErrSSOInvalidState
+	}
+
+	var login models.SSOLoginState
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("state_hash = ?", hashSSOState(state)).
+			First(&login).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSSOInvalidState
+			}
+			return err
+		}
+		return tx.Delete(&login).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(login.ExpiresAt) {
+		return nil, ErrSSOInvalidState
+	}
+	var stateUserID uint
+	if login.LinkUserID != nil {
+		stateUserID = *login.LinkUserID
+	}
+	if stateUserID != linkUserID {
+		return nil, ErrSSOInvalidState
+	}
+
+	return s.oidc.Exchange(ctx, code, login.CodeVerifier, login.RedirectURL, login.Nonce)
+}
+
+func hashSSOState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// matchGroupRole - role dari grup pertama di SSO_GROUP_ROLES yang dimiliki user
+func matchGroupRole(mappings []config.GroupRole, groups []string) (models.Role, bool) {
+	for _, mapping := range mappings {
+		for _, group := range groups {
+			if strings.EqualFold(strings.TrimSpace(group), mapping.Group) {
+				return models.Role(mapping.Role), true
+			}
+		}
+	}
+	return "", false
+}
+
+// Provision mencari atau membuat user lokal untuk identitas eksternal:
+//  1. identitas yang sudah tertaut langsung dipakai;
+//  2. jika belum dan email terverifikasi, ditautkan ke user dengan email yang
+//     sama, asalkan akun tersebut belum punya password, 2FA, maupun izin
+//     admin (lihat autoLinkable). Akun lain harus ditautkan lewat Link;
+//  3. jika tidak ada, user baru dibuat dengan role dari pemetaan grup (atau
+//     SSO_DEFAULT_ROLE).
+//
+// Role dari pemetaan grup diterapkan ulang hanya untuk akun yang dibuat lewat
+// SSO sehingga perubahan grup di direktori terbawa saat login berikutnya;
+// roleChanged menandakan sesi lama perlu dicabut. Role akun yang dibuat lokal
+// tidak pernah diubah oleh pemetaan grup.
+func (s *SSOService) Provision(identity *ExternalIdentity) (*models.User, bool, error) {
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	if email == "" {
+		return nil, false, ErrSSOEmailMissing
+	}
+
+	mappedRole, mapped := matchGroupRole(s.cfg.GroupRoles, identity.Groups)
+	rbac := NewRBACService(s.db)
+	if mapped && !rbac.RoleExists(mappedRole) {
+		log.Printf("SSO group mapping points to unknown role %q, ignored", mappedRole)
+		mapped = false
+	}
+
+	var (
+		user        models.User
+		roleChanged bool
+	)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var link models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&link).Error
+		switch {
+		case err == nil:
+			if err := tx.First(&user, link.UserID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrSSOAccountRemoved
+				}
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := s.findOrCreateUser(tx, &user, identity, email, mappedRole, mapped); err != nil {
+				return err
+			}
+			link = models.UserIdentity{UserID: user.ID, Provider: identity.Provider, Subject: identity.Subject}
+			if err := tx.Create(&link).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&link).Update("last_login_at", &now).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]any{}
+		if mapped && user.ProvisionedBy != "" && user.Role != mappedRole {
+			updates["role"] = mappedRole
+			roleChanged = true
+		}
+		// Akun undangan yang belum diaktifkan dianggap aktif setelah login
+		// lewat direktori
+		if user.Status == models.UserStatusPending {
+			updates["status"] = models.UserStatusActive
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&user).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if roleChanged {
+			if _, err := NewSessionService(tx).RevokeAll(user.ID); err != nil {
+				return err
+			}
+		}
+		// Dimuat ulang agar versi token yang baru ikut di token berikutnya
+		return tx.First(&user, user.ID).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &user, roleChanged, nil
+}
+
+func (s *SSOService) findOrCreateUser(tx *gorm.DB, user *models.User, identity *ExternalIdentity, email string, mappedRole models.Role, mapped bool) error {
+	// Email yang belum diverifikasi provider bisa diklaim siapa saja
+	if !identity.EmailVerified {
+		return ErrSSOEmailMissing
+	}
+
+	// Unscoped: email user yang sudah dihapus tetap terkunci oleh unique index
+	err := tx.Unscoped().Where("email = ?", email).First(user).Error
+	if err == nil {
+		if user.DeletedAt.Valid {
+			return ErrSSOAccountRemoved
+		}
+		linkable, err := autoLinkable(tx, user)
+		if err != nil {
+			return err
+		}
+		if !linkable {
+			return ErrSSOLinkRequired
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	role := mappedRole
+	if !mapped {
+		role = models.Role(s.cfg.DefaultRole)
+		if role == "" || !NewRBACService(tx).RoleExists(role) {
+			return ErrSSONoRole
+		}
+	}
+
+	username, err := availableUsername(tx, identity.Username, email)
+	if err != nil {
+		return err
+	}
+	*user = models.User{
+		Username:      username,
+		FirstName:     identity.FirstName,
+		LastName:      identity.LastName,
+		Email:         email,
+		Role:          role,
+		Status:        models.UserStatusActive,
+		ProvisionedBy: identity.Provider,
+	}
+	return tx.Create(user).Error
+}
+
+// autoLinkable - akun lokal hanya boleh ditautkan otomatis lewat kecocokan
+// email jika belum ada yang bisa direbut darinya: belum punya password
+// (undangan yang belum diterima atau akun SSO), belum memakai 2FA, dan tidak
+// memegang izin admin di role mana pun yang bisa dipakainya
+func autoLinkable(tx *gorm.DB, user *models.User) (bool, error) {
+	if user.PasswordHash != "" {
+		return false, nil
+	}
+	twoFactor, err := NewTwoFactorService(tx).Enabled(user.ID)
+	if err != nil || twoFactor {
+		return false, err
+	}
+	roles, err := NewUserRoleService(tx).AvailableRoles(user)
+	if err != nil {
+		return false, err
+	}
+	rbac := NewRBACService(tx)
+	for _, role := range roles {
+		if rbac.HasPermission(role.Role, models.AdminPermissions...) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Link menautkan identitas eksternal ke user secara eksplisit: oleh user yang
+// sudah login (setelah membuktikan akun provider) atau oleh admin. Email
+// identitas tidak harus sama dengan email user. Role user tidak diubah.
+func (s *SSOService) Link(userID uint, identity *ExternalIdentity) (*models.UserIdentity, error) {
+	var link models.UserIdentity
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&models.User{}, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		err := tx.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&link).Error
+		switch {
+		case err == nil:
+			if link.UserID != userID {
+				return ErrSSOIdentityLinked
+			}
+			return nil
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		link = models.UserIdentity{UserID: userID, Provider: identity.Provider, Subject: identity.Subject}
+		return tx.Create(&link).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Identities - akun provider yang tertaut ke user
+func (s *SSOService) Identities(userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := s.db.Where("user_id = ?", userID).Order("id ASC").Find(&identities).Error
+	return identities, err
+}
+
+// Unlink melepas tautan identitas dari user
+func (s *SSOService) Unlink(userID, identityID uint) error {
+	res := s.db.Where("id = ? AND user_id = ?", identityID, userID).Delete(&models.UserIdentity{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSSOIdentityNotFound
+	}
+	return nil
+}
+
+// availableUsername memakai username dari provider (atau bagian lokal email)
+// dan menambahkan angka jika sudah dipakai
+func availableUsername(tx *gorm.DB, preferred, email string) (string, error) {
+	base := strings.ToLower(strings.TrimSpace(preferred))
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	if len(base) > 90 {
+		base = base[:90]
+	}
+
+	candidate := base
+	for i := 2; i <= 100; i++ {
+		var count int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+	return "", fmt.Errorf("no available username for %q", base)
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/utils/dbtest"
+	"TugasAkhir/utils/oidc/oidctest"
+
+	"gorm.io/gorm"
+)
+
+func newTestSSOService(t *testing.T) (*SSOService, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t,
+		&models.User{}, &models.UserIdentity{}, &models.SSOLoginState{}, &models.UserTwoFactor{},
+		&models.RoleDefinition{}, &models.Permission{}, &models.UserRoleAssignment{},
+		&models.UserSession{}, &models.RefreshToken{},
+	)
+	if err := SeedRBAC(db); err != nil {
+		t.Fatalf("SeedRBAC: %v", err)
+	}
+	InvalidatePermissionCache()
+	t.Cleanup(InvalidatePermissionCache)
+	mappings, err := config.ParseGroupRoles("direksi=direktur;staf=staf_program")
+	if err != nil {
+		t.Fatalf("ParseGroupRoles: %v", err)
+	}
+	return &SSOService{db: db, cfg: config.SSOConfig{GroupRoles: mappings}}, db
+}
+
+func oidcIdentity(subject, email string, verified bool, groups ...string) *ExternalIdentity {
+	return &ExternalIdentity{
+		Provider:      models.IdentityProviderOIDC,
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: verified,
+		Groups:        groups,
+	}
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, user models.User) models.User {
+	t.Helper()
+	if user.Status == "" {
+		user.Status = models.UserStatusActive
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func TestProvisionRejectsUnverifiedEmail(t *testing.T) {
+	sso, db := newTestSSOService(t)
+	createTestUser(t, db, models.User{Username: "dewi", Email: "dewi@yayasan.org", Role: models.RoleStafProgram, Status: models.UserStatusPending})
+
+	for _, email := range []string{"dewi@yayasan.org", "baru@yayasan.org"} {
+		if _, _, err := sso.Provision(oidcIdentity("sub-"+email, email, false, "staf")); !errors.Is(err, ErrSSOEmailMissing) {
+			t.Fatalf("Provision(%s) err = %v, want ErrSSOEmailMissing", email, err)
+		}
+	}
+
+	var links int64
+	db.Model(&models.UserIdentity{}).Count(&links)
+	if links != 0 {
+		t.Fatalf("unverified identity was linked (%d links)", links)
+	}
+}
+
+func TestProvisionRefusesToAutoLinkProtectedAccounts(t *testing.T) {
+	sso, db := newTestSSOService(t)
+	withPassword := createTestUser(t, db, models.User{Username: "budi", Email: "budi@yayasan.org", Role: models.RoleStafProgram, PasswordHash: "$2a$12$hash"})
+	withTwoFactor := createTestUser(t, db, models.User{Username: "sari", Email: "sari@yayasan.org", Role: models.RoleStafProgram, Status: models.UserStatusPending})
+	enabledAt := time.Now()
+	if err := db.Create(&models.UserTwoFactor{UserID: withTwoFactor.ID, Secret: "x", EnabledAt: &enabledAt}).Error; err != nil {
+		t.Fatalf("create 2fa: %v", err)
+	}
+	admin := createTestUser(t, db, models.User{Username: "admin", Email: "admin@yayasan.org", Role: models.RoleAdmin, Status: models.UserStatusPending})
+
+	for _, user := range []models.User{withPassword, withTwoFactor, admin} {
+		_, _, err := sso.Provision(oidcIdentity("sub-"+user.Username, user.Email, true, "direksi"))
+		if !errors.Is(err, ErrSSOLinkRequired) {
+			t.Errorf("Provision(%s) err = %v, want ErrSSOLinkRequired", user.Username, err)
+		}
+	}
+
+	var reloaded models.User
+	db.First(&reloaded, admin.ID)
+	if reloaded.Role != models.RoleAdmin || reloaded.Status != models.UserStatusPending {
+		t.Fatalf("admin account changed: role=%s status=%s", reloaded.Role, reloaded.Status)
+	}
+}
+
+func TestProvisionAutoLinksInvitationWithoutChangingRole(t *testing.T) {
+	sso, db := newTestSSOService(t)
+	invited := createTestUser(t, db, models.User{Username: "dewi", Email: "dewi@yayasan.org", Role: models.RoleStafProgram, Status: models.UserStatusPending})
+
+	user, roleChanged, err := sso.Provision(oidcIdentity("0f6c2a", "Dewi@Yayasan.org", true, "direksi"))
+	if err != nil {
+		t.Fatalf("Provision: %v", err)
+	}
+	if user.ID != invited.ID || user.Status != models.UserStatusActive {
+		t.Fatalf("user = #%d %s, want invited user activated", user.ID, user.Status)
+	}
+	// Akun dibuat admin secara lokal: grup direktori tidak boleh mengubah rolenya
+	if roleChanged || user.Role != models.RoleStafProgram {
+		t.Fatalf("role = %s (changed=%v), want staf_program unchanged", user.Role, roleChanged)
+	}
+}
+
+func TestProvisionSyncsRoleOnlyForSSOAccounts(t *testing.T) {
+	sso, _ := newTestSSOService(t)
+
+	user, _, err := sso.Provision(oidcIdentity("77", "rina@yayasan.org", true, "staf"))
+	if err != nil {
+		t.Fatalf("Provision (create): %v", err)
+	}
+	if user.Role != models.RoleStafProgram || user.ProvisionedBy != models.IdentityProviderOIDC {
+		t.Fatalf("created user role=%s provisioned_by=%q", user.Role, user.ProvisionedBy)
+	}
+
+	user, roleChanged, err := sso.Provision(oidcIdentity("77", "rina@yayasan.org", true, "direksi"))
+	if err != nil {
+		t.Fatalf("Provision (login): %v", err)
+	}
+	if !roleChanged || user.Role != models.RoleDirektur {
+		t.Fatalf("role = %s (changed=%v), want direktur from group mapping", user.Role, roleChanged)
+	}
+}
+
+func TestLinkExplicitlyThenLoginKeepsLocalRole(t *testing.T) {
+	sso, db := newTestSSOService(t)
+	budi := createTestUser(t, db, models.User{Username: "budi", Email: "budi@yayasan.org", Role: models.RoleStafProgram, PasswordHash: "$2a$12$hash"})
+	other := createTestUser(t, db, models.User{Username: "lain", Email: "lain@yayasan.org", Role: models.RoleStafProgram, PasswordHash: "$2a$12$hash"})
+
+	identity := oidcIdentity("budi-sub", "budi@yayasan.org", true, "direksi")
+	if _, err := sso.Link(budi.ID, identity); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	// Menautkan ulang ke user yang sama tidak gagal; ke user lain ditolak
+	if _, err := sso.Link(budi.ID, identity); err != nil {
+		t.Fatalf("Link again: %v", err)
+	}
+	if _, err := sso.Link(other.ID, identity); !errors.Is(err, ErrSSOIdentityLinked) {
+		t.Fatalf("Link to other user err = %v, want ErrSSOIdentityLinked", err)
+	}
+
+	user, roleChanged, err := sso.Provision(identity)
+	if err != nil {
+		t.Fatalf("Provision: %v", err)
+	}
+	if user.ID != budi.ID || roleChanged || user.Role != models.RoleStafProgram {
+		t.Fatalf("user = #%d role=%s changed=%v", user.ID, user.Role, roleChanged)
+	}
+}
+
+func TestCompleteOIDCRequiresMatchingLinkUser(t *testing.T) {
+	idp := oidctest.NewServer("digital-mail", "client-secret")
+	t.Cleanup(idp.Close)
+
+	sso, _ := newTestSSOService(t)
+	sso.oidc = NewOIDCProvider(config.OIDCConfig{IssuerURL: idp.Issuer, ClientID: "digital-mail", ClientSecret: "client-secret"})
+	ctx := context.Background()
+
+	begin := func(linkUserID uint) string {
+		authURL, err := sso.BeginOIDC(ctx, "https://mail.yayasan.org/callback", linkUserID)
+		if err != nil {
+			t.Fatalf("BeginOIDC: %v", err)
+		}
+		parsed, err := url.Parse(authURL)
+		if err != nil {
+			t.Fatalf("parse auth URL: %v", err)
+		}
+		return parsed.Query().Get("state")
+	}
+
+	// State penautan tidak bisa dipakai untuk login, begitu juga sebaliknya
+	if _, err := sso.CompleteOIDC(ctx, begin(7), "code", 0); !errors.Is(err, ErrSSOInvalidState) {
+		t.Fatalf("link state used for login: err = %v", err)
+	}
+	if _, err := sso.CompleteOIDC(ctx, begin(0), "code", 7); !errors.Is(err, ErrSSOInvalidState) {
+		t.Fatalf("login state used for link: err = %v", err)
+	}
+	if _, err := sso.CompleteOIDC(ctx, begin(7), "code", 8); !errors.Is(err, ErrSSOInvalidState) {
+		t.Fatalf("link state used by another user: err = %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"TugasAkhir/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidSyncCursor = errors.New("invalid sync cursor")
+
+const (
+	// Perubahan yang lebih baru dari ini ditunda ke sync berikutnya, agar
+	// transaksi yang belum commit (updated_at lebih lama) tidak terlewat
+	syncSettleDelay = 2 * time.Second
+
+	letterChangedAtExpr       = "GREATEST(surat.updated_at, COALESCE(surat.deleted_at, surat.updated_at))"
+	notificationChangedAtExpr = "GREATEST(notifications.updated_at, COALESCE(notifications.deleted_at, notifications.updated_at))"
+	revokedAtExpr             = "sync_letter_deliveries.revoked_at"
+)
+
+// syncPosition adalah posisi terakhir yang sudah dikirim: waktu perubahan
+// dan ID sebagai tie-breaker
+type syncPosition struct {
+	At time.Time `json:"t"`
+	ID uint      `json:"i"`
+}
+
+// SyncCursor disimpan klien apa adanya (opaque) dan dikirim lagi di ?since=
+type SyncCursor struct {
+	Letters       syncPosition `json:"l"`
+	Notifications syncPosition `json:"n"`
+	Revoked       syncPosition `json:"r"`
+}
+
+func (c SyncCursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeSyncCursor membaca cursor dari klien. Cursor kosong berarti sync penuh.
+func DecodeSyncCursor(s string) (SyncCursor, error) {
+	var c SyncCursor
+	if s == "" {
+		return c, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidSyncCursor
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, ErrInvalidSyncCursor
+	}
+	return c, nil
+}
+
+// SyncResult berisi perubahan sejak cursor. Surat & notifikasi yang dihapus,
+// serta surat yang tidak lagi boleh dilihat user, dikembalikan terpisah
+// sebagai tombstone.
+type SyncResult struct {
+	Letters              []models.Letter
+	DeletedLetters       []models.Letter
+	RevokedLetters       []models.SyncLetterDelivery
+	Notifications        []models.Notification
+	DeletedNotifications []models.Notification
+	Cursor               SyncCursor
+	HasMore              bool
+}
+
+type SyncService struct {
+	db          *gorm.DB
+	permService *PermissionService
+	settleDelay time.Duration
+}
+
+func NewSyncService(db *gorm.DB) *SyncService {
+	return &SyncService{
+		db:          db,
+		permService: NewPermissionService(db),
+		settleDelay: syncSettleDelay,
+	}
+}
+
+// Changes mengembalikan maksimal limit perubahan per jenis data sejak cursor.
+// Jika HasMore true, klien langsung memanggil lagi dengan cursor baru.
+func (s *SyncService) Changes(user *models.User, cursor SyncCursor, limit int) (*SyncResult, error) {
+	now := time.Now()
+	until := now.Add(-s.settleDelay)
+	result := &SyncResult{Cursor: cursor}
+
+	// Sync penuh: cache klien masih kosong, pencabutan akses sebelumnya tidak relevan
+	if cursor.Letters.At.IsZero() && cursor.Letters.ID == 0 && cursor.Revoked.At.IsZero() {
+		result.Cursor.Revoked = syncPosition{At: until}
+	}
+
+	if err := s.revokeHiddenLetters(user, now); err != nil {
+		return nil, err
+	}
+
+	var letters []models.Letter
+	err := s.db.Unscoped().
+		Scopes(s.permService.ScopeViewableLetters(user), afterPosition(letterChangedAtExpr, "surat.id", cursor.Letters, until), preloadSyncLetter).
+		Limit(limit + 1).
+		Find(&letters).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(letters) > limit {
+		letters = letters[:limit]
+		result.HasMore = true
+	}
+	for _, l := range letters {
+		if l.DeletedAt.Valid {
+			result.DeletedLetters = append(result.DeletedLetters, l)
+		} else {
+			result.Letters = append(result.Letters, l)
+		}
+		result.Cursor.Letters = syncPosition{At: changedAt(l.Model), ID: l.ID}
+	}
+
+	// Surat yang baru terlihat tanpa barisnya berubah (user masuk unit tujuan,
+	// ganti role, mendapat tembusan lewat role, akses dikembalikan)
+	appeared, more, err := s.newlyVisibleLetters(user, cursor.Letters, limit)
+	if err != nil {
+		return nil, err
+	}
+	result.Letters = append(result.Letters, appeared...)
+	result.HasMore = result.HasMore || more
+
+	if err := s.recordDeliveries(user, result.Letters); err != nil {
+		return nil, err
+	}
+
+	var revoked []models.SyncLetterDelivery
+	err = s.db.
+		Where("sync_letter_deliveries.user_id = ? AND sync_letter_deliveries.revoked_at IS NOT NULL", user.ID).
+		Scopes(afterPosition(revokedAtExpr, "sync_letter_deliveries.id", result.Cursor.Revoked, until)).
+		Limit(limit + 1).
+		Find(&revoked).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(revoked) > limit {
+		revoked = revoked[:limit]
+		result.HasMore = true
+	}
+	for _, d := range revoked {
+		result.RevokedLetters = append(result.RevokedLetters, d)
+		result.Cursor.Revoked = syncPosition{At: *d.RevokedAt, ID: d.ID}
+	}
+
+	var notifications []models.Notification
+	err = s.db.Unscoped().
+		Where("notifications.user_id = ? OR notifications.role = ?", user.ID, user.Role).
+		Scopes(afterPosition(notificationChangedAtExpr, "notifications.id", cursor.Notifications, until)).
+		Limit(limit + 1).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		result.HasMore = true
+	}
+	for _, n := range notifications {
+		if n.DeletedAt.Valid {
+			result.DeletedNotifications = append(result.DeletedNotifications, n)
+		} else {
+			result.Notifications = append(result.Notifications, n)
+		}
+		result.Cursor.Notifications = syncPosition{At: changedAt(n.Model), ID: n.ID}
+	}
+
+	return result, nil
+}
+
+// newlyVisibleLetters mengambil surat yang sekarang boleh dilihat user tetapi
+// belum pernah dikirim (atau aksesnya pernah dicabut), padahal posisinya sudah
+// dilewati cursor. Surat setelah cursor akan terkirim lewat query perubahan
+// biasa. Setelah dikirim surat tercatat di SyncLetterDelivery sehingga tidak
+// terambil lagi.
+func (s *SyncService) newlyVisibleLetters(user *models.User, pos syncPosition, limit int) ([]models.Letter, bool, error) {
+	if pos.At.IsZero() && pos.ID == 0 {
+		return nil, false, nil
+	}
+
+	delivered := s.db.Session(&gorm.Session{NewDB: true}).
+		Model(&models.SyncLetterDelivery{}).
+		Select("letter_id").
+		Where("user_id = ? AND revoked_at IS NULL", user.ID)
+
+	var letters []models.Letter
+	err := s.db.
+		Scopes(s.permService.ScopeViewableLetters(user)).
+		Where("surat.id NOT IN (?)", delivered).
+		Where("("+letterChangedAtExpr+" < ? OR ("+letterChangedAtExpr+" = ? AND surat.id <= ?))", pos.At, pos.At, pos.ID).
+		Scopes(preloadSyncLetter).
+		Order("surat.id ASC").
+		Limit(limit + 1).
+		Find(&letters).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(letters) > limit {
+		return letters[:limit], true, nil
+	}
+	return letters, false, nil
+}
+
+// preloadSyncLetter memuat relasi surat yang dikirim lewat sync
+func preloadSyncLetter(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("CreatedBy").Preload("AssignedVerifier").Preload("VerifiedBy").Preload("DisposedBy").Preload("SignedBy").Preload("Verification").
+		Preload("Tembusan", func(db *gorm.DB) *gorm.DB { return db.Order("urutan ASC") }).Preload("Tembusan.User")
+}
+
+// revokeHiddenLetters menandai surat yang sudah pernah dikirim ke user tetapi
+// sekarang tidak lagi lolos ScopeViewableLetters. Surat yang dihapus tetap
+// dianggap terlihat karena sudah mendapat tombstone sendiri.
+func (s *SyncService) revokeHiddenLetters(user *models.User, now time.Time) error {
+	viewable := s.db.Session(&gorm.Session{NewDB: true}).
+		Unscoped().
+		Model(&models.Letter{}).
+		Select("surat.id").
+		Scopes(s.permService.ScopeViewableLetters(user))
+
+	return s.db.Model(&models.SyncLetterDelivery{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Where("letter_id NOT IN (?)", viewable).
+		Update("revoked_at", now).Error
+}
+
+// recordDeliveries mencatat surat yang dikirim ke user. Surat yang kembali
+// terlihat setelah aksesnya dicabut dihapus tanda pencabutannya.
+func (s *SyncService) recordDeliveries(user *models.User, letters []models.Letter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+	deliveries := make([]models.SyncLetterDelivery, len(letters))
+	for i, l := range letters {
+		deliveries[i] = models.SyncLetterDelivery{UserID: user.ID, LetterID: l.ID}
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "letter_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"revoked_at": nil, "updated_at": time.Now()}),
+	}).Create(&deliveries).Error
+}
+
+// afterPosition memfilter baris yang berubah setelah pos (dan sebelum until),
+// diurutkan dari perubahan paling lama
+func afterPosition(changedExpr, idColumn string, pos syncPosition, until time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Where("("+changedExpr+" > ? OR ("+changedExpr+" = ? AND "+idColumn+" > ?))", pos.At, pos.At, pos.ID).
+			Where(changedExpr+" <= ?", until).
+			Order(changedExpr + " ASC").
+			Order(idColumn + " ASC")
+	}
+}
+
+// changedAt adalah waktu perubahan terakhir baris, sama dengan *ChangedAtExpr.
+// Soft delete GORM hanya mengisi deleted_at tanpa mengubah updated_at.
+func changedAt(m gorm.Model) time.Time {
+	if m.DeletedAt.Valid && m.DeletedAt.Time.After(m.UpdatedAt) {
+		return m.DeletedAt.Time
+	}
+	return m.UpdatedAt
+}
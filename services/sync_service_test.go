@@ -0,0 +1,191 @@
+package services
+
+import (
+	"testing"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils/dbtest"
+
+	"gorm.io/gorm"
+)
+
+func newTestSyncService(t *testing.T) (*SyncService, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t,
+		&models.Unit{}, &models.User{}, &models.Letter{}, &models.LetterTembusan{}, &models.LetterVerification{},
+		&models.Notification{}, &models.SyncLetterDelivery{},
+		&models.RoleDefinition{}, &models.Permission{}, &models.UserRoleAssignment{},
+	)
+	if err := SeedRBAC(db); err != nil {
+		t.Fatalf("SeedRBAC: %v", err)
+	}
+	InvalidatePermissionCache()
+	t.Cleanup(InvalidatePermissionCache)
+	// Tanpa jeda agar perubahan langsung terlihat di sync berikutnya
+	return &SyncService{db: db, permService: NewPermissionService(db)}, db
+}
+
+// syncLetters menjalankan satu sync dan mengembalikan ID surat & tombstone surat
+func syncLetters(t *testing.T, s *SyncService, user *models.User, cursor *SyncCursor) (letters, tombstones []uint) {
+	t.Helper()
+	result, err := s.Changes(user, *cursor, 100)
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	*cursor = result.Cursor
+	for _, l := range result.Letters {
+		letters = append(letters, l.ID)
+	}
+	for _, l := range result.DeletedLetters {
+		tombstones = append(tombstones, l.ID)
+	}
+	for _, d := range result.RevokedLetters {
+		tombstones = append(tombstones, d.LetterID)
+	}
+	return letters, tombstones
+}
+
+func TestSyncSendsTombstoneWhenLetterLeavesVisibility(t *testing.T) {
+	sync, db := newTestSyncService(t)
+
+	unitA := models.Unit{Kode: "KEU", Nama: "Keuangan"}
+	unitB := models.Unit{Kode: "PRG", Nama: "Program"}
+	db.Create(&unitA)
+	db.Create(&unitB)
+	user := createTestUser(t, db, models.User{Username: "rina", Email: "rina@yayasan.org", Role: models.RoleStafProgram, UnitID: &unitA.ID})
+	other := createTestUser(t, db, models.User{Username: "budi", Email: "budi@yayasan.org", Role: models.RoleStafProgram})
+	author := createTestUser(t, db, models.User{Username: "sari", Email: "sari@yayasan.org", Role: models.RoleStafProgram})
+
+	newLetter := func(l models.Letter) models.Letter {
+		l.CreatedByID = author.ID
+		if l.JenisSurat == "" {
+			l.JenisSurat = models.LetterKeluar
+			l.Scope = models.ScopeInternal
+		}
+		if err := db.Create(&l).Error; err != nil {
+			t.Fatalf("create letter: %v", err)
+		}
+		return l
+	}
+	verifierID := user.ID
+	reassigned := newLetter(models.Letter{Status: models.StatusPerluVerifikasi, AssignedVerifierID: &verifierID})
+	disposed := newLetter(models.Letter{Status: models.StatusDiarsipkan, TujuanUnitID: &unitA.ID})
+	copied := newLetter(models.Letter{Status: models.StatusDiarsipkan})
+	tembusan := models.LetterTembusan{LetterID: copied.ID, UserID: &user.ID}
+	db.Create(&tembusan)
+
+	var cursor SyncCursor
+	letters, tombstones := syncLetters(t, sync, &user, &cursor)
+	if len(letters) != 3 || len(tombstones) != 0 {
+		t.Fatalf("full sync letters=%v tombstones=%v, want 3 letters", letters, tombstones)
+	}
+
+	// Surat dialihkan ke verifikator lain, user pindah unit, tembusan dicabut
+	db.Model(&models.Letter{}).Where("id = ?", reassigned.ID).Update("assigned_verifier_id", other.ID)
+	db.Model(&models.User{}).Where("id = ?", user.ID).Update("unit_id", unitB.ID)
+	db.Delete(&tembusan)
+
+	letters, tombstones = syncLetters(t, sync, &user, &cursor)
+	if len(letters) != 0 {
+		t.Fatalf("hidden letters were sent: %v", letters)
+	}
+	want := map[uint]bool{reassigned.ID: true, disposed.ID: true, copied.ID: true}
+	if len(tombstones) != len(want) {
+		t.Fatalf("tombstones = %v, want %v", tombstones, want)
+	}
+	for _, id := range tombstones {
+		if !want[id] {
+			t.Fatalf("unexpected tombstone %d", id)
+		}
+	}
+
+	// Tombstone hanya dikirim sekali per cursor
+	if letters, tombstones = syncLetters(t, sync, &user, &cursor); len(letters)+len(tombstones) != 0 {
+		t.Fatalf("repeated sync letters=%v tombstones=%v, want nothing", letters, tombstones)
+	}
+
+	// Akses dikembalikan & surat berubah: surat dikirim lagi tanpa tombstone
+	db.Model(&models.Letter{}).Where("id = ?", reassigned.ID).Update("assigned_verifier_id", user.ID)
+	letters, tombstones = syncLetters(t, sync, &user, &cursor)
+	if len(letters) != 1 || letters[0] != reassigned.ID || len(tombstones) != 0 {
+		t.Fatalf("restored access letters=%v tombstones=%v, want [%d]", letters, tombstones, reassigned.ID)
+	}
+}
+
+func TestSyncNeverSendsTombstonesForUnseenLetters(t *testing.T) {
+	sync, db := newTestSyncService(t)
+	user := createTestUser(t, db, models.User{Username: "rina", Email: "rina@yayasan.org", Role: models.RoleStafProgram})
+	author := createTestUser(t, db, models.User{Username: "sari", Email: "sari@yayasan.org", Role: models.RoleStafProgram})
+
+	var cursor SyncCursor
+	syncLetters(t, sync, &user, &cursor)
+
+	// Surat milik orang lain berubah & dihapus: user tidak pernah melihatnya
+	hidden := models.Letter{JenisSurat: models.LetterKeluar, Scope: models.ScopeInternal, Status: models.StatusDraft, CreatedByID: author.ID}
+	db.Create(&hidden)
+	db.Model(&hidden).Update("judul_surat", "Rahasia")
+	db.Delete(&hidden)
+
+	if letters, tombstones := syncLetters(t, sync, &user, &cursor); len(letters)+len(tombstones) != 0 {
+		t.Fatalf("letters=%v tombstones=%v, want nothing", letters, tombstones)
+	}
+}
+
+// Surat yang baru terlihat karena perubahan di luar baris surat (user masuk
+// unit tujuan, ganti role) tetap dikirim meski updated_at surat tidak berubah
+func TestSyncSendsLettersThatBecomeVisibleWithoutChanging(t *testing.T) {
+	sync, db := newTestSyncService(t)
+
+	unit := models.Unit{Kode: "KEU", Nama: "Keuangan"}
+	db.Create(&unit)
+	user := createTestUser(t, db, models.User{Username: "rina", Email: "rina@yayasan.org", Role: models.RoleStafProgram})
+	author := createTestUser(t, db, models.User{Username: "sari", Email: "sari@yayasan.org", Role: models.RoleStafProgram})
+
+	newLetter := func(l models.Letter) models.Letter {
+		l.JenisSurat, l.Scope = models.LetterKeluar, models.ScopeInternal
+		if err := db.Create(&l).Error; err != nil {
+			t.Fatalf("create letter: %v", err)
+		}
+		return l
+	}
+	disposed := newLetter(models.Letter{Status: models.StatusDiarsipkan, CreatedByID: author.ID, TujuanUnitID: &unit.ID})
+	draft := newLetter(models.Letter{Status: models.StatusDraft, CreatedByID: author.ID})
+	own := newLetter(models.Letter{Status: models.StatusDraft, CreatedByID: user.ID})
+
+	var cursor SyncCursor
+	if letters, _ := syncLetters(t, sync, &user, &cursor); len(letters) != 1 || letters[0] != own.ID {
+		t.Fatalf("full sync letters = %v, want [%d]", letters, own.ID)
+	}
+
+	// User masuk unit tujuan disposisi
+	db.Model(&models.User{}).Where("id = ?", user.ID).Update("unit_id", unit.ID)
+	user.UnitID = &unit.ID
+	letters, tombstones := syncLetters(t, sync, &user, &cursor)
+	if len(letters) != 1 || letters[0] != disposed.ID || len(tombstones) != 0 {
+		t.Fatalf("after unit join letters=%v tombstones=%v, want [%d]", letters, tombstones, disposed.ID)
+	}
+	if letters, tombstones = syncLetters(t, sync, &user, &cursor); len(letters)+len(tombstones) != 0 {
+		t.Fatalf("repeated sync letters=%v tombstones=%v, want nothing", letters, tombstones)
+	}
+
+	// Ganti role aktif (seperti /auth/switch-role): semua surat terlihat
+	acting := user
+	acting.Role = models.RoleDirektur
+	if letters, _ = syncLetters(t, sync, &acting, &cursor); len(letters) != 1 || letters[0] != draft.ID {
+		t.Fatalf("after role switch letters = %v, want [%d]", letters, draft.ID)
+	}
+
+	// Kembali ke role utama: surat dicabut, lalu dikirim lagi saat role
+	// dipakai kembali walau suratnya tetap tidak berubah
+	if _, tombstones = syncLetters(t, sync, &user, &cursor); len(tombstones) != 1 || tombstones[0] != draft.ID {
+		t.Fatalf("after switching back tombstones = %v, want [%d]", tombstones, draft.ID)
+	}
+	letters, tombstones = syncLetters(t, sync, &acting, &cursor)
+	if len(letters) != 1 || letters[0] != draft.ID || len(tombstones) != 0 {
+		t.Fatalf("restored access letters=%v tombstones=%v, want [%d]", letters, tombstones, draft.ID)
+	}
+	var delivery models.SyncLetterDelivery
+	if err := db.Where("user_id = ? AND letter_id = ?", user.ID, draft.ID).First(&delivery).Error; err != nil || delivery.RevokedAt != nil {
+		t.Fatalf("delivery = %+v, %v; want revocation cleared", delivery, err)
+	}
+}
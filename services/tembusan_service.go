@@ -0,0 +1,98 @@
+package services
+
+import (
+	"TugasAkhir/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTembusanUserNotFound = errors.New("tembusan user not found")
+	ErrTembusanRoleNotFound = errors.New("tembusan role not found")
+)
+
+type TembusanService struct {
+	db *gorm.DB
+}
+
+func NewTembusanService(db *gorm.DB) *TembusanService {
+	return &TembusanService{db: db}
+}
+
+// Validate memastikan semua user & role yang ditembuskan ada
+func (ts *TembusanService) Validate(ids []uint, roles []models.Role) error {
+	rbac := NewRBACService(ts.db)
+	for _, role := range roles {
+		if !rbac.RoleExists(role) {
+			return ErrTembusanRoleNotFound
+		}
+	}
+	return ts.validateUsers(ids)
+}
+
+func (ts *TembusanService) validateUsers(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	unique := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		unique[id] = struct{}{}
+	}
+
+	var count int64
+	if err := ts.db.Model(&models.User{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(unique) {
+		return ErrTembusanUserNotFound
+	}
+	return nil
+}
+
+// Replace mengganti seluruh daftar tembusan surat (dipanggil di dalam transaksi)
+func (ts *TembusanService) Replace(tx *gorm.DB, letterID uint, tembusan []models.LetterTembusan) error {
+	if err := tx.Unscoped().Where("letter_id = ?", letterID).Delete(&models.LetterTembusan{}).Error; err != nil {
+		return err
+	}
+	if len(tembusan) == 0 {
+		return nil
+	}
+	for i := range tembusan {
+		tembusan[i].LetterID = letterID
+	}
+	return tx.Create(&tembusan).Error
+}
+
+// LoadInto mengisi letter.Tembusan, dipakai sebelum mengirim event agar
+// consumer notifikasi tahu siapa saja penerima tembusan
+func (ts *TembusanService) LoadInto(letter *models.Letter) error {
+	return ts.db.Where("letter_id = ?", letter.ID).Order("urutan ASC").Find(&letter.Tembusan).Error
+}
+
+// IsRecipient mengecek apakah user menerima tembusan surat, baik langsung
+// maupun melalui unit (role)
+func (ts *TembusanService) IsRecipient(user *models.User, letterID uint) (bool, error) {
+	var count int64
+	err := ts.db.Model(&models.LetterTembusan{}).
+		Where("letter_id = ? AND (user_id = ? OR role = ?)", letterID, user.ID, user.Role).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ScopeTembusanRecipient adalah GORM scope untuk query surat yang ditembuskan
+// ke user dan sudah boleh dibaca (sama dengan Letter.IsVisibleToTembusan)
+func ScopeTembusanRecipient(user *models.User) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		recipients := db.Session(&gorm.Session{NewDB: true}).
+			Model(&models.LetterTembusan{}).
+			Select("letter_id").
+			Where("user_id = ? OR role = ?", user.ID, user.Role)
+
+		return db.Where("surat.id IN (?)", recipients).
+			Where("(surat.jenis_surat = ? AND surat.status <> ?) OR (surat.jenis_surat <> ? AND surat.status IN ?)",
+				models.LetterMasuk, models.StatusDraft,
+				models.LetterMasuk, []models.LetterStatus{models.StatusDisetujui, models.StatusDiarsipkan})
+	}
+}
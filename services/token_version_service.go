@@ -0,0 +1,100 @@
+package services
+
+import (
+	"TugasAkhir/models"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// tokenStateCacheTTL - RequireAuth membaca versi token dari cache ini.
+// Perubahan dari proses ini langsung meng-invalidate; perubahan dari instance
+// lain terlihat paling lambat setelah selang ini.
+const tokenStateCacheTTL = 5 * time.Second
+
+// tokenStateCacheLimit - cache dikosongkan jika melebihi jumlah user ini
+const tokenStateCacheLimit = 10000
+
+type tokenState struct {
+	version  uint
+	active   bool // user masih ada dan boleh login
+	loadedAt time.Time
+}
+
+var tokenStateCache = struct {
+	sync.RWMutex
+	users map[uint]tokenState
+}{users: make(map[uint]tokenState)}
+
+// InvalidateTokenState memaksa pengecekan token berikutnya untuk user membaca
+// ulang database
+func InvalidateTokenState(userID uint) {
+	tokenStateCache.Lock()
+	delete(tokenStateCache.users, userID)
+	tokenStateCache.Unlock()
+}
+
+type TokenVersionService struct {
+	db *gorm.DB
+}
+
+func NewTokenVersionService(db *gorm.DB) *TokenVersionService {
+	return &TokenVersionService{db: db}
+}
+
+// Check menolak access token milik user yang sudah dihapus / tidak aktif, atau
+// yang dibuat sebelum versi token user dinaikkan
+func (s *TokenVersionService) Check(userID, version uint) error {
+	state, err := s.state(userID)
+	if err != nil {
+		return err
+	}
+	if !state.active || version != state.version {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// Bump membatalkan semua access token user yang sudah terbit
+func (s *TokenVersionService) Bump(userID uint) error {
+	err := s.db.Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+	InvalidateTokenState(userID)
+	return err
+}
+
+func (s *TokenVersionService) state(userID uint) (tokenState, error) {
+	tokenStateCache.RLock()
+	cached, ok := tokenStateCache.users[userID]
+	tokenStateCache.RUnlock()
+	if ok && time.Since(cached.loadedAt) < tokenStateCacheTTL {
+		return cached, nil
+	}
+
+	var user models.User
+	err := s.db.Select("id", "status", "token_version").First(&user, userID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		if ok {
+			return cached, nil // Pakai data lama sampai database bisa dibaca lagi
+		}
+		return tokenState{}, err
+	}
+	state := tokenState{
+		version:  user.TokenVersion,
+		active:   err == nil && user.CanLogin(),
+		loadedAt: time.Now(),
+	}
+
+	tokenStateCache.Lock()
+	if len(tokenStateCache.users) >= tokenStateCacheLimit {
+		tokenStateCache.users = make(map[uint]tokenState)
+	}
+	tokenStateCache.users[userID] = state
+	tokenStateCache.Unlock()
+	return state, nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils/dbtest"
+
+	"gorm.io/gorm"
+)
+
+func newTestTokenVersionDB(t *testing.T) (*gorm.DB, models.User) {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{}, &models.UserSession{}, &models.RefreshToken{})
+	user := createTestUser(t, db, models.User{Username: "dewi", Email: "dewi@yayasan.org", Role: models.RoleStafProgram})
+	// Cache versi token bersifat global, ID user bisa sama antar test
+	InvalidateTokenState(user.ID)
+	t.Cleanup(func() { InvalidateTokenState(user.ID) })
+	return db, user
+}
+
+func TestRevokeAllRejectsTokensIssuedBefore(t *testing.T) {
+	db, user := newTestTokenVersionDB(t)
+	tvs := NewTokenVersionService(db)
+
+	issued := user.TokenVersion
+	if err := tvs.Check(user.ID, issued); err != nil {
+		t.Fatalf("Check before revoke: %v", err)
+	}
+
+	if _, err := NewSessionService(db).RevokeAll(user.ID); err != nil {
+		t.Fatalf("RevokeAll: %v", err)
+	}
+	// Versi yang sudah di-cache sebelum RevokeAll tidak boleh dipakai lagi
+	if err := tvs.Check(user.ID, issued); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("Check old token err = %v, want ErrTokenRevoked", err)
+	}
+
+	var fresh models.User
+	if err := db.First(&fresh, user.ID).Error; err != nil {
+		t.Fatalf("reload user: %v", err)
+	}
+	if fresh.TokenVersion != issued+1 {
+		t.Fatalf("token_version = %d, want %d", fresh.TokenVersion, issued+1)
+	}
+	if err := tvs.Check(user.ID, fresh.TokenVersion); err != nil {
+		t.Fatalf("Check new token: %v", err)
+	}
+}
+
+func TestTokenStateCacheIsInvalidatedPerUser(t *testing.T) {
+	db, user := newTestTokenVersionDB(t)
+	other := createTestUser(t, db, models.User{Username: "budi", Email: "budi@yayasan.org", Role: models.RoleStafProgram})
+	InvalidateTokenState(other.ID)
+	t.Cleanup(func() { InvalidateTokenState(other.ID) })
+	tvs := NewTokenVersionService(db)
+
+	for _, u := range []models.User{user, other} {
+		if err := tvs.Check(u.ID, u.TokenVersion); err != nil {
+			t.Fatalf("Check user %d: %v", u.ID, err)
+		}
+	}
+
+	// Perubahan langsung di database (misal dari instance lain) baru terlihat
+	// setelah cache kedaluwarsa atau di-invalidate
+	if err := db.Model(&models.User{}).Where("id IN ?", []uint{user.ID, other.ID}).
+		UpdateColumn("token_version", 5).Error; err != nil {
+		t.Fatalf("update token_version: %v", err)
+	}
+	if err := tvs.Check(user.ID, user.TokenVersion); err != nil {
+		t.Fatalf("Check within cache TTL: %v", err)
+	}
+
+	InvalidateTokenState(user.ID)
+	if err := tvs.Check(user.ID, user.TokenVersion); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("Check after invalidate err = %v, want ErrTokenRevoked", err)
+	}
+	// Cache user lain tidak ikut dibuang
+	if err := tvs.Check(other.ID, other.TokenVersion); err != nil {
+		t.Fatalf("Check other user: %v", err)
+	}
+}
+
+func TestTokenVersionRejectsInactiveUser(t *testing.T) {
+	db, user := newTestTokenVersionDB(t)
+	tvs := NewTokenVersionService(db)
+
+	if err := db.Model(&user).Update("status", models.UserStatusInactive).Error; err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	InvalidateTokenState(user.ID)
+	if err := tvs.Check(user.ID, user.TokenVersion); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("Check inactive user err = %v, want ErrTokenRevoked", err)
+	}
+}
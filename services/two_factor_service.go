@@ -0,0 +1,402 @@
+package services
+
+import (
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+	"TugasAkhir/utils/totp"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTwoFactorNotEnrolled      = errors.New("two-factor authentication is not set up")
+	ErrTwoFactorAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorInvalidCode      = errors.New("invalid two-factor code")
+	ErrTwoFactorRequiredByRole   = errors.New("two-factor authentication is required for this role")
+	ErrTwoFactorEnrolmentMissing = errors.New("two-factor enrolment has not been started")
+	ErrTwoFactorEnrolmentToken   = errors.New("invalid or expired enrolment token")
+)
+
+const (
+	// Toleransi satu step (±30 detik) untuk jam perangkat yang meleset
+	twoFactorSkew      = 1
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10 // karakter hex, ditampilkan "xxxxx-xxxxx"
+
+	enrolmentTokenLength = 20 // karakter hex, ditampilkan "xxxxx-xxxxx-xxxxx-xxxxx"
+	enrolmentTokenTTL    = 24 * time.Hour
+)
+
+type TwoFactorService struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorService(db *gorm.DB) *TwoFactorService {
+	return &TwoFactorService{db: db}
+}
+
+// TwoFactorEnrolment - data untuk didaftarkan ke aplikasi authenticator
+type TwoFactorEnrolment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// Get mengambil data 2FA user, nil jika user belum pernah memulai enrolment
+func (s *TwoFactorService) Get(userID uint) (*models.UserTwoFactor, error) {
+	var record models.UserTwoFactor
+	err := s.db.Where("user_id = ?", userID).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Enabled - user sudah mengaktifkan 2FA
+func (s *TwoFactorService) Enabled(userID uint) (bool, error) {
+	record, err := s.Get(userID)
+	if err != nil {
+		return false, err
+	}
+	return record != nil && record.Enabled(), nil
+}
+
+// Required - salah satu role user (utama atau penugasan aktif) mewajibkan 2FA
+func (s *TwoFactorService) Required(user *models.User) (bool, error) {
+	roles, err := NewUserRoleService(s.db).AvailableRoles(user)
+	if err != nil {
+		return false, err
+	}
+	names := make([]models.Role, 0, len(roles))
+	for _, r := range roles {
+		names = append(names, r.Role)
+	}
+
+	var count int64
+	err = s.db.Model(&models.RoleDefinition{}).
+		Where("name IN ? AND require_two_factor = ?", names, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// BeginEnrolment menyiapkan secret baru untuk user yang sudah login. Setiap
+// panggilan membuat secret baru; secret yang sudah pernah dibuat tidak pernah
+// dikembalikan lagi.
+func (s *TwoFactorService) BeginEnrolment(user *models.User) (*TwoFactorEnrolment, error) {
+	var enrolment *TwoFactorEnrolment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		enrolment, err = beginEnrolment(tx, user)
+		return err
+	})
+	return enrolment, err
+}
+
+// BeginEnrolmentWithToken - enrolment pertama saat login (hanya dengan
+// challenge token) harus memakai token enrolment dari admin. Token langsung
+// terpakai, meskipun enrolment belum dikonfirmasi.
+func (s *TwoFactorService) BeginEnrolmentWithToken(user *models.User, token string) (*TwoFactorEnrolment, error) {
+	normalized := normalizeRecoveryCode(token)
+	if len(normalized) != enrolmentTokenLength {
+		return nil, ErrTwoFactorEnrolmentToken
+	}
+
+	var enrolment *TwoFactorEnrolment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		res := tx.Model(&models.TwoFactorEnrolmentToken{}).
+			Where("user_id = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", user.ID, hashRecoveryCode(normalized), now).
+			Update("used_at", &now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrTwoFactorEnrolmentToken
+		}
+
+		var err error
+		enrolment, err = beginEnrolment(tx, user)
+		return err
+	})
+	return enrolment, err
+}
+
+func beginEnrolment(tx *gorm.DB, user *models.User) (*TwoFactorEnrolment, error) {
+	var record models.UserTwoFactor
+	err := tx.Where("user_id = ?", user.ID).First(&record).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if record.Enabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if record.ID == 0 {
+		record = models.UserTwoFactor{UserID: user.ID, Secret: secret}
+		err = tx.Create(&record).Error
+	} else {
+		err = tx.Model(&record).Updates(map[string]any{"secret": secret, "last_used_step": 0}).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := config.LoadAuthConfig().TwoFactorIssuer
+	return &TwoFactorEnrolment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(issuer, user.Email, secret),
+	}, nil
+}
+
+// IssueEnrolmentToken membuat token enrolment untuk user yang belum memakai
+// 2FA. Token sebelumnya yang belum terpakai dibatalkan. Token mentah hanya
+// dikembalikan sekali ini dan disampaikan admin ke user.
+func (s *TwoFactorService) IssueEnrolmentToken(userID uint, issuedByID *uint) (string, time.Time, error) {
+	enabled, err := s.Enabled(userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if enabled {
+		return "", time.Time{}, ErrTwoFactorAlreadyEnabled
+	}
+
+	raw := make([]byte, enrolmentTokenLength/2)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+	code := hex.EncodeToString(raw)
+
+	now := time.Now()
+	token := models.TwoFactorEnrolmentToken{
+		UserID:     userID,
+		TokenHash:  hashRecoveryCode(code),
+		ExpiresAt:  now.Add(enrolmentTokenTTL),
+		IssuedByID: issuedByID,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.TwoFactorEnrolmentToken{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Update("used_at", &now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&token).Error
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return code[0:5] + "-" + code[5:10] + "-" + code[10:15] + "-" + code[15:20], token.ExpiresAt, nil
+}
+
+// Pending - user sudah memulai enrolment tapi belum mengonfirmasi kode
+func (s *TwoFactorService) Pending(userID uint) (bool, error) {
+	record, err := s.Get(userID)
+	if err != nil {
+		return false, err
+	}
+	return record != nil && !record.Enabled(), nil
+}
+
+// ConfirmEnrolment mengaktifkan 2FA setelah kode pertama dari authenticator
+// cocok, lalu membuat recovery code (dikembalikan sekali ini saja)
+func (s *TwoFactorService) ConfirmEnrolment(userID uint, code string) ([]string, error) {
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var record models.UserTwoFactor
+		if err := tx.Where("user_id = ?", userID).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTwoFactorEnrolmentMissing
+			}
+			return err
+		}
+		if record.Enabled() {
+			return ErrTwoFactorAlreadyEnabled
+		}
+
+		step, ok := totp.Validate(record.Secret, code, time.Now(), twoFactorSkew)
+		if !ok {
+			return ErrTwoFactorInvalidCode
+		}
+
+		now := time.Now()
+		if err := tx.Model(&record).Updates(map[string]any{
+			"enabled_at":     &now,
+			"last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// Verify memeriksa kode TOTP atau recovery code saat login / aksi sensitif.
+// Kode TOTP yang sudah dipakai dan recovery code yang sudah terpakai ditolak.
+func (s *TwoFactorService) Verify(userID uint, code string) error {
+	record, err := s.enabledRecord(userID)
+	if err != nil {
+		return err
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(record.Secret, code, time.Now(), twoFactorSkew); ok {
+		return s.useStep(record, step)
+	}
+	return s.useRecoveryCode(userID, code)
+}
+
+// VerifyTOTP seperti Verify tetapi hanya menerima kode dari aplikasi
+// authenticator. Dipakai jika kode 2FA menggantikan password (akun SSO tanpa
+// password lokal), sehingga recovery code yang tercetak tidak cukup.
+func (s *TwoFactorService) VerifyTOTP(userID uint, code string) error {
+	record, err := s.enabledRecord(userID)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(record.Secret, strings.TrimSpace(code), time.Now(), twoFactorSkew)
+	if !ok {
+		return ErrTwoFactorInvalidCode
+	}
+	return s.useStep(record, step)
+}
+
+func (s *TwoFactorService) enabledRecord(userID uint) (*models.UserTwoFactor, error) {
+	record, err := s.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || !record.Enabled() {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	return record, nil
+}
+
+// useStep menandai time step kode TOTP sudah dipakai. Update bersyarat: dua
+// request bersamaan dengan kode yang sama hanya satu yang lolos.
+func (s *TwoFactorService) useStep(record *models.UserTwoFactor, step int64) error {
+	res := s.db.Model(&models.UserTwoFactor{}).
+		Where("id = ? AND last_used_step < ?", record.ID, step).
+		Update("last_used_step", step)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTwoFactorInvalidCode
+	}
+	return nil
+}
+
+func (s *TwoFactorService) useRecoveryCode(userID uint, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return ErrTwoFactorInvalidCode
+	}
+
+	now := time.Now()
+	res := s.db.Model(&models.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(normalized)).
+		Update("used_at", &now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTwoFactorInvalidCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes mengganti seluruh recovery code user
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint) ([]string, error) {
+	enabled, err := s.Enabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// RemainingRecoveryCodes - jumlah recovery code yang belum dipakai
+func (s *TwoFactorService) RemainingRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.UserRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// Disable mematikan 2FA dan menghapus secret beserta recovery code.
+// Ditolak jika role user mewajibkan 2FA.
+func (s *TwoFactorService) Disable(user *models.User) error {
+	required, err := s.Required(user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequiredByRole
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.UserTwoFactor{}).Error
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.UserRecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, recoveryCodeLength/2)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(raw)
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		records = append(records, models.UserRecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils/dbtest"
+	"TugasAkhir/utils/totp"
+)
+
+func newTestTwoFactorService(t *testing.T) (*TwoFactorService, *models.User) {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{}, &models.UserTwoFactor{}, &models.UserRecoveryCode{}, &models.TwoFactorEnrolmentToken{})
+	user := models.User{Username: "dewi", Email: "dewi@yayasan.org", Role: models.RoleDirektur, Status: models.UserStatusActive}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return NewTwoFactorService(db), &user
+}
+
+func TestBeginEnrolmentWithTokenRequiresAdminToken(t *testing.T) {
+	tfs, user := newTestTwoFactorService(t)
+
+	for _, token := range []string{"", "00000-00000-00000-00000", "abc"} {
+		if _, err := tfs.BeginEnrolmentWithToken(user, token); !errors.Is(err, ErrTwoFactorEnrolmentToken) {
+			t.Fatalf("BeginEnrolmentWithToken(%q) err = %v, want ErrTwoFactorEnrolmentToken", token, err)
+		}
+	}
+	if pending, _ := tfs.Pending(user.ID); pending {
+		t.Fatal("enrolment started without a valid token")
+	}
+
+	token, expiresAt, err := tfs.IssueEnrolmentToken(user.ID, nil)
+	if err != nil {
+		t.Fatalf("IssueEnrolmentToken: %v", err)
+	}
+	if time.Until(expiresAt) <= 0 {
+		t.Fatalf("token already expired at %v", expiresAt)
+	}
+	if _, err := tfs.BeginEnrolmentWithToken(user, token); err != nil {
+		t.Fatalf("BeginEnrolmentWithToken: %v", err)
+	}
+	// Token sekali pakai
+	if _, err := tfs.BeginEnrolmentWithToken(user, token); !errors.Is(err, ErrTwoFactorEnrolmentToken) {
+		t.Fatalf("reused token err = %v, want ErrTwoFactorEnrolmentToken", err)
+	}
+}
+
+func TestIssueEnrolmentTokenRevokesPreviousToken(t *testing.T) {
+	tfs, user := newTestTwoFactorService(t)
+
+	first, _, err := tfs.IssueEnrolmentToken(user.ID, nil)
+	if err != nil {
+		t.Fatalf("IssueEnrolmentToken: %v", err)
+	}
+	second, _, err := tfs.IssueEnrolmentToken(user.ID, nil)
+	if err != nil {
+		t.Fatalf("IssueEnrolmentToken: %v", err)
+	}
+	if _, err := tfs.BeginEnrolmentWithToken(user, first); !errors.Is(err, ErrTwoFactorEnrolmentToken) {
+		t.Fatalf("old token err = %v, want ErrTwoFactorEnrolmentToken", err)
+	}
+	if _, err := tfs.BeginEnrolmentWithToken(user, second); err != nil {
+		t.Fatalf("new token: %v", err)
+	}
+}
+
+func TestIssueEnrolmentTokenRejectsExpiredAndOtherUsersTokens(t *testing.T) {
+	tfs, user := newTestTwoFactorService(t)
+	other := models.User{Username: "budi", Email: "budi@yayasan.org", Role: models.RoleDirektur, Status: models.UserStatusActive}
+	if err := tfs.db.Create(&other).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	token, _, err := tfs.IssueEnrolmentToken(other.ID, nil)
+	if err != nil {
+		t.Fatalf("IssueEnrolmentToken: %v", err)
+	}
+	if _, err := tfs.BeginEnrolmentWithToken(user, token); !errors.Is(err, ErrTwoFactorEnrolmentToken) {
+		t.Fatalf("other user's token err = %v, want ErrTwoFactorEnrolmentToken", err)
+	}
+
+	token, _, err = tfs.IssueEnrolmentToken(user.ID, nil)
+	if err != nil {
+		t.Fatalf("IssueEnrolmentToken: %v", err)
+	}
+	tfs.db.Model(&models.TwoFactorEnrolmentToken{}).Where("user_id = ?", user.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := tfs.BeginEnrolmentWithToken(user, token); !errors.Is(err, ErrTwoFactorEnrolmentToken) {
+		t.Fatalf("expired token err = %v, want ErrTwoFactorEnrolmentToken", err)
+	}
+}
+
+func TestBeginEnrolmentNeverReturnsExistingSecret(t *testing.T) {
+	tfs, user := newTestTwoFactorService(t)
+
+	first, err := tfs.BeginEnrolment(user)
+	if err != nil {
+		t.Fatalf("BeginEnrolment: %v", err)
+	}
+	second, err := tfs.BeginEnrolment(user)
+	if err != nil {
+		t.Fatalf("BeginEnrolment again: %v", err)
+	}
+	if first.Secret == second.Secret {
+		t.Fatal("BeginEnrolment returned the previously generated secret")
+	}
+
+	record, err := tfs.Get(user.ID)
+	if err != nil || record == nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if record.Secret != second.Secret {
+		t.Fatal("stored secret is not the latest one")
+	}
+}
+
+func TestIssueEnrolmentTokenRejectsEnabledUser(t *testing.T) {
+	tfs, user := newTestTwoFactorService(t)
+	now := time.Now()
+	if err := tfs.db.Create(&models.UserTwoFactor{UserID: user.ID, Secret: "x", EnabledAt: &now}).Error; err != nil {
+		t.Fatalf("create 2fa: %v", err)
+	}
+	if _, _, err := tfs.IssueEnrolmentToken(user.ID, nil); !errors.Is(err, ErrTwoFactorAlreadyEnabled) {
+		t.Fatalf("IssueEnrolmentToken err = %v, want ErrTwoFactorAlreadyEnabled", err)
+	}
+}
+
+func TestVerifyTOTPRejectsRecoveryCodes(t *testing.T) {
+	tfs, user := newTestTwoFactorService(t)
+	enrolment, err := tfs.BeginEnrolment(user)
+	if err != nil {
+		t.Fatalf("BeginEnrolment: %v", err)
+	}
+	// Kode enrolment dari time step sebelumnya agar kode saat ini belum terpakai
+	first, err := totp.Code(enrolment.Secret, time.Now().Add(-totp.Period*time.Second))
+	if err != nil {
+		t.Fatalf("totp.Code: %v", err)
+	}
+	recovery, err := tfs.ConfirmEnrolment(user.ID, first)
+	if err != nil {
+		t.Fatalf("ConfirmEnrolment: %v", err)
+	}
+
+	if err := tfs.VerifyTOTP(user.ID, recovery[0]); !errors.Is(err, ErrTwoFactorInvalidCode) {
+		t.Fatalf("VerifyTOTP(recovery code) err = %v, want ErrTwoFactorInvalidCode", err)
+	}
+	// Recovery code yang ditolak tidak ikut terpakai
+	if err := tfs.Verify(user.ID, recovery[0]); err != nil {
+		t.Fatalf("Verify(recovery code): %v", err)
+	}
+
+	code, err := totp.Code(enrolment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("totp.Code: %v", err)
+	}
+	if err := tfs.VerifyTOTP(user.ID, code); err != nil {
+		t.Fatalf("VerifyTOTP: %v", err)
+	}
+	if err := tfs.VerifyTOTP(user.ID, code); !errors.Is(err, ErrTwoFactorInvalidCode) {
+		t.Fatalf("reused code err = %v, want ErrTwoFactorInvalidCode", err)
+	}
+}
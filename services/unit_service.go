@@ -0,0 +1,340 @@
+package services
+
+import (
+	"TugasAkhir/models"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnitNotFound       = errors.New("unit not found")
+	ErrUnitInactive       = errors.New("unit is inactive")
+	ErrUnitCycle          = errors.New("unit parent would create a cycle")
+	ErrUnitParentNotFound = errors.New("unit parent not found")
+	ErrUnitHeadNotFound   = errors.New("unit head user not found")
+	ErrUnitHasChildren    = errors.New("unit still has sub-units")
+)
+
+type UnitService struct {
+	db *gorm.DB
+}
+
+func NewUnitService(db *gorm.DB) *UnitService {
+	return &UnitService{db: db}
+}
+
+// Get mengambil unit beserta parent & kepala unit
+func (us *UnitService) Get(id uint) (*models.Unit, error) {
+	var unit models.Unit
+	if err := us.db.Preload("Parent").Preload("HeadUser").First(&unit, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnitNotFound
+		}
+		return nil, err
+	}
+	return &unit, nil
+}
+
+// GetActive sama seperti Get tetapi menolak unit nonaktif, dipakai saat unit
+// dipilih sebagai tujuan (disposisi, anggota baru)
+func (us *UnitService) GetActive(id uint) (*models.Unit, error) {
+	unit, err := us.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !unit.IsActive {
+		return nil, ErrUnitInactive
+	}
+	return unit, nil
+}
+
+// FindByKodeOrNama mencari unit aktif dari teks bebas (kode atau nama, tidak
+// case-sensitive). Dipakai agar klien lama yang masih mengirim nama bidang
+// tetap terhubung ke unit. Mengembalikan nil jika tidak ada yang cocok.
+func (us *UnitService) FindByKodeOrNama(text string) (*models.Unit, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	var unit models.Unit
+	err := us.db.Where("is_active = ? AND (LOWER(kode) = LOWER(?) OR LOWER(nama) = LOWER(?))", true, text, text).
+		First(&unit).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+// UserUnit mengembalikan unit user, nil jika user belum masuk unit mana pun
+func (us *UnitService) UserUnit(userID uint) (*models.Unit, error) {
+	var user models.User
+	if err := us.db.Select("id", "unit_id").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.UnitID == nil {
+		return nil, nil
+	}
+	return us.Get(*user.UnitID)
+}
+
+// LetterUnit mengembalikan unit pembuat surat, nil untuk surat tanpa unit
+func (us *UnitService) LetterUnit(letter *models.Letter) (*models.Unit, error) {
+	if letter.UnitID == nil {
+		return nil, nil
+	}
+	return us.Get(*letter.UnitID)
+}
+
+// IsMember - User anggota atau kepala unit tersebut, atau anggota/kepala
+// salah satu unit induknya (cakupan sama dengan filter unit_id di list surat)
+func (us *UnitService) IsMember(userID, unitID uint) (bool, error) {
+	ids, err := us.MemberUnitIDs(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range ids {
+		if id == unitID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MemberUnitIDs mengembalikan unit tempat user menjadi anggota atau kepala,
+// beserta seluruh sub-unitnya
+func (us *UnitService) MemberUnitIDs(userID uint) ([]uint, error) {
+	var roots []uint
+	if err := us.db.Model(&models.User{}).
+		Where("id = ? AND unit_id IS NOT NULL", userID).
+		Pluck("unit_id", &roots).Error; err != nil {
+		return nil, err
+	}
+	var headed []uint
+	if err := us.db.Model(&models.Unit{}).
+		Where("head_user_id = ?", userID).
+		Pluck("id", &headed).Error; err != nil {
+		return nil, err
+	}
+	roots = append(roots, headed...)
+	if len(roots) == 0 {
+		return nil, nil
+	}
+	return us.descendantIDs(roots)
+}
+
+// ValidateParent memastikan parent ada dan tidak membuat siklus (unit menjadi
+// sub-unit dari dirinya sendiri atau dari turunannya). unitID 0 untuk unit baru.
+func (us *UnitService) ValidateParent(unitID uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+
+	seen := map[uint]bool{}
+	current := *parentID
+	for {
+		if current == unitID && unitID != 0 {
+			return ErrUnitCycle
+		}
+		if seen[current] {
+			return ErrUnitCycle
+		}
+		seen[current] = true
+
+		var parent models.Unit
+		if err := us.db.Select("id", "parent_id").First(&parent, current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUnitParentNotFound
+			}
+			return err
+		}
+		if parent.ParentID == nil {
+			return nil
+		}
+		current = *parent.ParentID
+	}
+}
+
+// ValidateHead memastikan kepala unit adalah user yang ada
+func (us *UnitService) ValidateHead(headUserID *uint) error {
+	if headUserID == nil {
+		return nil
+	}
+	var count int64
+	if err := us.db.Model(&models.User{}).Where("id = ?", *headUserID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUnitHeadNotFound
+	}
+	return nil
+}
+
+// Delete menghapus unit (soft delete). Unit yang masih punya sub-unit ditolak;
+// anggota dilepas dari unit, surat lama tetap menyimpan ID unitnya.
+func (us *UnitService) Delete(id uint) error {
+	return us.db.Transaction(func(tx *gorm.DB) error {
+		var children int64
+		if err := tx.Model(&models.Unit{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrUnitHasChildren
+		}
+
+		if err := tx.Model(&models.User{}).Where("unit_id = ?", id).Update("unit_id", nil).Error; err != nil {
+			return err
+		}
+		res := tx.Delete(&models.Unit{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUnitNotFound
+		}
+		return nil
+	})
+}
+
+// DescendantIDs mengembalikan ID unit beserta seluruh sub-unitnya
+func (us *UnitService) DescendantIDs(unitID uint) ([]uint, error) {
+	return us.descendantIDs([]uint{unitID})
+}
+
+func (us *UnitService) descendantIDs(roots []uint) ([]uint, error) {
+	var units []models.Unit
+	if err := us.db.Select("id", "parent_id").Find(&units).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint, len(units))
+	for _, u := range units {
+		if u.ParentID != nil {
+			children[*u.ParentID] = append(children[*u.ParentID], u.ID)
+		}
+	}
+
+	var ids []uint
+	seen := map[uint]bool{}
+	for _, root := range roots {
+		if !seen[root] {
+			seen[root] = true
+			ids = append(ids, root)
+		}
+	}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// UnitReportRow adalah rekap surat per unit. Angka tidak termasuk sub-unit;
+// klien bisa menjumlahkan sendiri memakai ParentID.
+type UnitReportRow struct {
+	UnitID           uint   `json:"unit_id"`
+	Kode             string `json:"kode"`
+	Nama             string `json:"nama"`
+	ParentID         *uint  `json:"parent_id"`
+	JumlahAnggota    int64  `json:"jumlah_anggota"`
+	SuratKeluar      int64  `json:"surat_keluar"`
+	SuratKeluarFinal int64  `json:"surat_keluar_final"` // Disetujui atau diarsipkan
+	Disposisi        int64  `json:"disposisi"`          // Surat masuk yang didisposisikan ke unit
+	MenungguBalasan  int64  `json:"menunggu_balasan"`   // Disposisi yang masih butuh surat balasan
+}
+
+// Report merekap surat per unit. from/to (opsional) menyaring tanggal surat
+// dibuat untuk surat keluar dan tanggal disposisi untuk surat masuk.
+func (us *UnitService) Report(from, to *time.Time) ([]UnitReportRow, error) {
+	var units []models.Unit
+	if err := us.db.Order("kode ASC").Find(&units).Error; err != nil {
+		return nil, err
+	}
+
+	rows := make([]UnitReportRow, len(units))
+	index := make(map[uint]*UnitReportRow, len(units))
+	for i, u := range units {
+		rows[i] = UnitReportRow{UnitID: u.ID, Kode: u.Kode, Nama: u.Nama, ParentID: u.ParentID}
+		index[u.ID] = &rows[i]
+	}
+
+	var members []struct {
+		UnitID uint
+		Total  int64
+	}
+	if err := us.db.Model(&models.User{}).
+		Select("unit_id, COUNT(*) AS total").
+		Where("unit_id IS NOT NULL").
+		Group("unit_id").
+		Scan(&members).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if row, ok := index[m.UnitID]; ok {
+			row.JumlahAnggota = m.Total
+		}
+	}
+
+	var keluar []struct {
+		UnitID uint
+		Total  int64
+		Final  int64
+	}
+	keluarQuery := us.db.Model(&models.Letter{}).
+		Select("unit_id, COUNT(*) AS total, SUM(status IN ?) AS final",
+			[]models.LetterStatus{models.StatusDisetujui, models.StatusDiarsipkan}).
+		Where("jenis_surat = ? AND unit_id IS NOT NULL", models.LetterKeluar).
+		Group("unit_id")
+	if from != nil {
+		keluarQuery = keluarQuery.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		keluarQuery = keluarQuery.Where("created_at < ?", *to)
+	}
+	if err := keluarQuery.Scan(&keluar).Error; err != nil {
+		return nil, err
+	}
+	for _, k := range keluar {
+		if row, ok := index[k.UnitID]; ok {
+			row.SuratKeluar = k.Total
+			row.SuratKeluarFinal = k.Final
+		}
+	}
+
+	var disposisi []struct {
+		TujuanUnitID uint
+		Total        int64
+		Pending      int64
+	}
+	disposisiQuery := us.db.Model(&models.Letter{}).
+		Select("tujuan_unit_id, COUNT(*) AS total, SUM(status = ?) AS pending", models.StatusSudahDisposisi).
+		Where("jenis_surat = ? AND tujuan_unit_id IS NOT NULL", models.LetterMasuk).
+		Group("tujuan_unit_id")
+	if from != nil {
+		disposisiQuery = disposisiQuery.Where("tanggal_disposisi >= ?", *from)
+	}
+	if to != nil {
+		disposisiQuery = disposisiQuery.Where("tanggal_disposisi < ?", *to)
+	}
+	if err := disposisiQuery.Scan(&disposisi).Error; err != nil {
+		return nil, err
+	}
+	for _, d := range disposisi {
+		if row, ok := index[d.TujuanUnitID]; ok {
+			row.Disposisi = d.Total
+			row.MenungguBalasan = d.Pending
+		}
+	}
+
+	return rows, nil
+}
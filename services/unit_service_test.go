@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils/dbtest"
+
+	"gorm.io/gorm"
+)
+
+// newTestUnitTree membuat pohon unit:
+//
+//	KPP (root) ─┬─ PRG (child) ── EVL (grandchild)
+//	            └─ KEU (sibling)
+//	UMM (unit lain tanpa induk)
+func newTestUnitTree(t *testing.T, db *gorm.DB) (root, child, grandchild, sibling, other models.Unit) {
+	t.Helper()
+	create := func(kode string, parent *models.Unit) models.Unit {
+		unit := models.Unit{Kode: kode, Nama: kode, IsActive: true}
+		if parent != nil {
+			unit.ParentID = &parent.ID
+		}
+		if err := db.Create(&unit).Error; err != nil {
+			t.Fatalf("create unit %s: %v", kode, err)
+		}
+		return unit
+	}
+	root = create("KPP", nil)
+	child = create("PRG", &root)
+	grandchild = create("EVL", &child)
+	sibling = create("KEU", &root)
+	other = create("UMM", nil)
+	return root, child, grandchild, sibling, other
+}
+
+func TestValidateParent(t *testing.T) {
+	db := dbtest.Open(t, &models.Unit{}, &models.User{})
+	root, child, grandchild, _, other := newTestUnitTree(t, db)
+	units := NewUnitService(db)
+	missing := uint(999)
+
+	cases := []struct {
+		name     string
+		unitID   uint
+		parentID *uint
+		want     error
+	}{
+		{"tanpa induk", root.ID, nil, nil},
+		{"unit baru di bawah sub-unit", 0, &grandchild.ID, nil},
+		{"pindah ke pohon lain", child.ID, &other.ID, nil},
+		{"induk dirinya sendiri", child.ID, &child.ID, ErrUnitCycle},
+		{"induk turunan langsung", root.ID, &child.ID, ErrUnitCycle},
+		{"induk turunan bertingkat", root.ID, &grandchild.ID, ErrUnitCycle},
+		{"induk tidak ada", child.ID, &missing, ErrUnitParentNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := units.ValidateParent(tc.unitID, tc.parentID); !errors.Is(err, tc.want) {
+				t.Fatalf("ValidateParent = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestDescendantIDs(t *testing.T) {
+	db := dbtest.Open(t, &models.Unit{}, &models.User{})
+	root, child, grandchild, sibling, other := newTestUnitTree(t, db)
+	units := NewUnitService(db)
+
+	cases := []struct {
+		name string
+		unit uint
+		want []uint
+	}{
+		{"root", root.ID, []uint{root.ID, child.ID, grandchild.ID, sibling.ID}},
+		{"child", child.ID, []uint{child.ID, grandchild.ID}},
+		{"leaf", grandchild.ID, []uint{grandchild.ID}},
+		{"pohon lain", other.ID, []uint{other.ID}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := units.DescendantIDs(tc.unit)
+			if err != nil {
+				t.Fatalf("DescendantIDs: %v", err)
+			}
+			if got[0] != tc.unit {
+				t.Fatalf("DescendantIDs = %v, want the unit itself first", got)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			sort.Slice(tc.want, func(i, j int) bool { return tc.want[i] < tc.want[j] })
+			if len(got) != len(tc.want) {
+				t.Fatalf("DescendantIDs = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("DescendantIDs = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+// Cakupan unit pada visibilitas surat sama dengan filter unit_id di list:
+// unit induk ikut melihat disposisi ke sub-unitnya, tidak sebaliknya
+func TestSubUnitDispositionVisibility(t *testing.T) {
+	db := dbtest.Open(t,
+		&models.Unit{}, &models.User{}, &models.Letter{}, &models.LetterTembusan{},
+		&models.RoleDefinition{}, &models.Permission{}, &models.UserRoleAssignment{},
+	)
+	if err := SeedRBAC(db); err != nil {
+		t.Fatalf("SeedRBAC: %v", err)
+	}
+	InvalidatePermissionCache()
+	t.Cleanup(InvalidatePermissionCache)
+	root, child, grandchild, sibling, _ := newTestUnitTree(t, db)
+
+	rootMember := createTestUser(t, db, models.User{Username: "kpp", Email: "kpp@yayasan.org", Role: models.RoleStafProgram, UnitID: &root.ID})
+	childHead := createTestUser(t, db, models.User{Username: "kepala", Email: "kepala@yayasan.org", Role: models.RoleStafProgram})
+	if err := db.Model(&models.Unit{}).Where("id = ?", child.ID).Update("head_user_id", childHead.ID).Error; err != nil {
+		t.Fatalf("set head: %v", err)
+	}
+	leafMember := createTestUser(t, db, models.User{Username: "evl", Email: "evl@yayasan.org", Role: models.RoleStafProgram, UnitID: &grandchild.ID})
+
+	letterTo := func(unit models.Unit) models.Letter {
+		letter := models.Letter{JenisSurat: models.LetterKeluar, Scope: models.ScopeInternal, Status: models.StatusDraft, JudulSurat: "Disposisi " + unit.Kode, TujuanUnitID: &unit.ID}
+		if err := db.Create(&letter).Error; err != nil {
+			t.Fatalf("create letter: %v", err)
+		}
+		return letter
+	}
+	toRoot, toChild, toLeaf, toSibling := letterTo(root), letterTo(child), letterTo(grandchild), letterTo(sibling)
+
+	perms := NewPermissionService(db)
+	cases := []struct {
+		user models.User
+		want []uint
+	}{
+		{rootMember, []uint{toRoot.ID, toChild.ID, toLeaf.ID, toSibling.ID}},
+		{childHead, []uint{toChild.ID, toLeaf.ID}},
+		{leafMember, []uint{toLeaf.ID}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.user.Username, func(t *testing.T) {
+			var scoped []uint
+			if err := db.Model(&models.Letter{}).Scopes(perms.ScopeViewableLetters(&tc.user)).
+				Order("id").Pluck("surat.id", &scoped).Error; err != nil {
+				t.Fatalf("ScopeViewableLetters: %v", err)
+			}
+			if len(scoped) != len(tc.want) {
+				t.Fatalf("ScopeViewableLetters = %v, want %v", scoped, tc.want)
+			}
+			for i := range scoped {
+				if scoped[i] != tc.want[i] {
+					t.Fatalf("ScopeViewableLetters = %v, want %v", scoped, tc.want)
+				}
+			}
+
+			visible := map[uint]bool{}
+			for _, id := range tc.want {
+				visible[id] = true
+			}
+			for _, letter := range []models.Letter{toRoot, toChild, toLeaf, toSibling} {
+				ok, err := perms.CanUserViewLetter(&tc.user, &letter)
+				if err != nil {
+					t.Fatalf("CanUserViewLetter: %v", err)
+				}
+				if ok != visible[letter.ID] {
+					t.Errorf("CanUserViewLetter(%s) = %v, want %v", letter.JudulSurat, ok, visible[letter.ID])
+				}
+			}
+		})
+	}
+}
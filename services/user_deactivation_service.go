@@ -0,0 +1,234 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"TugasAkhir/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrUserAlreadyInactive = errors.New("user is already inactive")
+	ErrUserNotInactive     = errors.New("user is not inactive")
+	ErrDeactivateSelf      = errors.New("cannot deactivate your own account")
+)
+
+// UnassignedLettersError - user masih menjadi verifikator surat yang belum
+// selesai dan belum semua surat diberi pengganti
+type UnassignedLettersError struct {
+	LetterIDs []uint
+}
+
+func (e *UnassignedLettersError) Error() string {
+	return fmt.Sprintf("%d pending letters need a replacement verifier", len(e.LetterIDs))
+}
+
+// ReplacementError - pengganti yang dipilih untuk surat tidak aktif atau tidak
+// punya izin verifikasi sesuai scope surat
+type ReplacementError struct {
+	LetterID   uint
+	VerifierID uint
+}
+
+func (e *ReplacementError) Error() string {
+	return fmt.Sprintf("user %d cannot verify letter %d", e.VerifierID, e.LetterID)
+}
+
+// pendingVerifierStatuses - surat yang masih bisa sampai ke verifikator yang
+// ditunjuk: menunggu verifikasi, atau kembali ke verifikator setelah revisi /
+// ditolak Direktur
+var pendingVerifierStatuses = []models.LetterStatus{
+	models.StatusDraft,
+	models.StatusPerluVerifikasi,
+	models.StatusPerluRevisi,
+	models.StatusPerluPersetujuan,
+}
+
+// ReassignPlan - pengganti verifikator saat user dinonaktifkan. Letters
+// (ID surat → ID verifikator) mengalahkan Default.
+type ReassignPlan struct {
+	Default uint
+	Letters map[uint]uint
+}
+
+func (p ReassignPlan) target(letterID uint) uint {
+	if id, ok := p.Letters[letterID]; ok && id != 0 {
+		return id
+	}
+	return p.Default
+}
+
+// UserDeactivationService menonaktifkan user tanpa menghapusnya. Surat,
+// riwayat, dan penugasan yang menunjuk user tetap utuh; surat yang masih
+// menunggu user sebagai verifikator dialihkan lebih dulu.
+type UserDeactivationService struct {
+	db   *gorm.DB
+	rbac *RBACService
+}
+
+func NewUserDeactivationService(db *gorm.DB) *UserDeactivationService {
+	return &UserDeactivationService{db: db, rbac: NewRBACService(db)}
+}
+
+// PendingLetters - surat belum selesai dengan user sebagai verifikator
+func (s *UserDeactivationService) PendingLetters(userID uint) ([]models.Letter, error) {
+	var letters []models.Letter
+	err := s.db.Preload("CreatedBy").
+		Where("assigned_verifier_id = ? AND status IN ?", userID, pendingVerifierStatuses).
+		Order("id ASC").
+		Find(&letters).Error
+	return letters, err
+}
+
+// ReplacementVerifiers - user aktif selain userID yang bisa memverifikasi
+// surat dengan scope tersebut
+func (s *UserDeactivationService) ReplacementVerifiers(scope string, userID uint) ([]models.User, error) {
+	var users []models.User
+	err := s.verifiersQuery(s.db, scope).
+		Where("users.id <> ?", userID).
+		Order("username ASC").
+		Find(&users).Error
+	return users, err
+}
+
+func (s *UserDeactivationService) verifiersQuery(db *gorm.DB, scope string) *gorm.DB {
+	return db.Model(&models.User{}).
+		Scopes(ScopeActiveUsers, ScopeUsersWithRoles(s.rbac.RolesWithPermission(models.VerifyPermission(scope))))
+}
+
+// Deactivate mengalihkan surat yang menunggu user ke verifikator pengganti,
+// menandai user inactive, lalu mencabut semua sesi & token-nya. Pengalihan
+// dicatat di riwayat surat atas nama actor.
+func (s *UserDeactivationService) Deactivate(userID uint, plan ReassignPlan, actor *models.User) (*models.User, []models.Letter, error) {
+	if actor != nil && actor.ID == userID {
+		return nil, nil, ErrDeactivateSelf
+	}
+
+	var user models.User
+	var reassigned []models.Letter
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if user.Status == models.UserStatusInactive {
+			return ErrUserAlreadyInactive
+		}
+
+		var letters []models.Letter
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("assigned_verifier_id = ? AND status IN ?", userID, pendingVerifierStatuses).
+			Order("id ASC").
+			Find(&letters).Error; err != nil {
+			return err
+		}
+
+		var missing []uint
+		for _, letter := range letters {
+			if plan.target(letter.ID) == 0 {
+				missing = append(missing, letter.ID)
+			}
+		}
+		if len(missing) > 0 {
+			return &UnassignedLettersError{LetterIDs: missing}
+		}
+
+		for i := range letters {
+			letter := &letters[i]
+			target := plan.target(letter.ID)
+			replacement, err := s.replacement(tx, letter, target, userID)
+			if err != nil {
+				return err
+			}
+
+			letter.AssignedVerifierID = &replacement.ID
+			if err := UpdateLetterFields(tx, letter, letter.Status, "assigned_verifier_id"); err != nil {
+				return err
+			}
+			entry := models.LetterHistory{
+				Action:  models.LetterActionVerifierReassigned,
+				Catatan: fmt.Sprintf("Verifikator %s dinonaktifkan, dialihkan ke %s", user.Username, replacement.Username),
+			}
+			if actor != nil {
+				entry.ActorID = actor.ID
+				entry.ActorRole = actor.Role
+			}
+			if err := RecordLetterAction(tx, letter, letter.Status, entry); err != nil {
+				return err
+			}
+			letter.AssignedVerifier = replacement
+			reassigned = append(reassigned, *letter)
+		}
+
+		now := time.Now()
+		if err := tx.Model(&user).Updates(map[string]any{
+			"status":         models.UserStatusInactive,
+			"deactivated_at": &now,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Sesi, refresh token, dan access token yang sudah terbit ikut dicabut
+		_, err := NewSessionService(tx).RevokeAll(userID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	InvalidateTokenState(userID)
+	return &user, reassigned, nil
+}
+
+// replacement memastikan pengganti aktif, bukan user yang dinonaktifkan, dan
+// punya izin verifikasi untuk scope surat
+func (s *UserDeactivationService) replacement(tx *gorm.DB, letter *models.Letter, verifierID, userID uint) (*models.User, error) {
+	if verifierID == userID {
+		return nil, &ReplacementError{LetterID: letter.ID, VerifierID: verifierID}
+	}
+	var verifier models.User
+	err := s.verifiersQuery(tx, letter.Scope).Where("users.id = ?", verifierID).First(&verifier).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &ReplacementError{LetterID: letter.ID, VerifierID: verifierID}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &verifier, nil
+}
+
+// Reactivate mengaktifkan kembali user. User yang belum pernah mengatur
+// password (undangan belum diterima) kembali ke status pending.
+func (s *UserDeactivationService) Reactivate(userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.Status != models.UserStatusInactive {
+		return nil, ErrUserNotInactive
+	}
+
+	status := models.UserStatusActive
+	if user.PasswordHash == "" {
+		status = models.UserStatusPending
+	}
+	if err := s.db.Model(&user).Updates(map[string]any{
+		"status":         status,
+		"deactivated_at": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+	InvalidateTokenState(userID)
+	user.Status = status
+	user.DeactivatedAt = nil
+	return &user, nil
+}
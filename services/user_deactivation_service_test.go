@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils/dbtest"
+
+	"gorm.io/gorm"
+)
+
+func newTestDeactivationService(t *testing.T) (*UserDeactivationService, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t,
+		&models.Unit{}, &models.User{}, &models.UserRoleAssignment{}, &models.RoleDefinition{}, &models.Permission{},
+		&models.Letter{}, &models.LetterHistory{}, &models.UserSession{}, &models.RefreshToken{},
+	)
+	if err := SeedRBAC(db); err != nil {
+		t.Fatalf("SeedRBAC: %v", err)
+	}
+	InvalidatePermissionCache()
+	t.Cleanup(InvalidatePermissionCache)
+	return NewUserDeactivationService(db), db
+}
+
+// createPendingLetter membuat surat eksternal yang menunggu verifikasi verifierID
+func createPendingLetter(t *testing.T, db *gorm.DB, creatorID, verifierID uint) models.Letter {
+	t.Helper()
+	letter := models.Letter{
+		JenisSurat:         models.LetterKeluar,
+		Scope:              models.ScopeEksternal,
+		Status:             models.StatusPerluVerifikasi,
+		CreatedByID:        creatorID,
+		AssignedVerifierID: &verifierID,
+	}
+	if err := db.Create(&letter).Error; err != nil {
+		t.Fatalf("create letter: %v", err)
+	}
+	return letter
+}
+
+func TestDeactivateReassignsPendingLetters(t *testing.T) {
+	svc, db := newTestDeactivationService(t)
+	admin := createTestUser(t, db, models.User{Username: "admin", Email: "admin@yayasan.org", Role: models.RoleAdmin})
+	staf := createTestUser(t, db, models.User{Username: "rina", Email: "rina@yayasan.org", Role: models.RoleStafProgram})
+	leaving := createTestUser(t, db, models.User{Username: "dewi", Email: "dewi@yayasan.org", Role: models.RoleManajerKPP})
+	pemas := createTestUser(t, db, models.User{Username: "budi", Email: "budi@yayasan.org", Role: models.RoleManajerPemas})
+	kpp := createTestUser(t, db, models.User{Username: "sari", Email: "sari@yayasan.org", Role: models.RoleManajerKPP})
+	first := createPendingLetter(t, db, staf.ID, leaving.ID)
+	second := createPendingLetter(t, db, staf.ID, leaving.ID)
+
+	sessions := NewSessionService(db)
+	session, err := sessions.Start(leaving.ID, SessionDevice{})
+	if err != nil {
+		t.Fatalf("Start session: %v", err)
+	}
+	InvalidateTokenState(leaving.ID)
+	t.Cleanup(func() { InvalidateTokenState(leaving.ID) })
+
+	plan := ReassignPlan{Default: pemas.ID, Letters: map[uint]uint{second.ID: kpp.ID}}
+	user, reassigned, err := svc.Deactivate(leaving.ID, plan, &admin)
+	if err != nil {
+		t.Fatalf("Deactivate: %v", err)
+	}
+	if user.Status != models.UserStatusInactive {
+		t.Fatalf("status = %s, want inactive", user.Status)
+	}
+	if len(reassigned) != 2 {
+		t.Fatalf("reassigned %d letters, want 2", len(reassigned))
+	}
+
+	want := map[uint]uint{first.ID: pemas.ID, second.ID: kpp.ID}
+	for letterID, verifierID := range want {
+		var letter models.Letter
+		if err := db.First(&letter, letterID).Error; err != nil {
+			t.Fatalf("load letter %d: %v", letterID, err)
+		}
+		if letter.AssignedVerifierID == nil || *letter.AssignedVerifierID != verifierID {
+			t.Fatalf("letter %d verifier = %v, want %d", letterID, letter.AssignedVerifierID, verifierID)
+		}
+
+		var history []models.LetterHistory
+		db.Where("letter_id = ? AND action = ?", letterID, models.LetterActionVerifierReassigned).Find(&history)
+		if len(history) != 1 || history[0].ActorID != admin.ID {
+			t.Fatalf("letter %d reassignment history = %+v, want one entry by admin", letterID, history)
+		}
+	}
+
+	if err := db.First(&models.UserSession{}, session.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("load session err = %v, want session revoked", err)
+	}
+	if err := NewTokenVersionService(db).Check(leaving.ID, leaving.TokenVersion); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("access token check err = %v, want ErrTokenRevoked", err)
+	}
+}
+
+func TestDeactivateRejectsMissingOrInvalidReplacement(t *testing.T) {
+	svc, db := newTestDeactivationService(t)
+	staf := createTestUser(t, db, models.User{Username: "rina", Email: "rina@yayasan.org", Role: models.RoleStafProgram})
+	leaving := createTestUser(t, db, models.User{Username: "dewi", Email: "dewi@yayasan.org", Role: models.RoleManajerKPP})
+	inactive := createTestUser(t, db, models.User{Username: "budi", Email: "budi@yayasan.org", Role: models.RoleManajerPemas, Status: models.UserStatusInactive})
+	// Manajer PKL hanya memverifikasi surat internal
+	pkl := createTestUser(t, db, models.User{Username: "sari", Email: "sari@yayasan.org", Role: models.RoleManajerPKL})
+	letter := createPendingLetter(t, db, staf.ID, leaving.ID)
+
+	var unassigned *UnassignedLettersError
+	if _, _, err := svc.Deactivate(leaving.ID, ReassignPlan{}, nil); !errors.As(err, &unassigned) {
+		t.Fatalf("Deactivate without replacement err = %v, want UnassignedLettersError", err)
+	}
+	if len(unassigned.LetterIDs) != 1 || unassigned.LetterIDs[0] != letter.ID {
+		t.Fatalf("unassigned letters = %v, want [%d]", unassigned.LetterIDs, letter.ID)
+	}
+
+	for name, replacementID := range map[string]uint{
+		"inactive":           inactive.ID,
+		"without permission": pkl.ID,
+		"same user":          leaving.ID,
+	} {
+		var replacementErr *ReplacementError
+		_, _, err := svc.Deactivate(leaving.ID, ReassignPlan{Default: replacementID}, nil)
+		if !errors.As(err, &replacementErr) || replacementErr.LetterID != letter.ID || replacementErr.VerifierID != replacementID {
+			t.Fatalf("%s replacement err = %v, want ReplacementError for letter %d", name, err, letter.ID)
+		}
+	}
+
+	// Penolakan membatalkan seluruh perubahan
+	var user models.User
+	db.First(&user, leaving.ID)
+	if user.Status != models.UserStatusActive {
+		t.Fatalf("status = %s after rejected deactivation, want active", user.Status)
+	}
+	var history int64
+	db.Model(&models.LetterHistory{}).Where("letter_id = ?", letter.ID).Count(&history)
+	if history != 0 {
+		t.Fatalf("history entries = %d after rejected deactivation, want 0", history)
+	}
+}
+
+func TestReactivateRestoresStatus(t *testing.T) {
+	svc, db := newTestDeactivationService(t)
+	withPassword := createTestUser(t, db, models.User{Username: "dewi", Email: "dewi@yayasan.org", Role: models.RoleStafProgram, PasswordHash: "hash"})
+	invited := createTestUser(t, db, models.User{Username: "budi", Email: "budi@yayasan.org", Role: models.RoleStafProgram, Status: models.UserStatusPending})
+
+	if _, err := svc.Reactivate(withPassword.ID); !errors.Is(err, ErrUserNotInactive) {
+		t.Fatalf("Reactivate active user err = %v, want ErrUserNotInactive", err)
+	}
+
+	for _, tc := range []struct {
+		user models.User
+		want models.UserStatus
+	}{
+		{withPassword, models.UserStatusActive},
+		// Undangan belum diterima: tanpa password, kembali ke pending
+		{invited, models.UserStatusPending},
+	} {
+		if _, _, err := svc.Deactivate(tc.user.ID, ReassignPlan{}, nil); err != nil {
+			t.Fatalf("Deactivate %s: %v", tc.user.Username, err)
+		}
+		user, err := svc.Reactivate(tc.user.ID)
+		if err != nil {
+			t.Fatalf("Reactivate %s: %v", tc.user.Username, err)
+		}
+		var stored models.User
+		db.First(&stored, tc.user.ID)
+		if user.Status != tc.want || stored.Status != tc.want || stored.DeactivatedAt != nil {
+			t.Fatalf("%s status = %s (stored %s, deactivated_at %v), want %s", tc.user.Username, user.Status, stored.Status, stored.DeactivatedAt, tc.want)
+		}
+		InvalidateTokenState(tc.user.ID)
+	}
+}
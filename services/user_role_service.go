@@ -0,0 +1,128 @@
+package services
+
+import (
+	"TugasAkhir/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleNotAssigned           = errors.New("role is not assigned to user")
+	ErrRoleAssignmentNotFound    = errors.New("role assignment not found")
+	ErrRoleAssignmentPrimary     = errors.New("role is already the user's primary role")
+	ErrRoleAssignmentInvalidSpan = errors.New("valid_until must be after valid_from")
+)
+
+// UserRoleService mengelola role tambahan user (penugasan Plt./Plh.) dan
+// menentukan role mana saja yang boleh dipakai user saat ini
+type UserRoleService struct {
+	db *gorm.DB
+}
+
+func NewUserRoleService(db *gorm.DB) *UserRoleService {
+	return &UserRoleService{db: db}
+}
+
+// AvailableRoles mengembalikan role utama user diikuti penugasan yang sedang
+// berlaku. user harus dimuat dari database (Role = role utama).
+func (us *UserRoleService) AvailableRoles(user *models.User) ([]models.ActingRole, error) {
+	roles := []models.ActingRole{{Role: user.Role, Primary: true}}
+
+	var assignments []models.UserRoleAssignment
+	if err := us.db.Where("user_id = ?", user.ID).Order("role ASC, id ASC").Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	seen := map[models.Role]bool{user.Role: true}
+	for _, a := range assignments {
+		if seen[a.Role] || !a.ActiveAt(now) {
+			continue
+		}
+		seen[a.Role] = true
+		roles = append(roles, models.ActingRole{Role: a.Role, Label: a.Label, ValidUntil: a.ValidUntil})
+	}
+	return roles, nil
+}
+
+// Resolve memeriksa bahwa user boleh memakai role tersebut sekarang
+func (us *UserRoleService) Resolve(user *models.User, role models.Role) (models.ActingRole, error) {
+	roles, err := us.AvailableRoles(user)
+	if err != nil {
+		return models.ActingRole{}, err
+	}
+	for _, r := range roles {
+		if r.Role == role {
+			return r, nil
+		}
+	}
+	return models.ActingRole{}, ErrRoleNotAssigned
+}
+
+// ListAssignments mengambil semua penugasan user, termasuk yang sudah berakhir
+func (us *UserRoleService) ListAssignments(userID uint) ([]models.UserRoleAssignment, error) {
+	var assignments []models.UserRoleAssignment
+	err := us.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&assignments).Error
+	return assignments, err
+}
+
+// Assign menambahkan role ke user. Role harus terdaftar dan berbeda dari role
+// utama user; rentang waktu (jika ada) harus valid.
+func (us *UserRoleService) Assign(assignment *models.UserRoleAssignment) error {
+	var user models.User
+	if err := us.db.Select("id, role").First(&user, assignment.UserID).Error; err != nil {
+		return err
+	}
+	if !NewRBACService(us.db).RoleExists(assignment.Role) {
+		return ErrRoleNotFound
+	}
+	if assignment.Role == user.Role {
+		return ErrRoleAssignmentPrimary
+	}
+	if assignment.ValidFrom != nil && assignment.ValidUntil != nil && !assignment.ValidUntil.After(*assignment.ValidFrom) {
+		return ErrRoleAssignmentInvalidSpan
+	}
+	return us.db.Create(assignment).Error
+}
+
+// DeleteAssignment mencabut penugasan milik user tertentu. Semua sesi user
+// dicabut dalam transaksi yang sama: jika pencabutan sesi gagal, penugasan
+// batal dihapus sehingga tidak ada token lama yang tetap membawa role tersebut.
+func (us *UserRoleService) DeleteAssignment(userID, id uint) error {
+	err := us.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ?", userID).Delete(&models.UserRoleAssignment{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRoleAssignmentNotFound
+		}
+		_, err := NewSessionService(tx).RevokeAll(userID)
+		return err
+	})
+	// Cache dibuang lagi setelah commit agar tidak menyimpan versi token lama
+	InvalidateTokenState(userID)
+	return err
+}
+
+// ScopeActiveUsers - hanya user yang bisa login (bukan pending / nonaktif)
+func ScopeActiveUsers(db *gorm.DB) *gorm.DB {
+	return db.Where("users.status = ?", models.UserStatusActive)
+}
+
+// ScopeUsersWithRoles memfilter user yang memegang salah satu role, baik
+// sebagai role utama maupun lewat penugasan yang sedang berlaku
+func ScopeUsersWithRoles(roles []models.Role) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		now := time.Now()
+		assigned := db.Session(&gorm.Session{NewDB: true}).
+			Model(&models.UserRoleAssignment{}).
+			Select("user_id").
+			Where("role IN ?", roles).
+			Where("valid_from IS NULL OR valid_from <= ?", now).
+			Where("valid_until IS NULL OR valid_until > ?", now)
+		return db.Where("users.role IN ? OR users.id IN (?)", roles, assigned)
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"TugasAkhir/models"
+	"TugasAkhir/utils/dbtest"
+
+	"gorm.io/gorm"
+)
+
+func newTestUserRoleService(t *testing.T, extra ...any) (*UserRoleService, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t, append([]any{
+		&models.Unit{}, &models.User{}, &models.UserRoleAssignment{},
+		&models.RoleDefinition{}, &models.Permission{},
+	}, extra...)...)
+	if err := SeedRBAC(db); err != nil {
+		t.Fatalf("SeedRBAC: %v", err)
+	}
+	InvalidatePermissionCache()
+	t.Cleanup(InvalidatePermissionCache)
+	return NewUserRoleService(db), db
+}
+
+// assignRole membuat penugasan langsung di database (tanpa validasi Assign)
+func assignRole(t *testing.T, db *gorm.DB, userID uint, role models.Role, from, until *time.Time) models.UserRoleAssignment {
+	t.Helper()
+	a := models.UserRoleAssignment{UserID: userID, Role: role, Label: "Plt. " + string(role), ValidFrom: from, ValidUntil: until}
+	if err := db.Create(&a).Error; err != nil {
+		t.Fatalf("create assignment: %v", err)
+	}
+	return a
+}
+
+func TestAvailableRolesAndResolve(t *testing.T) {
+	roles, db := newTestUserRoleService(t)
+	user := createTestUser(t, db, models.User{Username: "sari", Email: "sari@yayasan.org", Role: models.RoleManajerPKL})
+
+	now := time.Now()
+	past, future := now.Add(-48*time.Hour), now.Add(48*time.Hour)
+	yesterday, tomorrow := now.Add(-24*time.Hour), now.Add(24*time.Hour)
+	assignRole(t, db, user.ID, models.RoleDirektur, &yesterday, &tomorrow)
+	assignRole(t, db, user.ID, models.RolePengurus, &past, &yesterday)     // sudah berakhir
+	assignRole(t, db, user.ID, models.RoleStafLembaga, &tomorrow, &future) // belum mulai
+	assignRole(t, db, user.ID, models.RoleManajerPKL, nil, nil)            // sama dengan role utama
+	assignRole(t, db, user.ID, models.RoleManajerKPP, nil, nil)            // tanpa batas waktu
+	assignRole(t, db, user.ID+1, models.RoleAdmin, nil, nil)               // milik user lain
+
+	available, err := roles.AvailableRoles(&user)
+	if err != nil {
+		t.Fatalf("AvailableRoles: %v", err)
+	}
+	want := []models.Role{models.RoleManajerPKL, models.RoleDirektur, models.RoleManajerKPP}
+	if len(available) != len(want) {
+		t.Fatalf("AvailableRoles = %+v, want %v", available, want)
+	}
+	for i, r := range available {
+		if r.Role != want[i] {
+			t.Fatalf("AvailableRoles = %+v, want %v", available, want)
+		}
+		if r.Primary != (i == 0) {
+			t.Errorf("%s: Primary = %v", r.Role, r.Primary)
+		}
+	}
+	if available[1].Label != "Plt. direktur" || available[1].ValidUntil == nil || !available[1].ValidUntil.Equal(tomorrow) {
+		t.Errorf("acting direktur = %+v", available[1])
+	}
+
+	for _, role := range []models.Role{models.RoleManajerPKL, models.RoleDirektur, models.RoleManajerKPP} {
+		if got, err := roles.Resolve(&user, role); err != nil || got.Role != role {
+			t.Errorf("Resolve(%s) = %+v, %v", role, got, err)
+		}
+	}
+	for _, role := range []models.Role{models.RolePengurus, models.RoleStafLembaga, models.RoleAdmin} {
+		if _, err := roles.Resolve(&user, role); !errors.Is(err, ErrRoleNotAssigned) {
+			t.Errorf("Resolve(%s) err = %v, want ErrRoleNotAssigned", role, err)
+		}
+	}
+}
+
+func TestScopeUsersWithRoles(t *testing.T) {
+	_, db := newTestUserRoleService(t)
+	now := time.Now()
+	yesterday, tomorrow := now.Add(-24*time.Hour), now.Add(24*time.Hour)
+
+	direktur := createTestUser(t, db, models.User{Username: "direktur", Email: "direktur@yayasan.org", Role: models.RoleDirektur})
+	acting := createTestUser(t, db, models.User{Username: "plt", Email: "plt@yayasan.org", Role: models.RoleManajerPKL})
+	assignRole(t, db, acting.ID, models.RoleDirektur, &yesterday, &tomorrow)
+	expired := createTestUser(t, db, models.User{Username: "mantan", Email: "mantan@yayasan.org", Role: models.RoleManajerKPP})
+	assignRole(t, db, expired.ID, models.RoleDirektur, nil, &yesterday)
+	upcoming := createTestUser(t, db, models.User{Username: "calon", Email: "calon@yayasan.org", Role: models.RoleManajerPemas})
+	assignRole(t, db, upcoming.ID, models.RoleDirektur, &tomorrow, nil)
+	createTestUser(t, db, models.User{Username: "staf", Email: "staf@yayasan.org", Role: models.RoleStafProgram})
+
+	var ids []uint
+	if err := db.Model(&models.User{}).Scopes(ScopeUsersWithRoles([]models.Role{models.RoleDirektur})).
+		Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatalf("ScopeUsersWithRoles: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != direktur.ID || ids[1] != acting.ID {
+		t.Fatalf("users = %v, want [%d %d]", ids, direktur.ID, acting.ID)
+	}
+}
+
+func TestDeleteAssignmentRevokesSessions(t *testing.T) {
+	roles, db := newTestUserRoleService(t, &models.UserSession{}, &models.RefreshToken{})
+	user := createTestUser(t, db, models.User{Username: "sari", Email: "sari@yayasan.org", Role: models.RoleManajerPKL})
+	assignment := assignRole(t, db, user.ID, models.RoleDirektur, nil, nil)
+
+	if err := roles.DeleteAssignment(user.ID+1, assignment.ID); !errors.Is(err, ErrRoleAssignmentNotFound) {
+		t.Fatalf("DeleteAssignment other user err = %v, want ErrRoleAssignmentNotFound", err)
+	}
+	if err := roles.DeleteAssignment(user.ID, assignment.ID); err != nil {
+		t.Fatalf("DeleteAssignment: %v", err)
+	}
+
+	var reloaded models.User
+	if err := db.First(&reloaded, user.ID).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	if reloaded.TokenVersion != user.TokenVersion+1 {
+		t.Fatalf("token_version = %d, want %d", reloaded.TokenVersion, user.TokenVersion+1)
+	}
+}
+
+func TestDeleteAssignmentKeptWhenSessionRevokeFails(t *testing.T) {
+	// Tabel sesi sengaja tidak dibuat sehingga pencabutan sesi gagal
+	roles, db := newTestUserRoleService(t)
+	user := createTestUser(t, db, models.User{Username: "sari", Email: "sari@yayasan.org", Role: models.RoleManajerPKL})
+	assignment := assignRole(t, db, user.ID, models.RoleDirektur, nil, nil)
+
+	if err := roles.DeleteAssignment(user.ID, assignment.ID); err == nil {
+		t.Fatal("DeleteAssignment succeeded without revoking sessions")
+	}
+	if _, err := roles.Resolve(&user, models.RoleDirektur); err != nil {
+		t.Fatalf("assignment removed although sessions were not revoked: %v", err)
+	}
+}
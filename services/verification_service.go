@@ -0,0 +1,89 @@
+package services
+
+import (
+	"TugasAkhir/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrVerificationNotFound = errors.New("verification token not found")
+	ErrVerificationRevoked  = errors.New("verification already revoked")
+)
+
+type VerificationService struct {
+	db *gorm.DB
+}
+
+func NewVerificationService(db *gorm.DB) *VerificationService {
+	return &VerificationService{db: db}
+}
+
+// newVerificationToken menghasilkan token acak (dimuat di QR code) beserta hash
+// yang disimpan di database
+func newVerificationToken() (string, string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(tokenBytes)
+	return raw, hashVerificationToken(raw), nil
+}
+
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// FindByToken mencari data verifikasi beserta surat & penandatangannya.
+// Surat yang sudah dihapus (soft delete) tidak ikut ter-load, Letter bernilai nil.
+func (vs *VerificationService) FindByToken(token string) (*models.LetterVerification, error) {
+	var v models.LetterVerification
+	err := vs.db.
+		Preload("Letter").
+		Preload("Letter.SignedBy").
+		Where("token_hash = ?", hashVerificationToken(token)).
+		First(&v).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVerificationNotFound
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Revoke mencabut verifikasi terakhir milik surat, misal karena surat ditarik
+// atau diganti. Setelah dicabut halaman verifikasi menampilkan status "dicabut".
+func (vs *VerificationService) Revoke(letterID, revokerID uint, reason string) (*models.LetterVerification, error) {
+	var v models.LetterVerification
+	if err := vs.db.Where("letter_id = ?", letterID).Order("id DESC").First(&v).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVerificationNotFound
+		}
+		return nil, err
+	}
+	if v.IsRevoked() {
+		return nil, ErrVerificationRevoked
+	}
+
+	now := time.Now()
+	v.RevokedAt = &now
+	v.RevokedByID = &revokerID
+	v.RevocationReason = reason
+
+	if err := vs.db.Model(&v).Updates(map[string]any{
+		"revoked_at":        v.RevokedAt,
+		"revoked_by_id":     v.RevokedByID,
+		"revocation_reason": v.RevocationReason,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
@@ -14,27 +14,42 @@ import (
 //
 // Features:
 // - Independent sequences for masuk/keluar (filtered by jenis_surat)
+// - Independent sequence per unit when unit is given, formatted "<seq>/<kode unit>"
 // - Yearly reset (filtered by YEAR(created_at))
 // - Race condition protection via FOR UPDATE
 //
+// Without a unit (unit == nil, e.g. surat masuk registered centrally) the
+// global sequence of plain numbers is used, as before units existed.
+//
 // Returns empty string if there's an error, caller should check error.
-func GenerateNomorAgenda(tx *gorm.DB, jenisSurat models.LetterType) (string, error) {
+func GenerateNomorAgenda(tx *gorm.DB, jenisSurat models.LetterType, unit *models.Unit) (string, error) {
 	var lastSeq int
 	currentYear := time.Now().Year()
 
+	unitFilter := "nomor_agenda NOT LIKE '%/%'"
+	args := []interface{}{jenisSurat, currentYear}
+	if unit != nil {
+		unitFilter = "unit_id = ?"
+		args = append(args, unit.ID)
+	}
+
 	// Use raw SQL with FOR UPDATE to lock rows and prevent race conditions
 	// COALESCE handles the case when table is empty (returns 0)
 	// Filter nomor_agenda != '' to exclude drafts that never got a number
+	// CAST takes the leading number, so "12/KPP" is read as 12
 	err := tx.Raw(`
 		SELECT COALESCE(MAX(CAST(nomor_agenda AS UNSIGNED)), 0) 
 		FROM surat 
-		WHERE jenis_surat = ? AND YEAR(created_at) = ? AND nomor_agenda != ''
+		WHERE jenis_surat = ? AND YEAR(created_at) = ? AND nomor_agenda != '' AND `+unitFilter+`
 		FOR UPDATE
-	`, jenisSurat, currentYear).Scan(&lastSeq).Error
+	`, args...).Scan(&lastSeq).Error
 
 	if err != nil {
 		return "", err
 	}
 
+	if unit != nil {
+		return strconv.Itoa(lastSeq+1) + "/" + unit.Kode, nil
+	}
 	return strconv.Itoa(lastSeq + 1), nil
 }
@@ -0,0 +1,45 @@
+package dbtest
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	sqlite "github.com/glebarez/go-sqlite"
+)
+
+// Fungsi MySQL yang dipakai query aplikasi tetapi tidak ada di SQLite
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("GREATEST", -1, greatest)
+}
+
+// greatest meniru GREATEST MySQL untuk angka dan teks (termasuk DATETIME,
+// yang disimpan SQLite sebagai teks berformat seragam)
+func greatest(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	var best driver.Value
+	for _, arg := range args {
+		if arg == nil {
+			return nil, nil
+		}
+		if best == nil {
+			best = arg
+			continue
+		}
+		switch v := arg.(type) {
+		case int64:
+			if b, ok := best.(int64); ok && v > b {
+				best = v
+			}
+		case float64:
+			if b, ok := best.(float64); ok && v > b {
+				best = v
+			}
+		case string:
+			if b, ok := best.(string); ok && v > b {
+				best = v
+			}
+		default:
+			return nil, fmt.Errorf("GREATEST: unsupported argument type %T", arg)
+		}
+	}
+	return best, nil
+}
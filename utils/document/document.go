@@ -0,0 +1,118 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/skip2/go-qrcode"
+)
+
+func init() {
+	// pdfcpu secara default menulis file konfigurasi ke home directory,
+	// server tidak membutuhkannya.
+	api.DisableConfigDir()
+}
+
+// Posisi stempel tanda tangan: pojok kanan bawah halaman terakhir. Offset
+// horizontal -70pt (~25mm) menyamakan tepi kanan stempel dengan margin kanan
+// surat hasil RenderLetter (pageMarginX), sehingga stempel jatuh tepat di atas
+// blok tanda tangan; offset vertikal 60pt tidak berubah.
+const SignatureStampDesc = "position:br, offset:-70 60, scalefactor:0.25 rel, rotation:0"
+
+// Posisi stempel penerimaan surat masuk: pojok kanan atas halaman pertama
+const ReceiptStampDesc = "fontname:Helvetica, points:9, position:tr, offset:-20 -20, scalefactor:1 abs, rotation:0, fillcolor:#B00000, backgroundcolor:#FFFFFF, border:1 #B00000, margins:4"
+
+// Posisi QR code verifikasi: pojok kiri bawah halaman terakhir
+const QRCodeStampDesc = "position:bl, offset:40 40, scalefactor:0.12 rel, rotation:0"
+
+// newConfiguration menghasilkan konfigurasi pdfcpu yang menulis xref table klasik
+// (tanpa xref/object stream) agar file bisa ditandatangani dengan incremental update.
+func newConfiguration() *model.Configuration {
+	conf := model.NewDefaultConfiguration()
+	conf.WriteObjectStream = false
+	conf.WriteXRefStream = false
+	conf.ValidationMode = model.ValidationRelaxed
+	return conf
+}
+
+// IsPDF mengecek apakah file (berdasarkan nama/key) adalah PDF
+func IsPDF(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".pdf")
+}
+
+// EnsurePDF mengembalikan data apa adanya jika sudah PDF, atau mengonversi
+// gambar hasil scan (JPG/PNG) menjadi PDF satu halaman.
+func EnsurePDF(data []byte, name string) ([]byte, error) {
+	if IsPDF(name) {
+		return data, nil
+	}
+
+	imp := pdfcpu.DefaultImportConfig()
+	var out bytes.Buffer
+	if err := api.ImportImages(nil, &out, []io.Reader{bytes.NewReader(data)}, imp, newConfiguration()); err != nil {
+		return nil, fmt.Errorf("convert image to pdf: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// StampImage menempelkan gambar (misal tanda tangan) pada halaman terakhir PDF
+func StampImage(pdf, image []byte, desc string) ([]byte, error) {
+	wm, err := api.ImageWatermarkForReader(bytes.NewReader(image), desc, true, false, types.POINTS)
+	if err != nil {
+		return nil, fmt.Errorf("prepare image stamp: %w", err)
+	}
+	return stampLastPage(pdf, wm)
+}
+
+// StampText menempelkan blok teks pada halaman terakhir PDF
+func StampText(pdf []byte, text, desc string) ([]byte, error) {
+	wm, err := api.TextWatermark(text, desc, true, false, types.POINTS)
+	if err != nil {
+		return nil, fmt.Errorf("prepare text stamp: %w", err)
+	}
+	return stampLastPage(pdf, wm)
+}
+
+// StampTextFirstPage menempelkan blok teks pada halaman pertama PDF
+// (misal stempel penerimaan surat masuk)
+func StampTextFirstPage(pdf []byte, text, desc string) ([]byte, error) {
+	wm, err := api.TextWatermark(text, desc, true, false, types.POINTS)
+	if err != nil {
+		return nil, fmt.Errorf("prepare text stamp: %w", err)
+	}
+	return stampPages(pdf, wm, []string{"1"})
+}
+
+// StampQRCode menempelkan QR code berisi content (misal URL verifikasi)
+// pada halaman terakhir PDF
+func StampQRCode(pdf []byte, content, desc string) ([]byte, error) {
+	png, err := qrcode.Encode(content, qrcode.Medium, 256)
+	if err != nil {
+		return nil, fmt.Errorf("generate qr code: %w", err)
+	}
+	return StampImage(pdf, png, desc)
+}
+
+func stampLastPage(pdf []byte, wm *model.Watermark) ([]byte, error) {
+	pages, err := api.PageCount(bytes.NewReader(pdf), newConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("read pdf: %w", err)
+	}
+
+	return stampPages(pdf, wm, []string{fmt.Sprintf("%d", pages)})
+}
+
+func stampPages(pdf []byte, wm *model.Watermark, selected []string) ([]byte, error) {
+	var out bytes.Buffer
+	if err := api.AddWatermarks(bytes.NewReader(pdf), &out, selected, wm, newConfiguration()); err != nil {
+		return nil, fmt.Errorf("stamp pdf: %w", err)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,120 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// LetterContent adalah data surat yang sudah diisi dari template, siap dirender
+type LetterContent struct {
+	KopSurat       string
+	NomorSurat     string
+	Perihal        string
+	Tanggal        string
+	Penerima       string
+	Body           string
+	SignatureBlock string
+}
+
+// Ukuran halaman A4 & area blok tanda tangan (mm). Blok tanda tangan selalu di
+// kanan bawah halaman terakhir agar pas dengan posisi SignatureStampDesc.
+const (
+	pageMarginX       = 25.0
+	pageMarginTop     = 20.0
+	lineHeight        = 6.0
+	signatureWidth    = 52.0 // sama dengan lebar stempel tanda tangan (25% lebar A4)
+	signatureTopY     = 48.0 // jarak baris terakhir judul blok dari bawah halaman
+	signatureNameY    = 14.0 // jarak baris nama penandatangan dari bawah halaman
+	signatureMinSpace = 80.0 // ruang minimum yang dibutuhkan blok tanda tangan
+)
+
+// RenderLetter membuat PDF surat (kop, nomor, perihal, penerima, isi dan blok
+// tanda tangan) dari konten yang sudah diisi
+func RenderLetter(content LetterContent) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMarginX, pageMarginTop, pageMarginX)
+	pdf.SetAutoPageBreak(true, 25)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+
+	pageW, pageH := pdf.GetPageSize()
+	contentW := pageW - 2*pageMarginX
+
+	// Kop surat
+	if kop := splitLines(content.KopSurat); len(kop) > 0 {
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(contentW, 7, tr(kop[0]), "", 1, "C", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		for _, line := range kop[1:] {
+			pdf.CellFormat(contentW, 5, tr(line), "", 1, "C", false, 0, "")
+		}
+		y := pdf.GetY() + 2
+		pdf.SetLineWidth(0.8)
+		pdf.Line(pageMarginX, y, pageW-pageMarginX, y)
+		pdf.SetLineWidth(0.2)
+		pdf.Line(pageMarginX, y+1.2, pageW-pageMarginX, y+1.2)
+		pdf.SetY(y + 8)
+	}
+
+	// Nomor, perihal & tanggal
+	pdf.SetFont("Helvetica", "", 11)
+	y := pdf.GetY()
+	pdf.CellFormat(contentW, lineHeight, tr(content.Tanggal), "", 0, "R", false, 0, "")
+	pdf.SetXY(pageMarginX, y)
+	pdf.CellFormat(20, lineHeight, "Nomor", "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, lineHeight, tr(": "+content.NomorSurat), "", 1, "L", false, 0, "")
+	pdf.CellFormat(20, lineHeight, "Perihal", "", 0, "L", false, 0, "")
+	pdf.MultiCell(contentW-20-50, lineHeight, tr(": "+content.Perihal), "", "L", false)
+	pdf.Ln(lineHeight)
+
+	// Penerima
+	if penerima := strings.TrimSpace(content.Penerima); penerima != "" {
+		pdf.CellFormat(0, lineHeight, "Kepada Yth.", "", 1, "L", false, 0, "")
+		pdf.MultiCell(contentW/2+20, lineHeight, tr(penerima), "", "L", false)
+		pdf.CellFormat(0, lineHeight, "di tempat", "", 1, "L", false, 0, "")
+		pdf.Ln(lineHeight)
+	}
+
+	// Isi surat
+	pdf.MultiCell(contentW, lineHeight, tr(content.Body), "", "J", false)
+
+	// Blok tanda tangan di kanan bawah halaman terakhir
+	if sig := splitLines(content.SignatureBlock); len(sig) > 0 {
+		if pdf.GetY() > pageH-signatureMinSpace {
+			pdf.AddPage()
+		}
+		pdf.SetAutoPageBreak(false, 0)
+
+		x := pageW - pageMarginX - signatureWidth
+		header, name := sig[:len(sig)-1], sig[len(sig)-1]
+		top := pageH - signatureTopY - float64(len(header))*lineHeight
+		for i, line := range header {
+			pdf.SetXY(x, top+float64(i)*lineHeight)
+			pdf.CellFormat(signatureWidth, lineHeight, tr(line), "", 0, "C", false, 0, "")
+		}
+		pdf.SetFont("Helvetica", "BU", 11)
+		pdf.SetXY(x, pageH-signatureNameY-lineHeight)
+		pdf.CellFormat(signatureWidth, lineHeight, tr(name), "", 0, "C", false, 0, "")
+	}
+
+	if err := pdf.Error(); err != nil {
+		return nil, fmt.Errorf("render letter: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, fmt.Errorf("render letter: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,468 @@
+package document
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"TugasAkhir/config"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/smallstep/pkcs7"
+)
+
+var ErrSignerNotConfigured = errors.New("document signer is not configured")
+
+// OID id-aa-signingCertificateV2 (RFC 5035), wajib untuk profil PAdES
+var oidAttributeSigningCertificateV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+
+// Signer menandatangani PDF menggunakan sertifikat organisasi
+type Signer struct {
+	cert        *x509.Certificate
+	chain       []*x509.Certificate
+	key         crypto.Signer
+	fingerprint string
+	reason      string
+	location    string
+}
+
+// SignatureInfo berisi keterangan yang ditulis pada dictionary tanda tangan PDF
+type SignatureInfo struct {
+	Name     string
+	Reason   string
+	Location string
+	Time     time.Time
+}
+
+var defaultSigner *Signer
+
+// InitSigner memuat sertifikat & key organisasi dari konfigurasi.
+// Jika belum dikonfigurasi, surat tetap bisa disetujui tanpa tanda tangan kriptografis.
+func InitSigner() {
+	cfg := config.LoadSigningConfig()
+	if !cfg.Enabled() {
+		log.Println("⚠️ Document signing disabled: SIGNING_CERT_FILE / SIGNING_KEY_FILE not set")
+		return
+	}
+
+	signer, err := LoadSigner(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		log.Fatalf("failed to load document signing key: %v", err)
+	}
+	signer.reason = cfg.Reason
+	signer.location = cfg.Location
+
+	defaultSigner = signer
+	log.Println("✅ Document signer loaded. Certificate:", signer.cert.Subject.CommonName)
+}
+
+// DefaultSigner mengembalikan signer organisasi, nil jika belum dikonfigurasi
+func DefaultSigner() *Signer {
+	return defaultSigner
+}
+
+// LoadSigner membaca sertifikat (beserta chain) dan private key dalam format PEM
+func LoadSigner(certFile, keyFile string) (*Signer, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("read certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	return NewSigner(certPEM, keyPEM)
+}
+
+// NewSigner membuat signer dari data PEM. Sertifikat pertama adalah sertifikat
+// penandatangan, sisanya dianggap sebagai chain (intermediate/root).
+func NewSigner(certPEM, keyPEM []byte) (*Signer, error) {
+	var certs []*x509.Certificate
+	for rest := certPEM; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found in PEM data")
+	}
+
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(certs[0].Raw)
+	return &Signer{
+		cert:        certs[0],
+		chain:       certs[1:],
+		key:         key,
+		fingerprint: hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+func parsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no private key found in PEM data")
+	}
+
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return signer, nil
+}
+
+// Fingerprint adalah SHA-256 (hex) dari sertifikat penandatangan
+func (s *Signer) Fingerprint() string { return s.fingerprint }
+
+// Subject adalah nama (CN) pada sertifikat penandatangan
+func (s *Signer) Subject() string { return s.cert.Subject.CommonName }
+
+// Reason & Location default dari konfigurasi
+func (s *Signer) Reason() string   { return s.reason }
+func (s *Signer) Location() string { return s.location }
+
+// SignPDF menambahkan tanda tangan digital (CMS detached, SubFilter ETSI.CAdES.detached)
+// ke PDF menggunakan incremental update, sehingga isi dokumen asli tidak diubah.
+func (s *Signer) SignPDF(pdf []byte, info SignatureInfo) ([]byte, error) {
+	if s == nil {
+		return nil, ErrSignerNotConfigured
+	}
+	if info.Time.IsZero() {
+		info.Time = time.Now()
+	}
+
+	// Pastikan PDF memakai xref table klasik sebelum di-append
+	normalized, err := normalize(pdf)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := api.ReadContext(bytes.NewReader(normalized), newConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("read pdf: %w", err)
+	}
+	xrt := ctx.XRefTable
+	if xrt.Encrypt != nil {
+		return nil, errors.New("encrypted pdf cannot be signed")
+	}
+	if err := xrt.EnsurePageCount(); err != nil {
+		return nil, fmt.Errorf("read pdf pages: %w", err)
+	}
+
+	prevXref, err := lastStartXref(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := xrt.Catalog()
+	if err != nil {
+		return nil, fmt.Errorf("read pdf catalog: %w", err)
+	}
+	pageDict, pageRef, _, err := xrt.PageDict(xrt.PageCount, false)
+	if err != nil || pageRef == nil {
+		return nil, fmt.Errorf("read last page: %w", err)
+	}
+
+	size := *xrt.Size
+	sigObj := size
+	fieldObj := size + 1
+	acroObj := size + 2
+	newSize := size + 3
+
+	fieldRef := *types.NewIndirectRef(fieldObj, 0)
+
+	// AcroForm baru (atau salinan AcroForm lama) dengan field tanda tangan tambahan
+	acroForm := types.Dict{}
+	if existing, found := catalog.Find("AcroForm"); found {
+		obj, err := xrt.Dereference(existing)
+		if err != nil {
+			return nil, fmt.Errorf("read acroform: %w", err)
+		}
+		if d, ok := obj.(types.Dict); ok {
+			acroForm = d.Clone().(types.Dict)
+		}
+	}
+	fields := types.Array{}
+	if existing, found := acroForm.Find("Fields"); found {
+		obj, err := xrt.Dereference(existing)
+		if err != nil {
+			return nil, fmt.Errorf("read acroform fields: %w", err)
+		}
+		if arr, ok := obj.(types.Array); ok {
+			fields = append(fields, arr...)
+		}
+	}
+	acroForm["Fields"] = append(fields, fieldRef)
+	acroForm["SigFlags"] = types.Integer(3)
+
+	newCatalog := catalog.Clone().(types.Dict)
+	newCatalog["AcroForm"] = *types.NewIndirectRef(acroObj, 0)
+
+	annots := types.Array{}
+	if existing, found := pageDict.Find("Annots"); found {
+		obj, err := xrt.Dereference(existing)
+		if err != nil {
+			return nil, fmt.Errorf("read page annotations: %w", err)
+		}
+		if arr, ok := obj.(types.Array); ok {
+			annots = append(annots, arr...)
+		}
+	}
+	newPage := pageDict.Clone().(types.Dict)
+	newPage["Annots"] = append(annots, fieldRef)
+
+	contentsSize := s.estimateSignatureSize()
+	byteRangePlaceholder := "[0 " + strings.Repeat("0", 10) + " " + strings.Repeat("0", 10) + " " + strings.Repeat("0", 10) + "]"
+
+	var sigDict strings.Builder
+	sigDict.WriteString("<<\n/Type /Sig\n/Filter /Adobe.PPKLite\n/SubFilter /ETSI.CAdES.detached\n")
+	sigDict.WriteString("/ByteRange " + byteRangePlaceholder + "\n")
+	sigDict.WriteString("/Contents <" + strings.Repeat("0", contentsSize*2) + ">\n")
+	sigDict.WriteString("/M " + pdfText(pdfDate(info.Time)) + "\n")
+	if info.Name != "" {
+		sigDict.WriteString("/Name " + pdfText(info.Name) + "\n")
+	}
+	if info.Reason != "" {
+		sigDict.WriteString("/Reason " + pdfText(info.Reason) + "\n")
+	}
+	if info.Location != "" {
+		sigDict.WriteString("/Location " + pdfText(info.Location) + "\n")
+	}
+	sigDict.WriteString(">>")
+
+	field := types.Dict{
+		"Type":    types.Name("Annot"),
+		"Subtype": types.Name("Widget"),
+		"FT":      types.Name("Sig"),
+		"T":       types.StringLiteral(fmt.Sprintf("Signature%d", sigObj)),
+		"F":       types.Integer(132),
+		"Rect":    types.Array{types.Integer(0), types.Integer(0), types.Integer(0), types.Integer(0)},
+		"V":       *types.NewIndirectRef(sigObj, 0),
+		"P":       *pageRef,
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(normalized)+contentsSize*2+4096))
+	out.Write(normalized)
+	if !bytes.HasSuffix(normalized, []byte("\n")) {
+		out.WriteString("\n")
+	}
+
+	offsets := map[int]int{}
+	generations := map[int]int{}
+	writeObj := func(num, gen int, body string) {
+		offsets[num] = out.Len()
+		generations[num] = gen
+		fmt.Fprintf(out, "%d %d obj\n%s\nendobj\n", num, gen, body)
+	}
+
+	writeObj(xrt.Root.ObjectNumber.Value(), xrt.Root.GenerationNumber.Value(), newCatalog.PDFString())
+	writeObj(pageRef.ObjectNumber.Value(), pageRef.GenerationNumber.Value(), newPage.PDFString())
+	writeObj(sigObj, 0, sigDict.String())
+	writeObj(fieldObj, 0, field.PDFString())
+	writeObj(acroObj, 0, acroForm.PDFString())
+
+	xrefOffset := out.Len()
+	writeXRef(out, offsets, generations)
+
+	trailer := types.Dict{
+		"Size": types.Integer(newSize),
+		"Root": *xrt.Root,
+		"Prev": types.Integer(prevXref),
+	}
+	if xrt.Info != nil {
+		trailer["Info"] = *xrt.Info
+	}
+	if len(xrt.ID) > 0 {
+		trailer["ID"] = xrt.ID
+	}
+	fmt.Fprintf(out, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer.PDFString(), xrefOffset)
+
+	signed := out.Bytes()
+
+	// Hitung ByteRange berdasarkan posisi /Contents pada objek tanda tangan
+	sigStart := offsets[sigObj]
+	contentsIdx := bytes.Index(signed[sigStart:], []byte("/Contents <"))
+	if contentsIdx < 0 {
+		return nil, errors.New("signature placeholder not found")
+	}
+	contentsStart := sigStart + contentsIdx + len("/Contents ")
+	contentsEnd := contentsStart + contentsSize*2 + 2
+
+	byteRange := fmt.Sprintf("[0 %d %d %d]", contentsStart, contentsEnd, len(signed)-contentsEnd)
+	if len(byteRange) > len(byteRangePlaceholder) {
+		return nil, errors.New("byte range does not fit placeholder")
+	}
+	byteRange += strings.Repeat(" ", len(byteRangePlaceholder)-len(byteRange))
+	brIdx := bytes.Index(signed[sigStart:], []byte(byteRangePlaceholder))
+	if brIdx < 0 {
+		return nil, errors.New("byte range placeholder not found")
+	}
+	copy(signed[sigStart+brIdx:], byteRange)
+
+	signedContent := make([]byte, 0, len(signed)-(contentsEnd-contentsStart))
+	signedContent = append(signedContent, signed[:contentsStart]...)
+	signedContent = append(signedContent, signed[contentsEnd:]...)
+
+	cms, err := s.signCMS(signedContent)
+	if err != nil {
+		return nil, err
+	}
+	if len(cms) > contentsSize {
+		return nil, errors.New("signature does not fit reserved space")
+	}
+	encoded := strings.ToUpper(hex.EncodeToString(cms))
+	copy(signed[contentsStart+1:], encoded)
+
+	return signed, nil
+}
+
+func (s *Signer) signCMS(content []byte) ([]byte, error) {
+	sd, err := pkcs7.NewSignedData(content)
+	if err != nil {
+		return nil, fmt.Errorf("prepare signature: %w", err)
+	}
+	sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+
+	certHash := sha256.Sum256(s.cert.Raw)
+	signingCertificate := struct {
+		Certs []struct {
+			CertHash []byte
+		}
+	}{Certs: []struct{ CertHash []byte }{{CertHash: certHash[:]}}}
+
+	if err := sd.AddSignerChain(s.cert, s.key, s.chain, pkcs7.SignerInfoConfig{
+		ExtraSignedAttributes: []pkcs7.Attribute{
+			{Type: oidAttributeSigningCertificateV2, Value: signingCertificate},
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("sign document: %w", err)
+	}
+	sd.Detach()
+
+	return sd.Finish()
+}
+
+// estimateSignatureSize menghitung ruang (byte) yang dicadangkan untuk CMS
+func (s *Signer) estimateSignatureSize() int {
+	size := len(s.cert.Raw) + 4096
+	for _, c := range s.chain {
+		size += len(c.Raw)
+	}
+	if size < 8192 {
+		size = 8192
+	}
+	return size
+}
+
+// normalize menulis ulang PDF memakai xref table klasik. File yang sudah memakai
+// xref table klasik dikembalikan apa adanya agar tanda tangan sebelumnya tetap valid.
+func normalize(pdf []byte) ([]byte, error) {
+	ctx, err := api.ReadContext(bytes.NewReader(pdf), newConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("read pdf: %w", err)
+	}
+	if !ctx.Read.UsingXRefStreams && !ctx.Read.UsingObjectStreams {
+		return pdf, nil
+	}
+
+	var out bytes.Buffer
+	if err := api.WriteContext(ctx, &out); err != nil {
+		return nil, fmt.Errorf("write pdf: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+var startXrefPattern = regexp.MustCompile(`startxref\s+(\d+)`)
+
+func lastStartXref(pdf []byte) (int, error) {
+	matches := startXrefPattern.FindAllSubmatch(pdf, -1)
+	if len(matches) == 0 {
+		return 0, errors.New("startxref not found")
+	}
+	return strconv.Atoi(string(matches[len(matches)-1][1]))
+}
+
+func writeXRef(out *bytes.Buffer, offsets, generations map[int]int) {
+	nums := make([]int, 0, len(offsets))
+	for n := range offsets {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+
+	out.WriteString("xref\n")
+	for i := 0; i < len(nums); {
+		j := i
+		for j+1 < len(nums) && nums[j+1] == nums[j]+1 {
+			j++
+		}
+		fmt.Fprintf(out, "%d %d\n", nums[i], j-i+1)
+		for k := i; k <= j; k++ {
+			fmt.Fprintf(out, "%010d %05d n\r\n", offsets[nums[k]], generations[nums[k]])
+		}
+		i = j + 1
+	}
+}
+
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, (offset%3600)/60)
+}
+
+// pdfText menulis string PDF: literal untuk ASCII, UTF-16BE (hex) untuk selainnya
+func pdfText(s string) string {
+	for _, r := range s {
+		if r > 126 {
+			return "<" + strings.ToUpper(hex.EncodeToString([]byte(types.EncodeUTF16String(s)))) + ">"
+		}
+	}
+	r := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`)
+	return "(" + r.Replace(s) + ")"
+}
@@ -0,0 +1,160 @@
+package document
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/smallstep/pkcs7"
+)
+
+// newTestSigner membuat signer dengan sertifikat self-signed
+func newTestSigner(t *testing.T) (*Signer, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Yayasan Uji"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	signer, err := NewSigner(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return signer, cert
+}
+
+// minimalPDF membuat PDF satu halaman dengan xref table klasik
+func minimalPDF() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << >> >>",
+	}
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f\r\n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n\r\n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+var byteRangePattern = regexp.MustCompile(`/ByteRange \[0 (\d+) (\d+) (\d+)\s*\]`)
+
+func TestSignPDFRoundTrip(t *testing.T) {
+	signer, cert := newTestSigner(t)
+	original := minimalPDF()
+
+	signed, err := signer.SignPDF(original, SignatureInfo{Name: "Dewi Lestari", Reason: "Persetujuan", Location: "Jakarta"})
+	if err != nil {
+		t.Fatalf("SignPDF: %v", err)
+	}
+
+	// Incremental update: isi dokumen asli tidak berubah
+	if !bytes.HasPrefix(signed, original) {
+		t.Fatal("signed PDF does not start with the original document")
+	}
+	if _, err := api.ReadContext(bytes.NewReader(signed), newConfiguration()); err != nil {
+		t.Fatalf("signed PDF cannot be read back: %v", err)
+	}
+
+	// ByteRange mencakup seluruh file kecuali nilai /Contents
+	m := byteRangePattern.FindSubmatch(signed)
+	if m == nil {
+		t.Fatal("ByteRange not found")
+	}
+	var r [3]int
+	for i := range r {
+		r[i], _ = strconv.Atoi(string(m[i+1]))
+	}
+	contentsStart, contentsEnd, tailLen := r[0], r[1], r[2]
+	if contentsEnd+tailLen != len(signed) {
+		t.Fatalf("ByteRange ends at %d, file is %d bytes", contentsEnd+tailLen, len(signed))
+	}
+	if signed[contentsStart] != '<' || signed[contentsEnd-1] != '>' {
+		t.Fatalf("ByteRange gap is not the /Contents hex string: %q...%q", signed[contentsStart], signed[contentsEnd-1])
+	}
+	if !bytes.HasSuffix(signed[:contentsStart], []byte("/Contents ")) {
+		t.Fatal("ByteRange gap does not start at /Contents")
+	}
+
+	// CMS detached memverifikasi tepat bagian yang dicakup ByteRange
+	padded, err := hex.DecodeString(string(signed[contentsStart+1 : contentsEnd-1]))
+	if err != nil {
+		t.Fatalf("decode /Contents: %v", err)
+	}
+	// /Contents diisi nol setelah CMS; ambil satu elemen DER saja
+	var cms asn1.RawValue
+	if _, err := asn1.Unmarshal(padded, &cms); err != nil {
+		t.Fatalf("read CMS: %v", err)
+	}
+	p7, err := pkcs7.Parse(cms.FullBytes)
+	if err != nil {
+		t.Fatalf("parse CMS: %v", err)
+	}
+	p7.Content = append(append([]byte(nil), signed[:contentsStart]...), signed[contentsEnd:]...)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	if err := p7.VerifyWithChain(roots); err != nil {
+		t.Fatalf("verify CMS: %v", err)
+	}
+	if got := p7.GetOnlySigner(); got == nil || !got.Equal(cert) {
+		t.Fatal("CMS signer is not the configured certificate")
+	}
+	sum := sha256.Sum256(cert.Raw)
+	if signer.Fingerprint() != hex.EncodeToString(sum[:]) {
+		t.Fatal("fingerprint does not match the certificate")
+	}
+
+	// Satu byte saja diubah, tanda tangan harus gagal
+	tampered := append([]byte(nil), p7.Content...)
+	tampered[len(tampered)/3] ^= 0xFF
+	p7.Content = tampered
+	if err := p7.VerifyWithChain(roots); err == nil {
+		t.Fatal("tampered content still verifies")
+	}
+}
@@ -0,0 +1,64 @@
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Placeholder yang boleh dipakai di template surat, format {{nama}}
+const (
+	PlaceholderNomorSurat   = "nomor_surat"
+	PlaceholderJudulSurat   = "judul_surat"
+	PlaceholderIsiSurat     = "isi_surat"
+	PlaceholderPenerima     = "penerima"
+	PlaceholderTanggalSurat = "tanggal_surat"
+	PlaceholderPengirim     = "pengirim"
+)
+
+var knownPlaceholders = map[string]bool{
+	PlaceholderNomorSurat:   true,
+	PlaceholderJudulSurat:   true,
+	PlaceholderIsiSurat:     true,
+	PlaceholderPenerima:     true,
+	PlaceholderTanggalSurat: true,
+	PlaceholderPengirim:     true,
+}
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+
+var bulanIndonesia = [...]string{
+	"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember",
+}
+
+// UnknownPlaceholders mengembalikan placeholder di text yang tidak dikenali
+func UnknownPlaceholders(text string) []string {
+	seen := map[string]bool{}
+	var unknown []string
+	for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(m[1])
+		if !knownPlaceholders[name] && !seen[name] {
+			seen[name] = true
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// FillPlaceholders mengganti {{nama}} dengan nilai dari values.
+// Placeholder tanpa nilai diganti string kosong.
+func FillPlaceholders(text string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(m string) string {
+		name := strings.ToLower(placeholderPattern.FindStringSubmatch(m)[1])
+		return values[name]
+	})
+}
+
+// FormatTanggal memformat tanggal gaya surat resmi, misal "19 Oktober 2026"
+func FormatTanggal(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), bulanIndonesia[t.Month()-1], t.Year())
+}
@@ -0,0 +1,72 @@
+package document
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUnknownPlaceholders(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"Nomor: {{nomor_surat}}, perihal {{ judul_surat }}", nil},
+		// Nama placeholder tidak case-sensitive
+		{"{{NOMOR_SURAT}} {{Penerima}}", nil},
+		{"{{jabatan}} {{alamat}} {{JABATAN}} {{nomor_surat}}", []string{"alamat", "jabatan"}},
+		// Bukan placeholder: kurung tidak lengkap atau nama tidak valid
+		{"{nomor} {{nama lengkap}} {{}}", nil},
+		{"", nil},
+	}
+	for _, tc := range cases {
+		if got := UnknownPlaceholders(tc.text); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("UnknownPlaceholders(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestFillPlaceholders(t *testing.T) {
+	values := map[string]string{
+		PlaceholderNomorSurat: "12/KPP",
+		PlaceholderPenerima:   "Kepala Dinas",
+		// Nilai berisi kurung kurawal tidak diproses ulang
+		PlaceholderJudulSurat: "{{isi_surat}}",
+	}
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"Nomor: {{nomor_surat}}", "Nomor: 12/KPP"},
+		{"Yth. {{ Penerima }}, {{PENERIMA}}", "Yth. Kepala Dinas, Kepala Dinas"},
+		{"Perihal: {{judul_surat}}", "Perihal: {{isi_surat}}"},
+		// Placeholder tanpa nilai (dikenal atau tidak) menjadi kosong
+		{"[{{pengirim}}][{{jabatan}}]", "[][]"},
+		{"Tanpa placeholder {nomor_surat}", "Tanpa placeholder {nomor_surat}"},
+	}
+	for _, tc := range cases {
+		if got := FillPlaceholders(tc.text, values); got != tc.want {
+			t.Errorf("FillPlaceholders(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestFormatTanggal(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	cases := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), "1 Januari 2026"},
+		{time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC), "19 Oktober 2026"},
+		{time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), "29 Februari 2024"},
+		{time.Date(2025, time.December, 31, 23, 59, 0, 0, time.UTC), "31 Desember 2025"},
+		// Tanggal mengikuti zona waktu nilai yang diberikan
+		{time.Date(2025, time.December, 31, 23, 0, 0, 0, time.UTC).In(jakarta), "1 Januari 2026"},
+	}
+	for _, tc := range cases {
+		if got := FormatTanggal(tc.t); got != tc.want {
+			t.Errorf("FormatTanggal(%v) = %q, want %q", tc.t, got, tc.want)
+		}
+	}
+}
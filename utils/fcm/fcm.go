@@ -1,6 +1,7 @@
 package fcm
 
 import (
+	"TugasAkhir/config"
 	"TugasAkhir/models"
 	"TugasAkhir/utils/events"
 	"context"
@@ -15,6 +16,10 @@ import (
 
 const FCMTopicPrefix = "digitalmail_role_"
 
+// FCMUserTopicPrefix - topic per user (digitalmail_user_<id>), di-subscribe
+// aplikasi mobile setelah login untuk notifikasi yang ditujukan ke satu orang
+const FCMUserTopicPrefix = "digitalmail_user_"
+
 var fcmClient *messaging.Client
 
 // InitializeFCM initializes the Firebase Admin SDK
@@ -22,11 +27,11 @@ var fcmClient *messaging.Client
 func InitializeFCM() {
 	log.Println("🔥 Initializing Firebase Admin SDK...")
 	ctx := context.Background()
-	config := &firebase.Config{ProjectID: "digimail-mobile"}
+	fbConfig := &firebase.Config{ProjectID: "digimail-mobile"}
 
 	// Gunakan GOOGLE_APPLICATION_CREDENTIALS dari environment
 	// Pastikan variable ini sudah diset sebelum fungsi ini dipanggil
-	app, err := firebase.NewApp(ctx, config)
+	app, err := firebase.NewApp(ctx, fbConfig)
 	if err != nil {
 		log.Printf("❌ Error initializing Firebase app: %v\n", err)
 		return
@@ -46,6 +51,43 @@ func mapRoleToTopic(role models.Role) string {
 	return FCMTopicPrefix + string(role)
 }
 
+func mapUserToTopic(userID uint) string {
+	return FCMUserTopicPrefix + strconv.FormatUint(uint64(userID), 10)
+}
+
+// sendToRole menyimpan notifikasi untuk semua user dengan role tersebut lalu
+// mengirim push ke topic role
+func sendToRole(ctx context.Context, role models.Role, title, body string, data map[string]string) {
+	storeNotification(models.Notification{Role: &role}, title, body, data)
+	SendNotificationToTopic(ctx, mapRoleToTopic(role), title, body, data)
+}
+
+// sendToUser menyimpan notifikasi untuk satu user lalu mengirim push ke topic user
+func sendToUser(ctx context.Context, userID uint, title, body string, data map[string]string) {
+	storeNotification(models.Notification{UserID: &userID}, title, body, data)
+	SendNotificationToTopic(ctx, mapUserToTopic(userID), title, body, data)
+}
+
+// storeNotification menyimpan salinan notifikasi untuk endpoint sync. Tetap
+// disimpan walau FCM tidak aktif agar aplikasi bisa mengambilnya nanti.
+func storeNotification(n models.Notification, title, body string, data map[string]string) {
+	if config.DB == nil {
+		return
+	}
+
+	n.Title = title
+	n.Body = body
+	n.Status = data["status"]
+	if id, err := strconv.ParseUint(data["letter_id"], 10, 64); err == nil {
+		letterID := uint(id)
+		n.LetterID = &letterID
+	}
+
+	if err := config.DB.Create(&n).Error; err != nil {
+		log.Printf("❌ Gagal simpan notifikasi: %v\n", err)
+	}
+}
+
 func SendNotificationToTopic(ctx context.Context, topic, title, body string, data map[string]string) {
 	if fcmClient == nil {
 		return
@@ -148,6 +190,9 @@ func handleEvent(event events.LetterEvent) {
 			// Logic notifyArchivist bisa kita panggil agar Staf Lembaga (Admin Arsip) tau ada surat baru masuk arsip
 			notifyArchivist(ctx, letter, data)
 
+			// 3. Beritahu penerima tembusan internal (user & unit)
+			notifyTembusan(ctx, letter, data)
+
 		case models.StatusSudahDisposisi:
 			// 5. Disposisi Turun -> Staf
 			title := "Disposisi Baru"
@@ -172,8 +217,7 @@ func notifySpecificManajer(ctx context.Context, l models.Letter, data map[string
 
 	// 1. Cek Data Object Manajer (Hasil Preload)
 	if l.AssignedVerifier != nil {
-		topic := mapRoleToTopic(l.AssignedVerifier.Role)
-		SendNotificationToTopic(ctx, topic, title, body, data)
+		sendToRole(ctx, l.AssignedVerifier.Role, title, body, data)
 		return
 	}
 
@@ -191,11 +235,11 @@ func notifyArchivist(ctx context.Context, l models.Letter, data map[string]strin
 	// 6. Surat Final/Arsip (Target: Archivist/Staf Lembaga)
 	title := "Surat Selesai & Diarsipkan"
 	body := fmt.Sprintf("Surat #%s telah selesai diproses dan diarsipkan.", l.NomorSurat)
-	SendNotificationToTopic(ctx, mapRoleToTopic(models.RoleStafLembaga), title, body, data)
+	sendToRole(ctx, models.RoleStafLembaga, title, body, data)
 }
 
 func notifyDirektur(ctx context.Context, title, body string, data map[string]string) {
-	SendNotificationToTopic(ctx, mapRoleToTopic(models.RoleDirektur), title, body, data)
+	sendToRole(ctx, models.RoleDirektur, title, body, data)
 }
 
 func notifyStaf(ctx context.Context, l models.Letter, title, body string, data map[string]string) {
@@ -203,5 +247,38 @@ func notifyStaf(ctx context.Context, l models.Letter, title, body string, data m
 	if l.Scope == models.ScopeInternal {
 		targetRole = models.RoleStafLembaga
 	}
-	SendNotificationToTopic(ctx, mapRoleToTopic(targetRole), title, body, data)
+	sendToRole(ctx, targetRole, title, body, data)
+}
+
+func notifyTembusan(ctx context.Context, l models.Letter, data map[string]string) {
+	title := "Tembusan Surat"
+	body := fmt.Sprintf("Anda menerima tembusan surat #%s perihal '%s'.", l.NomorSurat, truncateString(l.JudulSurat, 30))
+
+	sent := map[string]bool{}
+	for _, t := range l.Tembusan {
+		var topic string
+		switch {
+		case t.UserID != nil:
+			topic = mapUserToTopic(*t.UserID)
+		case t.Role != nil:
+			topic = mapRoleToTopic(*t.Role)
+		default:
+			continue // Tembusan eksternal tidak dinotifikasi
+		}
+		if sent[topic] {
+			continue
+		}
+		sent[topic] = true
+
+		// SendNotificationToTopic mengubah map data, kirim salinan per topic
+		payload := make(map[string]string, len(data))
+		for k, v := range data {
+			payload[k] = v
+		}
+		if t.UserID != nil {
+			sendToUser(ctx, *t.UserID, title, body, payload)
+		} else {
+			sendToRole(ctx, *t.Role, title, body, payload)
+		}
+	}
 }
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ParseFields membaca parameter sparse fieldset, contoh:
+// ?fields=id_surat,judul_surat,status
+func ParseFields(c *fiber.Ctx) []string {
+	raw := strings.TrimSpace(c.Query("fields"))
+	if raw == "" {
+		return nil
+	}
+
+	var fields []string
+	for _, f := range strings.Split(raw, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// SelectFields mengembalikan hanya field JSON yang diminta dari struct atau
+// slice struct. Jika fields kosong, data dikembalikan apa adanya. Field yang
+// tidak dikenal menghasilkan error agar klien tahu ada typo.
+func SelectFields(data interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(data))
+	wanted := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		if _, ok := known[f]; !ok {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+		wanted[f] = struct{}{}
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pick := func(obj map[string]json.RawMessage) map[string]json.RawMessage {
+		for k := range obj {
+			if _, ok := wanted[k]; !ok {
+				delete(obj, k)
+			}
+		}
+		return obj
+	}
+
+	if t := reflect.TypeOf(data); t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		var items []map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		for i := range items {
+			items[i] = pick(items[i])
+		}
+		if items == nil {
+			items = []map[string]json.RawMessage{}
+		}
+		return items, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	return pick(obj), nil
+}
+
+// jsonFieldNames mengumpulkan nama field JSON dari tipe struct (atau elemen slice)
+func jsonFieldNames(t reflect.Type) map[string]struct{} {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+
+	names := map[string]struct{}{}
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// Sama seperti encoding/json: field struct embedded yang tidak
+		// diekspor tetap menyumbang field-field ekspornya
+		if !f.IsExported() && !(f.Anonymous && f.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			for n := range jsonFieldNames(f.Type) {
+				names[n] = struct{}{}
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[name] = struct{}{}
+	}
+	return names
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type fieldsTestUser struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+type fieldsTestAudit struct {
+	CreatedAt string `json:"created_at"`
+}
+
+type fieldsTestLetter struct {
+	fieldsTestAudit
+	ID        uint            `json:"id_surat"`
+	Judul     string          `json:"judul_surat"`
+	Status    string          `json:"status,omitempty"`
+	CreatedBy *fieldsTestUser `json:"created_by"`
+	Secret    string          `json:"-"`
+	Kode      string
+	internal  string
+}
+
+func selectFieldsJSON(t *testing.T, data interface{}, fields []string) string {
+	t.Helper()
+	got, err := SelectFields(data, fields)
+	if err != nil {
+		t.Fatalf("SelectFields(%v): %v", fields, err)
+	}
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(b)
+}
+
+func TestSelectFields(t *testing.T) {
+	letter := fieldsTestLetter{
+		fieldsTestAudit: fieldsTestAudit{CreatedAt: "2026-10-19"},
+		ID:              7,
+		Judul:           "Undangan",
+		Status:          "draft",
+		CreatedBy:       &fieldsTestUser{ID: 3, Username: "sari"},
+		Secret:          "rahasia",
+		Kode:            "KPP",
+		internal:        "x",
+	}
+
+	cases := []struct {
+		name   string
+		data   interface{}
+		fields []string
+		want   string
+	}{
+		{"struct", letter, []string{"id_surat", "status"}, `{"id_surat":7,"status":"draft"}`},
+		{"pointer", &letter, []string{"judul_surat"}, `{"judul_surat":"Undangan"}`},
+		// Field bertingkat dipilih utuh lewat nama field induknya
+		{"objek bertingkat", letter, []string{"created_by"}, `{"created_by":{"id":3,"username":"sari"}}`},
+		{"field struct embedded", letter, []string{"created_at"}, `{"created_at":"2026-10-19"}`},
+		{"tanpa tag json", letter, []string{"Kode"}, `{"Kode":"KPP"}`},
+		{"field duplikat", letter, []string{"id_surat", "id_surat"}, `{"id_surat":7}`},
+		// omitempty tetap berlaku: field kosong tidak dimunculkan
+		{"omitempty", fieldsTestLetter{ID: 1}, []string{"id_surat", "status"}, `{"id_surat":1}`},
+		{"slice", []fieldsTestLetter{letter, {ID: 8}}, []string{"id_surat"}, `[{"id_surat":7},{"id_surat":8}]`},
+		{"slice kosong", []fieldsTestLetter(nil), []string{"id_surat"}, `[]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := selectFieldsJSON(t, tc.data, tc.fields); got != tc.want {
+				t.Fatalf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSelectFieldsEmptySelectionReturnsData(t *testing.T) {
+	letter := &fieldsTestLetter{ID: 7, Secret: "rahasia"}
+	for _, fields := range [][]string{nil, {}} {
+		got, err := SelectFields(letter, fields)
+		if err != nil {
+			t.Fatalf("SelectFields(%v): %v", fields, err)
+		}
+		if got != letter {
+			t.Fatalf("SelectFields(%v) = %#v, want the original value", fields, got)
+		}
+	}
+}
+
+func TestSelectFieldsRejectsUnknownFields(t *testing.T) {
+	cases := map[string][]string{
+		"typo":               {"id_surat", "judul"},
+		"path bertingkat":    {"created_by.username"},
+		"field json:\"-\"":   {"Secret"},
+		"nama field Go":      {"Judul"},
+		"field tidak ekspor": {"internal"},
+		"beda huruf besar":   {"ID_SURAT"},
+	}
+	for name, fields := range cases {
+		t.Run(name, func(t *testing.T) {
+			for _, data := range []interface{}{fieldsTestLetter{}, []fieldsTestLetter{{}}} {
+				_, err := SelectFields(data, fields)
+				if err == nil || !strings.Contains(err.Error(), "unknown field") {
+					t.Fatalf("SelectFields(%T, %v) err = %v, want unknown field", data, fields, err)
+				}
+			}
+		})
+	}
+}
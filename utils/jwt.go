@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"strconv"
 	"strings"
@@ -14,16 +16,39 @@ import (
 
 type JWTClaims struct {
 	UserID    uint        `json:"user_id"`
-	Role      models.Role `json:"role"`
+	Role      models.Role `json:"role"`                 // Role aktif (role utama atau penugasan)
+	RoleLabel string      `json:"role_label,omitempty"` // Label penugasan, misal "Plt. Direktur"
 	Email     string      `json:"email"`
 	Username  string      `json:"username"`
 	TokenType string      `json:"token_type,omitempty"`
+	SessionID uint        `json:"sid,omitempty"` // Sesi login (models.UserSession) pemilik token
+	Version   uint        `json:"tv,omitempty"`  // models.User.TokenVersion saat token dibuat
+
+	// Hanya di challenge 2FA: password benar tetapi kedaluwarsa, sehingga kode
+	// yang valid ditukar dengan token reset password, bukan sesi login
+	PasswordExpired bool `json:"pwx,omitempty"`
+
+	// Diisi RequireAuth untuk request dengan API key, tidak pernah ada di token
+	APIKeyID uint     `json:"-"`
+	Scopes   []string `json:"-"` // nil = tidak dibatasi (login biasa)
 	jwt.RegisteredClaims
 }
 
 func GenerateAccessToken(user models.User) (string, *JWTClaims, error) {
+	return GenerateAccessTokenAs(user, models.ActingRole{Role: user.Role, Primary: true}, 0)
+}
+
+// GenerateAccessTokenAs membuat access token dengan role aktif tertentu untuk
+// sesi sessionID. Masa berlaku token tidak melewati akhir penugasan role tersebut.
+func GenerateAccessTokenAs(user models.User, active models.ActingRole, sessionID uint) (string, *JWTClaims, error) {
 	cfg := config.LoadJWTConfig()
-	return generateToken(user, "access", cfg.AccessTokenTTL)
+	ttl := cfg.AccessTokenTTL
+	if active.ValidUntil != nil {
+		if remaining := time.Until(*active.ValidUntil); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	return generateToken(user, active, sessionID, "access", ttl)
 }
 
 func VerifyAccessToken(tokenString string) (*JWTClaims, error) {
@@ -31,34 +56,84 @@ func VerifyAccessToken(tokenString string) (*JWTClaims, error) {
 }
 
 func GenerateRefreshToken(user models.User) (string, *JWTClaims, error) {
+	return GenerateRefreshTokenAs(user, models.ActingRole{Role: user.Role, Primary: true}, 0)
+}
+
+// GenerateRefreshTokenAs membuat refresh token yang mengingat role aktif. Saat
+// refresh, role ini diperiksa ulang dan kembali ke role utama jika penugasannya
+// sudah berakhir.
+func GenerateRefreshTokenAs(user models.User, active models.ActingRole, sessionID uint) (string, *JWTClaims, error) {
 	cfg := config.LoadJWTConfig()
-	return generateToken(user, "refresh", cfg.RefreshTokenTTL)
+	return generateToken(user, active, sessionID, "refresh", cfg.RefreshTokenTTL)
 }
 
 func VerifyRefreshToken(tokenString string) (*JWTClaims, error) {
 	return verifyToken(tokenString, "refresh")
 }
 
-func generateToken(user models.User, tokenType string, ttl time.Duration) (string, *JWTClaims, error) {
+// TwoFactorChallengeTTL - batas waktu memasukkan kode 2FA setelah password benar
+const TwoFactorChallengeTTL = 5 * time.Minute
+
+// GenerateTwoFactorChallenge membuat token langkah kedua login. Token ini tidak
+// bisa dipakai sebagai access token; hanya ditukar di /auth/2fa/verify.
+func GenerateTwoFactorChallenge(user models.User) (string, *JWTClaims, error) {
+	return generateToken(user, models.ActingRole{Role: user.Role, Primary: true}, 0, "2fa_challenge", TwoFactorChallengeTTL)
+}
+
+// GeneratePasswordExpiredChallenge sama seperti GenerateTwoFactorChallenge
+// untuk user yang password-nya kedaluwarsa (klaim PasswordExpired)
+func GeneratePasswordExpiredChallenge(user models.User) (string, *JWTClaims, error) {
+	return generateTokenWith(user, models.ActingRole{Role: user.Role, Primary: true}, 0, "2fa_challenge", TwoFactorChallengeTTL, func(claims *JWTClaims) {
+		claims.PasswordExpired = true
+	})
+}
+
+func VerifyTwoFactorChallenge(tokenString string) (*JWTClaims, error) {
+	return verifyToken(tokenString, "2fa_challenge")
+}
+
+func generateToken(user models.User, active models.ActingRole, sessionID uint, tokenType string, ttl time.Duration) (string, *JWTClaims, error) {
+	return generateTokenWith(user, active, sessionID, tokenType, ttl, nil)
+}
+
+// generateTokenWith - generateToken dengan klaim tambahan yang diisi extra
+func generateTokenWith(user models.User, active models.ActingRole, sessionID uint, tokenType string, ttl time.Duration, extra func(*JWTClaims)) (string, *JWTClaims, error) {
 	cfg := config.LoadJWTConfig()
 	now := time.Now()
 
+	// jti acak: dua token yang dibuat di detik yang sama tetap berbeda (hash
+	// refresh token disimpan dengan index unik)
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", nil, err
+	}
+
 	claims := &JWTClaims{
 		UserID:    user.ID,
-		Role:      user.Role,
+		Role:      active.Role,
+		RoleLabel: active.Label,
 		Email:     user.Email,
 		Username:  user.Username,
 		TokenType: tokenType,
+		SessionID: sessionID,
+		Version:   user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    cfg.Issuer,
 			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			ID:        hex.EncodeToString(jti),
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 		},
 	}
+	if extra != nil {
+		extra(claims)
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString(cfg.SecretKey)
+	keys, err := currentJWTKeys()
+	if err != nil {
+		return "", nil, err
+	}
+	signed, err := keys.sign(claims)
 	if err != nil {
 		return "", nil, err
 	}
@@ -72,18 +147,17 @@ func verifyToken(tokenString, expectedType string) (*JWTClaims, error) {
 	}
 
 	cfg := config.LoadJWTConfig()
+	keys, err := currentJWTKeys()
+	if err != nil {
+		return nil, err
+	}
 
 	claims := &JWTClaims{}
 	parsed, err := jwt.ParseWithClaims(
 		tokenString,
 		claims,
-		func(token *jwt.Token) (interface{}, error) {
-			if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-				return nil, errors.New("unexpected signing method")
-			}
-			return cfg.SecretKey, nil
-		},
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		keys.verificationKey,
+		jwt.WithValidMethods(keys.validMethods()),
 		jwt.WithIssuer(cfg.Issuer),
 	)
 	if err != nil {
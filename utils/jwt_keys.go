@@ -0,0 +1,373 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync/atomic"
+
+	"TugasAkhir/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits - key RSA di bawah ukuran ini ditolak saat startup
+const minRSAKeyBits = 2048
+
+var (
+	ErrJWTUnknownKey     = errors.New("unknown signing key id")
+	ErrJWTKeyAlgMismatch = errors.New("signing method does not match key")
+)
+
+// jwtKey - satu key JWT yang dikenali dari kid-nya
+type jwtKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer    // nil untuk key yang hanya memverifikasi
+	public  crypto.PublicKey // *rsa.PublicKey atau ed25519.PublicKey
+}
+
+// JWTKeySet - key untuk menandatangani dan memverifikasi token. Token baru
+// ditandatangani dengan key aktif; key lama tetap diterima selama masih
+// terdaftar agar token yang sudah terbit tidak langsung gugur saat rotasi.
+type JWTKeySet struct {
+	signing *jwtKey
+	keys    map[string]*jwtKey // kid -> key (aktif + lama)
+	order   []string           // urutan kid untuk JWKS, key aktif pertama
+	secret  []byte             // HS256 lama, nil jika tidak dipakai
+}
+
+var activeJWTKeys atomic.Pointer[JWTKeySet]
+
+// InitJWTKeys memuat key JWT dari file sekali saat startup. Tanpa
+// JWT_PRIVATE_KEY_FILE token tetap ditandatangani HS256 dengan JWT_SECRET.
+func InitJWTKeys() {
+	keys, err := LoadJWTKeySet(config.LoadJWTConfig())
+	if err != nil {
+		log.Fatalf("failed to load JWT keys: %v", err)
+	}
+	activeJWTKeys.Store(keys)
+
+	if keys.signing == nil {
+		log.Println("⚠️ JWT signed with HS256: JWT_PRIVATE_KEY_FILE not set, JWKS is empty")
+		return
+	}
+	log.Printf("✅ JWT keys loaded. Signing kid: %s (%s), verification keys: %d",
+		keys.signing.kid, keys.signing.method.Alg(), len(keys.keys))
+}
+
+// currentJWTKeys mengembalikan key hasil InitJWTKeys. Binary yang tidak
+// memanggil InitJWTKeys (misal tool CLI) memuatnya saat pertama dipakai.
+func currentJWTKeys() (*JWTKeySet, error) {
+	if keys := activeJWTKeys.Load(); keys != nil {
+		return keys, nil
+	}
+	keys, err := LoadJWTKeySet(config.LoadJWTConfig())
+	if err != nil {
+		return nil, err
+	}
+	activeJWTKeys.CompareAndSwap(nil, keys)
+	return activeJWTKeys.Load(), nil
+}
+
+// LoadJWTKeySet membaca private key aktif dan public key lama dari file PEM
+func LoadJWTKeySet(cfg config.JWTConfig) (*JWTKeySet, error) {
+	var privatePEM []byte
+	if cfg.PrivateKeyFile != "" {
+		data, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read private key: %w", err)
+		}
+		privatePEM = data
+	}
+
+	publicPEMs := make([][]byte, 0, len(cfg.PublicKeyFiles))
+	for _, file := range cfg.PublicKeyFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read public key %s: %w", file, err)
+		}
+		publicPEMs = append(publicPEMs, data)
+	}
+
+	return NewJWTKeySet(privatePEM, publicPEMs, cfg.SecretKey)
+}
+
+// NewJWTKeySet membuat key set dari data PEM. privatePEM kosong berarti token
+// ditandatangani HS256 dengan secret (perilaku lama).
+func NewJWTKeySet(privatePEM []byte, publicPEMs [][]byte, secret []byte) (*JWTKeySet, error) {
+	ks := &JWTKeySet{keys: make(map[string]*jwtKey), secret: secret}
+
+	if len(privatePEM) > 0 {
+		signer, err := parseJWTPrivateKey(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		key, err := newJWTKey(signer.Public())
+		if err != nil {
+			return nil, err
+		}
+		key.private = signer
+		ks.signing = key
+		ks.add(key)
+	} else if len(publicPEMs) > 0 {
+		return nil, errors.New("public keys require a private signing key")
+	}
+
+	for i, data := range publicPEMs {
+		pub, err := parseJWTPublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("public key #%d: %w", i+1, err)
+		}
+		key, err := newJWTKey(pub)
+		if err != nil {
+			return nil, fmt.Errorf("public key #%d: %w", i+1, err)
+		}
+		ks.add(key)
+	}
+
+	if ks.signing == nil && len(ks.secret) == 0 {
+		return nil, errors.New("no JWT signing key or secret configured")
+	}
+	return ks, nil
+}
+
+func (ks *JWTKeySet) add(key *jwtKey) {
+	if _, exists := ks.keys[key.kid]; exists {
+		return // File yang sama terdaftar dua kali
+	}
+	ks.keys[key.kid] = key
+	ks.order = append(ks.order, key.kid)
+}
+
+// sign menandatangani token dengan key aktif (header kid) atau HS256
+func (ks *JWTKeySet) sign(claims jwt.Claims) (string, error) {
+	if ks.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secret)
+	}
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.kid
+	return token.SignedString(ks.signing.private)
+}
+
+// verificationKey memilih key berdasarkan header kid. Algoritme token harus
+// sama dengan algoritme key agar public key tidak bisa dipakai sebagai secret
+// HMAC.
+func (ks *JWTKeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		if len(ks.secret) == 0 {
+			return nil, ErrJWTKeyAlgMismatch
+		}
+		return ks.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrJWTUnknownKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, ErrJWTKeyAlgMismatch
+	}
+	return key.public, nil
+}
+
+// validMethods - algoritme yang diterima saat verifikasi
+func (ks *JWTKeySet) validMethods() []string {
+	methods := make([]string, 0, 3)
+	seen := make(map[string]bool)
+	for _, key := range ks.keys {
+		if alg := key.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	if len(ks.secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	return methods
+}
+
+// JWK - public key dalam format JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS - isi /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS mengembalikan public key aktif dan lama. Secret HS256 tidak pernah
+// dipublikasikan.
+func (ks *JWTKeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(ks.order))}
+	for _, kid := range ks.order {
+		set.Keys = append(set.Keys, ks.keys[kid].jwk())
+	}
+	return set
+}
+
+// CurrentJWKS - JWKS dari key yang dimuat saat startup
+func CurrentJWKS() (JWKS, error) {
+	keys, err := currentJWTKeys()
+	if err != nil {
+		return JWKS{}, err
+	}
+	return keys.JWKS(), nil
+}
+
+func (k *jwtKey) jwk() JWK {
+	jwk := k.thumbprintMembers()
+	jwk.Kid = k.kid
+	jwk.Use = "sig"
+	jwk.Alg = k.method.Alg()
+	return jwk
+}
+
+// thumbprintMembers - member wajib JWK yang dipakai untuk thumbprint RFC 7638
+func (k *jwtKey) thumbprintMembers() JWK {
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(pub)}
+	}
+	return JWK{}
+}
+
+// newJWTKey menentukan algoritme dari jenis key dan menurunkan kid dari
+// thumbprint RFC 7638, sehingga kid stabil tanpa konfigurasi tambahan
+func newJWTKey(pub crypto.PublicKey) (*jwtKey, error) {
+	key := &jwtKey{public: pub}
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T (use RSA or Ed25519)", pub)
+	}
+
+	kid, err := jwkThumbprint(key.thumbprintMembers())
+	if err != nil {
+		return nil, err
+	}
+	key.kid = kid
+	return key, nil
+}
+
+// jwkThumbprint - SHA-256 dari member wajib JWK yang diurutkan secara
+// leksikografis tanpa spasi (RFC 7638)
+func jwkThumbprint(jwk JWK) (string, error) {
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return "", fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+
+	raw, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func parseJWTPrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no private key found in PEM data")
+	}
+
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported private key PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T (use RSA or Ed25519)", key)
+}
+
+// parseJWTPublicKey menerima public key PKIX/PKCS#1, atau private key (public
+// key-nya yang diambil) agar file key lama bisa didaftarkan apa adanya
+func parseJWTPublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no public key found in PEM data")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
+		return key, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
+		return key, nil
+	case "RSA PRIVATE KEY", "PRIVATE KEY":
+		signer, err := parseJWTPrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+	return nil, fmt.Errorf("unsupported public key PEM type %q", block.Type)
+}
@@ -0,0 +1,309 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"TugasAkhir/config"
+	"TugasAkhir/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestMain(m *testing.M) {
+	// LoadJWTConfig hanya membaca environment sekali
+	os.Setenv("JWT_SECRET", "legacy-secret")
+	os.Setenv("JWT_ISSUER", "digital-mail-test")
+	os.Exit(m.Run())
+}
+
+func rsaKeyPEM(t *testing.T, bits int) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func ed25519KeyPEM(t *testing.T) []byte {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate Ed25519 key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal Ed25519 key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicKeyPEM(t *testing.T, privatePEM []byte) []byte {
+	t.Helper()
+	signer, err := parseJWTPrivateKey(privatePEM)
+	if err != nil {
+		t.Fatalf("parse private key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// useJWTKeys mengganti key aktif selama satu test
+func useJWTKeys(t *testing.T, keys *JWTKeySet) {
+	t.Helper()
+	previous := activeJWTKeys.Swap(keys)
+	t.Cleanup(func() { activeJWTKeys.Store(previous) })
+}
+
+func newTestKeySet(t *testing.T, privatePEM []byte, publicPEMs [][]byte, secret []byte) *JWTKeySet {
+	t.Helper()
+	keys, err := NewJWTKeySet(privatePEM, publicPEMs, secret)
+	if err != nil {
+		t.Fatalf("NewJWTKeySet: %v", err)
+	}
+	return keys
+}
+
+func tokenHeader(t *testing.T, token string) map[string]any {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &JWTClaims{})
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	return parsed.Header
+}
+
+var testUser = func() models.User {
+	user := models.User{Email: "user@example.com", Username: "user", Role: models.RoleStafProgram}
+	user.ID = 7
+	return user
+}()
+
+func TestJWKThumbprintRFC7638(t *testing.T) {
+	// Contoh dari RFC 7638 bagian 3.1
+	jwk := JWK{
+		Kty: "RSA",
+		E:   "AQAB",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMs" +
+			"tn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbIS" +
+			"D08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+	kid, err := jwkThumbprint(jwk)
+	if err != nil {
+		t.Fatalf("thumbprint: %v", err)
+	}
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; kid != want {
+		t.Fatalf("thumbprint = %s, want %s", kid, want)
+	}
+}
+
+func TestAsymmetricSignAndVerify(t *testing.T) {
+	cases := []struct {
+		name string
+		pem  []byte
+		alg  string
+		kty  string
+	}{
+		{"RS256", rsaKeyPEM(t, 2048), "RS256", "RSA"},
+		{"EdDSA", ed25519KeyPEM(t), "EdDSA", "OKP"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys := newTestKeySet(t, tc.pem, nil, nil)
+			useJWTKeys(t, keys)
+
+			token, _, err := GenerateAccessToken(testUser)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+
+			header := tokenHeader(t, token)
+			if header["alg"] != tc.alg {
+				t.Fatalf("alg = %v, want %s", header["alg"], tc.alg)
+			}
+			jwks := keys.JWKS()
+			if len(jwks.Keys) != 1 || header["kid"] != jwks.Keys[0].Kid {
+				t.Fatalf("kid %v not published in JWKS %+v", header["kid"], jwks.Keys)
+			}
+			if jwks.Keys[0].Kty != tc.kty || jwks.Keys[0].Alg != tc.alg || jwks.Keys[0].Use != "sig" {
+				t.Fatalf("unexpected JWK %+v", jwks.Keys[0])
+			}
+
+			claims, err := VerifyAccessToken(token)
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if claims.UserID != testUser.ID || claims.Issuer != "digital-mail-test" {
+				t.Fatalf("unexpected claims %+v", claims)
+			}
+
+			if _, err := VerifyRefreshToken(token); err == nil {
+				t.Fatal("access token accepted as refresh token")
+			}
+		})
+	}
+}
+
+func TestJWKSPublishesRSAComponents(t *testing.T) {
+	privatePEM := rsaKeyPEM(t, 2048)
+	signer, err := parseJWTPrivateKey(privatePEM)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	pub := signer.Public().(*rsa.PublicKey)
+
+	jwk := newTestKeySet(t, privatePEM, nil, nil).JWKS().Keys[0]
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil || new(big.Int).SetBytes(n).Cmp(pub.N) != 0 {
+		t.Fatal("JWK modulus does not match key")
+	}
+	if jwk.E != "AQAB" {
+		t.Fatalf("JWK exponent = %s, want AQAB", jwk.E)
+	}
+}
+
+func TestKeyRotationAcceptsPreviousKey(t *testing.T) {
+	dir := t.TempDir()
+	oldPEM := rsaKeyPEM(t, 2048)
+	newPEM := ed25519KeyPEM(t)
+
+	oldPrivate := filepath.Join(dir, "old.pem")
+	oldPublic := filepath.Join(dir, "old.pub.pem")
+	newPrivate := filepath.Join(dir, "new.pem")
+	for file, data := range map[string][]byte{
+		oldPrivate: oldPEM,
+		oldPublic:  publicKeyPEM(t, oldPEM),
+		newPrivate: newPEM,
+	} {
+		if err := os.WriteFile(file, data, 0o600); err != nil {
+			t.Fatalf("write %s: %v", file, err)
+		}
+	}
+
+	oldKeys, err := LoadJWTKeySet(config.JWTConfig{PrivateKeyFile: oldPrivate})
+	if err != nil {
+		t.Fatalf("load old keys: %v", err)
+	}
+	useJWTKeys(t, oldKeys)
+	oldToken, _, err := GenerateRefreshToken(testUser)
+	if err != nil {
+		t.Fatalf("generate with old key: %v", err)
+	}
+
+	// Rotasi: key baru menandatangani, key lama hanya memverifikasi
+	rotated, err := LoadJWTKeySet(config.JWTConfig{PrivateKeyFile: newPrivate, PublicKeyFiles: []string{oldPublic}})
+	if err != nil {
+		t.Fatalf("load rotated keys: %v", err)
+	}
+	useJWTKeys(t, rotated)
+
+	if _, err := VerifyRefreshToken(oldToken); err != nil {
+		t.Fatalf("token signed with previous key rejected: %v", err)
+	}
+
+	newToken, _, err := GenerateRefreshToken(testUser)
+	if err != nil {
+		t.Fatalf("generate with new key: %v", err)
+	}
+	jwks := rotated.JWKS()
+	if len(jwks.Keys) != 2 || tokenHeader(t, newToken)["kid"] != jwks.Keys[0].Kid {
+		t.Fatalf("active key must be listed first in JWKS: %+v", jwks.Keys)
+	}
+	if tokenHeader(t, oldToken)["kid"] != jwks.Keys[1].Kid {
+		t.Fatal("previous key missing from JWKS")
+	}
+
+	// Setelah key lama dicabut, token lama ditolak
+	useJWTKeys(t, newTestKeySet(t, newPEM, nil, nil))
+	if _, err := VerifyRefreshToken(oldToken); err == nil {
+		t.Fatal("token signed with retired key accepted")
+	}
+
+	// kid yang tidak dikenal ditolak walau algoritmenya sama
+	useJWTKeys(t, newTestKeySet(t, ed25519KeyPEM(t), nil, nil))
+	if _, err := VerifyRefreshToken(newToken); !errors.Is(err, ErrJWTUnknownKey) {
+		t.Fatalf("expected ErrJWTUnknownKey, got %v", err)
+	}
+}
+
+func TestLegacyHS256Tokens(t *testing.T) {
+	secret := []byte("legacy-secret")
+	useJWTKeys(t, newTestKeySet(t, nil, nil, secret))
+	legacyToken, _, err := GenerateAccessToken(testUser)
+	if err != nil {
+		t.Fatalf("generate HS256: %v", err)
+	}
+	if _, ok := tokenHeader(t, legacyToken)["kid"]; ok {
+		t.Fatal("HS256 token must not carry a kid")
+	}
+
+	privatePEM := rsaKeyPEM(t, 2048)
+
+	// Selama JWT_SECRET masih diisi, token HS256 lama tetap diterima
+	useJWTKeys(t, newTestKeySet(t, privatePEM, nil, secret))
+	if _, err := VerifyAccessToken(legacyToken); err != nil {
+		t.Fatalf("legacy token rejected during migration: %v", err)
+	}
+
+	// Tanpa secret, HS256 tidak lagi diterima
+	useJWTKeys(t, newTestKeySet(t, privatePEM, nil, nil))
+	if _, err := VerifyAccessToken(legacyToken); err == nil {
+		t.Fatal("HS256 token accepted without a configured secret")
+	}
+}
+
+func TestHS256SignedWithPublicKeyRejected(t *testing.T) {
+	privatePEM := rsaKeyPEM(t, 2048)
+	keys := newTestKeySet(t, privatePEM, nil, nil)
+	useJWTKeys(t, keys)
+
+	// Serangan klasik: public key (yang dipublikasikan) dipakai sebagai secret HMAC
+	claims := &JWTClaims{
+		UserID:    testUser.ID,
+		TokenType: "access",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "digital-mail-test",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = keys.signing.kid
+	token, err := forged.SignedString(publicKeyPEM(t, privatePEM))
+	if err != nil {
+		t.Fatalf("sign forged token: %v", err)
+	}
+
+	if _, err := VerifyAccessToken(token); err == nil {
+		t.Fatal("forged HS256 token accepted")
+	}
+}
+
+func TestNewJWTKeySetRejectsInvalidKeys(t *testing.T) {
+	if _, err := NewJWTKeySet(rsaKeyPEM(t, 1024), nil, nil); err == nil {
+		t.Fatal("expected error for RSA key below 2048 bits")
+	}
+	if _, err := NewJWTKeySet(nil, nil, nil); err == nil {
+		t.Fatal("expected error without key or secret")
+	}
+	if _, err := NewJWTKeySet(nil, [][]byte{publicKeyPEM(t, ed25519KeyPEM(t))}, []byte("secret")); err == nil {
+		t.Fatal("expected error for public keys without a signing key")
+	}
+	if _, err := NewJWTKeySet([]byte("not a key"), nil, nil); err == nil {
+		t.Fatal("expected error for invalid PEM")
+	}
+}
@@ -0,0 +1,210 @@
+// Package ldaptest menyediakan server LDAP tiruan di localhost untuk test,
+// seperti net/http/httptest. Hanya bind, search (equality) dan unbind yang
+// dilayani.
+package ldaptest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Entry - satu objek di direktori tiruan. Password kosong berarti objek tidak
+// bisa dipakai untuk bind.
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server - direktori tiruan yang mendengarkan di 127.0.0.1
+type Server struct {
+	URL string
+
+	listener net.Listener
+	entries  []Entry
+	wg       sync.WaitGroup
+
+	mu    sync.Mutex
+	binds []string
+}
+
+// NewServer menjalankan server dengan isi direktori tertentu
+func NewServer(entries ...Entry) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("ldaptest: failed to listen: " + err.Error())
+	}
+
+	s := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		listener: listener,
+		entries:  entries,
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Close menghentikan server dan menunggu semua koneksi selesai
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// Binds - DN yang berhasil bind, berurutan
+func (s *Server) Binds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		packet, err := ber.ReadPacket(r)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			responses = []*ber.Packet{s.bind(op)}
+		case ldap.ApplicationSearchRequest:
+			responses = s.search(op)
+		default: // UnbindRequest atau operasi yang tidak didukung
+			return
+		}
+
+		for _, resp := range responses {
+			msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+			msg.AppendChild(resp)
+			if _, err := conn.Write(msg.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) bind(op *ber.Packet) *ber.Packet {
+	dn, password := text(op.Children[1]), text(op.Children[2])
+
+	// Seperti server sungguhan: password kosong = unauthenticated bind, berhasil
+	if password == "" {
+		return result(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, "")
+	}
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			s.mu.Lock()
+			s.binds = append(s.binds, entry.DN)
+			s.mu.Unlock()
+			return result(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, "")
+		}
+	}
+	return result(ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials, "invalid credentials")
+}
+
+func (s *Server) search(op *ber.Packet) []*ber.Packet {
+	if len(op.Children) < 8 {
+		return []*ber.Packet{result(ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError, "malformed search request")}
+	}
+	baseDN := strings.ToLower(text(op.Children[0]))
+	filter := op.Children[6]
+	if filter.ClassType != ber.ClassContext || filter.Tag != ldap.FilterEqualityMatch || len(filter.Children) < 2 {
+		return []*ber.Packet{result(ldap.ApplicationSearchResultDone, ldap.LDAPResultUnwillingToPerform, "only equality filters are supported")}
+	}
+	attr, value := strings.ToLower(text(filter.Children[0])), text(filter.Children[1])
+
+	var wanted map[string]bool
+	if list := op.Children[7]; len(list.Children) > 0 {
+		wanted = make(map[string]bool)
+		for _, name := range list.Children {
+			wanted[strings.ToLower(text(name))] = true
+		}
+	}
+
+	var responses []*ber.Packet
+	for _, entry := range s.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.DN), baseDN) || !entry.matches(attr, value) {
+			continue
+		}
+		attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+		for name, values := range entry.Attributes {
+			if wanted != nil && !wanted[strings.ToLower(name)] {
+				continue
+			}
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, v := range values {
+				set.AppendChild(octetString(v))
+			}
+			attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+			attribute.AppendChild(octetString(name))
+			attribute.AppendChild(set)
+			attributes.AppendChild(attribute)
+		}
+		resp := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+		resp.AppendChild(octetString(entry.DN))
+		resp.AppendChild(attributes)
+		responses = append(responses, resp)
+	}
+	return append(responses, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, ""))
+}
+
+func (e Entry) matches(attr, value string) bool {
+	for name, values := range e.Attributes {
+		if strings.ToLower(name) != attr {
+			continue
+		}
+		for _, v := range values {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// text membaca isi packet sebagai string, termasuk tag context-specific
+// (misal password simple bind) yang tidak di-decode oleh ber
+func text(p *ber.Packet) string {
+	if p.Data == nil {
+		return ""
+	}
+	return p.Data.String()
+}
+
+func octetString(s string) *ber.Packet {
+	return ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, s, "")
+}
+
+func result(tag ber.Tag, code uint16, message string) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	p.AppendChild(octetString(""))
+	p.AppendChild(octetString(message))
+	return p
+}
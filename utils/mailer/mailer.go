@@ -12,10 +12,11 @@ import (
 )
 
 var (
-	//go:embed templates/password_reset.html
+	//go:embed templates/password_reset.html templates/invitation.html
 	emailTemplates embed.FS
 
 	passwordResetTemplate = template.Must(template.New("password_reset.html").ParseFS(emailTemplates, "templates/password_reset.html"))
+	invitationTemplate    = template.Must(template.New("invitation.html").ParseFS(emailTemplates, "templates/invitation.html"))
 )
 
 type Client struct {
@@ -27,6 +28,34 @@ func NewClient(cfg config.EmailConfig) *Client {
 }
 
 func (c *Client) SendPasswordResetEmail(toEmail, resetLink string) error {
+	body := bytes.Buffer{}
+	data := struct {
+		ResetLink string
+	}{ResetLink: resetLink}
+
+	if err := passwordResetTemplate.Execute(&body, data); err != nil {
+		return fmt.Errorf("render password reset template: %w", err)
+	}
+
+	return c.sendHTML(toEmail, "Reset Password", body.String())
+}
+
+// SendInvitationEmail mengirim link undangan untuk mengatur password pertama
+func (c *Client) SendInvitationEmail(toEmail, username, invitationLink string) error {
+	body := bytes.Buffer{}
+	data := struct {
+		Username       string
+		InvitationLink string
+	}{Username: username, InvitationLink: invitationLink}
+
+	if err := invitationTemplate.Execute(&body, data); err != nil {
+		return fmt.Errorf("render invitation template: %w", err)
+	}
+
+	return c.sendHTML(toEmail, "Undangan Akun Digital Mail", body.String())
+}
+
+func (c *Client) sendHTML(toEmail, subject, htmlBody string) error {
 	if c.cfg.Host == "" {
 		return fmt.Errorf("smtp host is not configured")
 	}
@@ -42,16 +71,7 @@ func (c *Client) SendPasswordResetEmail(toEmail, resetLink string) error {
 	addr := fmt.Sprintf("%s:%d", c.cfg.Host, c.cfg.Port)
 	auth := smtp.PlainAuth("", c.cfg.Username, c.cfg.Password, c.cfg.Host)
 
-	body := bytes.Buffer{}
-	data := struct {
-		ResetLink string
-	}{ResetLink: resetLink}
-
-	if err := passwordResetTemplate.Execute(&body, data); err != nil {
-		return fmt.Errorf("render password reset template: %w", err)
-	}
-
-	msg := buildHTMLMessage(from, toEmail, "Reset Password", body.String())
+	msg := buildHTMLMessage(from, toEmail, subject, htmlBody)
 
 	if c.cfg.Username == "" && c.cfg.Password == "" {
 		return smtp.SendMail(addr, nil, from, []string{toEmail}, []byte(msg))
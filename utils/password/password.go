@@ -0,0 +1,218 @@
+// Package password memeriksa password baru terhadap kebijakan aplikasi:
+// panjang minimum, kombinasi karakter, daftar password umum / bocor, dan
+// kemiripan dengan data akun. Riwayat dan masa berlaku password diperiksa
+// di services.PasswordService karena butuh database.
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Kode pelanggaran kebijakan, dipakai handler untuk menyusun pesan
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleUpper     = "uppercase"
+	RuleLower     = "lowercase"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleCommon    = "common"
+	RulePersonal  = "personal"
+	RuleReused    = "reused"
+)
+
+// MaxLength - batas bcrypt; byte setelah ke-72 diabaikan saat hashing
+const MaxLength = 72
+
+// Policy - aturan komposisi password
+type Policy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	History       int // Jumlah password terakhir yang tidak boleh dipakai ulang
+}
+
+// PolicyError berisi semua aturan yang dilanggar, bukan hanya yang pertama,
+// agar user bisa memperbaiki password sekali jalan
+type PolicyError struct {
+	Rules     []string
+	MinLength int
+	History   int
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, 0, len(e.Rules))
+	for _, rule := range e.Rules {
+		messages = append(messages, e.message(rule))
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *PolicyError) message(rule string) string {
+	switch rule {
+	case RuleMinLength:
+		return fmt.Sprintf("password must be at least %d characters", e.MinLength)
+	case RuleMaxLength:
+		return fmt.Sprintf("password must not be longer than %d bytes", MaxLength)
+	case RuleUpper:
+		return "password must contain an uppercase letter"
+	case RuleLower:
+		return "password must contain a lowercase letter"
+	case RuleDigit:
+		return "password must contain a digit"
+	case RuleSymbol:
+		return "password must contain a symbol"
+	case RuleCommon:
+		return "password is too common or has appeared in a data breach"
+	case RulePersonal:
+		return "password must not contain your username or email"
+	case RuleReused:
+		return fmt.Sprintf("password must not match any of your last %d passwords", e.History)
+	}
+	return rule
+}
+
+// Has - aturan tertentu ikut dilanggar
+func (e *PolicyError) Has(rule string) bool {
+	for _, r := range e.Rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// Check memeriksa password terhadap kebijakan. personal berisi data akun
+// (username, email, nama) yang tidak boleh muncul di password. Mengembalikan
+// *PolicyError atau nil.
+func (p Policy) Check(plain string, personal ...string) error {
+	var rules []string
+	if len([]rune(plain)) < p.MinLength {
+		rules = append(rules, RuleMinLength)
+	}
+	if len(plain) > MaxLength {
+		rules = append(rules, RuleMaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, ch := range plain {
+		switch {
+		case unicode.IsUpper(ch):
+			upper = true
+		case unicode.IsLower(ch):
+			lower = true
+		case unicode.IsDigit(ch):
+			digit = true
+		case unicode.IsPunct(ch) || unicode.IsSymbol(ch) || unicode.IsSpace(ch):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		rules = append(rules, RuleUpper)
+	}
+	if p.RequireLower && !lower {
+		rules = append(rules, RuleLower)
+	}
+	if p.RequireDigit && !digit {
+		rules = append(rules, RuleDigit)
+	}
+	if p.RequireSymbol && !symbol {
+		rules = append(rules, RuleSymbol)
+	}
+
+	if IsCommon(plain) {
+		rules = append(rules, RuleCommon)
+	}
+	if containsPersonal(plain, personal) {
+		rules = append(rules, RulePersonal)
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+	return &PolicyError{Rules: rules, MinLength: p.MinLength, History: p.History}
+}
+
+// containsPersonal - password memuat username, bagian lokal email, atau nama
+// (minimal 4 karakter agar nama pendek tidak memblokir terlalu banyak)
+func containsPersonal(plain string, personal []string) bool {
+	lowered := strings.ToLower(plain)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if at := strings.IndexByte(value, '@'); at >= 0 {
+			value = value[:at]
+		}
+		if len(value) >= 4 && strings.Contains(lowered, value) {
+			return true
+		}
+	}
+	return false
+}
+
+//go:embed common.txt
+var bundledList string
+
+var (
+	blocklistMu sync.RWMutex
+	blocklist   = map[string]struct{}{}
+)
+
+func init() {
+	addToBlocklist(strings.NewReader(bundledList))
+}
+
+// LoadBlocklist menambahkan daftar password dari file (satu per baris,
+// misalnya ekspor daftar password bocor) ke daftar bawaan
+func LoadBlocklist(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return addToBlocklist(f)
+}
+
+func addToBlocklist(r io.Reader) error {
+	blocklistMu.Lock()
+	defer blocklistMu.Unlock()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// IsCommon - password (tanpa membedakan huruf besar/kecil) ada di daftar,
+// termasuk variasi dengan angka / simbol di belakang seperti "Password123!"
+func IsCommon(plain string) bool {
+	lowered := strings.ToLower(strings.TrimSpace(plain))
+	base := strings.TrimRightFunc(lowered, func(ch rune) bool {
+		return !unicode.IsLetter(ch)
+	})
+
+	blocklistMu.RLock()
+	defer blocklistMu.RUnlock()
+	if _, ok := blocklist[lowered]; ok {
+		return true
+	}
+	if len(base) >= 4 {
+		if _, ok := blocklist[base]; ok {
+			return true
+		}
+	}
+	return false
+}
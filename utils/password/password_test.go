@@ -0,0 +1,97 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var defaultPolicy = Policy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true, History: 5}
+
+func rulesOf(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected *PolicyError, got %T", err)
+	}
+	return policyErr.Rules
+}
+
+func TestCheckAcceptsStrongPassword(t *testing.T) {
+	if err := defaultPolicy.Check("Surat-Masuk-2024", "budi", "budi@yayasan.org"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckReportsEveryViolation(t *testing.T) {
+	policy := defaultPolicy
+	policy.RequireSymbol = true
+
+	got := rulesOf(t, policy.Check("abc"))
+	want := []string{RuleMinLength, RuleUpper, RuleDigit, RuleSymbol}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rules = %v, want %v", got, want)
+	}
+}
+
+func TestCheckRejectsTooLongForBcrypt(t *testing.T) {
+	got := rulesOf(t, defaultPolicy.Check("Aa1"+strings.Repeat("x", MaxLength)))
+	if !reflect.DeepEqual(got, []string{RuleMaxLength}) {
+		t.Fatalf("rules = %v, want [%s]", got, RuleMaxLength)
+	}
+}
+
+func TestCheckRejectsCommonPasswordVariants(t *testing.T) {
+	// Lolos aturan komposisi tapi tetap ada di daftar password umum
+	for _, pwd := range []string{"Password123", "Qwerty123!", "Bismillah2024", "P@ssw0rd1"} {
+		got := rulesOf(t, defaultPolicy.Check(pwd))
+		if !reflect.DeepEqual(got, []string{RuleCommon}) {
+			t.Errorf("Check(%q) rules = %v, want [%s]", pwd, got, RuleCommon)
+		}
+	}
+}
+
+func TestCheckRejectsPersonalData(t *testing.T) {
+	err := defaultPolicy.Check("Siti.Rahma2024", "srahma", "siti.rahma@yayasan.org", "Siti")
+	if got := rulesOf(t, err); !reflect.DeepEqual(got, []string{RulePersonal}) {
+		t.Fatalf("rules = %v, want [%s]", got, RulePersonal)
+	}
+
+	// Nama pendek (< 4 karakter) tidak ikut diperiksa
+	if err := defaultPolicy.Check("Ani-Kantor-77", "ani"); err != nil {
+		t.Fatalf("unexpected error for short personal value: %v", err)
+	}
+}
+
+func TestLoadBlocklistAddsEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte("# bocor 2023\nYayasanKita\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if IsCommon("YayasanKita99") {
+		t.Fatal("password should not be blocked before the list is loaded")
+	}
+	if err := LoadBlocklist(path); err != nil {
+		t.Fatalf("LoadBlocklist: %v", err)
+	}
+	if !IsCommon("yayasankita99") {
+		t.Fatal("expected password from loaded list to be blocked")
+	}
+}
+
+func TestPolicyErrorMessages(t *testing.T) {
+	err := &PolicyError{Rules: []string{RuleMinLength, RuleReused}, MinLength: 10, History: 3}
+	want := "password must be at least 10 characters; password must not match any of your last 3 passwords"
+	if err.Error() != want {
+		t.Fatalf("Error() = %q, want %q", err.Error(), want)
+	}
+	if !err.Has(RuleReused) || err.Has(RuleCommon) {
+		t.Fatal("Has reported the wrong rules")
+	}
+}
@@ -0,0 +1,51 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Batas agar PDF besar tidak membebani index
+const (
+	maxExtractBytes = 20 << 20 // 20 MB
+	maxExtractChars = 100_000
+)
+
+// ExtractPDFText mengambil teks dari PDF. Hasil scan tanpa OCR akan
+// menghasilkan teks kosong.
+func ExtractPDFText(data []byte) (text string, err error) {
+	if len(data) > maxExtractBytes {
+		return "", nil
+	}
+
+	// Parser PDF bisa panic pada file yang rusak
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("extract pdf text: %v", r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("extract pdf text: %w", err)
+	}
+	plain, err := r.GetPlainText()
+	if err != nil {
+		return "", fmt.Errorf("extract pdf text: %w", err)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(plain, maxExtractChars*4))
+	if err != nil {
+		return "", fmt.Errorf("extract pdf text: %w", err)
+	}
+
+	text = strings.Join(strings.Fields(string(b)), " ")
+	if runes := []rune(text); len(runes) > maxExtractChars {
+		text = string(runes[:maxExtractChars])
+	}
+	return text, nil
+}
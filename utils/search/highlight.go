@@ -0,0 +1,68 @@
+package search
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2/registry"
+	"github.com/blevesearch/bleve/v2/search/highlight"
+	simpleFragmenter "github.com/blevesearch/bleve/v2/search/highlight/fragmenter/simple"
+	simpleHighlighter "github.com/blevesearch/bleve/v2/search/highlight/highlighter/simple"
+)
+
+// Highlighter fragmen HTML milik aplikasi. Teks surat selalu di-escape sebelum
+// <mark> disisipkan, sehingga fragmen aman ditampilkan sebagai HTML tanpa
+// bergantung pada perilaku formatter bawaan bleve.
+const (
+	highlighterName = "id_html"
+	formatterName   = "id_html_escaped"
+
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+)
+
+func init() {
+	if err := registry.RegisterFragmentFormatter(formatterName, func(map[string]interface{}, *registry.Cache) (highlight.FragmentFormatter, error) {
+		return escapedFormatter{}, nil
+	}); err != nil {
+		panic(err)
+	}
+	if err := registry.RegisterHighlighter(highlighterName, newHighlighter); err != nil {
+		panic(err)
+	}
+}
+
+func newHighlighter(_ map[string]interface{}, cache *registry.Cache) (highlight.Highlighter, error) {
+	fragmenter, err := cache.FragmenterNamed(simpleFragmenter.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error building fragmenter: %v", err)
+	}
+	formatter, err := cache.FragmentFormatterNamed(formatterName)
+	if err != nil {
+		return nil, fmt.Errorf("error building fragment formatter: %v", err)
+	}
+	return simpleHighlighter.NewHighlighter(fragmenter, formatter, simpleHighlighter.DefaultSeparator), nil
+}
+
+type escapedFormatter struct{}
+
+func (escapedFormatter) Format(f *highlight.Fragment, locations highlight.TermLocations) string {
+	var b strings.Builder
+	curr := f.Start
+	for _, loc := range locations {
+		if loc == nil || !loc.ArrayPositions.Equals(f.ArrayPositions) || loc.Start < curr {
+			continue
+		}
+		if loc.End > f.End {
+			break
+		}
+		b.WriteString(html.EscapeString(string(f.Orig[curr:loc.Start])))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(string(f.Orig[loc.Start:loc.End])))
+		b.WriteString(markClose)
+		curr = loc.End
+	}
+	b.WriteString(html.EscapeString(string(f.Orig[curr:f.End])))
+	return b.String()
+}
@@ -0,0 +1,268 @@
+package search
+
+import (
+	"TugasAkhir/config"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// Field teks yang dicari full-text beserta bobotnya
+var TextFields = map[string]float64{
+	"judul_surat": 3,
+	"nomor_surat": 3,
+	"pengirim":    2,
+	"isi_surat":   1,
+	"kesimpulan":  1,
+	"lampiran":    0.5, // Teks hasil ekstraksi PDF
+}
+
+// Field keyword untuk filter & facet
+var FacetFields = []string{"jenis_surat", "status", "scope", "prioritas", "created_by_id"}
+
+const textAnalyzer = "id_text"
+
+// LetterDocument adalah representasi surat di index
+type LetterDocument struct {
+	JudulSurat  string    `json:"judul_surat"`
+	NomorSurat  string    `json:"nomor_surat"`
+	Pengirim    string    `json:"pengirim"`
+	IsiSurat    string    `json:"isi_surat"`
+	Kesimpulan  string    `json:"kesimpulan"`
+	Lampiran    string    `json:"lampiran"`
+	JenisSurat  string    `json:"jenis_surat"`
+	Status      string    `json:"status"`
+	Scope       string    `json:"scope"`
+	Prioritas   string    `json:"prioritas"`
+	CreatedByID string    `json:"created_by_id"`
+	Tanggal     time.Time `json:"tanggal"`
+}
+
+// Hit adalah satu hasil pencarian dari index (belum difilter permission)
+type Hit struct {
+	ID         uint
+	Score      float64
+	Fields     map[string]string
+	Highlights map[string][]string
+}
+
+type Index struct {
+	idx bleve.Index
+}
+
+var defaultIndex *Index
+
+// InitIndex membuka (atau membuat) index di SEARCH_INDEX_PATH. Jika gagal,
+// endpoint pencarian dinonaktifkan tanpa menghentikan server.
+func InitIndex() {
+	cfg := config.LoadSearchConfig()
+	idx, err := Open(cfg.IndexPath)
+	if err != nil {
+		log.Printf("⚠️ Search index tidak tersedia: %v", err)
+		return
+	}
+	defaultIndex = idx
+	log.Printf("✅ Search index dibuka: %s", cfg.IndexPath)
+}
+
+// DefaultIndex mengembalikan index global, nil jika belum/tidak bisa dibuka
+func DefaultIndex() *Index {
+	return defaultIndex
+}
+
+// Open membuka index yang sudah ada atau membuat baru
+func Open(path string) (*Index, error) {
+	idx, err := bleve.Open(path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		m, mErr := newMapping()
+		if mErr != nil {
+			return nil, mErr
+		}
+		idx, err = bleve.New(path, m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open search index: %w", err)
+	}
+	return &Index{idx: idx}, nil
+}
+
+func newMapping() (mapping.IndexMapping, error) {
+	im := bleve.NewIndexMapping()
+	// Tanpa stop word bahasa Inggris, cukup tokenisasi unicode + lowercase
+	if err := im.AddCustomAnalyzer(textAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name},
+	}); err != nil {
+		return nil, err
+	}
+
+	doc := bleve.NewDocumentMapping()
+	for field := range TextFields {
+		fm := bleve.NewTextFieldMapping()
+		fm.Analyzer = textAnalyzer
+		fm.Store = true
+		fm.IncludeTermVectors = true // dibutuhkan untuk highlight
+		doc.AddFieldMappingsAt(field, fm)
+	}
+	for _, field := range FacetFields {
+		fm := bleve.NewKeywordFieldMapping()
+		fm.Analyzer = keyword.Name
+		fm.Store = true
+		doc.AddFieldMappingsAt(field, fm)
+	}
+	doc.AddFieldMappingsAt("tanggal", bleve.NewDateTimeFieldMapping())
+
+	im.DefaultMapping = doc
+	return im, nil
+}
+
+func (i *Index) Close() error {
+	return i.idx.Close()
+}
+
+func (i *Index) Upsert(id uint, doc LetterDocument) error {
+	return i.idx.Index(docID(id), doc)
+}
+
+func (i *Index) Delete(id uint) error {
+	return i.idx.Delete(docID(id))
+}
+
+// GetInternal / SetInternal menyimpan metadata indexer (checkpoint, cache lampiran)
+func (i *Index) GetInternal(key string) ([]byte, error) {
+	return i.idx.GetInternal([]byte(key))
+}
+
+func (i *Index) SetInternal(key string, val []byte) error {
+	return i.idx.SetInternal([]byte(key), val)
+}
+
+// Query adalah parameter pencarian di index. From & MaxResult memilih satu
+// batch hit; urutan hit stabil sehingga batch berikutnya bisa diambil dengan
+// From yang digeser.
+type Query struct {
+	Text      string
+	Filters   map[string]string // field keyword -> nilai
+	DateFrom  *time.Time
+	DateTo    *time.Time
+	From      int
+	MaxResult int
+}
+
+// Search menjalankan query dan mengembalikan hit terurut skor (atau tanggal
+// terbaru jika Text kosong), lengkap dengan nilai facet & highlight. Teks di
+// highlight sudah di-escape; hanya tag <mark> yang berupa HTML.
+func (i *Index) Search(q Query) ([]Hit, error) {
+	var must []query.Query
+
+	if q.Text != "" {
+		var should []query.Query
+		for field, boost := range TextFields {
+			mq := bleve.NewMatchQuery(q.Text)
+			mq.SetField(field)
+			mq.Analyzer = textAnalyzer
+			mq.SetBoost(boost)
+			should = append(should, mq)
+		}
+		must = append(must, bleve.NewDisjunctionQuery(should...))
+	}
+
+	for field, value := range q.Filters {
+		if value == "" {
+			continue
+		}
+		tq := bleve.NewTermQuery(value)
+		tq.SetField(field)
+		must = append(must, tq)
+	}
+
+	if q.DateFrom != nil || q.DateTo != nil {
+		var from, to time.Time
+		if q.DateFrom != nil {
+			from = *q.DateFrom
+		}
+		if q.DateTo != nil {
+			to = *q.DateTo
+		}
+		inclusive := true
+		dq := bleve.NewDateRangeInclusiveQuery(from, to, &inclusive, &inclusive)
+		dq.SetField("tanggal")
+		must = append(must, dq)
+	}
+
+	var root query.Query = bleve.NewMatchAllQuery()
+	if len(must) > 0 {
+		root = bleve.NewConjunctionQuery(must...)
+	}
+
+	req := bleve.NewSearchRequestOptions(root, q.MaxResult, q.From, false)
+	req.Fields = FacetFields
+	if q.Text != "" {
+		req.Highlight = bleve.NewHighlightWithStyle(highlighterName)
+		for field := range TextFields {
+			req.Highlight.AddField(field)
+		}
+		req.SortBy([]string{"-_score", "-_id"})
+	} else {
+		req.SortBy([]string{"-tanggal", "-_id"})
+	}
+
+	res, err := i.idx.Search(req)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, 0, len(res.Hits))
+	for _, h := range res.Hits {
+		id, err := strconv.ParseUint(h.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		fields := make(map[string]string, len(h.Fields))
+		for k, v := range h.Fields {
+			if s, ok := v.(string); ok {
+				fields[k] = s
+			}
+		}
+		// Bleve juga mengembalikan fragmen field yang tidak cocok, buang saja
+		var highlights map[string][]string
+		for field, frags := range h.Fragments {
+			for _, f := range frags {
+				if strings.Contains(f, markOpen) {
+					if highlights == nil {
+						highlights = map[string][]string{}
+					}
+					highlights[field] = append(highlights[field], f)
+				}
+			}
+		}
+		hits = append(hits, Hit{
+			ID:         uint(id),
+			Score:      h.Score,
+			Fields:     fields,
+			Highlights: highlights,
+		})
+	}
+	return hits, nil
+}
+
+func docID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
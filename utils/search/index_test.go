@@ -0,0 +1,146 @@
+package search
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestIndex(t *testing.T) *Index {
+	t.Helper()
+	idx, err := Open(filepath.Join(t.TempDir(), "idx"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { idx.Close() })
+	return idx
+}
+
+func hitIDs(hits []Hit) []uint {
+	ids := make([]uint, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	return ids
+}
+
+func TestSearchHighlightEscapesLetterText(t *testing.T) {
+	idx := openTestIndex(t)
+	if err := idx.Upsert(1, LetterDocument{
+		JudulSurat: `Undangan rapat <script>alert("x")</script> & evaluasi`,
+		JenisSurat: "keluar",
+	}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	hits, err := idx.Search(Query{Text: "rapat", MaxResult: 10})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("hits = %v, want 1", hitIDs(hits))
+	}
+	frags := hits[0].Highlights["judul_surat"]
+	if len(frags) == 0 {
+		t.Fatalf("no highlight for judul_surat: %v", hits[0].Highlights)
+	}
+	for _, f := range frags {
+		if strings.Contains(f, "<script>") {
+			t.Errorf("fragment not escaped: %q", f)
+		}
+		if !strings.Contains(f, "<mark>rapat</mark>") || !strings.Contains(f, "&lt;script&gt;") || !strings.Contains(f, "&amp;") {
+			t.Errorf("fragment = %q, want escaped text with <mark>", f)
+		}
+	}
+	// Field lain yang tidak cocok tidak ikut dikembalikan
+	if len(hits[0].Highlights) != 1 {
+		t.Errorf("highlights = %v, want only judul_surat", hits[0].Highlights)
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	idx := openTestIndex(t)
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	docs := map[uint]LetterDocument{
+		1: {JudulSurat: "Laporan keuangan", JenisSurat: "keluar", Status: "draft", Tanggal: base},
+		2: {JudulSurat: "Laporan kegiatan", JenisSurat: "masuk", Status: "draft", Tanggal: base.AddDate(0, 0, 10)},
+		3: {JudulSurat: "Laporan tahunan", JenisSurat: "keluar", Status: "disetujui", Tanggal: base.AddDate(0, 1, 0)},
+	}
+	for id, doc := range docs {
+		if err := idx.Upsert(id, doc); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+
+	to := base.AddDate(0, 0, 15)
+	tests := []struct {
+		name string
+		q    Query
+		want []uint
+	}{
+		{"jenis", Query{Text: "laporan", Filters: map[string]string{"jenis_surat": "keluar"}}, []uint{1, 3}},
+		{"jenis+status", Query{Filters: map[string]string{"jenis_surat": "keluar", "status": "draft"}}, []uint{1}},
+		{"tanggal", Query{DateFrom: &base, DateTo: &to}, []uint{2, 1}},
+		// Tanpa teks, hasil diurutkan dari tanggal terbaru
+		{"semua", Query{Filters: map[string]string{"status": ""}}, []uint{3, 2, 1}},
+	}
+	for _, tt := range tests {
+		tt.q.MaxResult = 10
+		hits, err := idx.Search(tt.q)
+		if err != nil {
+			t.Fatalf("%s: Search: %v", tt.name, err)
+		}
+		got := hitIDs(hits)
+		if tt.q.Text != "" {
+			// Urutan hasil teks mengikuti skor, jadi urutkan per ID
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: hits = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: hits = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSearchFromReturnsDisjointBatches(t *testing.T) {
+	idx := openTestIndex(t)
+	const total = 25
+	for id := uint(1); id <= total; id++ {
+		// Skor semua dokumen sama; urutan tetap stabil lewat _id
+		if err := idx.Upsert(id, LetterDocument{JudulSurat: "Nota dinas", Status: "draft"}); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+
+	for _, text := range []string{"nota", ""} {
+		seen := map[uint]bool{}
+		q := Query{Text: text, MaxResult: 10}
+		for {
+			hits, err := idx.Search(q)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			for _, h := range hits {
+				if seen[h.ID] {
+					t.Fatalf("text=%q: hit %d returned in two batches", text, h.ID)
+				}
+				seen[h.ID] = true
+			}
+			if len(hits) < q.MaxResult {
+				break
+			}
+			q.From += len(hits)
+		}
+		if len(seen) != total {
+			t.Fatalf("text=%q: paged through %d hits, want %d", text, len(seen), total)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"mime/multipart"
 	"time"
@@ -67,13 +69,77 @@ func UploadFile(ctx context.Context, fileHeader *multipart.FileHeader, key strin
 	return key, nil
 }
 
+// UploadBytes mengunggah konten yang dihasilkan server (misal PDF bertanda tangan) ke S3
+func UploadBytes(ctx context.Context, data []byte, key, contentType string) (string, error) {
+	uploader := manager.NewUploader(s3Client)
+
+	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s3Cfg.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file to S3: %w", err)
+	}
+
+	return key, nil
+}
+
+// DownloadFile mengambil seluruh isi objek dari S3
+func DownloadFile(ctx context.Context, key string) ([]byte, error) {
+	out, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s3Cfg.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download S3 object %s: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read S3 object %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Object adalah objek S3 yang dibuka untuk di-stream. Body wajib ditutup pemanggil.
+type Object struct {
+	Body          io.ReadCloser
+	ContentType   string
+	ContentLength int64
+}
+
+// OpenFile membuka objek S3 tanpa membaca seluruh isinya ke memori
+func OpenFile(ctx context.Context, key string) (*Object, error) {
+	out, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s3Cfg.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open S3 object %s: %w", key, err)
+	}
+
+	return &Object{
+		Body:          out.Body,
+		ContentType:   aws.ToString(out.ContentType),
+		ContentLength: aws.ToInt64(out.ContentLength),
+	}, nil
+}
+
 // GetPresignedURL membuat URL berbatas waktu (Presigned URL) untuk mengakses file
 func GetPresignedURL(key string) (string, error) {
 	// URL berlaku selama 15 menit
+	return GetPresignedURLWithExpiry(key, 15*time.Minute)
+}
+
+// GetPresignedURLWithExpiry sama seperti GetPresignedURL dengan masa berlaku custom
+func GetPresignedURLWithExpiry(key string, expiry time.Duration) (string, error) {
 	req, err := presignClient.PresignGetObject(context.TODO(), &s3.GetObjectInput{
 		Bucket: aws.String(s3Cfg.Bucket),
 		Key:    aws.String(key),
-	}, s3.WithPresignExpires(15*time.Minute))
+	}, s3.WithPresignExpires(expiry))
 
 	if err != nil {
 		return "", fmt.Errorf("failed to presign URL: %w", err)
@@ -0,0 +1,91 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Secret ASCII "12345678901234567890" dari RFC 6238 Appendix B
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeMatchesRFC6238Vectors(t *testing.T) {
+	// RFC memakai 8 digit; 6 digit terakhir adalah kode 6 digit yang sama
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		got, err := Code(rfcSecret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("Code(%d) error: %v", unix, err)
+		}
+		if got != want {
+			t.Errorf("Code(%d) = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateAcceptsAdjacentStepWithinSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	previous, _ := Code(rfcSecret, now.Add(-Period*time.Second))
+
+	step, ok := Validate(rfcSecret, previous, now, 1)
+	if !ok {
+		t.Fatal("expected code from previous step to be accepted")
+	}
+	if step != Step(now)-1 {
+		t.Errorf("step = %d, want %d", step, Step(now)-1)
+	}
+
+	if _, ok := Validate(rfcSecret, previous, now, 0); ok {
+		t.Error("expected code from previous step to be rejected without skew")
+	}
+}
+
+func TestValidateRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now, 1); ok {
+			t.Errorf("Validate(%q) unexpectedly succeeded", code)
+		}
+	}
+	if _, ok := Validate("not base32!", "287082", now, 1); ok {
+		t.Error("Validate with invalid secret unexpectedly succeeded")
+	}
+}
+
+func TestGenerateSecretRoundTrip(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Fatalf("secret length = %d, want 32", len(secret))
+	}
+
+	now := time.Now()
+	code, err := Code(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Validate(secret, code, now, 0); !ok {
+		t.Error("freshly generated code was rejected")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Digital Mail", "direktur@example.com", rfcSecret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Digital%20Mail:direktur@example.com?") {
+		t.Errorf("unexpected label in %s", uri)
+	}
+	for _, part := range []string{"secret=" + rfcSecret, "issuer=Digital+Mail", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("uri %s missing %s", uri, part)
+		}
+	}
+}